@@ -0,0 +1,144 @@
+package main
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/jmcgover/zfs_exporter/v2/zfs"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// adaptiveSmoothing is the weight of the latest command duration in the moving average
+const adaptiveSmoothing = 0.2
+
+var backgroundIntervalDesc = prometheus.NewDesc(
+	`zfs_exporter_background_interval_seconds`,
+	`Current interval of background collection by task, stretched from the configured interval while ZFS is under load.`,
+	[]string{`task`},
+	nil,
+)
+
+// adaptiveIntervals stretches the intervals of background collection while ZFS is under load, as indicated by slow
+// commands or a resilver in progress, so that the exporter does not add to the load it is observing. It wraps the
+// runner in order to time every command.
+type adaptiveIntervals struct {
+	zfs.Runner
+	logger *slog.Logger
+	// status reports the state of the pools, from which resilvers are detected
+	status func() (map[string]zfs.PoolStatusT, error)
+	// maxFactor bounds the factor by which intervals are stretched
+	maxFactor float64
+	// slow is the average command duration beyond which intervals are stretched in proportion
+	slow time.Duration
+
+	mu      sync.Mutex
+	average time.Duration
+	factor  float64
+	tasks   map[string]time.Duration
+}
+
+func newAdaptiveIntervals(logger *slog.Logger, runner zfs.Runner, maxFactor float64, slow time.Duration) *adaptiveIntervals {
+	return &adaptiveIntervals{
+		Runner:    runner,
+		logger:    logger,
+		maxFactor: maxFactor,
+		slow:      slow,
+		factor:    1,
+		tasks:     make(map[string]time.Duration),
+	}
+}
+
+// Run implements the zfs.Runner interface, recording the duration of the command
+func (a *adaptiveIntervals) Run(ctx context.Context, name string, args ...string) ([]byte, []byte, error) {
+	begin := time.Now()
+	stdout, stderr, err := a.Runner.Run(ctx, name, args...)
+	a.record(time.Since(begin))
+
+	return stdout, stderr, err
+}
+
+// Stream implements the zfs.StreamRunner interface, recording the duration of the command
+func (a *adaptiveIntervals) Stream(ctx context.Context, read func(stdout io.Reader) error, name string, args ...string) ([]byte, error) {
+	begin := time.Now()
+	stderr, err := zfs.RunStream(ctx, a.Runner, read, name, args...)
+	a.record(time.Since(begin))
+
+	return stderr, err
+}
+
+// record adds the duration of a command to the moving average
+func (a *adaptiveIntervals) record(duration time.Duration) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.average == 0 {
+		a.average = duration
+	} else {
+		a.average = time.Duration(adaptiveSmoothing*float64(duration) + (1-adaptiveSmoothing)*float64(a.average))
+	}
+}
+
+// interval returns the interval of the task, to be called before each run so that the latest load is reflected
+func (a *adaptiveIntervals) interval(task string, base time.Duration) func() time.Duration {
+	a.mu.Lock()
+	a.tasks[task] = base
+	a.mu.Unlock()
+
+	return func() time.Duration {
+		return time.Duration(float64(base) * a.update())
+	}
+}
+
+// update recalculates the factor by which intervals are stretched
+func (a *adaptiveIntervals) update() float64 {
+	if a.maxFactor <= 1 {
+		return 1
+	}
+
+	resilvering := false
+	if a.status != nil {
+		pools, err := a.status()
+		if err != nil {
+			a.logger.Debug("Error getting pool status for adaptive intervals", "err", err)
+		}
+		for _, pool := range pools {
+			if pool.ScanStats.Function == `RESILVER` && pool.ScanStats.State == `SCANNING` {
+				resilvering = true
+				break
+			}
+		}
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	factor := 1.0
+	if a.slow > 0 && a.average > a.slow {
+		factor = float64(a.average) / float64(a.slow)
+	}
+	if resilvering {
+		factor = a.maxFactor
+	}
+	factor = min(factor, a.maxFactor)
+	if (factor > 1) != (a.factor > 1) {
+		a.logger.Info("Adjusted background collection intervals", "factor", factor, "average_command_duration", a.average, "resilvering", resilvering)
+	}
+	a.factor = factor
+
+	return factor
+}
+
+// Describe implements the prometheus.Collector interface
+func (a *adaptiveIntervals) Describe(ch chan<- *prometheus.Desc) {
+	ch <- backgroundIntervalDesc
+}
+
+// Collect implements the prometheus.Collector interface
+func (a *adaptiveIntervals) Collect(ch chan<- prometheus.Metric) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for task, base := range a.tasks {
+		ch <- prometheus.MustNewConstMetric(backgroundIntervalDesc, prometheus.GaugeValue, (time.Duration(float64(base) * a.factor)).Seconds(), task)
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jmcgover/zfs_exporter/v2/zfs"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestAdaptiveIntervals(t *testing.T) {
+	scan := zfs.ScanStatsT{Function: `SCRUB`, State: `FINISHED`}
+	a := newAdaptiveIntervals(discardLogger, stubRunner{}, 4, 10*time.Second)
+	a.status = func() (map[string]zfs.PoolStatusT, error) {
+		return map[string]zfs.PoolStatusT{`tank`: {Name: `tank`, ScanStats: scan}}, nil
+	}
+	events := a.interval(`events`, 30*time.Second)
+
+	if _, _, err := a.Run(context.Background(), `zpool`, `status`); err != nil {
+		t.Fatal(err)
+	}
+	if interval := events(); interval != 30*time.Second {
+		t.Errorf("Expected the configured interval while idle, got %s", interval)
+	}
+
+	// Slow commands stretch the interval in proportion, up to the maximum
+	a.average = 20 * time.Second
+	if interval := events(); interval != time.Minute {
+		t.Errorf("Expected the interval to double, got %s", interval)
+	}
+	a.average = 10 * time.Minute
+	if interval := events(); interval != 2*time.Minute {
+		t.Errorf("Expected the maximum interval, got %s", interval)
+	}
+
+	// A resilver stretches the interval to the maximum
+	a.average = time.Second
+	scan = zfs.ScanStatsT{Function: `RESILVER`, State: `SCANNING`}
+	if interval := events(); interval != 2*time.Minute {
+		t.Errorf("Expected the maximum interval while resilvering, got %s", interval)
+	}
+
+	const expected = `# HELP zfs_exporter_background_interval_seconds Current interval of background collection by task, stretched from the configured interval while ZFS is under load.
+# TYPE zfs_exporter_background_interval_seconds gauge
+zfs_exporter_background_interval_seconds{task="events"} 120
+`
+	if err := testutil.CollectAndCompare(a, strings.NewReader(expected)); err != nil {
+		t.Error(err)
+	}
+
+	disabled := newAdaptiveIntervals(discardLogger, stubRunner{}, 1, 10*time.Second)
+	disabled.average = time.Hour
+	if interval := disabled.interval(`history`, 5*time.Minute)(); interval != 5*time.Minute {
+		t.Errorf("Expected the configured interval when disabled, got %s", interval)
+	}
+}
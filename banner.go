@@ -0,0 +1,57 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"sort"
+
+	"github.com/alecthomas/kingpin/v2"
+	"github.com/jmcgover/zfs_exporter/v2/zfs"
+	"github.com/prometheus/common/version"
+)
+
+// startupSummary describes the running exporter, logged once at startup so that logs attached to bug reports are
+// self-describing
+type startupSummary struct {
+	zfsVersion      zfs.ZFSVersionT
+	capabilities    zfs.Capabilities
+	collectors      []string
+	pools           []string
+	configHash      string
+	listenAddresses []string
+	systemdSocket   bool
+}
+
+func (s startupSummary) LogValue() slog.Value {
+	pools := s.pools
+	if len(pools) == 0 {
+		pools = []string{`(all)`}
+	}
+	return slog.GroupValue(
+		slog.String("version", version.Info()),
+		slog.String("zfs_userland", s.zfsVersion.Userland),
+		slog.String("zfs_kernel", s.zfsVersion.Kernel),
+		slog.Any("capabilities", s.capabilities),
+		slog.Any("collectors", s.collectors),
+		slog.Any("pools", pools),
+		slog.String("config_hash", s.configHash),
+		slog.Any("listen_addresses", s.listenAddresses),
+		slog.Bool("systemd_socket", s.systemdSocket),
+	)
+}
+
+// configHash returns a short digest of the effective value of every flag, after any configuration file has been
+// applied, so that differing configurations can be spotted at a glance.
+func configHash(app *kingpin.Application) string {
+	flags := app.Model().Flags
+	sort.Slice(flags, func(i, j int) bool { return flags[i].Name < flags[j].Name })
+
+	h := sha256.New()
+	for _, flag := range flags {
+		fmt.Fprintf(h, "%s=%s\n", flag.Name, flag.Value.String())
+	}
+
+	return hex.EncodeToString(h.Sum(nil))[:12]
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/alecthomas/kingpin/v2"
+	"github.com/jmcgover/zfs_exporter/v2/zfs"
+)
+
+func TestConfigHash(t *testing.T) {
+	app := kingpin.New(`test`, ``)
+	app.Flag(`deadline`, ``).Default(`8s`).Duration()
+	pools := app.Flag(`pool`, ``).Strings()
+	if _, err := app.Parse(nil); err != nil {
+		t.Fatal(err)
+	}
+
+	before := configHash(app)
+	if again := configHash(app); again != before {
+		t.Errorf("Expected stable hash %s, got %s", before, again)
+	}
+	*pools = []string{`tank`}
+	if after := configHash(app); after == before {
+		t.Errorf("Expected hash to change with flag values, got %s", after)
+	}
+}
+
+func TestStartupSummary(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, nil))
+	logger.Info(`Starting zfs_exporter`, `summary`, startupSummary{
+		zfsVersion:      zfs.ZFSVersionT{Userland: `zfs-2.3.1-1`, Kernel: `zfs-kmod-2.3.1-1`},
+		capabilities:    zfs.Capabilities{zfs.CapabilityJSON: true},
+		collectors:      []string{`dataset-filesystem`, `pool`},
+		configHash:      `0123456789ab`,
+		listenAddresses: []string{`:9134`},
+	})
+
+	for _, want := range []string{
+		`summary.zfs_userland=zfs-2.3.1-1`,
+		`summary.zfs_kernel=zfs-kmod-2.3.1-1`,
+		`summary.capabilities.json=true`,
+		`summary.collectors="[dataset-filesystem pool]"`,
+		`summary.pools=[(all)]`,
+		`summary.config_hash=0123456789ab`,
+		`summary.listen_addresses=[:9134]`,
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("Expected log to contain %q, got %s", want, buf.String())
+		}
+	}
+}
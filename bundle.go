@@ -0,0 +1,250 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alecthomas/kingpin/v2"
+	"github.com/jmcgover/zfs_exporter/v2/zfs"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/expfmt"
+)
+
+const redacted = `<redacted>`
+
+// sensitiveFlagRe matches the names of flags whose values are omitted from support bundles. The web configuration
+// file, which holds TLS keys and credentials, is never included.
+var sensitiveFlagRe = regexp.MustCompile(`(?i)password|secret|token|credential`)
+
+// recordedCommand is the output of a command run while collecting a support bundle
+type recordedCommand struct {
+	Args   []string
+	Stdout []byte
+	Stderr []byte
+	Err    error
+}
+
+// recordingRunner records the output of every command run, so that the raw output may be included in a support bundle
+type recordingRunner struct {
+	zfs.Runner
+	mu       sync.Mutex
+	commands []recordedCommand
+}
+
+func (r *recordingRunner) Run(ctx context.Context, name string, args ...string) ([]byte, []byte, error) {
+	stdout, stderr, err := r.Runner.Run(ctx, name, args...)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.commands = append(r.commands, recordedCommand{
+		Args:   append([]string{name}, args...),
+		Stdout: stdout,
+		Stderr: stderr,
+		Err:    err,
+	})
+
+	return stdout, stderr, err
+}
+
+func (r *recordingRunner) recorded() []recordedCommand {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]recordedCommand(nil), r.commands...)
+}
+
+// teeHandler passes log records to each of its handlers that is enabled for the level
+type teeHandler []slog.Handler
+
+func (h teeHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	for _, handler := range h {
+		if handler.Enabled(ctx, level) {
+			return true
+		}
+	}
+	return false
+}
+
+func (h teeHandler) Handle(ctx context.Context, r slog.Record) error {
+	var errs []error
+	for _, handler := range h {
+		if handler.Enabled(ctx, r.Level) {
+			errs = append(errs, handler.Handle(ctx, r.Clone()))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (h teeHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	result := make(teeHandler, len(h))
+	for i, handler := range h {
+		result[i] = handler.WithAttrs(attrs)
+	}
+	return result
+}
+
+func (h teeHandler) WithGroup(name string) slog.Handler {
+	result := make(teeHandler, len(h))
+	for i, handler := range h {
+		result[i] = handler.WithGroup(name)
+	}
+	return result
+}
+
+// bundleContents holds the diagnostics included in a support bundle
+type bundleContents struct {
+	version      *zfs.ZFSVersionT
+	capabilities zfs.Capabilities
+	status       map[string]zfs.PoolStatusT
+	gatherer     prometheus.Gatherer
+	recorder     *recordingRunner
+	flags        []string
+	configFile   string
+	kstatPath    string
+	// log is read once the bundle has otherwise been written, so that it includes messages logged while gathering
+	log *bytes.Buffer
+}
+
+// bundleFlags returns the value of every flag as name=value, omitting the values of sensitive flags
+func bundleFlags(app *kingpin.Application) []string {
+	var result []string
+	for _, flag := range app.Model().Flags {
+		value := flag.String()
+		if sensitiveFlagRe.MatchString(flag.Name) {
+			value = redacted
+		}
+		result = append(result, flag.Name+`=`+value)
+	}
+	sort.Strings(result)
+
+	return result
+}
+
+// writeBundle writes a gzipped tarball of the bundle contents to w, with every file under a directory named for the
+// time of collection
+func writeBundle(w io.Writer, b bundleContents, now time.Time) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	dir := `zfs_exporter-bundle-` + now.UTC().Format(`20060102T150405Z`)
+	add := func(name string, data []byte) error {
+		if err := tw.WriteHeader(&tar.Header{
+			Name:    dir + `/` + name,
+			Mode:    0o644,
+			Size:    int64(len(data)),
+			ModTime: now,
+		}); err != nil {
+			return err
+		}
+		_, err := tw.Write(data)
+		return err
+	}
+	addJSON := func(name string, v any) error {
+		data, err := json.MarshalIndent(v, ``, `  `)
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		return add(name, append(data, '\n'))
+	}
+
+	if err := addJSON(`version.json`, struct {
+		ZFS          *zfs.ZFSVersionT `json:"zfs"`
+		Capabilities zfs.Capabilities `json:"capabilities"`
+	}{b.version, b.capabilities}); err != nil {
+		return err
+	}
+	if err := addJSON(`status.json`, b.status); err != nil {
+		return err
+	}
+	if err := add(`flags.txt`, []byte(strings.Join(b.flags, "\n")+"\n")); err != nil {
+		return err
+	}
+	if b.configFile != `` {
+		data, err := os.ReadFile(b.configFile)
+		if err != nil {
+			data = []byte(err.Error())
+		}
+		if err = add(`config.yml`, data); err != nil {
+			return err
+		}
+	}
+	if data, err := os.ReadFile(filepath.Join(b.kstatPath, `arcstats`)); err == nil {
+		if err = add(`kstat/arcstats`, data); err != nil {
+			return err
+		}
+	}
+
+	if b.gatherer != nil {
+		var metrics bytes.Buffer
+		mfs, err := b.gatherer.Gather()
+		if err != nil {
+			fmt.Fprintf(&metrics, "# Error gathering metrics: %s\n", err)
+		}
+		enc := expfmt.NewEncoder(&metrics, expfmt.NewFormat(expfmt.TypeTextPlain))
+		for _, mf := range mfs {
+			if err = enc.Encode(mf); err != nil {
+				return err
+			}
+		}
+		if err = add(`metrics.prom`, metrics.Bytes()); err != nil {
+			return err
+		}
+	}
+
+	var index bytes.Buffer
+	for i, cmd := range b.recorder.recorded() {
+		name := fmt.Sprintf("commands/%03d", i)
+		fmt.Fprintf(&index, "%03d: %s", i, strings.Join(cmd.Args, ` `))
+		if cmd.Err != nil {
+			fmt.Fprintf(&index, " (error: %s)", cmd.Err)
+		}
+		index.WriteByte('\n')
+		if err := add(name+`.stdout`, cmd.Stdout); err != nil {
+			return err
+		}
+		if err := add(name+`.stderr`, cmd.Stderr); err != nil {
+			return err
+		}
+	}
+	if err := add(`commands/index.txt`, index.Bytes()); err != nil {
+		return err
+	}
+
+	if err := add(`bundle.log`, b.log.Bytes()); err != nil {
+		return err
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// writeBundleFile writes the bundle to path, or to a file named for the time of collection if path is empty, and
+// returns the path written
+func writeBundleFile(path string, b bundleContents, now time.Time) (string, error) {
+	if path == `` {
+		path = `zfs_exporter-bundle-` + now.UTC().Format(`20060102T150405Z`) + `.tar.gz`
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return ``, err
+	}
+	if err = writeBundle(f, b, now); err != nil {
+		_ = f.Close()
+		return ``, err
+	}
+
+	return path, f.Close()
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alecthomas/kingpin/v2"
+	"github.com/jmcgover/zfs_exporter/v2/zfs"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// stubRunner returns fixed output for every command, failing those named in failures
+type stubRunner struct {
+	failures map[string]bool
+}
+
+func (r stubRunner) Run(ctx context.Context, name string, args ...string) ([]byte, []byte, error) {
+	cmd := strings.Join(append([]string{name}, args...), ` `)
+	if r.failures[cmd] {
+		return nil, []byte(`cannot open 'missing': no such pool`), errors.New(`exit status 1`)
+	}
+	return []byte(`output of ` + cmd), nil, nil
+}
+
+func readBundle(t *testing.T, r io.Reader) map[string]string {
+	t.Helper()
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := make(map[string]string)
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[hdr.Name] = string(data)
+	}
+
+	return files
+}
+
+func TestWriteBundle(t *testing.T) {
+	kstatPath := t.TempDir()
+	if err := os.WriteFile(filepath.Join(kstatPath, `arcstats`), []byte(`hits 4 1234`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	app := kingpin.New(`test`, ``)
+	app.Flag(`pool`, ``).Default(`tank`).String()
+	app.Flag(`web.password`, ``).Default(`hunter2`).String()
+	if _, err := app.Parse(nil); err != nil {
+		t.Fatal(err)
+	}
+
+	recorder := &recordingRunner{Runner: stubRunner{failures: map[string]bool{`zpool status missing`: true}}}
+	for _, args := range [][]string{{`status`, `-p`}, {`status`, `missing`}} {
+		_, _, _ = recorder.Run(context.Background(), `zpool`, args...)
+	}
+
+	registry := prometheus.NewRegistry()
+	gauge := prometheus.NewGauge(prometheus.GaugeOpts{Name: `zfs_pool_health`, Help: `Health.`})
+	registry.MustRegister(gauge)
+
+	var buf bytes.Buffer
+	now := time.Date(2025, 7, 1, 12, 0, 0, 0, time.UTC)
+	err := writeBundle(&buf, bundleContents{
+		version:      &zfs.ZFSVersionT{Userland: `zfs-2.3.1-1`, Kernel: `zfs-kmod-2.3.1-1`},
+		capabilities: zfs.Capabilities{zfs.CapabilityJSON: true},
+		status:       map[string]zfs.PoolStatusT{`tank`: {Name: `tank`, State: `ONLINE`}},
+		gatherer:     registry,
+		recorder:     recorder,
+		flags:        bundleFlags(app),
+		kstatPath:    kstatPath,
+		log:          bytes.NewBufferString("level=INFO msg=test\n"),
+	}, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files := readBundle(t, &buf)
+	const dir = `zfs_exporter-bundle-20250701T120000Z/`
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, strings.TrimPrefix(name, dir))
+	}
+	sort.Strings(names)
+	want := []string{
+		`bundle.log`,
+		`commands/000.stderr`,
+		`commands/000.stdout`,
+		`commands/001.stderr`,
+		`commands/001.stdout`,
+		`commands/index.txt`,
+		`flags.txt`,
+		`kstat/arcstats`,
+		`metrics.prom`,
+		`status.json`,
+		`version.json`,
+	}
+	if strings.Join(names, ` `) != strings.Join(want, ` `) {
+		t.Fatalf("Expected files %v, got %v", want, names)
+	}
+
+	for name, substr := range map[string]string{
+		`commands/index.txt`:  "001: zpool status missing (error: exit status 1)\n",
+		`commands/000.stdout`: `output of zpool status -p`,
+		`flags.txt`:           "pool=tank\nweb.password=<redacted>\n",
+		`metrics.prom`:        `zfs_pool_health 0`,
+		`status.json`:         `"state": "ONLINE"`,
+		`version.json`:        `"json": true`,
+		`bundle.log`:          `msg=test`,
+	} {
+		if !strings.Contains(files[dir+name], substr) {
+			t.Errorf("Expected %s to contain %q, got %q", name, substr, files[dir+name])
+		}
+	}
+	if strings.Contains(files[dir+`flags.txt`], `hunter2`) {
+		t.Error("Expected sensitive flag value to be redacted")
+	}
+}
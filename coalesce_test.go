@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jmcgover/zfs_exporter/v2/collector"
+	"github.com/jmcgover/zfs_exporter/v2/zfs"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// commandCounter returns fixed output per command, counting the commands run
+type commandCounter struct {
+	output map[string][]byte
+	mu     sync.Mutex
+	calls  map[string]int
+}
+
+func (r *commandCounter) Run(ctx context.Context, name string, args ...string) ([]byte, []byte, error) {
+	cmd := strings.Join(append([]string{name}, args...), ` `)
+	r.mu.Lock()
+	r.calls[cmd]++
+	r.mu.Unlock()
+	if out, ok := r.output[cmd]; ok {
+		return out, nil, nil
+	}
+	return nil, nil, errors.New(`exit status 1`)
+}
+
+func TestRequestCoalescing(t *testing.T) {
+	status, err := os.ReadFile(filepath.Join(`zfs`, `testdata`, `zpool_status.json`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	runner := &commandCounter{
+		output: map[string][]byte{
+			`zpool list -Ho name`:            []byte("tank\n"),
+			`zpool status --json --json-int`: status,
+		},
+		calls: make(map[string]int),
+	}
+	// As in main, the metrics and the API endpoints share a single client
+	client := zfs.New(zfs.Config{Logger: discardLogger, Runner: runner, CacheTTL: time.Minute})
+	c, err := collector.NewZFS(collector.ZFSConfig{Deadline: time.Minute, Logger: discardLogger, ZFSClient: client})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, state := range c.Collectors {
+		enabled := name == `status`
+		state.Enabled = &enabled
+		c.Collectors[name] = state
+	}
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(c)
+
+	mux := http.NewServeMux()
+	mux.Handle(`/metrics`, promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
+	mux.Handle(`/api/v1/resolve`, resolveHandler{logger: discardLogger, client: client})
+	mux.Handle(`/api/v1/metrics-metadata`, metadataHandler{logger: discardLogger, gatherer: registry, origins: c.MetricOrigins})
+	targets := []string{`/metrics`, `/api/v1/resolve?guid=8148409446217839142`, `/api/v1/metrics-metadata`}
+
+	start := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := range 30 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			target := targets[i%len(targets)]
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+			if rec.Code != http.StatusOK {
+				t.Errorf("Expected status %d for %s, got %d", http.StatusOK, target, rec.Code)
+			}
+		}()
+	}
+	close(start)
+	wg.Wait()
+
+	for cmd, calls := range runner.calls {
+		if calls != 1 {
+			t.Errorf("Expected %s to run once within the TTL, ran %d times", cmd, calls)
+		}
+	}
+	if runner.calls[`zpool status --json --json-int`] != 1 {
+		t.Errorf("Expected zpool status to run once, got %v", runner.calls)
+	}
+}
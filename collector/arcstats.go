@@ -0,0 +1,132 @@
+package collector
+
+import (
+	"log/slog"
+
+	"github.com/jmcgover/zfs_exporter/v2/zfs"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	defaultArcstatsProps = `c,c_max,c_min,hits,memory_throttle_count,mfu_size,misses,mru_size,size`
+)
+
+var arcstatsProperties = propertyStore{
+	defaultSubsystem: subsystemArc,
+	store: map[string]property{
+		`c`: newProperty(
+			subsystemArc,
+			`target_size_bytes`,
+			`The size in bytes the ARC is currently targeting.`,
+			transformNumeric,
+			prometheus.GaugeValue,
+		),
+		`c_max`: newProperty(
+			subsystemArc,
+			`max_size_bytes`,
+			`The maximum size in bytes the ARC may grow to.`,
+			transformNumeric,
+			prometheus.GaugeValue,
+		),
+		`c_min`: newProperty(
+			subsystemArc,
+			`min_size_bytes`,
+			`The minimum size in bytes the ARC will shrink to.`,
+			transformNumeric,
+			prometheus.GaugeValue,
+		),
+		`hits`: newProperty(
+			subsystemArc,
+			`hits_total`,
+			`The number of requests satisfied from the ARC.`,
+			transformNumeric,
+			prometheus.CounterValue,
+		),
+		`memory_throttle_count`: newProperty(
+			subsystemArc,
+			`memory_throttle_total`,
+			`The number of times writes were throttled due to memory pressure.`,
+			transformNumeric,
+			prometheus.CounterValue,
+		),
+		`mfu_size`: newProperty(
+			subsystemArc,
+			`mfu_size_bytes`,
+			`The size in bytes of the most frequently used portion of the ARC.`,
+			transformNumeric,
+			prometheus.GaugeValue,
+		),
+		`misses`: newProperty(
+			subsystemArc,
+			`misses_total`,
+			`The number of requests that could not be satisfied from the ARC.`,
+			transformNumeric,
+			prometheus.CounterValue,
+		),
+		`mru_size`: newProperty(
+			subsystemArc,
+			`mru_size_bytes`,
+			`The size in bytes of the most recently used portion of the ARC.`,
+			transformNumeric,
+			prometheus.GaugeValue,
+		),
+		`size`: newProperty(
+			subsystemArc,
+			`size_bytes`,
+			`The current size in bytes of the ARC.`,
+			transformNumeric,
+			prometheus.GaugeValue,
+		),
+	},
+}
+
+func init() {
+	registerCollector(`arcstats`, defaultDisabled, defaultArcstatsProps, newArcstatsCollector)
+}
+
+// arcstatsCollector exports statistics from the arcstats kstat, which is shared by the arcstats and l2arc collectors
+type arcstatsCollector struct {
+	log    *slog.Logger
+	client zfs.Client
+	props  []string
+	name   string
+	store  propertyStore
+}
+
+func (c *arcstatsCollector) describe(ch chan<- *prometheus.Desc) {
+	for _, k := range c.props {
+		prop, err := c.store.find(k)
+		if err != nil {
+			c.log.Warn(propertyUnsupportedMsg, `help`, helpIssue, `collector`, c.name, `property`, k, `err`, err)
+		}
+		ch <- prop.desc
+	}
+}
+
+func (c *arcstatsCollector) update(ch chan<- metric, pools []string, excludes regexpCollection) error {
+	stats, err := c.client.ArcStats()
+	if err != nil {
+		return err
+	}
+
+	for _, k := range c.props {
+		v, ok := stats[k]
+		if !ok {
+			c.log.Warn("Property missing from arcstats", `collector`, c.name, `property`, k)
+			continue
+		}
+		prop, err := c.store.find(k)
+		if err != nil {
+			c.log.Warn(propertyUnsupportedMsg, `help`, helpIssue, `collector`, c.name, `property`, k, `err`, err)
+		}
+		if err = prop.push(ch, v); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func newArcstatsCollector(l *slog.Logger, c zfs.Client, props []string) (Collector, error) {
+	return &arcstatsCollector{log: l, client: c, props: props, name: `arcstats`, store: arcstatsProperties}, nil
+}
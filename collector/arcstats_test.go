@@ -0,0 +1,52 @@
+package collector
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/jmcgover/zfs_exporter/v2/zfs/mock_zfs"
+	"go.uber.org/mock/gomock"
+)
+
+func TestArcstatsMetrics(t *testing.T) {
+	const result = `# HELP zfs_arc_hits_total The number of requests satisfied from the ARC.
+# TYPE zfs_arc_hits_total counter
+zfs_arc_hits_total 123456
+# HELP zfs_arc_max_size_bytes The maximum size in bytes the ARC may grow to.
+# TYPE zfs_arc_max_size_bytes gauge
+zfs_arc_max_size_bytes 8.589934592e+09
+# HELP zfs_arc_size_bytes The current size in bytes of the ARC.
+# TYPE zfs_arc_size_bytes gauge
+zfs_arc_size_bytes 4.294967296e+09
+`
+	propsRequested := []string{`hits`, `c_max`, `size`}
+
+	ctrl, ctx := gomock.WithContext(context.Background(), t)
+	zfsClient := mock_zfs.NewMockClient(ctrl)
+	zfsClient.EXPECT().PoolNames().Return([]string{`testpool`}, nil).Times(1)
+	zfsClient.EXPECT().ArcStats().Return(map[string]string{
+		`hits`:   `123456`,
+		`misses`: `654`,
+		`c_max`:  `8589934592`,
+		`size`:   `4294967296`,
+	}, nil).Times(1)
+
+	collector, err := NewZFS(defaultConfig(zfsClient))
+	if err != nil {
+		t.Fatal(err)
+	}
+	collector.Collectors = map[string]State{
+		`arcstats`: {
+			Name:       "arcstats",
+			Enabled:    boolPointer(true),
+			Properties: stringPointer(strings.Join(propsRequested, `,`)),
+			factory:    newArcstatsCollector,
+		},
+	}
+
+	metricNames := []string{`zfs_arc_hits_total`, `zfs_arc_max_size_bytes`, `zfs_arc_size_bytes`, `zfs_arc_misses_total`}
+	if err = callCollector(ctx, collector, []byte(result), metricNames); err != nil {
+		t.Fatal(err)
+	}
+}
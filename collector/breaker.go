@@ -0,0 +1,54 @@
+package collector
+
+import (
+	"sync"
+	"time"
+)
+
+// circuitBreaker tracks consecutive failures of a collector, opening once threshold is reached so that the collector
+// is skipped until cooldown has elapsed. A single trial collection is then allowed, which closes the circuit on
+// success or re-opens it on failure.
+type circuitBreaker struct {
+	threshold int
+	cooldown  time.Duration
+	failures  int
+	openUntil time.Time
+	now       func() time.Time
+	sync.Mutex
+}
+
+// allow reports whether the collector should be executed
+func (b *circuitBreaker) allow() bool {
+	b.Lock()
+	defer b.Unlock()
+	return !b.now().Before(b.openUntil)
+}
+
+// isOpen reports whether the collector is currently being skipped
+func (b *circuitBreaker) isOpen() bool {
+	return !b.allow()
+}
+
+// record the result of executing the collector, returning true if the circuit was opened as a result
+func (b *circuitBreaker) record(err error) bool {
+	b.Lock()
+	defer b.Unlock()
+	if err == nil {
+		b.failures = 0
+		return false
+	}
+	b.failures++
+	if b.threshold <= 0 || b.failures < b.threshold {
+		return false
+	}
+	b.openUntil = b.now().Add(b.cooldown)
+	return true
+}
+
+func newCircuitBreaker(threshold int, cooldown time.Duration) *circuitBreaker {
+	return &circuitBreaker{
+		threshold: threshold,
+		cooldown:  cooldown,
+		now:       time.Now,
+	}
+}
@@ -0,0 +1,106 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jmcgover/zfs_exporter/v2/zfs/mock_zfs"
+	"go.uber.org/mock/gomock"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	now := time.Unix(0, 0)
+	b := newCircuitBreaker(2, time.Minute)
+	b.now = func() time.Time { return now }
+	errFailed := errors.New(`failed`)
+
+	if b.record(errFailed) || !b.allow() {
+		t.Fatal(`Expected circuit to remain closed below threshold`)
+	}
+	if b.record(nil); b.record(errFailed) || !b.allow() {
+		t.Fatal(`Expected success to reset consecutive failures`)
+	}
+	if !b.record(errFailed) || b.allow() {
+		t.Fatal(`Expected circuit to open at threshold`)
+	}
+
+	now = now.Add(time.Minute)
+	if !b.allow() {
+		t.Fatal(`Expected trial collection after cooldown`)
+	}
+	if !b.record(errFailed) || b.allow() {
+		t.Fatal(`Expected failed trial to re-open circuit`)
+	}
+
+	now = now.Add(time.Minute)
+	if b.record(nil); !b.allow() || b.isOpen() {
+		t.Fatal(`Expected successful trial to close circuit`)
+	}
+}
+
+func TestCircuitBreakerDisabled(t *testing.T) {
+	b := newCircuitBreaker(0, time.Minute)
+	for range 10 {
+		if b.record(errors.New(`failed`)) {
+			t.Fatal(`Expected disabled circuit breaker never to open`)
+		}
+	}
+}
+
+func TestZFSCollectCircuitOpen(t *testing.T) {
+	const failed = `# HELP zfs_exporter_collector_circuit_open zfs_exporter: Whether a collector is being skipped after repeated failures.
+# TYPE zfs_exporter_collector_circuit_open gauge
+zfs_exporter_collector_circuit_open{collector="arcstats"} 0
+# HELP zfs_scrape_collector_success zfs_exporter: Whether a collector succeeded.
+# TYPE zfs_scrape_collector_success gauge
+zfs_scrape_collector_success{collector="arcstats"} 0
+`
+	const opened = `# HELP zfs_exporter_collector_circuit_open zfs_exporter: Whether a collector is being skipped after repeated failures.
+# TYPE zfs_exporter_collector_circuit_open gauge
+zfs_exporter_collector_circuit_open{collector="arcstats"} 1
+# HELP zfs_scrape_collector_success zfs_exporter: Whether a collector succeeded.
+# TYPE zfs_scrape_collector_success gauge
+zfs_scrape_collector_success{collector="arcstats"} 0
+`
+
+	ctrl, ctx := gomock.WithContext(context.Background(), t)
+	zfsClient := mock_zfs.NewMockClient(ctrl)
+	zfsClient.EXPECT().PoolNames().Return([]string{`testpool`}, nil).Times(3)
+	// The collector is not executed again once the circuit has opened.
+	zfsClient.EXPECT().ArcStats().Return(nil, errors.New(`arcstats unavailable`)).Times(2)
+
+	config := defaultConfig(zfsClient)
+	config.DisableMetrics = false
+	config.CircuitThreshold = 2
+	config.CircuitCooldown = time.Hour
+	collector, err := NewZFS(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	collector.Collectors = map[string]State{
+		`arcstats`: {
+			Name:       `arcstats`,
+			Enabled:    boolPointer(true),
+			Properties: stringPointer(`size`),
+			factory:    newArcstatsCollector,
+		},
+	}
+
+	metricNames := []string{`zfs_exporter_collector_circuit_open`, `zfs_scrape_collector_success`}
+	if err = callCollector(ctx, collector, []byte(failed), metricNames); err != nil {
+		t.Fatal(err)
+	}
+	if err = callCollector(ctx, collector, []byte(opened), metricNames); err != nil {
+		t.Fatal(err)
+	}
+
+	const skipped = `# HELP zfs_exporter_collector_circuit_open zfs_exporter: Whether a collector is being skipped after repeated failures.
+# TYPE zfs_exporter_collector_circuit_open gauge
+zfs_exporter_collector_circuit_open{collector="arcstats"} 1
+`
+	if err = callCollector(ctx, collector, []byte(skipped), metricNames); err != nil {
+		t.Fatal(err)
+	}
+}
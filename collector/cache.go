@@ -2,12 +2,15 @@ package collector
 
 import (
 	"sync"
+	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
 )
 
 type metricCache struct {
-	cache map[string]prometheus.Metric
+	cache      map[string]prometheus.Metric
+	generation uint64
+	updated    time.Time
 	sync.RWMutex
 }
 
@@ -30,12 +33,29 @@ func (c *metricCache) merge(other *metricCache) {
 	for name, value := range other.cache {
 		c.cache[name] = value
 	}
+	c.generation++
 }
 
 func (c *metricCache) replace(other *metricCache) {
 	c.Lock()
 	defer c.Unlock()
 	c.cache = other.cache
+	c.generation++
+	c.updated = time.Now()
+}
+
+// lastUpdated returns the completion time of the collection that last replaced the cache contents
+func (c *metricCache) lastUpdated() time.Time {
+	c.RLock()
+	defer c.RUnlock()
+	return c.updated
+}
+
+// gen returns a counter that is incremented whenever the cache contents change
+func (c *metricCache) gen() uint64 {
+	c.RLock()
+	defer c.RUnlock()
+	return c.generation
 }
 
 func (c *metricCache) index() map[string]struct{} {
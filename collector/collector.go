@@ -4,8 +4,10 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"runtime/debug"
 	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/alecthomas/kingpin/v2"
 	"github.com/jmcgover/zfs_exporter/v2/zfs"
@@ -16,11 +18,18 @@ const (
 	defaultEnabled           = true
 	defaultDisabled          = false
 	namespace                = `zfs`
+	exporterNamespace        = `zfs_exporter`
 	helpDefaultStateEnabled  = `enabled`
 	helpDefaultStateDisabled = `disabled`
 
+	subsystemArc     = `arc`
 	subsystemDataset = `dataset`
+	subsystemHost    = `host`
+	subsystemL2arc   = `l2arc`
 	subsystemPool    = `pool`
+	subsystemScan    = `scan`
+	subsystemSlog    = `slog`
+	subsystemVdev    = `vdev`
 
 	propertyUnsupportedDesc = `!!! This property is unsupported, results are likely to be undesirable, please file an issue at https://github.com/pdf/zfs_exporter/issues to have this property supported !!!`
 	propertyUnsupportedMsg  = `Unsupported dataset property, results are likely to be undesirable`
@@ -28,7 +37,41 @@ const (
 )
 
 var (
-	collectorStates        = make(map[string]State)
+	collectorStates = make(map[string]State)
+	// forcedCollectors records collectors whose state was set explicitly on the command line
+	forcedCollectors = make(map[string]bool)
+	// collectorConflicts maps collectors to those that export the same metrics, which may not be enabled together.
+	collectorConflicts = map[string][]string{
+		`pool-list`:       {`pool`},
+		`dataset-list`:    {`dataset-filesystem`, `dataset-volume`},
+		`dataset-program`: {`dataset-filesystem`, `dataset-volume`, `dataset-list`},
+	}
+	// kstatCollectors read kstats from the local filesystem rather than running commands, and are disabled for remote
+	// hosts. TestKstatCollectors checks this against the client methods each collector calls.
+	kstatCollectors = map[string]bool{
+		`arcstats`: true,
+		`l2arc`:    true,
+		`objset`:   true,
+		`txg`:      true,
+	}
+	// collectorRequirements maps collectors to the ZFS capabilities they depend upon, collectors are disabled where
+	// these are unsupported.
+	collectorRequirements = map[string][]zfs.Capability{
+		`pool-get`:         {zfs.CapabilityJSON, zfs.CapabilityJSONInt},
+		`dataset-list`:     {zfs.CapabilityJSON, zfs.CapabilityJSONInt},
+		`dataset-get`:      {zfs.CapabilityJSON, zfs.CapabilityJSONInt},
+		`encryption`:       {zfs.CapabilityJSON, zfs.CapabilityJSONInt},
+		`compression`:      {zfs.CapabilityJSON, zfs.CapabilityJSONInt},
+		`data-errors`:      {zfs.CapabilityJSON, zfs.CapabilityJSONInt},
+		`snapshot-summary`: {zfs.CapabilityJSON, zfs.CapabilityJSONInt},
+		`pool-creation`:    {zfs.CapabilityJSON, zfs.CapabilityJSONInt},
+		`health-policy`:    {zfs.CapabilityJSON},
+		`io`:               {zfs.CapabilityIostatJSON},
+		`latency`:          {zfs.CapabilityIostatLatency},
+		`vdev-io`:          {zfs.CapabilityIostatJSON, zfs.CapabilityIostatLatency},
+		`vdev-power`:       {zfs.CapabilityJSON, zfs.CapabilityJSONInt, zfs.CapabilityPowerStatus},
+		`slog`:             {zfs.CapabilityIostatJSON, zfs.CapabilityIostatLatency},
+	}
 	scrapeDurationDescName = prometheus.BuildFQName(namespace, `scrape`, `collector_duration_seconds`)
 	scrapeDurationDesc     = prometheus.NewDesc(
 		scrapeDurationDescName,
@@ -43,8 +86,38 @@ var (
 		[]string{`collector`},
 		nil,
 	)
+	// collectorDurationDesc and collectorSuccessDesc follow the naming of other exporters, and are sent alongside the
+	// zfs_scrape_collector_* metrics that existing dashboards and alerts rely on
+	collectorDurationDescName = prometheus.BuildFQName(exporterNamespace, `collector`, `duration_seconds`)
+	collectorDurationDesc     = prometheus.NewDesc(
+		collectorDurationDescName,
+		`zfs_exporter: Duration of the most recent run of a collector.`,
+		[]string{`collector`},
+		nil,
+	)
+	collectorSuccessDescName = prometheus.BuildFQName(exporterNamespace, `collector`, `success`)
+	collectorSuccessDesc     = prometheus.NewDesc(
+		collectorSuccessDescName,
+		`zfs_exporter: Whether the most recent run of a collector succeeded (1) or failed (0).`,
+		[]string{`collector`},
+		nil,
+	)
+	circuitOpenDescName = prometheus.BuildFQName(exporterNamespace, `collector`, `circuit_open`)
+	circuitOpenDesc     = prometheus.NewDesc(
+		circuitOpenDescName,
+		`zfs_exporter: Whether a collector is being skipped after repeated failures.`,
+		[]string{`collector`},
+		nil,
+	)
+	dataAgeDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(exporterNamespace, ``, `data_age_seconds`),
+		`zfs_exporter: Age of the data returned by the most recent scrape, non-zero when cached data was served.`,
+		nil,
+		nil,
+	)
 
 	errUnsupportedProperty = errors.New(`unsupported property`)
+	errUnknownCollector    = errors.New(`unknown collector`)
 )
 
 type factoryFunc func(l *slog.Logger, c zfs.Client, properties []string) (Collector, error)
@@ -59,6 +132,17 @@ type State struct {
 	factory    factoryFunc
 }
 
+// properties returns the configured property names, ignoring empty entries
+func (s State) properties() []string {
+	props := make([]string, 0)
+	for _, prop := range strings.Split(*s.Properties, `,`) {
+		if prop != `` {
+			props = append(props, prop)
+		}
+	}
+	return props
+}
+
 // Collector defines the minimum functionality for registering a collector
 type Collector interface {
 	update(ch chan<- metric, pools []string, excludes regexpCollection) error
@@ -82,6 +166,12 @@ func (p property) push(ch chan<- metric, value string, labelValues ...string) er
 	if err != nil {
 		return err
 	}
+	p.send(ch, v, labelValues...)
+
+	return nil
+}
+
+func (p property) send(ch chan<- metric, v float64, labelValues ...string) {
 	ch <- metric{
 		name: expandMetricName(p.name, labelValues...),
 		prometheus: prometheus.MustNewConstMetric(
@@ -91,8 +181,6 @@ func (p property) push(ch chan<- metric, value string, labelValues ...string) er
 			labelValues...,
 		),
 	}
-
-	return nil
 }
 
 type propertyStore struct {
@@ -127,11 +215,15 @@ func registerCollector(collector string, isDefaultEnabled bool, defaultProps str
 	enabledFlagHelp := fmt.Sprintf("Enable the %s collector (default: %s)", collector, helpDefaultState)
 	enabledDefaultValue := strconv.FormatBool(isDefaultEnabled)
 
-	propsFlagName := fmt.Sprintf("properties.%s", collector)
-	propsFlagHelp := fmt.Sprintf("Properties to include for the %s collector, comma-separated.", collector)
+	enabledFlag := kingpin.Flag(enabledFlagName, enabledFlagHelp).Default(enabledDefaultValue).Action(collectorFlagAction(collector)).Bool()
 
-	enabledFlag := kingpin.Flag(enabledFlagName, enabledFlagHelp).Default(enabledDefaultValue).Bool()
-	propsFlag := kingpin.Flag(propsFlagName, propsFlagHelp).Default(defaultProps).String()
+	// Collectors without selectable properties do not get a properties flag.
+	propsFlag := new(string)
+	if defaultProps != `` {
+		propsFlagName := fmt.Sprintf("properties.%s", collector)
+		propsFlagHelp := fmt.Sprintf("Properties to include for the %s collector, comma-separated.", collector)
+		propsFlag = kingpin.Flag(propsFlagName, propsFlagHelp).Default(defaultProps).String()
+	}
 
 	collectorStates[collector] = State{
 		Enabled:    enabledFlag,
@@ -140,6 +232,85 @@ func registerCollector(collector string, isDefaultEnabled bool, defaultProps str
 	}
 }
 
+func collectorFlagAction(collector string) kingpin.Action {
+	return func(ctx *kingpin.ParseContext) error {
+		forcedCollectors[collector] = true
+		return nil
+	}
+}
+
+// DisableDefaultCollectors disables all collectors that were not explicitly enabled on the command line
+func DisableDefaultCollectors() {
+	for name, state := range collectorStates {
+		if !forcedCollectors[name] {
+			*state.Enabled = false
+		}
+	}
+}
+
+// Configure overrides the flag values of the named collector. A nil enabled or properties leaves the respective value
+// unchanged.
+func Configure(name string, enabled *bool, properties []string) error {
+	state, ok := collectorStates[name]
+	if !ok {
+		return fmt.Errorf("%w: %s", errUnknownCollector, name)
+	}
+	if enabled != nil {
+		*state.Enabled = *enabled
+	}
+	if properties != nil {
+		*state.Properties = strings.Join(properties, `,`)
+	}
+
+	return nil
+}
+
+// panicError records a recovered panic, along with the stack of the goroutine that panicked
+type panicError struct {
+	value any
+	stack []byte
+}
+
+func (e *panicError) Error() string {
+	return fmt.Sprintf("panic: %v", e.value)
+}
+
+// recoverPanic converts a panic in the calling goroutine into a *panicError assigned to err, and must be deferred
+// directly so that recover() takes effect.
+func recoverPanic(err *error) {
+	if r := recover(); r != nil {
+		*err = &panicError{value: r, stack: debug.Stack()}
+	}
+}
+
+// updatePools calls update concurrently for each pool, returning the first error encountered
+func updatePools(pools []string, update func(pool string) error) error {
+	var wg sync.WaitGroup
+	errChan := make(chan error, len(pools))
+	for _, pool := range pools {
+		wg.Add(1)
+		go func(pool string) {
+			var err error
+			defer func() {
+				if err != nil {
+					errChan <- err
+				}
+				wg.Done()
+			}()
+			defer recoverPanic(&err)
+			err = update(pool)
+		}(pool)
+	}
+	wg.Wait()
+
+	select {
+	case err := <-errChan:
+		return err
+	default:
+		return nil
+	}
+}
+
 func expandMetricName(prefix string, context ...string) string {
 	return strings.Join(append(context, prefix), `-`)
 }
@@ -0,0 +1,128 @@
+package collector
+
+import (
+	"log/slog"
+	"strconv"
+	"strings"
+
+	"github.com/jmcgover/zfs_exporter/v2/zfs"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func init() {
+	registerCollector(`compression`, defaultDisabled, ``, newCompressionCollector)
+}
+
+// compressionCollector exports the compression algorithm configured for each dataset, and the space referenced by
+// datasets of each algorithm per pool, so that a migration to another algorithm, such as zstd, can be tracked. The
+// property only applies to blocks written after it is set, so the space of datasets whose algorithm changed includes
+// blocks compressed with earlier algorithms.
+type compressionCollector struct {
+	log               *slog.Logger
+	client            zfs.Client
+	info              property
+	datasets          property
+	referenced        property
+	logicalReferenced property
+}
+
+// compressionTotals sums the datasets of an algorithm within a pool
+type compressionTotals struct {
+	datasets          float64
+	referenced        float64
+	logicalReferenced float64
+}
+
+func (c *compressionCollector) describe(ch chan<- *prometheus.Desc) {
+	ch <- c.info.desc
+	ch <- c.datasets.desc
+	ch <- c.referenced.desc
+	ch <- c.logicalReferenced.desc
+}
+
+func (c *compressionCollector) update(ch chan<- metric, pools []string, excludes regexpCollection) error {
+	return updatePools(pools, func(pool string) error {
+		datasets, err := c.client.DatasetGet(pool, datasetGetKinds, `compression`, `referenced`, `logicalreferenced`)
+		if err != nil {
+			return err
+		}
+		totals := make(map[string]*compressionTotals)
+		for name, dataset := range datasets {
+			if excludes.MatchString(name) {
+				continue
+			}
+			algorithm, level := compressionAlgorithm(string(dataset.Properties[`compression`].Value))
+			c.info.send(ch, 1, name, pool, string(dataset.Kind()), algorithm, level)
+
+			t, ok := totals[algorithm]
+			if !ok {
+				t = &compressionTotals{}
+				totals[algorithm] = t
+			}
+			t.datasets++
+			// Volumes and unavailable datasets may report - rather than a size
+			referenced, _ := strconv.ParseFloat(string(dataset.Properties[`referenced`].Value), 64)
+			logicalReferenced, _ := strconv.ParseFloat(string(dataset.Properties[`logicalreferenced`].Value), 64)
+			t.referenced += referenced
+			t.logicalReferenced += logicalReferenced
+		}
+		for algorithm, t := range totals {
+			c.datasets.send(ch, t.datasets, pool, algorithm)
+			c.referenced.send(ch, t.referenced, pool, algorithm)
+			c.logicalReferenced.send(ch, t.logicalReferenced, pool, algorithm)
+		}
+		return nil
+	})
+}
+
+// compressionAlgorithm splits the value of the compression property into the algorithm and its level, e.g. zstd-fast-10
+// into zstd-fast and 10. The level is empty where the default level of the algorithm applies.
+func compressionAlgorithm(value string) (string, string) {
+	i := strings.LastIndexByte(value, '-')
+	if i < 0 {
+		return value, ``
+	}
+	if _, err := strconv.Atoi(value[i+1:]); err != nil {
+		return value, ``
+	}
+	return value[:i], value[i+1:]
+}
+
+func newCompressionCollector(l *slog.Logger, c zfs.Client, props []string) (Collector, error) {
+	return &compressionCollector{
+		log:    l,
+		client: c,
+		info: newProperty(
+			subsystemDataset,
+			`compression_info`,
+			`Compression algorithm configured for the dataset, and its level where set explicitly, e.g. algorithm="zstd",level="19". Always 1.`,
+			transformNumeric,
+			prometheus.GaugeValue,
+			`name`, `pool`, `type`, `algorithm`, `level`,
+		),
+		datasets: newProperty(
+			subsystemPool,
+			`compression_datasets`,
+			`Number of filesystems and volumes in the pool configured with the compression algorithm.`,
+			transformNumeric,
+			prometheus.GaugeValue,
+			`pool`, `algorithm`,
+		),
+		referenced: newProperty(
+			subsystemPool,
+			`compression_referenced_bytes`,
+			`Space referenced by filesystems and volumes in the pool configured with the compression algorithm in bytes.`,
+			transformNumeric,
+			prometheus.GaugeValue,
+			`pool`, `algorithm`,
+		),
+		logicalReferenced: newProperty(
+			subsystemPool,
+			`compression_logical_referenced_bytes`,
+			`Space logically referenced, before compression, by filesystems and volumes in the pool configured with the compression algorithm in bytes.`,
+			transformNumeric,
+			prometheus.GaugeValue,
+			`pool`, `algorithm`,
+		),
+	}, nil
+}
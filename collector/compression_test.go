@@ -0,0 +1,70 @@
+package collector
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jmcgover/zfs_exporter/v2/zfs"
+	"github.com/jmcgover/zfs_exporter/v2/zfs/mock_zfs"
+	"go.uber.org/mock/gomock"
+)
+
+func TestCompressionMetrics(t *testing.T) {
+	const result = `# HELP zfs_dataset_compression_info Compression algorithm configured for the dataset, and its level where set explicitly, e.g. algorithm="zstd",level="19". Always 1.
+# TYPE zfs_dataset_compression_info gauge
+zfs_dataset_compression_info{algorithm="lz4",level="",name="testpool",pool="testpool",type="filesystem"} 1
+zfs_dataset_compression_info{algorithm="zstd",level="",name="testpool/vm",pool="testpool",type="volume"} 1
+zfs_dataset_compression_info{algorithm="zstd",level="19",name="testpool/archive",pool="testpool",type="filesystem"} 1
+zfs_dataset_compression_info{algorithm="zstd-fast",level="10",name="testpool/scratch",pool="testpool",type="filesystem"} 1
+# HELP zfs_pool_compression_datasets Number of filesystems and volumes in the pool configured with the compression algorithm.
+# TYPE zfs_pool_compression_datasets gauge
+zfs_pool_compression_datasets{algorithm="lz4",pool="testpool"} 1
+zfs_pool_compression_datasets{algorithm="zstd",pool="testpool"} 2
+zfs_pool_compression_datasets{algorithm="zstd-fast",pool="testpool"} 1
+# HELP zfs_pool_compression_logical_referenced_bytes Space logically referenced, before compression, by filesystems and volumes in the pool configured with the compression algorithm in bytes.
+# TYPE zfs_pool_compression_logical_referenced_bytes gauge
+zfs_pool_compression_logical_referenced_bytes{algorithm="lz4",pool="testpool"} 2000
+zfs_pool_compression_logical_referenced_bytes{algorithm="zstd",pool="testpool"} 12000
+zfs_pool_compression_logical_referenced_bytes{algorithm="zstd-fast",pool="testpool"} 500
+# HELP zfs_pool_compression_referenced_bytes Space referenced by filesystems and volumes in the pool configured with the compression algorithm in bytes.
+# TYPE zfs_pool_compression_referenced_bytes gauge
+zfs_pool_compression_referenced_bytes{algorithm="lz4",pool="testpool"} 1000
+zfs_pool_compression_referenced_bytes{algorithm="zstd",pool="testpool"} 4000
+zfs_pool_compression_referenced_bytes{algorithm="zstd-fast",pool="testpool"} 400
+`
+
+	ctrl, ctx := gomock.WithContext(context.Background(), t)
+	zfsClient := mock_zfs.NewMockClient(ctrl)
+	zfsClient.EXPECT().PoolNames().Return([]string{`testpool`}, nil).Times(1)
+	dataset := func(name, kind, compression, referenced, logicalReferenced string) zfs.DatasetListT {
+		return zfs.DatasetListT{Name: name, Type: kind, Properties: map[string]zfs.PropertyT{
+			`compression`:       {Value: zfs.PropertyValue(compression)},
+			`referenced`:        {Value: zfs.PropertyValue(referenced)},
+			`logicalreferenced`: {Value: zfs.PropertyValue(logicalReferenced)},
+		}}
+	}
+	zfsClient.EXPECT().DatasetGet(`testpool`, datasetGetKinds, `compression`, `referenced`, `logicalreferenced`).Return(map[string]zfs.DatasetListT{
+		`testpool`:         dataset(`testpool`, `FILESYSTEM`, `lz4`, `1000`, `2000`),
+		`testpool/archive`: dataset(`testpool/archive`, `FILESYSTEM`, `zstd-19`, `1000`, `8000`),
+		`testpool/scratch`: dataset(`testpool/scratch`, `FILESYSTEM`, `zstd-fast-10`, `400`, `500`),
+		`testpool/vm`:      dataset(`testpool/vm`, `VOLUME`, `zstd`, `3000`, `4000`),
+	}, nil).Times(1)
+
+	collector, err := NewZFS(defaultConfig(zfsClient))
+	if err != nil {
+		t.Fatal(err)
+	}
+	collector.Collectors = map[string]State{
+		`compression`: {
+			Name:       "compression",
+			Enabled:    boolPointer(true),
+			Properties: stringPointer(``),
+			factory:    newCompressionCollector,
+		},
+	}
+
+	metricNames := []string{`zfs_dataset_compression_info`, `zfs_pool_compression_datasets`, `zfs_pool_compression_logical_referenced_bytes`, `zfs_pool_compression_referenced_bytes`}
+	if err = callCollector(ctx, collector, []byte(result), metricNames); err != nil {
+		t.Fatal(err)
+	}
+}
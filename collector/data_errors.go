@@ -0,0 +1,156 @@
+package collector
+
+import (
+	"errors"
+	"log/slog"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/alecthomas/kingpin/v2"
+	"github.com/jmcgover/zfs_exporter/v2/zfs"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// dataErrorsUnknownDataset labels entries of the error log whose dataset could not be determined, such as paths that
+// are not below a mountpoint of the pool
+const dataErrorsUnknownDataset = `<unknown>`
+
+var (
+	dataErrorsMountpointKinds = []zfs.DatasetKind{zfs.DatasetFilesystem}
+	dataErrorsMaxDatasets     = kingpin.Flag(`collector.data-errors.max-datasets`, `Maximum number of affected datasets per pool to export with the data-errors collector, those with the most errors first.`).Default(`10`).Int()
+)
+
+func init() {
+	registerCollector(`data-errors`, defaultDisabled, ``, newDataErrorsCollector)
+}
+
+// dataErrorsCollector exports the permanent data errors of each pool, as listed by `zpool status -v`, which otherwise
+// only appear as "Permanent errors have been detected" in its output. The affected datasets are exported, rather than
+// the affected files, so that the number of series is bounded.
+type dataErrorsCollector struct {
+	log         *slog.Logger
+	client      zfs.Client
+	errorLog    zfs.ErrorLogClient
+	maxDatasets int
+	errors      property
+	datasets    property
+	datasetInfo property
+}
+
+func (c *dataErrorsCollector) describe(ch chan<- *prometheus.Desc) {
+	ch <- c.errors.desc
+	ch <- c.datasets.desc
+	ch <- c.datasetInfo.desc
+}
+
+func (c *dataErrorsCollector) update(ch chan<- metric, pools []string, excludes regexpCollection) error {
+	return updatePools(pools, func(pool string) error {
+		errorLog, err := c.errorLog.PoolErrorLog(pool)
+		if err != nil {
+			return err
+		}
+		c.errors.send(ch, float64(errorLog.Count), pool)
+
+		var mountpoints map[string]string
+		counts := make(map[string]int)
+		for _, entry := range errorLog.Entries {
+			if strings.HasPrefix(entry, `/`) && mountpoints == nil {
+				if mountpoints, err = c.mountpoints(pool); err != nil {
+					return err
+				}
+			}
+			counts[dataErrorsDataset(entry, mountpoints)]++
+		}
+		c.datasets.send(ch, float64(len(counts)), pool)
+
+		affected := make([]string, 0, len(counts))
+		for dataset := range counts {
+			if !excludes.MatchString(dataset) {
+				affected = append(affected, dataset)
+			}
+		}
+		sort.Slice(affected, func(i, j int) bool {
+			if counts[affected[i]] != counts[affected[j]] {
+				return counts[affected[i]] > counts[affected[j]]
+			}
+			return affected[i] < affected[j]
+		})
+		for _, dataset := range affected[:min(len(affected), c.maxDatasets)] {
+			c.datasetInfo.send(ch, 1, pool, dataset)
+		}
+		return nil
+	})
+}
+
+// mountpoints returns the filesystems of the pool keyed by mountpoint
+func (c *dataErrorsCollector) mountpoints(pool string) (map[string]string, error) {
+	datasets, err := c.client.DatasetGet(pool, dataErrorsMountpointKinds, `mountpoint`)
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[string]string, len(datasets))
+	for name, dataset := range datasets {
+		if mountpoint := string(dataset.Properties[`mountpoint`].Value); strings.HasPrefix(mountpoint, `/`) {
+			result[mountpoint] = name
+		}
+	}
+	return result, nil
+}
+
+// dataErrorsDataset returns the dataset of an entry of the error log. Paths belong to the filesystem with the deepest
+// mountpoint containing them, and other entries are given as DATASET:OBJECT, where the dataset may be <metadata> or
+// an object number.
+func dataErrorsDataset(entry string, mountpoints map[string]string) string {
+	if !strings.HasPrefix(entry, `/`) {
+		if dataset, _, ok := strings.Cut(entry, `:`); ok && dataset != `` {
+			return dataset
+		}
+		return dataErrorsUnknownDataset
+	}
+	for dir := path.Dir(entry); ; dir = path.Dir(dir) {
+		if dataset, ok := mountpoints[dir]; ok {
+			return dataset
+		}
+		if dir == `/` {
+			return dataErrorsUnknownDataset
+		}
+	}
+}
+
+func newDataErrorsCollector(l *slog.Logger, c zfs.Client, props []string) (Collector, error) {
+	errorLog, ok := c.(zfs.ErrorLogClient)
+	if !ok {
+		return nil, errors.New(`client does not list data errors`)
+	}
+	return &dataErrorsCollector{
+		log:         l,
+		client:      c,
+		errorLog:    errorLog,
+		maxDatasets: max(*dataErrorsMaxDatasets, 0),
+		errors: newProperty(
+			subsystemPool,
+			`data_errors_total`,
+			`Number of permanent data errors in the pool, as reported by zpool status -v. Errors are cleared by a scrub once the affected files are restored or deleted.`,
+			transformNumeric,
+			prometheus.GaugeValue,
+			poolLabels...,
+		),
+		datasets: newProperty(
+			subsystemPool,
+			`data_error_datasets`,
+			`Number of datasets in the pool with permanent data errors, including those beyond --collector.data-errors.max-datasets.`,
+			transformNumeric,
+			prometheus.GaugeValue,
+			poolLabels...,
+		),
+		datasetInfo: newProperty(
+			subsystemPool,
+			`data_error_dataset_info`,
+			`Dataset of the pool with permanent data errors, limited to --collector.data-errors.max-datasets per pool. Always 1.`,
+			transformNumeric,
+			prometheus.GaugeValue,
+			`pool`, `dataset`,
+		),
+	}, nil
+}
@@ -0,0 +1,82 @@
+package collector
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jmcgover/zfs_exporter/v2/zfs"
+	"github.com/jmcgover/zfs_exporter/v2/zfs/mock_zfs"
+	"go.uber.org/mock/gomock"
+)
+
+// errorLogClient is a mock client that also lists data errors
+type errorLogClient struct {
+	*mock_zfs.MockClient
+	*mock_zfs.MockErrorLogClient
+}
+
+func TestDataErrorsMetrics(t *testing.T) {
+	const result = `# HELP zfs_pool_data_error_dataset_info Dataset of the pool with permanent data errors, limited to --collector.data-errors.max-datasets per pool. Always 1.
+# TYPE zfs_pool_data_error_dataset_info gauge
+zfs_pool_data_error_dataset_info{dataset="<metadata>",pool="testpool"} 1
+zfs_pool_data_error_dataset_info{dataset="<unknown>",pool="testpool"} 1
+zfs_pool_data_error_dataset_info{dataset="testpool/home",pool="testpool"} 1
+# HELP zfs_pool_data_error_datasets Number of datasets in the pool with permanent data errors, including those beyond --collector.data-errors.max-datasets.
+# TYPE zfs_pool_data_error_datasets gauge
+zfs_pool_data_error_datasets{pool="healthy"} 0
+zfs_pool_data_error_datasets{pool="testpool"} 5
+# HELP zfs_pool_data_errors_total Number of permanent data errors in the pool, as reported by zpool status -v. Errors are cleared by a scrub once the affected files are restored or deleted.
+# TYPE zfs_pool_data_errors_total gauge
+zfs_pool_data_errors_total{pool="healthy"} 0
+zfs_pool_data_errors_total{pool="testpool"} 7
+`
+
+	ctrl, ctx := gomock.WithContext(context.Background(), t)
+	client := errorLogClient{MockClient: mock_zfs.NewMockClient(ctrl), MockErrorLogClient: mock_zfs.NewMockErrorLogClient(ctrl)}
+	client.MockClient.EXPECT().PoolNames().Return([]string{`healthy`, `testpool`}, nil).Times(1)
+	client.MockErrorLogClient.EXPECT().PoolErrorLog(`healthy`).Return(zfs.PoolErrorLogT{}, nil).Times(1)
+	client.MockErrorLogClient.EXPECT().PoolErrorLog(`testpool`).Return(zfs.PoolErrorLogT{
+		Count: 7,
+		Entries: []string{
+			`/testpool/home/alice/notes.txt`,
+			`/testpool/home/bob/photo.jpg`,
+			`/testpool/home/bob/.cache/blob`,
+			`/mnt/elsewhere/file`,
+			`testpool/backup:/2025/07/db.dump`,
+			`testpool/scratch:<0x1b>`,
+			`<metadata>:<0x3f>`,
+			`<metadata>:<0x40>`,
+		},
+	}, nil).Times(1)
+	mountpoint := func(name, value string) zfs.DatasetListT {
+		return zfs.DatasetListT{Name: name, Type: `FILESYSTEM`, Properties: map[string]zfs.PropertyT{`mountpoint`: {Value: zfs.PropertyValue(value)}}}
+	}
+	client.MockClient.EXPECT().DatasetGet(`testpool`, dataErrorsMountpointKinds, `mountpoint`).Return(map[string]zfs.DatasetListT{
+		`testpool`:         mountpoint(`testpool`, `/testpool`),
+		`testpool/home`:    mountpoint(`testpool/home`, `/testpool/home`),
+		`testpool/backup`:  mountpoint(`testpool/backup`, `none`),
+		`testpool/scratch`: mountpoint(`testpool/scratch`, `legacy`),
+	}, nil).Times(1)
+
+	prev := *dataErrorsMaxDatasets
+	*dataErrorsMaxDatasets = 3
+	t.Cleanup(func() { *dataErrorsMaxDatasets = prev })
+
+	collector, err := NewZFS(defaultConfig(client))
+	if err != nil {
+		t.Fatal(err)
+	}
+	collector.Collectors = map[string]State{
+		`data-errors`: {
+			Name:       "data-errors",
+			Enabled:    boolPointer(true),
+			Properties: stringPointer(``),
+			factory:    newDataErrorsCollector,
+		},
+	}
+
+	metricNames := []string{`zfs_pool_data_error_dataset_info`, `zfs_pool_data_error_datasets`, `zfs_pool_data_errors_total`}
+	if err = callCollector(ctx, collector, []byte(result), metricNames); err != nil {
+		t.Fatal(err)
+	}
+}
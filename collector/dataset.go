@@ -3,7 +3,6 @@ package collector
 import (
 	"fmt"
 	"log/slog"
-	"sync"
 
 	"github.com/jmcgover/zfs_exporter/v2/zfs"
 	"github.com/prometheus/client_golang/prometheus"
@@ -203,32 +202,15 @@ func (c *datasetCollector) describe(ch chan<- *prometheus.Desc) {
 		prop, err := datasetProperties.find(k)
 		if err != nil {
 			c.log.Warn(propertyUnsupportedMsg, `help`, helpIssue, `collector`, c.kind, `property`, k, `err`, err)
-			continue
 		}
 		ch <- prop.desc
 	}
 }
 
 func (c *datasetCollector) update(ch chan<- metric, pools []string, excludes regexpCollection) error {
-	var wg sync.WaitGroup
-	errChan := make(chan error, len(pools))
-	for _, pool := range pools {
-		wg.Add(1)
-		go func(pool string) {
-			if err := c.updatePoolMetrics(ch, pool, excludes); err != nil {
-				errChan <- err
-			}
-			wg.Done()
-		}(pool)
-	}
-	wg.Wait()
-
-	select {
-	case err := <-errChan:
-		return err
-	default:
-		return nil
-	}
+	return updatePools(pools, func(pool string) error {
+		return c.updatePoolMetrics(ch, pool, excludes)
+	})
 }
 
 func (c *datasetCollector) updatePoolMetrics(ch chan<- metric, pool string, excludes regexpCollection) error {
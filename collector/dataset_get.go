@@ -0,0 +1,99 @@
+package collector
+
+import (
+	"errors"
+	"log/slog"
+
+	"github.com/alecthomas/kingpin/v2"
+	"github.com/jmcgover/zfs_exporter/v2/zfs"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var datasetGetKinds = []zfs.DatasetKind{zfs.DatasetFilesystem, zfs.DatasetVolume}
+
+func init() {
+	registerCollector(`dataset-get`, defaultDisabled, ``, newDatasetGetCollector)
+	// There is no sensible default set of properties, but they must still be selectable on the command line
+	state := collectorStates[`dataset-get`]
+	state.Properties = kingpin.Flag(`properties.dataset-get`, `Properties to include for the dataset-get collector, comma-separated.`).String()
+	collectorStates[`dataset-get`] = state
+}
+
+// datasetGetCollector exports arbitrary dataset properties, including user properties such as
+// `com.example:backup-policy`, from a single `zfs get` invocation per pool. Numeric and boolean values are exported as
+// gauges, and all other values as info metrics, so that metadata may be joined onto other series in alerts.
+type datasetGetCollector struct {
+	log    *slog.Logger
+	client zfs.Client
+	props  []string
+	value  property
+	info   property
+}
+
+func (c *datasetGetCollector) describe(ch chan<- *prometheus.Desc) {
+	ch <- c.value.desc
+	ch <- c.info.desc
+}
+
+func (c *datasetGetCollector) update(ch chan<- metric, pools []string, excludes regexpCollection) error {
+	return updatePools(pools, func(pool string) error {
+		return c.updatePoolMetrics(ch, pool, excludes)
+	})
+}
+
+func (c *datasetGetCollector) updatePoolMetrics(ch chan<- metric, pool string, excludes regexpCollection) error {
+	datasets, err := c.client.DatasetGet(pool, datasetGetKinds, c.props...)
+	if err != nil {
+		return err
+	}
+
+	for name, dataset := range datasets {
+		if excludes.MatchString(name) {
+			continue
+		}
+		kind := string(dataset.Kind())
+		for k, v := range dataset.Properties {
+			value := string(v.Value)
+			// User properties that are not set on or inherited by the dataset are reported as "-"
+			if value == `-` {
+				continue
+			}
+			if f, err := transformNumeric(value); err == nil {
+				c.value.send(ch, f, name, pool, kind, k)
+			} else if f, err := transformBool(value); err == nil {
+				c.value.send(ch, f, name, pool, kind, k)
+			} else {
+				c.info.send(ch, 1, name, pool, kind, k, value)
+			}
+		}
+	}
+
+	return nil
+}
+
+func newDatasetGetCollector(l *slog.Logger, c zfs.Client, props []string) (Collector, error) {
+	if len(props) == 0 {
+		return nil, errors.New(`no properties configured for the dataset-get collector`)
+	}
+	return &datasetGetCollector{
+		log:    l,
+		client: c,
+		props:  props,
+		value: newProperty(
+			subsystemDataset,
+			`property`,
+			`Value of a numeric or on/off dataset property reported by zfs get, where on is 1 and off is 0.`,
+			transformNumeric,
+			prometheus.GaugeValue,
+			`name`, `pool`, `type`, `property`,
+		),
+		info: newProperty(
+			subsystemDataset,
+			`property_info`,
+			`Value of a dataset property reported by zfs get that is neither numeric nor on/off, given by the value label.`,
+			transformNumeric,
+			prometheus.GaugeValue,
+			`name`, `pool`, `type`, `property`, `value`,
+		),
+	}, nil
+}
@@ -0,0 +1,60 @@
+package collector
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jmcgover/zfs_exporter/v2/zfs"
+	"github.com/jmcgover/zfs_exporter/v2/zfs/mock_zfs"
+	"go.uber.org/mock/gomock"
+)
+
+func TestDatasetGetMetrics(t *testing.T) {
+	const result = `# HELP zfs_dataset_property Value of a numeric or on/off dataset property reported by zfs get, where on is 1 and off is 0.
+# TYPE zfs_dataset_property gauge
+zfs_dataset_property{name="testpool/home",pool="testpool",property="com.example:retention-days",type="filesystem"} 30
+# HELP zfs_dataset_property_info Value of a dataset property reported by zfs get that is neither numeric nor on/off, given by the value label.
+# TYPE zfs_dataset_property_info gauge
+zfs_dataset_property_info{name="testpool/home",pool="testpool",property="com.example:backup-policy",type="filesystem",value="daily"} 1
+`
+
+	ctrl, ctx := gomock.WithContext(context.Background(), t)
+	zfsClient := mock_zfs.NewMockClient(ctrl)
+	zfsClient.EXPECT().PoolNames().Return([]string{`testpool`}, nil).Times(1)
+	zfsClient.EXPECT().DatasetGet(`testpool`, datasetGetKinds, `com.example:backup-policy`, `com.example:retention-days`).Return(map[string]zfs.DatasetListT{
+		`testpool`: {
+			Name: `testpool`,
+			Type: `FILESYSTEM`,
+			Properties: map[string]zfs.PropertyT{
+				`com.example:backup-policy`:  {Value: `-`},
+				`com.example:retention-days`: {Value: `-`},
+			},
+		},
+		`testpool/home`: {
+			Name: `testpool/home`,
+			Type: `FILESYSTEM`,
+			Properties: map[string]zfs.PropertyT{
+				`com.example:backup-policy`:  {Value: `daily`},
+				`com.example:retention-days`: {Value: `30`},
+			},
+		},
+	}, nil).Times(1)
+
+	collector, err := NewZFS(defaultConfig(zfsClient))
+	if err != nil {
+		t.Fatal(err)
+	}
+	collector.Collectors = map[string]State{
+		`dataset-get`: {
+			Name:       "dataset-get",
+			Enabled:    boolPointer(true),
+			Properties: stringPointer(`com.example:backup-policy,com.example:retention-days`),
+			factory:    newDatasetGetCollector,
+		},
+	}
+
+	metricNames := []string{`zfs_dataset_property`, `zfs_dataset_property_info`}
+	if err = callCollector(ctx, collector, []byte(result), metricNames); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,79 @@
+package collector
+
+import (
+	"log/slog"
+
+	"github.com/alecthomas/kingpin/v2"
+	"github.com/jmcgover/zfs_exporter/v2/zfs"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	defaultDatasetListProps = `available,quota,referenced,refquota,used,usedbychildren,usedbysnapshots`
+)
+
+var (
+	datasetListKinds = []zfs.DatasetKind{zfs.DatasetFilesystem, zfs.DatasetVolume}
+	datasetListDepth = kingpin.Flag(`collector.dataset-list.depth`, `Maximum depth below each pool's root dataset to collect with the dataset-list collector (default: unlimited).`).Default(`-1`).Int()
+)
+
+func init() {
+	registerCollector(`dataset-list`, defaultDisabled, defaultDatasetListProps, newDatasetListCollector)
+}
+
+// datasetListCollector exports the same metrics as the dataset-filesystem and dataset-volume collectors, but collects
+// both kinds in a single `zfs list` invocation per pool, so they are mutually exclusive.
+type datasetListCollector struct {
+	log    *slog.Logger
+	client zfs.Client
+	props  []string
+	depth  int
+}
+
+func (c *datasetListCollector) describe(ch chan<- *prometheus.Desc) {
+	for _, k := range c.props {
+		prop, err := datasetProperties.find(k)
+		if err != nil {
+			c.log.Warn(propertyUnsupportedMsg, `help`, helpIssue, `collector`, `dataset-list`, `property`, k, `err`, err)
+		}
+		ch <- prop.desc
+	}
+}
+
+func (c *datasetListCollector) update(ch chan<- metric, pools []string, excludes regexpCollection) error {
+	return updatePools(pools, func(pool string) error {
+		return c.updatePoolMetrics(ch, pool, excludes)
+	})
+}
+
+func (c *datasetListCollector) updatePoolMetrics(ch chan<- metric, pool string, excludes regexpCollection) error {
+	datasets, err := c.client.DatasetList(pool, c.depth, datasetListKinds, c.props...)
+	if err != nil {
+		return err
+	}
+
+	for name, dataset := range datasets {
+		if excludes.MatchString(name) {
+			continue
+		}
+		labelValues := []string{name, pool, string(dataset.Kind())}
+		for k, v := range dataset.Properties {
+			if k == `name` {
+				continue
+			}
+			prop, err := datasetProperties.find(k)
+			if err != nil {
+				c.log.Warn(propertyUnsupportedMsg, `help`, helpIssue, `collector`, `dataset-list`, `property`, k, `err`, err)
+			}
+			if err = prop.push(ch, string(v.Value), labelValues...); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func newDatasetListCollector(l *slog.Logger, c zfs.Client, props []string) (Collector, error) {
+	return &datasetListCollector{log: l, client: c, props: props, depth: *datasetListDepth}, nil
+}
@@ -0,0 +1,76 @@
+package collector
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/jmcgover/zfs_exporter/v2/zfs"
+	"github.com/jmcgover/zfs_exporter/v2/zfs/mock_zfs"
+	"go.uber.org/mock/gomock"
+)
+
+func TestDatasetListMetrics(t *testing.T) {
+	const result = `# HELP zfs_dataset_used_bytes The amount of space in bytes consumed by this dataset and all its descendents.
+# TYPE zfs_dataset_used_bytes gauge
+zfs_dataset_used_bytes{name="testpool",pool="testpool",type="filesystem"} 4096
+zfs_dataset_used_bytes{name="testpool/vol",pool="testpool",type="volume"} 1024
+# HELP zfs_dataset_referenced_quota_bytes The maximum amount of space in bytes this dataset can consume.
+# TYPE zfs_dataset_referenced_quota_bytes gauge
+zfs_dataset_referenced_quota_bytes{name="testpool",pool="testpool",type="filesystem"} 0
+zfs_dataset_referenced_quota_bytes{name="testpool/vol",pool="testpool",type="volume"} 0
+`
+	propsRequested := []string{`used`, `refquota`}
+
+	ctrl, ctx := gomock.WithContext(context.Background(), t)
+	zfsClient := mock_zfs.NewMockClient(ctrl)
+	zfsClient.EXPECT().PoolNames().Return([]string{`testpool`}, nil).Times(1)
+	zfsClient.EXPECT().DatasetList(`testpool`, gomock.Any(), datasetListKinds, propsRequested).Return(map[string]zfs.DatasetListT{
+		`testpool`: {
+			Name: `testpool`,
+			Type: `FILESYSTEM`,
+			Pool: `testpool`,
+			Properties: map[string]zfs.PropertyT{
+				`used`:     {Value: `4096`},
+				`refquota`: {Value: `0`},
+			},
+		},
+		`testpool/vol`: {
+			Name: `testpool/vol`,
+			Type: `VOLUME`,
+			Pool: `testpool`,
+			Properties: map[string]zfs.PropertyT{
+				`used`:     {Value: `1024`},
+				`refquota`: {Value: `-`},
+			},
+		},
+		`testpool/docker`: {
+			Name: `testpool/docker`,
+			Type: `FILESYSTEM`,
+			Pool: `testpool`,
+			Properties: map[string]zfs.PropertyT{
+				`used`: {Value: `1024`},
+			},
+		},
+	}, nil).Times(1)
+
+	config := defaultConfig(zfsClient)
+	config.Excludes = []string{`^testpool/docker`}
+	collector, err := NewZFS(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	collector.Collectors = map[string]State{
+		`dataset-list`: {
+			Name:       "dataset-list",
+			Enabled:    boolPointer(true),
+			Properties: stringPointer(strings.Join(propsRequested, `,`)),
+			factory:    newDatasetListCollector,
+		},
+	}
+
+	metricNames := []string{`zfs_dataset_used_bytes`, `zfs_dataset_referenced_quota_bytes`}
+	if err = callCollector(ctx, collector, []byte(result), metricNames); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,119 @@
+package collector
+
+import (
+	"log/slog"
+	"sync"
+
+	"github.com/alecthomas/kingpin/v2"
+	"github.com/jmcgover/zfs_exporter/v2/zfs"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	datasetProgramInstructions = kingpin.Flag(`collector.dataset-program.instruction-limit`, `Maximum number of Lua instructions executed by the channel program of the dataset-program collector per pool, at most 100000000 (default: 10000000, that of zfs program).`).Default(`0`).Uint64()
+	datasetProgramMemory       = kingpin.Flag(`collector.dataset-program.memory-limit`, `Maximum memory in bytes used by the channel program of the dataset-program collector per pool, at most 104857600 (default: 10485760, that of zfs program).`).Default(`0`).Uint64()
+)
+
+// datasetProgramWarned records the pools for which a failure of the channel program has been logged as a warning, as
+// the program is retried on every scrape.
+var datasetProgramWarned = struct {
+	sync.Mutex
+	pools map[string]bool
+}{
+	pools: make(map[string]bool),
+}
+
+func init() {
+	registerCollector(`dataset-program`, defaultDisabled, defaultDatasetListProps, newDatasetProgramCollector)
+}
+
+// datasetProgramCollector exports the same metrics as the dataset-filesystem and dataset-volume collectors, but reads
+// the properties of all datasets of a pool with a channel program run by `zfs program`, which avoids formatting and
+// parsing the output of `zfs list` for pools with very many datasets. Where the program fails, e.g. as channel
+// programs require root, or the client does not run them, the pool is collected as by those collectors instead.
+type datasetProgramCollector struct {
+	log      *slog.Logger
+	program  zfs.ProgramClient
+	props    []string
+	limits   zfs.ProgramLimits
+	fallback map[zfs.DatasetKind]*datasetCollector
+}
+
+func (c *datasetProgramCollector) describe(ch chan<- *prometheus.Desc) {
+	for _, k := range c.props {
+		prop, err := datasetProperties.find(k)
+		if err != nil {
+			c.log.Warn(propertyUnsupportedMsg, `help`, helpIssue, `collector`, `dataset-program`, `property`, k, `err`, err)
+		}
+		ch <- prop.desc
+	}
+}
+
+func (c *datasetProgramCollector) update(ch chan<- metric, pools []string, excludes regexpCollection) error {
+	return updatePools(pools, func(pool string) error {
+		return c.updatePoolMetrics(ch, pool, excludes)
+	})
+}
+
+func (c *datasetProgramCollector) updatePoolMetrics(ch chan<- metric, pool string, excludes regexpCollection) error {
+	if c.program == nil {
+		return c.updateFallback(ch, pool, excludes)
+	}
+
+	datasets, err := c.program.DatasetProgram(pool, c.limits, c.props...)
+	if err != nil {
+		datasetProgramWarned.Lock()
+		log := c.log.Debug
+		if !datasetProgramWarned.pools[pool] {
+			datasetProgramWarned.pools[pool] = true
+			log = c.log.Warn
+		}
+		datasetProgramWarned.Unlock()
+		log("Channel program failed, falling back to zfs get", "collector", "dataset-program", "pool", pool, "err", err)
+		return c.updateFallback(ch, pool, excludes)
+	}
+
+	for name, dataset := range datasets {
+		if excludes.MatchString(name) {
+			continue
+		}
+		labelValues := []string{name, pool, string(dataset.Kind())}
+		for k, v := range dataset.Properties {
+			prop, err := datasetProperties.find(k)
+			if err != nil {
+				c.log.Warn(propertyUnsupportedMsg, `help`, helpIssue, `collector`, `dataset-program`, `property`, k, `err`, err)
+			}
+			if err = prop.push(ch, string(v.Value), labelValues...); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// updateFallback collects the pool as the dataset-filesystem and dataset-volume collectors do
+func (c *datasetProgramCollector) updateFallback(ch chan<- metric, pool string, excludes regexpCollection) error {
+	for _, kind := range datasetListKinds {
+		if err := c.fallback[kind].updatePoolMetrics(ch, pool, excludes); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func newDatasetProgramCollector(l *slog.Logger, c zfs.Client, props []string) (Collector, error) {
+	fallback := make(map[zfs.DatasetKind]*datasetCollector, len(datasetListKinds))
+	for _, kind := range datasetListKinds {
+		fallback[kind] = &datasetCollector{kind: kind, log: l, client: c, props: props}
+	}
+	// Clients that do not run channel programs always fall back
+	program, _ := c.(zfs.ProgramClient)
+	return &datasetProgramCollector{
+		log:      l,
+		program:  program,
+		props:    props,
+		limits:   zfs.ProgramLimits{Instructions: *datasetProgramInstructions, Memory: *datasetProgramMemory},
+		fallback: fallback,
+	}, nil
+}
@@ -0,0 +1,90 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/jmcgover/zfs_exporter/v2/zfs"
+	"github.com/jmcgover/zfs_exporter/v2/zfs/mock_zfs"
+	"go.uber.org/mock/gomock"
+)
+
+// programClient is a mock client that also runs channel programs
+type programClient struct {
+	*mock_zfs.MockClient
+	*mock_zfs.MockProgramClient
+}
+
+func TestDatasetProgramMetrics(t *testing.T) {
+	const result = `# HELP zfs_dataset_used_bytes The amount of space in bytes consumed by this dataset and all its descendents.
+# TYPE zfs_dataset_used_bytes gauge
+zfs_dataset_used_bytes{name="fallback",pool="fallback",type="filesystem"} 2048
+zfs_dataset_used_bytes{name="fallback/vol",pool="fallback",type="volume"} 512
+zfs_dataset_used_bytes{name="testpool",pool="testpool",type="filesystem"} 4096
+zfs_dataset_used_bytes{name="testpool/vol",pool="testpool",type="volume"} 1024
+`
+	propsRequested := []string{`used`}
+
+	ctrl, ctx := gomock.WithContext(context.Background(), t)
+	client := programClient{MockClient: mock_zfs.NewMockClient(ctrl), MockProgramClient: mock_zfs.NewMockProgramClient(ctrl)}
+	client.MockClient.EXPECT().PoolNames().Return([]string{`fallback`, `testpool`}, nil).Times(1)
+	client.MockProgramClient.EXPECT().DatasetProgram(`testpool`, zfs.ProgramLimits{}, propsRequested).Return(map[string]zfs.DatasetListT{
+		`testpool`: {
+			Name:       `testpool`,
+			Type:       `FILESYSTEM`,
+			Pool:       `testpool`,
+			Properties: map[string]zfs.PropertyT{`used`: {Value: `4096`}},
+		},
+		`testpool/vol`: {
+			Name:       `testpool/vol`,
+			Type:       `VOLUME`,
+			Pool:       `testpool`,
+			Properties: map[string]zfs.PropertyT{`used`: {Value: `1024`}},
+		},
+		`testpool/docker`: {
+			Name:       `testpool/docker`,
+			Type:       `FILESYSTEM`,
+			Pool:       `testpool`,
+			Properties: map[string]zfs.PropertyT{`used`: {Value: `1024`}},
+		},
+	}, nil).Times(1)
+
+	// The program fails for the other pool, which is collected with zfs get instead
+	client.MockProgramClient.EXPECT().DatasetProgram(`fallback`, zfs.ProgramLimits{}, propsRequested).Return(nil, errors.New(`exit status 1`)).Times(1)
+	for _, fallback := range []struct {
+		kind zfs.DatasetKind
+		name string
+		used string
+	}{
+		{zfs.DatasetFilesystem, `fallback`, `2048`},
+		{zfs.DatasetVolume, `fallback/vol`, `512`},
+	} {
+		props := mock_zfs.NewMockDatasetProperties(ctrl)
+		props.EXPECT().DatasetName().Return(fallback.name).AnyTimes()
+		props.EXPECT().Properties().Return(map[string]string{`used`: fallback.used}).Times(1)
+		datasets := mock_zfs.NewMockDatasets(ctrl)
+		datasets.EXPECT().Properties(propsRequested).Return([]zfs.DatasetProperties{props}, nil).Times(1)
+		client.MockClient.EXPECT().Datasets(`fallback`, fallback.kind).Return(datasets).Times(1)
+	}
+
+	config := defaultConfig(client)
+	config.Excludes = []string{`^testpool/docker`}
+	collector, err := NewZFS(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	collector.Collectors = map[string]State{
+		`dataset-program`: {
+			Name:       "dataset-program",
+			Enabled:    boolPointer(true),
+			Properties: stringPointer(strings.Join(propsRequested, `,`)),
+			factory:    newDatasetProgramCollector,
+		},
+	}
+
+	if err = callCollector(ctx, collector, []byte(result), []string{`zfs_dataset_used_bytes`}); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,72 @@
+package collector
+
+import (
+	"log/slog"
+
+	"github.com/jmcgover/zfs_exporter/v2/zfs"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func init() {
+	registerCollector(`ddt`, defaultDisabled, ``, newDDTCollector)
+}
+
+// ddtCollector exports the size of each pool's dedup table, which must largely fit in memory for dedup to perform
+// acceptably. The dedup ratio is exported by the pool collector.
+type ddtCollector struct {
+	log       *slog.Logger
+	client    zfs.Client
+	entries   property
+	diskBytes property
+	coreBytes property
+}
+
+func (c *ddtCollector) describe(ch chan<- *prometheus.Desc) {
+	ch <- c.entries.desc
+	ch <- c.diskBytes.desc
+	ch <- c.coreBytes.desc
+}
+
+func (c *ddtCollector) update(ch chan<- metric, pools []string, excludes regexpCollection) error {
+	return updatePools(pools, func(pool string) error {
+		stats, err := c.client.DDTStats(pool)
+		if err != nil {
+			return err
+		}
+		c.entries.send(ch, float64(stats.Entries), pool)
+		c.diskBytes.send(ch, float64(stats.DiskBytes()), pool)
+		c.coreBytes.send(ch, float64(stats.CoreBytes()), pool)
+		return nil
+	})
+}
+
+func newDDTCollector(l *slog.Logger, c zfs.Client, props []string) (Collector, error) {
+	return &ddtCollector{
+		log:    l,
+		client: c,
+		entries: newProperty(
+			subsystemPool,
+			`ddt_entries`,
+			`Number of unique blocks tracked by the dedup table (DDT) of the pool.`,
+			transformNumeric,
+			prometheus.GaugeValue,
+			poolLabels...,
+		),
+		diskBytes: newProperty(
+			subsystemPool,
+			`ddt_disk_bytes`,
+			`Estimated on-disk size of the dedup table (DDT) of the pool in bytes, from the average size of each entry.`,
+			transformNumeric,
+			prometheus.GaugeValue,
+			poolLabels...,
+		),
+		coreBytes: newProperty(
+			subsystemPool,
+			`ddt_core_bytes`,
+			`Estimated in-memory size of the dedup table (DDT) of the pool in bytes, from the average size of each entry.`,
+			transformNumeric,
+			prometheus.GaugeValue,
+			poolLabels...,
+		),
+	}, nil
+}
@@ -0,0 +1,50 @@
+package collector
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jmcgover/zfs_exporter/v2/zfs"
+	"github.com/jmcgover/zfs_exporter/v2/zfs/mock_zfs"
+	"go.uber.org/mock/gomock"
+)
+
+func TestDDTMetrics(t *testing.T) {
+	const result = `# HELP zfs_pool_ddt_core_bytes Estimated in-memory size of the dedup table (DDT) of the pool in bytes, from the average size of each entry.
+# TYPE zfs_pool_ddt_core_bytes gauge
+zfs_pool_ddt_core_bytes{pool="testpool1"} 16100
+zfs_pool_ddt_core_bytes{pool="testpool2"} 0
+# HELP zfs_pool_ddt_disk_bytes Estimated on-disk size of the dedup table (DDT) of the pool in bytes, from the average size of each entry.
+# TYPE zfs_pool_ddt_disk_bytes gauge
+zfs_pool_ddt_disk_bytes{pool="testpool1"} 29700
+zfs_pool_ddt_disk_bytes{pool="testpool2"} 0
+# HELP zfs_pool_ddt_entries Number of unique blocks tracked by the dedup table (DDT) of the pool.
+# TYPE zfs_pool_ddt_entries gauge
+zfs_pool_ddt_entries{pool="testpool1"} 100
+zfs_pool_ddt_entries{pool="testpool2"} 0
+`
+
+	ctrl, ctx := gomock.WithContext(context.Background(), t)
+	zfsClient := mock_zfs.NewMockClient(ctrl)
+	zfsClient.EXPECT().PoolNames().Return([]string{`testpool1`, `testpool2`}, nil).Times(1)
+	zfsClient.EXPECT().DDTStats(`testpool1`).Return(zfs.DDTStatsT{Entries: 100, EntryDiskBytes: 297, EntryCoreBytes: 161}, nil).Times(1)
+	zfsClient.EXPECT().DDTStats(`testpool2`).Return(zfs.DDTStatsT{}, nil).Times(1)
+
+	collector, err := NewZFS(defaultConfig(zfsClient))
+	if err != nil {
+		t.Fatal(err)
+	}
+	collector.Collectors = map[string]State{
+		`ddt`: {
+			Name:       "ddt",
+			Enabled:    boolPointer(true),
+			Properties: stringPointer(``),
+			factory:    newDDTCollector,
+		},
+	}
+
+	metricNames := []string{`zfs_pool_ddt_entries`, `zfs_pool_ddt_disk_bytes`, `zfs_pool_ddt_core_bytes`}
+	if err = callCollector(ctx, collector, []byte(result), metricNames); err != nil {
+		t.Fatal(err)
+	}
+}
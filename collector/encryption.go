@@ -0,0 +1,106 @@
+package collector
+
+import (
+	"log/slog"
+	"slices"
+
+	"github.com/jmcgover/zfs_exporter/v2/zfs"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// keyStatuses are the key statuses of encrypted datasets
+var keyStatuses = []string{`available`, `unavailable`}
+
+func init() {
+	registerCollector(`encryption`, defaultDisabled, ``, newEncryptionCollector)
+}
+
+// encryptionCollector exports whether the keys of encrypted datasets are loaded, so that datasets left locked after a
+// reboot, and the services depending on them, do not go unnoticed
+type encryptionCollector struct {
+	log          *slog.Logger
+	client       zfs.Client
+	keyAvailable property
+	keyStatus    property
+	encrypted    property
+}
+
+func (c *encryptionCollector) describe(ch chan<- *prometheus.Desc) {
+	ch <- c.keyAvailable.desc
+	ch <- c.keyStatus.desc
+	ch <- c.encrypted.desc
+}
+
+func (c *encryptionCollector) update(ch chan<- metric, pools []string, excludes regexpCollection) error {
+	return updatePools(pools, func(pool string) error {
+		datasets, err := c.client.DatasetGet(pool, datasetGetKinds, `encryption`, `keystatus`)
+		if err != nil {
+			return err
+		}
+		// counts are the encrypted datasets of the pool by key status
+		counts := make(map[string]int, len(keyStatuses))
+		for name, dataset := range datasets {
+			if excludes.MatchString(name) {
+				continue
+			}
+			// Unencrypted datasets report encryption=off and keystatus=-
+			if v := string(dataset.Properties[`encryption`].Value); v == `off` || v == `` {
+				continue
+			}
+			status := string(dataset.Properties[`keystatus`].Value)
+			available := 0.0
+			if status == `available` {
+				available = 1
+			}
+			c.keyAvailable.send(ch, available, name, pool, string(dataset.Kind()))
+
+			if !slices.Contains(keyStatuses, status) {
+				c.log.Warn("Unknown key status", "collector", "encryption", "dataset", name, "status", status)
+				continue
+			}
+			counts[status]++
+			for _, s := range keyStatuses {
+				v := 0.0
+				if s == status {
+					v = 1
+				}
+				c.keyStatus.send(ch, v, name, pool, string(dataset.Kind()), s)
+			}
+		}
+		for _, s := range keyStatuses {
+			c.encrypted.send(ch, float64(counts[s]), pool, s)
+		}
+		return nil
+	})
+}
+
+func newEncryptionCollector(l *slog.Logger, c zfs.Client, props []string) (Collector, error) {
+	return &encryptionCollector{
+		log:    l,
+		client: c,
+		keyAvailable: newProperty(
+			subsystemDataset,
+			`key_available`,
+			`Whether the encryption key of the dataset is loaded (1) or not (0), in which case the dataset cannot be mounted. Only encrypted datasets are reported.`,
+			transformNumeric,
+			prometheus.GaugeValue,
+			datasetLabels...,
+		),
+		keyStatus: newProperty(
+			subsystemDataset,
+			`keystatus`,
+			`Whether the encryption key of the dataset is in the status given by the status label (1) or not (0). Only encrypted datasets are reported.`,
+			transformNumeric,
+			prometheus.GaugeValue,
+			`name`, `pool`, `type`, `status`,
+		),
+		encrypted: newProperty(
+			subsystemPool,
+			`encrypted_datasets`,
+			`Number of encrypted filesystems and volumes in the pool by the status of their encryption key.`,
+			transformNumeric,
+			prometheus.GaugeValue,
+			`pool`, `status`,
+		),
+	}, nil
+}
@@ -0,0 +1,70 @@
+package collector
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jmcgover/zfs_exporter/v2/zfs"
+	"github.com/jmcgover/zfs_exporter/v2/zfs/mock_zfs"
+	"go.uber.org/mock/gomock"
+)
+
+func TestEncryptionMetrics(t *testing.T) {
+	const result = `# HELP zfs_dataset_key_available Whether the encryption key of the dataset is loaded (1) or not (0), in which case the dataset cannot be mounted. Only encrypted datasets are reported.
+# TYPE zfs_dataset_key_available gauge
+zfs_dataset_key_available{name="testpool/secure",pool="testpool",type="filesystem"} 0
+zfs_dataset_key_available{name="testpool/vault",pool="testpool",type="volume"} 1
+# HELP zfs_dataset_keystatus Whether the encryption key of the dataset is in the status given by the status label (1) or not (0). Only encrypted datasets are reported.
+# TYPE zfs_dataset_keystatus gauge
+zfs_dataset_keystatus{name="testpool/secure",pool="testpool",status="available",type="filesystem"} 0
+zfs_dataset_keystatus{name="testpool/secure",pool="testpool",status="unavailable",type="filesystem"} 1
+zfs_dataset_keystatus{name="testpool/vault",pool="testpool",status="available",type="volume"} 1
+zfs_dataset_keystatus{name="testpool/vault",pool="testpool",status="unavailable",type="volume"} 0
+# HELP zfs_pool_encrypted_datasets Number of encrypted filesystems and volumes in the pool by the status of their encryption key.
+# TYPE zfs_pool_encrypted_datasets gauge
+zfs_pool_encrypted_datasets{pool="testpool",status="available"} 1
+zfs_pool_encrypted_datasets{pool="testpool",status="unavailable"} 1
+`
+
+	ctrl, ctx := gomock.WithContext(context.Background(), t)
+	zfsClient := mock_zfs.NewMockClient(ctrl)
+	zfsClient.EXPECT().PoolNames().Return([]string{`testpool`}, nil).Times(1)
+	zfsClient.EXPECT().DatasetGet(`testpool`, datasetGetKinds, `encryption`, `keystatus`).Return(map[string]zfs.DatasetListT{
+		`testpool`: {Name: `testpool`, Type: `FILESYSTEM`, Properties: map[string]zfs.PropertyT{
+			`encryption`: {Value: `off`},
+			`keystatus`:  {Value: `-`},
+		}},
+		`testpool/secure`: {Name: `testpool/secure`, Type: `FILESYSTEM`, Properties: map[string]zfs.PropertyT{
+			`encryption`: {Value: `aes-256-gcm`},
+			`keystatus`:  {Value: `unavailable`},
+		}},
+		`testpool/vault`: {Name: `testpool/vault`, Type: `VOLUME`, Properties: map[string]zfs.PropertyT{
+			`encryption`: {Value: `aes-256-gcm`},
+			`keystatus`:  {Value: `available`},
+		}},
+		`testpool/docker`: {Name: `testpool/docker`, Type: `FILESYSTEM`, Properties: map[string]zfs.PropertyT{
+			`encryption`: {Value: `aes-256-gcm`},
+			`keystatus`:  {Value: `unavailable`},
+		}},
+	}, nil).Times(1)
+
+	config := defaultConfig(zfsClient)
+	config.Excludes = []string{`^testpool/docker`}
+	collector, err := NewZFS(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	collector.Collectors = map[string]State{
+		`encryption`: {
+			Name:       "encryption",
+			Enabled:    boolPointer(true),
+			Properties: stringPointer(``),
+			factory:    newEncryptionCollector,
+		},
+	}
+
+	metricNames := []string{`zfs_dataset_key_available`, `zfs_dataset_keystatus`, `zfs_pool_encrypted_datasets`}
+	if err = callCollector(ctx, collector, []byte(result), metricNames); err != nil {
+		t.Fatal(err)
+	}
+}
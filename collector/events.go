@@ -0,0 +1,85 @@
+package collector
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/jmcgover/zfs_exporter/v2/zfs"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// EventsCollector counts the events reported by `zpool events`, which it polls in the background. As the kernel only
+// retains a limited number of events, the counters start from the events buffered when the exporter starts.
+type EventsCollector struct {
+	logger *slog.Logger
+	client zfs.Client
+	events *prometheus.CounterVec
+	mu     sync.Mutex
+	// lastEID is the ID of the most recent event that has been counted
+	lastEID uint64
+}
+
+// NewEventsCollector instantiates a collector for ZFS events
+func NewEventsCollector(logger *slog.Logger, client zfs.Client) *EventsCollector {
+	return &EventsCollector{
+		logger: logger,
+		client: client,
+		events: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: subsystemPool,
+			Name:      `event_total`,
+			Help:      `Number of events reported by zpool events, by class. Events that are not associated with a pool have an empty pool label.`,
+		}, []string{`pool`, `class`}),
+	}
+}
+
+// Run polls for new events until ctx is done, waiting for the duration returned by interval between polls
+func (c *EventsCollector) Run(ctx context.Context, interval func() time.Duration) {
+	for {
+		if err := c.Poll(); err != nil {
+			c.logger.Error("Error reading ZFS events", "err", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval()):
+		}
+	}
+}
+
+// Poll counts the events that have been reported since the previous poll
+func (c *EventsCollector) Poll() error {
+	events, err := c.client.Events()
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	// Event IDs restart when the ZFS module is reloaded, in which case every buffered event is new.
+	if len(events) > 0 && events[len(events)-1].EID < c.lastEID {
+		c.logger.Info("ZFS event IDs have been reset", "last_eid", c.lastEID)
+		c.lastEID = 0
+	}
+	for _, event := range events {
+		if event.EID <= c.lastEID {
+			continue
+		}
+		c.events.WithLabelValues(event.Pool, event.Class).Inc()
+		c.lastEID = event.EID
+	}
+
+	return nil
+}
+
+// Describe implements the prometheus.Collector interface.
+func (c *EventsCollector) Describe(ch chan<- *prometheus.Desc) {
+	c.events.Describe(ch)
+}
+
+// Collect implements the prometheus.Collector interface.
+func (c *EventsCollector) Collect(ch chan<- prometheus.Metric) {
+	c.events.Collect(ch)
+}
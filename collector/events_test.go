@@ -0,0 +1,51 @@
+package collector
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/jmcgover/zfs_exporter/v2/zfs"
+	"github.com/jmcgover/zfs_exporter/v2/zfs/mock_zfs"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"go.uber.org/mock/gomock"
+)
+
+func TestEventsCollector(t *testing.T) {
+	const result = `# HELP zfs_pool_event_total Number of events reported by zpool events, by class. Events that are not associated with a pool have an empty pool label.
+# TYPE zfs_pool_event_total counter
+zfs_pool_event_total{class="ereport.fs.zfs.checksum",pool="tank"} 3
+zfs_pool_event_total{class="sysevent.fs.zfs.config_sync",pool="tank"} 1
+zfs_pool_event_total{class="sysevent.fs.zfs.history_event",pool=""} 1
+`
+
+	ctrl, _ := gomock.WithContext(context.Background(), t)
+	zfsClient := mock_zfs.NewMockClient(ctrl)
+	gomock.InOrder(
+		zfsClient.EXPECT().Events().Return([]zfs.EventT{
+			{EID: 1, Class: `sysevent.fs.zfs.config_sync`, Pool: `tank`},
+			{EID: 2, Class: `ereport.fs.zfs.checksum`, Pool: `tank`},
+		}, nil),
+		// Previously counted events remain in the buffer
+		zfsClient.EXPECT().Events().Return([]zfs.EventT{
+			{EID: 1, Class: `sysevent.fs.zfs.config_sync`, Pool: `tank`},
+			{EID: 2, Class: `ereport.fs.zfs.checksum`, Pool: `tank`},
+			{EID: 3, Class: `ereport.fs.zfs.checksum`, Pool: `tank`},
+		}, nil),
+		// Event IDs restart after the module is reloaded
+		zfsClient.EXPECT().Events().Return([]zfs.EventT{
+			{EID: 1, Class: `ereport.fs.zfs.checksum`, Pool: `tank`},
+			{EID: 2, Class: `sysevent.fs.zfs.history_event`},
+		}, nil),
+	)
+
+	c := NewEventsCollector(logger, zfsClient)
+	for range 3 {
+		if err := c.Poll(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := testutil.CollectAndCompare(c, bytes.NewBufferString(result)); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,225 @@
+package collector
+
+import (
+	"fmt"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/jmcgover/zfs_exporter/v2/zfs"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Components of the health score
+const (
+	healthScoreState    = `state`
+	healthScoreErrors   = `errors`
+	healthScoreCapacity = `capacity`
+	healthScoreScrub    = `scrub`
+)
+
+var (
+	// DefaultHealthScoreWeights are the weights of the components of the health score that are not configured
+	DefaultHealthScoreWeights = map[string]float64{
+		healthScoreState:    50,
+		healthScoreErrors:   20,
+		healthScoreCapacity: 20,
+		healthScoreScrub:    10,
+	}
+
+	poolHealthScoreName = prometheus.BuildFQName(namespace, subsystemPool, `health_score`)
+	hostHealthScoreName = prometheus.BuildFQName(namespace, subsystemHost, `health_score`)
+	poolHealthScoreDesc = prometheus.NewDesc(
+		poolHealthScoreName,
+		`Health of the pool from 0 (worst) to 100 (best), combining its state, errors, capacity and scrub age.`,
+		[]string{`pool`},
+		nil,
+	)
+	hostHealthScoreDesc = prometheus.NewDesc(
+		hostHealthScoreName,
+		`Health of the host from 0 (worst) to 100 (best), the lowest health score of its pools.`,
+		nil,
+		nil,
+	)
+)
+
+// HealthScoreConfig configures the health-score collector. Zero thresholds take their defaults.
+type HealthScoreConfig struct {
+	// Weights of the state, errors, capacity and scrub components, relative to their sum. Components without a weight
+	// take their default weight, and a weight of zero ignores the component.
+	Weights map[string]float64
+	// CapacityThreshold is the fraction of the pool allocated above which the capacity component falls, 0.8 by default
+	CapacityThreshold float64
+	// ErrorThreshold is the number of errors at which the errors component reaches zero, 10 by default
+	ErrorThreshold int
+	// ScrubMaxAge is the age of the last completed scrub at which the scrub component reaches zero, 35 days by default
+	ScrubMaxAge time.Duration
+}
+
+func init() {
+	registerCollector(`health-score`, defaultDisabled, ``, newHealthScoreCollector)
+}
+
+// configuredHealthScorer scores pools for the health-score collector, set by ConfigureHealthScore. The defaults are
+// always valid.
+var configuredHealthScorer, _ = newHealthScorer(HealthScoreConfig{})
+
+// healthScorer holds the weights of the components of the health score, and the thresholds at which they fall
+type healthScorer struct {
+	weights           map[string]float64
+	capacityThreshold float64
+	errorThreshold    float64
+	scrubMaxAge       time.Duration
+}
+
+// newHealthScorer applies the defaults to the config, and validates its weights
+func newHealthScorer(config HealthScoreConfig) (healthScorer, error) {
+	s := healthScorer{
+		weights:           make(map[string]float64, len(DefaultHealthScoreWeights)),
+		capacityThreshold: 0.8,
+		errorThreshold:    10,
+		scrubMaxAge:       35 * 24 * time.Hour,
+	}
+	var total float64
+	for component, weight := range DefaultHealthScoreWeights {
+		if w, ok := config.Weights[component]; ok {
+			weight = w
+		}
+		s.weights[component] = weight
+		total += weight
+	}
+	for component := range config.Weights {
+		if _, ok := DefaultHealthScoreWeights[component]; !ok {
+			return s, fmt.Errorf("unknown health score component: %s", component)
+		}
+	}
+	if total <= 0 {
+		return s, fmt.Errorf("health score weights must not all be zero")
+	}
+	if config.CapacityThreshold > 0 {
+		s.capacityThreshold = config.CapacityThreshold
+	}
+	if config.ErrorThreshold > 0 {
+		s.errorThreshold = float64(config.ErrorThreshold)
+	}
+	if config.ScrubMaxAge > 0 {
+		s.scrubMaxAge = config.ScrubMaxAge
+	}
+
+	return s, nil
+}
+
+// ConfigureHealthScore sets the weights and thresholds of the health score, and enables the health-score collector
+// unless it was disabled on the command line. It must be called before collection starts.
+func ConfigureHealthScore(config HealthScoreConfig) error {
+	s, err := newHealthScorer(config)
+	if err != nil {
+		return err
+	}
+	configuredHealthScorer = s
+	if forcedCollectors[`health-score`] {
+		return nil
+	}
+	enabled := true
+	return Configure(`health-score`, &enabled, nil)
+}
+
+// healthScoreCollector exports a single score per pool and host, for wallboards that show one number per storage
+// host. Each component scores from 0 to 1, and the score is their weighted average scaled to 100. Components that
+// cannot be determined, such as the scrub age of a pool that has not been scrubbed since its last resilver, are
+// omitted from the average.
+type healthScoreCollector struct {
+	log    *slog.Logger
+	client zfs.Client
+	scorer healthScorer
+}
+
+func (c *healthScoreCollector) describe(ch chan<- *prometheus.Desc) {
+	ch <- poolHealthScoreDesc
+	ch <- hostHealthScoreDesc
+}
+
+// update scores the given pools, and the host by the lowest of their scores, which is omitted without pools
+func (c *healthScoreCollector) update(ch chan<- metric, pools []string, excludes regexpCollection) error {
+	status, err := c.client.PoolStatus(false)
+	// Where only some pools could be queried, the others are reported before the error is returned
+	if err != nil && len(status) == 0 {
+		return err
+	}
+	list, listErr := c.client.PoolList(`capacity`)
+	if listErr != nil {
+		// The score is still useful without the capacity component
+		c.log.Warn("Error getting pool capacity for health score", "err", listErr)
+	}
+
+	now := time.Now()
+	host := -1.0
+	for _, name := range pools {
+		pool, ok := status[name]
+		if !ok {
+			if err == nil {
+				c.log.Warn("Pool missing from status output", "collector", "health-score", "pool", name)
+			}
+			continue
+		}
+		score := c.scorer.score(pool, list[name], now)
+		ch <- metric{
+			name:       expandMetricName(poolHealthScoreName, name),
+			prometheus: prometheus.MustNewConstMetric(poolHealthScoreDesc, prometheus.GaugeValue, score, name),
+		}
+		if host < 0 || score < host {
+			host = score
+		}
+	}
+	if host >= 0 {
+		ch <- metric{
+			name:       hostHealthScoreName,
+			prometheus: prometheus.MustNewConstMetric(hostHealthScoreDesc, prometheus.GaugeValue, host),
+		}
+	}
+
+	return err
+}
+
+func newHealthScoreCollector(l *slog.Logger, c zfs.Client, props []string) (Collector, error) {
+	return &healthScoreCollector{log: l, client: c, scorer: configuredHealthScorer}, nil
+}
+
+// score returns the weighted average of the components of the pool that could be determined, scaled to 100
+func (s healthScorer) score(pool zfs.PoolStatusT, list zfs.PoolListT, now time.Time) float64 {
+	var sum, weights float64
+	add := func(component string, v float64) {
+		sum += s.weights[component] * min(max(v, 0), 1)
+		weights += s.weights[component]
+	}
+
+	switch zfs.PoolStatus(pool.State) {
+	case zfs.PoolOnline:
+		add(healthScoreState, 1)
+	case zfs.PoolDegraded:
+		add(healthScoreState, 0.5)
+	default:
+		add(healthScoreState, 0)
+	}
+
+	errors := pool.ErrorCount
+	for _, vdev := range pool.AllVdevs() {
+		errors += vdev.ReadErrors + vdev.WriteErrors + vdev.ChecksumErrors
+	}
+	add(healthScoreErrors, 1-float64(errors)/s.errorThreshold)
+
+	if capacity, err := strconv.ParseFloat(string(list.Properties[`capacity`].Value), 64); err == nil {
+		add(healthScoreCapacity, (1-capacity/100)/(1-s.capacityThreshold))
+	}
+
+	if scan := pool.ScanStats; scan.Function == `SCRUB` && scan.State == `FINISHED` {
+		age := now.Sub(time.Unix(int64(scan.EndTime), 0))
+		add(healthScoreScrub, 1-age.Seconds()/s.scrubMaxAge.Seconds())
+	}
+
+	if weights == 0 {
+		// Only components with a weight of zero could be determined
+		return 100
+	}
+	return 100 * sum / weights
+}
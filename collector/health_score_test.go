@@ -0,0 +1,96 @@
+package collector
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jmcgover/zfs_exporter/v2/zfs"
+	"github.com/jmcgover/zfs_exporter/v2/zfs/mock_zfs"
+	"go.uber.org/mock/gomock"
+)
+
+func TestHealthScoreCollector(t *testing.T) {
+	const result = `# HELP zfs_host_health_score Health of the host from 0 (worst) to 100 (best), the lowest health score of its pools.
+# TYPE zfs_host_health_score gauge
+zfs_host_health_score 38.888888888888886
+# HELP zfs_pool_health_score Health of the pool from 0 (worst) to 100 (best), combining its state, errors, capacity and scrub age.
+# TYPE zfs_pool_health_score gauge
+zfs_pool_health_score{pool="backup"} 38.888888888888886
+zfs_pool_health_score{pool="tank"} 100
+`
+
+	ctrl, ctx := gomock.WithContext(context.Background(), t)
+	zfsClient := mock_zfs.NewMockClient(ctrl)
+	zfsClient.EXPECT().PoolNames().Return([]string{`backup`, `scratch`, `tank`}, nil).Times(1)
+	zfsClient.EXPECT().PoolStatus(false).Return(map[string]zfs.PoolStatusT{
+		`tank`: {Name: `tank`, State: `ONLINE`},
+		// Degraded with 5 checksum errors and full, without a completed scrub
+		`backup`: {Name: `backup`, State: `DEGRADED`, Vdevs: map[string]zfs.VdevStatusT{
+			`sda`: {Name: `sda`, State: `FAULTED`, ChecksumErrors: 5},
+		}},
+		// Excluded by the pool filters, so neither scored nor counted towards the host score
+		`scratch`: {Name: `scratch`, State: `FAULTED`},
+	}, nil).Times(1)
+	zfsClient.EXPECT().PoolList(`capacity`).Return(map[string]zfs.PoolListT{
+		`tank`:   {Name: `tank`, Properties: map[string]zfs.PropertyT{`capacity`: {Value: `50`}}},
+		`backup`: {Name: `backup`, Properties: map[string]zfs.PropertyT{`capacity`: {Value: `100`}}},
+	}, nil).Times(1)
+
+	defer func(previous healthScorer) { configuredHealthScorer = previous }(configuredHealthScorer)
+	scorer, err := newHealthScorer(HealthScoreConfig{ScrubMaxAge: 24 * time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	configuredHealthScorer = scorer
+
+	config := defaultConfig(zfsClient)
+	config.PoolExcludes = []string{`scratch`}
+	collector, err := NewZFS(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	collector.Collectors = map[string]State{
+		`health-score`: {
+			Name:       "health-score",
+			Enabled:    boolPointer(true),
+			Properties: stringPointer(``),
+			factory:    newHealthScoreCollector,
+		},
+	}
+
+	if err = callCollector(ctx, collector, []byte(result), []string{`zfs_host_health_score`, `zfs_pool_health_score`}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestHealthScoreScrubAge(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	scorer, err := newHealthScorer(HealthScoreConfig{ScrubMaxAge: 24 * time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Scrubbed half the maximum age ago, and otherwise healthy
+	pool := zfs.PoolStatusT{Name: `tank`, State: `ONLINE`, ScanStats: zfs.ScanStatsT{
+		Function: `SCRUB`,
+		State:    `FINISHED`,
+		EndTime:  zfs.Uint64(now.Add(-12 * time.Hour).Unix()),
+	}}
+	list := zfs.PoolListT{Name: `tank`, Properties: map[string]zfs.PropertyT{`capacity`: {Value: `50`}}}
+	if score := scorer.score(pool, list, now); score != 95 {
+		t.Errorf("Expected a score of 95, got %g", score)
+	}
+}
+
+func TestNewHealthScorerErrors(t *testing.T) {
+	for name, config := range map[string]HealthScoreConfig{
+		`unknown component`: {Weights: map[string]float64{`temperature`: 1}},
+		`all weights zero`:  {Weights: map[string]float64{`state`: 0, `errors`: 0, `capacity`: 0, `scrub`: 0}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			if _, err := newHealthScorer(config); err == nil {
+				t.Fatal(`expected error`)
+			}
+		})
+	}
+}
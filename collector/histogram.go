@@ -0,0 +1,127 @@
+package collector
+
+import (
+	"math"
+	"slices"
+
+	"github.com/alecthomas/kingpin/v2"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	// nativeHistogramBucketFactor yields native histogram schema 3, around 9% relative bucket width.
+	nativeHistogramBucketFactor = 1.1
+	// nativeHistogramSchema is the schema yielded by nativeHistogramBucketFactor, for histograms built from counts.
+	nativeHistogramSchema = 3
+)
+
+var (
+	classicHistogramBuckets = kingpin.Flag(`histogram.classic-buckets`, `Include classic buckets in latency histograms for compatibility with scrapers that do not negotiate protobuf exposition. Native histogram buckets are always included.`).Default(`true`).Bool()
+
+	// latencyBuckets spans 1µs to ~67s, matching the power of two nanosecond buckets reported by ZFS.
+	latencyBuckets = prometheus.ExponentialBuckets(1024e-9, 2, 27)
+)
+
+// newLatencyHistogramVec creates a histogram that is exposed as a native histogram where protobuf exposition is
+// negotiated, and additionally with classic buckets unless disabled.
+func newLatencyHistogramVec(subsystem, metricName, helpText string, labels ...string) *prometheus.HistogramVec {
+	opts := prometheus.HistogramOpts{
+		Namespace:                   namespace,
+		Subsystem:                   subsystem,
+		Name:                        metricName,
+		Help:                        helpText,
+		NativeHistogramBucketFactor: nativeHistogramBucketFactor,
+	}
+	if *classicHistogramBuckets {
+		opts.Buckets = latencyBuckets
+	}
+
+	return prometheus.NewHistogramVec(opts, labels)
+}
+
+// latencyHistogram is a histogram built from the counts of power of two nanosecond buckets reported by ZFS, where
+// bucket i counts observations of between 2^i and 2^(i+1)-1 nanoseconds.
+type latencyHistogram struct {
+	desc      *prometheus.Desc
+	histogram *dto.Histogram
+	labels    []*dto.LabelPair
+}
+
+func (h latencyHistogram) Desc() *prometheus.Desc {
+	return h.desc
+}
+
+func (h latencyHistogram) Write(m *dto.Metric) error {
+	m.Histogram = h.histogram
+	m.Label = h.labels
+	return nil
+}
+
+// newLatencyHistogram returns the histogram of the ZFS bucket counts, with native buckets, and with the classic
+// latencyBuckets unless disabled. Each ZFS bucket is counted in the native bucket of its upper bound. ZFS does not report
+// the sum of observations, which is estimated from the midpoints of the buckets.
+func newLatencyHistogram(desc *prometheus.Desc, counts []uint64, labelValues ...string) prometheus.Metric {
+	var (
+		total uint64
+		sum   float64
+	)
+	native := make(map[int32]uint64, len(counts))
+	for i, count := range counts {
+		if count == 0 {
+			continue
+		}
+		total += count
+		sum += float64(count) * 1.5 * math.Ldexp(1, i)
+		upper := math.Ldexp(1e-9, i+1)
+		native[int32(math.Ceil(math.Log2(upper)*(1<<nativeHistogramSchema)))] += count
+	}
+
+	histogram := &dto.Histogram{
+		SampleCount:   proto.Uint64(total),
+		SampleSum:     proto.Float64(sum / 1e9),
+		Schema:        proto.Int32(nativeHistogramSchema),
+		ZeroThreshold: proto.Float64(0),
+		ZeroCount:     proto.Uint64(0),
+	}
+
+	indexes := make([]int32, 0, len(native))
+	for index := range native {
+		indexes = append(indexes, index)
+	}
+	slices.Sort(indexes)
+	var previous int32
+	var previousCount int64
+	for n, index := range indexes {
+		if n == 0 || index > previous+1 {
+			offset := index
+			if n > 0 {
+				offset = index - previous - 1
+			}
+			histogram.PositiveSpan = append(histogram.PositiveSpan, &dto.BucketSpan{Offset: proto.Int32(offset), Length: proto.Uint32(0)})
+		}
+		span := histogram.PositiveSpan[len(histogram.PositiveSpan)-1]
+		span.Length = proto.Uint32(span.GetLength() + 1)
+		histogram.PositiveDelta = append(histogram.PositiveDelta, int64(native[index])-previousCount)
+		previous, previousCount = index, int64(native[index])
+	}
+	// An empty span marks the histogram as native where it has no observations
+	if len(histogram.PositiveSpan) == 0 {
+		histogram.PositiveSpan = []*dto.BucketSpan{{Offset: proto.Int32(0), Length: proto.Uint32(0)}}
+	}
+
+	if *classicHistogramBuckets {
+		var cumulative uint64
+		i := 0
+		for _, bound := range latencyBuckets {
+			// Count the ZFS buckets whose upper bound of 2^(i+1) nanoseconds is within the classic bound
+			for ; i < len(counts) && math.Ldexp(1e-9, i+1) <= bound*(1+1e-9); i++ {
+				cumulative += counts[i]
+			}
+			histogram.Bucket = append(histogram.Bucket, &dto.Bucket{CumulativeCount: proto.Uint64(cumulative), UpperBound: proto.Float64(bound)})
+		}
+	}
+
+	return latencyHistogram{desc: desc, histogram: histogram, labels: prometheus.MakeLabelPairs(desc, labelValues)}
+}
@@ -0,0 +1,105 @@
+package collector
+
+import (
+	"log/slog"
+
+	"github.com/jmcgover/zfs_exporter/v2/zfs"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+type ioStat struct {
+	pool  property
+	host  property
+	value func(zfs.VdevIostatT) float64
+}
+
+var ioStats = []ioStat{
+	newIOStat(
+		`read_ops_per_second`,
+		`read operations per second`,
+		func(s zfs.VdevIostatT) float64 { return float64(s.ReadOps) },
+	),
+	newIOStat(
+		`write_ops_per_second`,
+		`write operations per second`,
+		func(s zfs.VdevIostatT) float64 { return float64(s.WriteOps) },
+	),
+	newIOStat(
+		`read_bytes_per_second`,
+		`bytes read per second`,
+		func(s zfs.VdevIostatT) float64 { return float64(s.ReadBytes) },
+	),
+	newIOStat(
+		`write_bytes_per_second`,
+		`bytes written per second`,
+		func(s zfs.VdevIostatT) float64 { return float64(s.WriteBytes) },
+	),
+}
+
+func init() {
+	registerCollector(`io`, defaultDisabled, ``, newIOCollector)
+}
+
+type ioCollector struct {
+	log    *slog.Logger
+	client zfs.Client
+}
+
+func (c *ioCollector) describe(ch chan<- *prometheus.Desc) {
+	for _, stat := range ioStats {
+		ch <- stat.pool.desc
+		ch <- stat.host.desc
+	}
+}
+
+func (c *ioCollector) update(ch chan<- metric, pools []string, excludes regexpCollection) error {
+	stats, err := c.client.PoolIostats(false)
+	if err != nil {
+		return err
+	}
+
+	totals := make([]float64, len(ioStats))
+	for _, pool := range pools {
+		poolStats, ok := stats[pool]
+		if !ok {
+			c.log.Warn("Pool missing from iostat output", "collector", "io", "pool", pool)
+			continue
+		}
+		for i, stat := range ioStats {
+			v := stat.value(poolStats.Totals())
+			totals[i] += v
+			stat.pool.send(ch, v, pool)
+		}
+	}
+
+	for i, stat := range ioStats {
+		stat.host.send(ch, totals[i])
+	}
+
+	return nil
+}
+
+func newIOStat(metricName, helpText string, value func(zfs.VdevIostatT) float64) ioStat {
+	return ioStat{
+		pool: newProperty(
+			subsystemPool,
+			metricName,
+			`Average `+helpText+` for the pool over --zfs.iostat-interval.`,
+			transformNumeric,
+			prometheus.GaugeValue,
+			poolLabels...,
+		),
+		host: newProperty(
+			subsystemHost,
+			metricName,
+			`Average `+helpText+` over --zfs.iostat-interval summed across all collected pools.`,
+			transformNumeric,
+			prometheus.GaugeValue,
+		),
+		value: value,
+	}
+}
+
+func newIOCollector(l *slog.Logger, c zfs.Client, props []string) (Collector, error) {
+	return &ioCollector{log: l, client: c}, nil
+}
@@ -0,0 +1,64 @@
+package collector
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jmcgover/zfs_exporter/v2/zfs"
+	"github.com/jmcgover/zfs_exporter/v2/zfs/mock_zfs"
+	"go.uber.org/mock/gomock"
+)
+
+func TestIOMetrics(t *testing.T) {
+	const result = `# HELP zfs_host_read_bytes_per_second Average bytes read per second over --zfs.iostat-interval summed across all collected pools.
+# TYPE zfs_host_read_bytes_per_second gauge
+zfs_host_read_bytes_per_second 3072
+# HELP zfs_host_read_ops_per_second Average read operations per second over --zfs.iostat-interval summed across all collected pools.
+# TYPE zfs_host_read_ops_per_second gauge
+zfs_host_read_ops_per_second 30
+# HELP zfs_pool_read_bytes_per_second Average bytes read per second for the pool over --zfs.iostat-interval.
+# TYPE zfs_pool_read_bytes_per_second gauge
+zfs_pool_read_bytes_per_second{pool="testpool1"} 1024
+zfs_pool_read_bytes_per_second{pool="testpool2"} 2048
+# HELP zfs_pool_write_ops_per_second Average write operations per second for the pool over --zfs.iostat-interval.
+# TYPE zfs_pool_write_ops_per_second gauge
+zfs_pool_write_ops_per_second{pool="testpool1"} 5
+zfs_pool_write_ops_per_second{pool="testpool2"} 7
+`
+
+	ctrl, ctx := gomock.WithContext(context.Background(), t)
+	zfsClient := mock_zfs.NewMockClient(ctrl)
+	zfsClient.EXPECT().PoolNames().Return([]string{`testpool1`, `testpool2`}, nil).Times(1)
+	zfsClient.EXPECT().PoolIostats(false).Return(map[string]zfs.PoolIostatT{
+		`testpool1`: {
+			Name: `testpool1`,
+			Vdevs: map[string]zfs.VdevIostatT{
+				`testpool1`: {Name: `testpool1`, ReadOps: 10, WriteOps: 5, ReadBytes: 1024, WriteBytes: 512},
+			},
+		},
+		`testpool2`: {
+			Name: `testpool2`,
+			Vdevs: map[string]zfs.VdevIostatT{
+				`testpool2`: {Name: `testpool2`, ReadOps: 20, WriteOps: 7, ReadBytes: 2048, WriteBytes: 256},
+			},
+		},
+	}, nil).Times(1)
+
+	collector, err := NewZFS(defaultConfig(zfsClient))
+	if err != nil {
+		t.Fatal(err)
+	}
+	collector.Collectors = map[string]State{
+		`io`: {
+			Name:       "io",
+			Enabled:    boolPointer(true),
+			Properties: stringPointer(``),
+			factory:    newIOCollector,
+		},
+	}
+
+	metricNames := []string{`zfs_host_read_bytes_per_second`, `zfs_host_read_ops_per_second`, `zfs_pool_read_bytes_per_second`, `zfs_pool_write_ops_per_second`}
+	if err = callCollector(ctx, collector, []byte(result), metricNames); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,103 @@
+package collector
+
+import (
+	"log/slog"
+
+	"github.com/jmcgover/zfs_exporter/v2/zfs"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	defaultL2arcProps = `l2_asize,l2_evict_l1cached,l2_evict_lock_retry,l2_evict_reading,l2_feeds,l2_hdr_size,l2_hits,l2_misses,l2_read_bytes,l2_size,l2_write_bytes`
+)
+
+var l2arcProperties = propertyStore{
+	defaultSubsystem: subsystemL2arc,
+	store: map[string]property{
+		`l2_asize`: newProperty(
+			subsystemL2arc,
+			`allocated_bytes`,
+			`The space in bytes allocated on cache devices, after compression.`,
+			transformNumeric,
+			prometheus.GaugeValue,
+		),
+		`l2_evict_l1cached`: newProperty(
+			subsystemL2arc,
+			`evict_l1cached_total`,
+			`The number of L2ARC buffers evicted while still cached in the ARC.`,
+			transformNumeric,
+			prometheus.CounterValue,
+		),
+		`l2_evict_lock_retry`: newProperty(
+			subsystemL2arc,
+			`evict_lock_retry_total`,
+			`The number of times L2ARC eviction had to retry acquiring a lock.`,
+			transformNumeric,
+			prometheus.CounterValue,
+		),
+		`l2_evict_reading`: newProperty(
+			subsystemL2arc,
+			`evict_reading_total`,
+			`The number of L2ARC buffers evicted while being read.`,
+			transformNumeric,
+			prometheus.CounterValue,
+		),
+		`l2_feeds`: newProperty(
+			subsystemL2arc,
+			`feeds_total`,
+			`The number of iterations of the thread that feeds cache devices from the ARC.`,
+			transformNumeric,
+			prometheus.CounterValue,
+		),
+		`l2_hdr_size`: newProperty(
+			subsystemL2arc,
+			`header_size_bytes`,
+			`The size in bytes of the ARC memory used by headers of buffers held in the L2ARC.`,
+			transformNumeric,
+			prometheus.GaugeValue,
+		),
+		`l2_hits`: newProperty(
+			subsystemL2arc,
+			`hits_total`,
+			`The number of ARC misses satisfied from cache devices.`,
+			transformNumeric,
+			prometheus.CounterValue,
+		),
+		`l2_misses`: newProperty(
+			subsystemL2arc,
+			`misses_total`,
+			`The number of ARC misses that could not be satisfied from cache devices.`,
+			transformNumeric,
+			prometheus.CounterValue,
+		),
+		`l2_read_bytes`: newProperty(
+			subsystemL2arc,
+			`read_bytes_total`,
+			`The number of bytes read from cache devices.`,
+			transformNumeric,
+			prometheus.CounterValue,
+		),
+		`l2_size`: newProperty(
+			subsystemL2arc,
+			`size_bytes`,
+			`The size in bytes of the data held on cache devices, before compression.`,
+			transformNumeric,
+			prometheus.GaugeValue,
+		),
+		`l2_write_bytes`: newProperty(
+			subsystemL2arc,
+			`write_bytes_total`,
+			`The number of bytes written to cache devices.`,
+			transformNumeric,
+			prometheus.CounterValue,
+		),
+	},
+}
+
+func init() {
+	registerCollector(`l2arc`, defaultDisabled, defaultL2arcProps, newL2arcCollector)
+}
+
+func newL2arcCollector(l *slog.Logger, c zfs.Client, props []string) (Collector, error) {
+	return &arcstatsCollector{log: l, client: c, props: props, name: `l2arc`, store: l2arcProperties}, nil
+}
@@ -0,0 +1,52 @@
+package collector
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/jmcgover/zfs_exporter/v2/zfs/mock_zfs"
+	"go.uber.org/mock/gomock"
+)
+
+func TestL2arcMetrics(t *testing.T) {
+	const result = `# HELP zfs_l2arc_hits_total The number of ARC misses satisfied from cache devices.
+# TYPE zfs_l2arc_hits_total counter
+zfs_l2arc_hits_total 4096
+# HELP zfs_l2arc_misses_total The number of ARC misses that could not be satisfied from cache devices.
+# TYPE zfs_l2arc_misses_total counter
+zfs_l2arc_misses_total 1024
+# HELP zfs_l2arc_size_bytes The size in bytes of the data held on cache devices, before compression.
+# TYPE zfs_l2arc_size_bytes gauge
+zfs_l2arc_size_bytes 1.073741824e+09
+`
+	propsRequested := []string{`l2_hits`, `l2_misses`, `l2_size`}
+
+	ctrl, ctx := gomock.WithContext(context.Background(), t)
+	zfsClient := mock_zfs.NewMockClient(ctrl)
+	zfsClient.EXPECT().PoolNames().Return([]string{`testpool`}, nil).Times(1)
+	zfsClient.EXPECT().ArcStats().Return(map[string]string{
+		`hits`:      `123456`,
+		`l2_hits`:   `4096`,
+		`l2_misses`: `1024`,
+		`l2_size`:   `1073741824`,
+	}, nil).Times(1)
+
+	collector, err := NewZFS(defaultConfig(zfsClient))
+	if err != nil {
+		t.Fatal(err)
+	}
+	collector.Collectors = map[string]State{
+		`l2arc`: {
+			Name:       "l2arc",
+			Enabled:    boolPointer(true),
+			Properties: stringPointer(strings.Join(propsRequested, `,`)),
+			factory:    newL2arcCollector,
+		},
+	}
+
+	metricNames := []string{`zfs_l2arc_hits_total`, `zfs_l2arc_misses_total`, `zfs_l2arc_size_bytes`, `zfs_arc_hits_total`}
+	if err = callCollector(ctx, collector, []byte(result), metricNames); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,99 @@
+package collector
+
+import (
+	"errors"
+	"log/slog"
+
+	"github.com/jmcgover/zfs_exporter/v2/zfs"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+type latencyStat struct {
+	name  string
+	desc  *prometheus.Desc
+	read  func(zfs.LatencyHistogramsT) []uint64
+	write func(zfs.LatencyHistogramsT) []uint64
+}
+
+var latencyStats = []latencyStat{
+	newLatencyStat(
+		`total_wait_seconds`,
+		`Total time operations on the pool spent queued and on the disk since import, as reported by zpool iostat -w.`,
+		func(h zfs.LatencyHistogramsT) []uint64 { return h.TotalReadWait },
+		func(h zfs.LatencyHistogramsT) []uint64 { return h.TotalWriteWait },
+	),
+	newLatencyStat(
+		`disk_wait_seconds`,
+		`Time operations on the pool spent on the disk since import, as reported by zpool iostat -w.`,
+		func(h zfs.LatencyHistogramsT) []uint64 { return h.DiskReadWait },
+		func(h zfs.LatencyHistogramsT) []uint64 { return h.DiskWriteWait },
+	),
+	newLatencyStat(
+		`syncq_wait_seconds`,
+		`Time synchronous operations on the pool spent in the vdev queue since import, as reported by zpool iostat -w.`,
+		func(h zfs.LatencyHistogramsT) []uint64 { return h.SyncqReadWait },
+		func(h zfs.LatencyHistogramsT) []uint64 { return h.SyncqWriteWait },
+	),
+	newLatencyStat(
+		`asyncq_wait_seconds`,
+		`Time asynchronous operations on the pool spent in the vdev queue since import, as reported by zpool iostat -w.`,
+		func(h zfs.LatencyHistogramsT) []uint64 { return h.AsyncqReadWait },
+		func(h zfs.LatencyHistogramsT) []uint64 { return h.AsyncqWriteWait },
+	),
+}
+
+func init() {
+	registerCollector(`latency`, defaultDisabled, ``, newLatencyCollector)
+}
+
+// latencyCollector exports the latency histograms of each pool, which, unlike the averages of the vdev-io collector,
+// show the tail latency of its operations.
+type latencyCollector struct {
+	log        *slog.Logger
+	client     zfs.Client
+	histograms zfs.HistogramClient
+}
+
+func (c *latencyCollector) describe(ch chan<- *prometheus.Desc) {
+	for _, stat := range latencyStats {
+		ch <- stat.desc
+	}
+}
+
+func (c *latencyCollector) update(ch chan<- metric, pools []string, excludes regexpCollection) error {
+	return updatePools(pools, func(pool string) error {
+		histograms, err := c.histograms.LatencyHistograms(pool)
+		if err != nil {
+			return err
+		}
+		for _, stat := range latencyStats {
+			ch <- metric{
+				name:       expandMetricName(stat.name, pool, `read`),
+				prometheus: newLatencyHistogram(stat.desc, stat.read(histograms), pool, `read`),
+			}
+			ch <- metric{
+				name:       expandMetricName(stat.name, pool, `write`),
+				prometheus: newLatencyHistogram(stat.desc, stat.write(histograms), pool, `write`),
+			}
+		}
+		return nil
+	})
+}
+
+func newLatencyStat(metricName, helpText string, read, write func(zfs.LatencyHistogramsT) []uint64) latencyStat {
+	name := prometheus.BuildFQName(namespace, subsystemPool, metricName)
+	return latencyStat{
+		name:  name,
+		desc:  prometheus.NewDesc(name, helpText, append(poolLabels, `op`), nil),
+		read:  read,
+		write: write,
+	}
+}
+
+func newLatencyCollector(l *slog.Logger, c zfs.Client, props []string) (Collector, error) {
+	histograms, ok := c.(zfs.HistogramClient)
+	if !ok {
+		return nil, errors.New(`client does not read latency histograms`)
+	}
+	return &latencyCollector{log: l, client: c, histograms: histograms}, nil
+}
@@ -0,0 +1,90 @@
+package collector
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jmcgover/zfs_exporter/v2/zfs"
+	"github.com/jmcgover/zfs_exporter/v2/zfs/mock_zfs"
+	dto "github.com/prometheus/client_model/go"
+	"go.uber.org/mock/gomock"
+)
+
+// histogramClient is a mock client that also reads latency histograms
+type histogramClient struct {
+	*mock_zfs.MockClient
+	*mock_zfs.MockHistogramClient
+}
+
+func TestLatencyMetrics(t *testing.T) {
+	const result = `# HELP zfs_pool_total_wait_seconds Total time operations on the pool spent queued and on the disk since import, as reported by zpool iostat -w.
+# TYPE zfs_pool_total_wait_seconds histogram
+zfs_pool_total_wait_seconds_bucket{op="read",pool="testpool",le="+Inf"} 2
+zfs_pool_total_wait_seconds_sum{op="read",pool="testpool"} 0.003145728
+zfs_pool_total_wait_seconds_count{op="read",pool="testpool"} 2
+zfs_pool_total_wait_seconds_bucket{op="write",pool="testpool",le="+Inf"} 0
+zfs_pool_total_wait_seconds_sum{op="write",pool="testpool"} 0
+zfs_pool_total_wait_seconds_count{op="write",pool="testpool"} 0
+`
+
+	ctrl, ctx := gomock.WithContext(context.Background(), t)
+	client := histogramClient{MockClient: mock_zfs.NewMockClient(ctrl), MockHistogramClient: mock_zfs.NewMockHistogramClient(ctrl)}
+	client.MockClient.EXPECT().PoolNames().Return([]string{`testpool`}, nil).Times(1)
+	client.MockHistogramClient.EXPECT().LatencyHistograms(`testpool`).Return(zfs.LatencyHistogramsT{
+		TotalReadWait: []uint64{20: 2},
+	}, nil).Times(1)
+
+	config := defaultConfig(client)
+	collector, err := NewZFS(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	collector.Collectors = map[string]State{
+		`latency`: {
+			Name:       "latency",
+			Enabled:    boolPointer(true),
+			Properties: stringPointer(``),
+			factory:    newLatencyCollector,
+		},
+	}
+
+	if err = callCollector(ctx, collector, []byte(result), []string{`zfs_pool_total_wait_seconds`}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLatencyHistogramBuckets(t *testing.T) {
+	classic := *classicHistogramBuckets
+	*classicHistogramBuckets = true
+	defer func() { *classicHistogramBuckets = classic }()
+
+	// Operations of 1-1023ns, 2-4µs and 16-32µs
+	counts := []uint64{5, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 3, 0, 0, 7}
+	var m dto.Metric
+	if err := newLatencyHistogram(latencyStats[0].desc, counts, `testpool`, `read`).Write(&m); err != nil {
+		t.Fatal(err)
+	}
+	h := m.GetHistogram()
+
+	if h.GetSampleCount() != 15 {
+		t.Errorf("Expected 15 observations, got %d", h.GetSampleCount())
+	}
+	// The first classic bucket of 1024ns includes all faster operations
+	for i, expected := range map[int]uint64{0: 5, 1: 5, 2: 8, 4: 8, 5: 15, 26: 15} {
+		if got := h.GetBucket()[i].GetCumulativeCount(); got != expected {
+			t.Errorf("Expected %d observations up to %gs, got %d", expected, h.GetBucket()[i].GetUpperBound(), got)
+		}
+	}
+
+	// Each ZFS bucket is a power of two, so eight native buckets of schema 3 apart
+	if h.GetSchema() != nativeHistogramSchema {
+		t.Errorf("Expected schema %d, got %d", nativeHistogramSchema, h.GetSchema())
+	}
+	spans := h.GetPositiveSpan()
+	if len(spans) != 3 || spans[1].GetOffset() != 8*11-1 || spans[2].GetOffset() != 8*3-1 {
+		t.Errorf("Unexpected native spans: %v", spans)
+	}
+	if deltas := h.GetPositiveDelta(); len(deltas) != 3 || deltas[0] != 5 || deltas[1] != -2 || deltas[2] != 4 {
+		t.Errorf("Unexpected native deltas: %v", deltas)
+	}
+}
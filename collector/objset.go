@@ -0,0 +1,102 @@
+package collector
+
+import (
+	"log/slog"
+
+	"github.com/jmcgover/zfs_exporter/v2/zfs"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// objsetLabels omit the dataset type, which the kstats do not record
+var objsetLabels = []string{`name`, `pool`}
+
+func init() {
+	registerCollector(`objset`, defaultDisabled, ``, newObjsetCollector)
+}
+
+// objsetCollector exports the I/O counters of each mounted dataset from the per-objset kstats, giving a per-dataset
+// breakdown of the pool's I/O without DTrace. The kstats are only available on Linux.
+type objsetCollector struct {
+	log          *slog.Logger
+	client       zfs.Client
+	reads        property
+	writes       property
+	readBytes    property
+	writtenBytes property
+	unlinks      property
+}
+
+func (c *objsetCollector) describe(ch chan<- *prometheus.Desc) {
+	ch <- c.reads.desc
+	ch <- c.writes.desc
+	ch <- c.readBytes.desc
+	ch <- c.writtenBytes.desc
+	ch <- c.unlinks.desc
+}
+
+func (c *objsetCollector) update(ch chan<- metric, pools []string, excludes regexpCollection) error {
+	return updatePools(pools, func(pool string) error {
+		datasets, err := c.client.ObjsetStats(pool)
+		if err != nil {
+			return err
+		}
+		for name, stats := range datasets {
+			if excludes.MatchString(name) {
+				continue
+			}
+			c.reads.send(ch, float64(stats.Reads), name, pool)
+			c.writes.send(ch, float64(stats.Writes), name, pool)
+			c.readBytes.send(ch, float64(stats.NRead), name, pool)
+			c.writtenBytes.send(ch, float64(stats.NWritten), name, pool)
+			c.unlinks.send(ch, float64(stats.NUnlinks), name, pool)
+		}
+		return nil
+	})
+}
+
+func newObjsetCollector(l *slog.Logger, c zfs.Client, props []string) (Collector, error) {
+	return &objsetCollector{
+		log:    l,
+		client: c,
+		reads: newProperty(
+			subsystemDataset,
+			`reads_total`,
+			`Number of read operations on the dataset since it was mounted.`,
+			transformNumeric,
+			prometheus.CounterValue,
+			objsetLabels...,
+		),
+		writes: newProperty(
+			subsystemDataset,
+			`writes_total`,
+			`Number of write operations on the dataset since it was mounted.`,
+			transformNumeric,
+			prometheus.CounterValue,
+			objsetLabels...,
+		),
+		readBytes: newProperty(
+			subsystemDataset,
+			`read_bytes_total`,
+			`Number of bytes read from the dataset since it was mounted.`,
+			transformNumeric,
+			prometheus.CounterValue,
+			objsetLabels...,
+		),
+		writtenBytes: newProperty(
+			subsystemDataset,
+			`written_bytes_total`,
+			`Number of bytes written to the dataset since it was mounted.`,
+			transformNumeric,
+			prometheus.CounterValue,
+			objsetLabels...,
+		),
+		unlinks: newProperty(
+			subsystemDataset,
+			`unlinks_total`,
+			`Number of files unlinked from the dataset since it was mounted.`,
+			transformNumeric,
+			prometheus.CounterValue,
+			objsetLabels...,
+		),
+	}, nil
+}
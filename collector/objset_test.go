@@ -0,0 +1,57 @@
+package collector
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jmcgover/zfs_exporter/v2/zfs"
+	"github.com/jmcgover/zfs_exporter/v2/zfs/mock_zfs"
+	"go.uber.org/mock/gomock"
+)
+
+func TestObjsetMetrics(t *testing.T) {
+	const result = `# HELP zfs_dataset_read_bytes_total Number of bytes read from the dataset since it was mounted.
+# TYPE zfs_dataset_read_bytes_total counter
+zfs_dataset_read_bytes_total{name="testpool/home",pool="testpool"} 12288
+# HELP zfs_dataset_reads_total Number of read operations on the dataset since it was mounted.
+# TYPE zfs_dataset_reads_total counter
+zfs_dataset_reads_total{name="testpool/home",pool="testpool"} 3
+# HELP zfs_dataset_unlinks_total Number of files unlinked from the dataset since it was mounted.
+# TYPE zfs_dataset_unlinks_total counter
+zfs_dataset_unlinks_total{name="testpool/home",pool="testpool"} 1
+# HELP zfs_dataset_writes_total Number of write operations on the dataset since it was mounted.
+# TYPE zfs_dataset_writes_total counter
+zfs_dataset_writes_total{name="testpool/home",pool="testpool"} 12
+# HELP zfs_dataset_written_bytes_total Number of bytes written to the dataset since it was mounted.
+# TYPE zfs_dataset_written_bytes_total counter
+zfs_dataset_written_bytes_total{name="testpool/home",pool="testpool"} 49152
+`
+
+	ctrl, ctx := gomock.WithContext(context.Background(), t)
+	zfsClient := mock_zfs.NewMockClient(ctrl)
+	zfsClient.EXPECT().PoolNames().Return([]string{`testpool`}, nil).Times(1)
+	zfsClient.EXPECT().ObjsetStats(`testpool`).Return(map[string]zfs.ObjsetStatsT{
+		`testpool/home`:   {Reads: 3, Writes: 12, NRead: 12288, NWritten: 49152, NUnlinks: 1},
+		`testpool/docker`: {Reads: 100, Writes: 100},
+	}, nil).Times(1)
+
+	config := defaultConfig(zfsClient)
+	config.Excludes = []string{`^testpool/docker$`}
+	collector, err := NewZFS(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	collector.Collectors = map[string]State{
+		`objset`: {
+			Name:       "objset",
+			Enabled:    boolPointer(true),
+			Properties: stringPointer(``),
+			factory:    newObjsetCollector,
+		},
+	}
+
+	metricNames := []string{`zfs_dataset_reads_total`, `zfs_dataset_writes_total`, `zfs_dataset_read_bytes_total`, `zfs_dataset_written_bytes_total`, `zfs_dataset_unlinks_total`}
+	if err = callCollector(ctx, collector, []byte(result), metricNames); err != nil {
+		t.Fatal(err)
+	}
+}
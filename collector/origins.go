@@ -0,0 +1,50 @@
+package collector
+
+import (
+	"maps"
+	"strings"
+	"sync"
+)
+
+// metricOrigins records the collector from which each metric was most recently collected, keyed by metric name
+type metricOrigins struct {
+	mu      sync.Mutex
+	origins map[string]string
+}
+
+func newMetricOrigins() *metricOrigins {
+	return &metricOrigins{origins: make(map[string]string)}
+}
+
+// track returns a channel forwarding metrics to ch, and records the metrics sent to it as originating from the
+// collector once it is closed, after which done is closed
+func (o *metricOrigins) track(collector string, ch chan<- metric) (chan<- metric, <-chan struct{}) {
+	tracked := make(chan metric)
+	done := make(chan struct{})
+	go func() {
+		names := make(map[string]struct{})
+		for m := range tracked {
+			names[metricFamilyName(m.name)] = struct{}{}
+			ch <- m
+		}
+		o.mu.Lock()
+		for name := range names {
+			o.origins[name] = collector
+		}
+		o.mu.Unlock()
+		close(done)
+	}()
+
+	return tracked, done
+}
+
+func (o *metricOrigins) snapshot() map[string]string {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return maps.Clone(o.origins)
+}
+
+// metricFamilyName returns the name of the metric from a name expanded with label values by expandMetricName
+func metricFamilyName(name string) string {
+	return name[strings.LastIndex(name, `-`)+1:]
+}
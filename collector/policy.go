@@ -0,0 +1,87 @@
+package collector
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/jmcgover/zfs_exporter/v2/policy"
+	"github.com/jmcgover/zfs_exporter/v2/zfs"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// healthPolicies are the policies evaluated by the health-policy collector, set by ConfigureHealthPolicies
+var healthPolicies []*policy.Policy
+
+func init() {
+	registerCollector(`health-policy`, defaultDisabled, ``, newPolicyCollector)
+}
+
+// ConfigureHealthPolicies sets the user-supplied policies by which the health-policy collector classifies pools, and
+// enables the collector where there are any, unless it was disabled on the command line. It must be called before
+// collection starts.
+func ConfigureHealthPolicies(policies []*policy.Policy) error {
+	healthPolicies = policies
+	if len(policies) == 0 || forcedCollectors[`health-policy`] {
+		return nil
+	}
+	enabled := true
+	return Configure(`health-policy`, &enabled, nil)
+}
+
+// policyCollector exports the health of each pool as classified by user-supplied policies, evaluated on each scrape
+type policyCollector struct {
+	log      *slog.Logger
+	client   zfs.Client
+	policies []*policy.Policy
+	health   property
+}
+
+func (c *policyCollector) describe(ch chan<- *prometheus.Desc) {
+	ch <- c.health.desc
+}
+
+// update evaluates the policies for the given pools. Pools for which a policy fails to evaluate are omitted.
+func (c *policyCollector) update(ch chan<- metric, pools []string, excludes regexpCollection) error {
+	status, err := c.client.PoolStatus(false)
+	// Where only some pools could be queried, the others are reported before the error is returned
+	if err != nil && len(status) == 0 {
+		return err
+	}
+
+	now := time.Now()
+	for _, name := range pools {
+		pool, ok := status[name]
+		if !ok {
+			if err == nil {
+				c.log.Warn("Pool missing from status output", "collector", "health-policy", "pool", name)
+			}
+			continue
+		}
+		for _, p := range c.policies {
+			v, evalErr := p.Evaluate(pool, now)
+			if evalErr != nil {
+				c.log.Warn("Error evaluating health policy", "policy", p.Name, "pool", name, "err", evalErr)
+				continue
+			}
+			c.health.send(ch, v, p.Name, name)
+		}
+	}
+
+	return err
+}
+
+func newPolicyCollector(l *slog.Logger, c zfs.Client, props []string) (Collector, error) {
+	return &policyCollector{
+		log:      l,
+		client:   c,
+		policies: healthPolicies,
+		health: newProperty(
+			``,
+			`custom_health`,
+			`Health of the pool as classified by a user-supplied policy.`,
+			transformNumeric,
+			prometheus.GaugeValue,
+			`policy`, `pool`,
+		),
+	}, nil
+}
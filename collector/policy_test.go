@@ -0,0 +1,59 @@
+package collector
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jmcgover/zfs_exporter/v2/policy"
+	"github.com/jmcgover/zfs_exporter/v2/zfs"
+	"github.com/jmcgover/zfs_exporter/v2/zfs/mock_zfs"
+	"go.uber.org/mock/gomock"
+)
+
+func TestPolicyCollector(t *testing.T) {
+	const result = `# HELP zfs_custom_health Health of the pool as classified by a user-supplied policy.
+# TYPE zfs_custom_health gauge
+zfs_custom_health{policy="strict",pool="backup"} 0
+zfs_custom_health{policy="strict",pool="tank"} 2
+`
+
+	ctrl, ctx := gomock.WithContext(context.Background(), t)
+	zfsClient := mock_zfs.NewMockClient(ctrl)
+	zfsClient.EXPECT().PoolNames().Return([]string{`backup`, `scratch`, `tank`}, nil).Times(1)
+	zfsClient.EXPECT().PoolStatus(false).Return(map[string]zfs.PoolStatusT{
+		`tank`:    {Name: `tank`, State: `DEGRADED`},
+		`backup`:  {Name: `backup`, State: `ONLINE`},
+		`scratch`: {Name: `scratch`, State: `FAULTED`},
+	}, nil).Times(1)
+
+	policies, err := policy.CompileAll(map[string]string{
+		`strict`: `pool.state == "ONLINE" ? 0 : 2`,
+		// Fails to evaluate, as pools have no such field, so is omitted
+		`broken`: `pool.missing > 0`,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func(previous []*policy.Policy) { healthPolicies = previous }(healthPolicies)
+	healthPolicies = policies
+
+	config := defaultConfig(zfsClient)
+	// Pools excluded by the filters are not evaluated
+	config.PoolExcludes = []string{`scratch`}
+	collector, err := NewZFS(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	collector.Collectors = map[string]State{
+		`health-policy`: {
+			Name:       "health-policy",
+			Enabled:    boolPointer(true),
+			Properties: stringPointer(``),
+			factory:    newPolicyCollector,
+		},
+	}
+
+	if err = callCollector(ctx, collector, []byte(result), []string{`zfs_custom_health`}); err != nil {
+		t.Fatal(err)
+	}
+}
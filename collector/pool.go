@@ -1,9 +1,7 @@
 package collector
 
 import (
-	"fmt"
 	"log/slog"
-	"sync"
 
 	"github.com/jmcgover/zfs_exporter/v2/zfs"
 	"github.com/prometheus/client_golang/prometheus"
@@ -78,15 +76,7 @@ var (
 			`health`: newProperty(
 				subsystemPool,
 				`health`,
-				fmt.Sprintf("Health status code for the pool [%d: %s, %d: %s, %d: %s, %d: %s, %d: %s, %d: %s, %d: %s].",
-					poolOnline, zfs.PoolOnline,
-					poolDegraded, zfs.PoolDegraded,
-					poolFaulted, zfs.PoolFaulted,
-					poolOffline, zfs.PoolOffline,
-					poolUnavail, zfs.PoolUnavail,
-					poolRemoved, zfs.PoolRemoved,
-					poolSuspended, zfs.PoolSuspended,
-				),
+				`Health status code for the pool `+stateCodesHelp(poolStates)+`.`,
 				transformHealthCode,
 				prometheus.GaugeValue,
 				poolLabels...,
@@ -117,6 +107,19 @@ var (
 			),
 		},
 	}
+	// poolHealth exports the health property in the selected state format
+	poolHealth = stateProperty{
+		code: poolProperties.store[`health`],
+		oneHot: newProperty(
+			subsystemPool,
+			`state`,
+			`Whether the pool is in the state given by the state label (1) or not (0).`,
+			transformNumeric,
+			prometheus.GaugeValue,
+			`pool`, `state`,
+		),
+		states: poolStates,
+	}
 )
 
 func init() {
@@ -131,35 +134,22 @@ type poolCollector struct {
 
 func (c *poolCollector) describe(ch chan<- *prometheus.Desc) {
 	for _, k := range c.props {
+		if k == `health` {
+			poolHealth.describe(ch)
+			continue
+		}
 		prop, err := poolProperties.find(k)
 		if err != nil {
 			c.log.Warn(propertyUnsupportedMsg, `help`, helpIssue, `collector`, `pool`, `property`, k, `err`, err)
-			continue
 		}
 		ch <- prop.desc
 	}
 }
 
 func (c *poolCollector) update(ch chan<- metric, pools []string, excludes regexpCollection) error {
-	var wg sync.WaitGroup
-	errChan := make(chan error, len(pools))
-	for _, pool := range pools {
-		wg.Add(1)
-		go func(pool string) {
-			if err := c.updatePoolMetrics(ch, pool); err != nil {
-				errChan <- err
-			}
-			wg.Done()
-		}(pool)
-	}
-	wg.Wait()
-
-	select {
-	case err := <-errChan:
-		return err
-	default:
-		return nil
-	}
+	return updatePools(pools, func(pool string) error {
+		return c.updatePoolMetrics(ch, pool)
+	})
 }
 
 func (c *poolCollector) updatePoolMetrics(ch chan<- metric, pool string) error {
@@ -171,6 +161,12 @@ func (c *poolCollector) updatePoolMetrics(ch chan<- metric, pool string) error {
 
 	labelValues := []string{pool}
 	for k, v := range props.Properties() {
+		if k == `health` {
+			if err = poolHealth.send(ch, v, labelValues...); err != nil {
+				return err
+			}
+			continue
+		}
 		prop, err := poolProperties.find(k)
 		if err != nil {
 			c.log.Warn(propertyUnsupportedMsg, `help`, helpIssue, `collector`, `pool`, `property`, k, `err`, err)
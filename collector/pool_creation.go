@@ -0,0 +1,75 @@
+package collector
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/jmcgover/zfs_exporter/v2/zfs"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var poolCreationKinds = []zfs.DatasetKind{zfs.DatasetFilesystem}
+
+func init() {
+	registerCollector(`pool-creation`, defaultDisabled, ``, newPoolCreationCollector)
+}
+
+// poolCreationCollector exports the creation time of each pool, which is that of its root dataset, as pools have no
+// creation property of their own
+type poolCreationCollector struct {
+	log      *slog.Logger
+	client   zfs.Client
+	now      func() time.Time
+	creation property
+	age      property
+}
+
+func (c *poolCreationCollector) describe(ch chan<- *prometheus.Desc) {
+	ch <- c.creation.desc
+	ch <- c.age.desc
+}
+
+func (c *poolCreationCollector) update(ch chan<- metric, pools []string, excludes regexpCollection) error {
+	return updatePools(pools, func(pool string) error {
+		datasets, err := c.client.DatasetList(pool, 0, poolCreationKinds, `creation`)
+		if err != nil {
+			return err
+		}
+		root, ok := datasets[pool]
+		if !ok {
+			return fmt.Errorf("root dataset of pool %s not found", pool)
+		}
+		creation, err := transformNumeric(string(root.Properties[`creation`].Value))
+		if err != nil {
+			return err
+		}
+		c.creation.send(ch, creation, pool)
+		c.age.send(ch, c.now().Sub(time.Unix(int64(creation), 0)).Seconds(), pool)
+		return nil
+	})
+}
+
+func newPoolCreationCollector(l *slog.Logger, c zfs.Client, props []string) (Collector, error) {
+	return &poolCreationCollector{
+		log:    l,
+		client: c,
+		now:    time.Now,
+		creation: newProperty(
+			subsystemPool,
+			`creation_timestamp_seconds`,
+			`The unix timestamp when this pool was created.`,
+			transformNumeric,
+			prometheus.GaugeValue,
+			poolLabels...,
+		),
+		age: newProperty(
+			subsystemPool,
+			`age_seconds`,
+			`Time since this pool was created in seconds.`,
+			transformNumeric,
+			prometheus.GaugeValue,
+			poolLabels...,
+		),
+	}, nil
+}
@@ -0,0 +1,54 @@
+package collector
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/jmcgover/zfs_exporter/v2/zfs"
+	"github.com/jmcgover/zfs_exporter/v2/zfs/mock_zfs"
+	"go.uber.org/mock/gomock"
+)
+
+func TestPoolCreationMetrics(t *testing.T) {
+	const result = `# HELP zfs_pool_age_seconds Time since this pool was created in seconds.
+# TYPE zfs_pool_age_seconds gauge
+zfs_pool_age_seconds{pool="testpool"} 86400
+# HELP zfs_pool_creation_timestamp_seconds The unix timestamp when this pool was created.
+# TYPE zfs_pool_creation_timestamp_seconds gauge
+zfs_pool_creation_timestamp_seconds{pool="testpool"} 1.7560331e+09
+`
+
+	ctrl, ctx := gomock.WithContext(context.Background(), t)
+	zfsClient := mock_zfs.NewMockClient(ctrl)
+	zfsClient.EXPECT().PoolNames().Return([]string{`testpool`}, nil).Times(1)
+	zfsClient.EXPECT().DatasetList(`testpool`, 0, poolCreationKinds, []string{`creation`}).Return(map[string]zfs.DatasetListT{
+		`testpool`: {
+			Name:       `testpool`,
+			Properties: map[string]zfs.PropertyT{`creation`: {Value: `1756033100`}},
+		},
+	}, nil).Times(1)
+
+	collector, err := NewZFS(defaultConfig(zfsClient))
+	if err != nil {
+		t.Fatal(err)
+	}
+	collector.Collectors = map[string]State{
+		`pool-creation`: {
+			Name:       "pool-creation",
+			Enabled:    boolPointer(true),
+			Properties: stringPointer(``),
+			factory: func(l *slog.Logger, c zfs.Client, props []string) (Collector, error) {
+				collector, err := newPoolCreationCollector(l, c, props)
+				collector.(*poolCreationCollector).now = func() time.Time { return time.Unix(1756033100+86400, 0) }
+				return collector, err
+			},
+		},
+	}
+
+	metricNames := []string{`zfs_pool_creation_timestamp_seconds`, `zfs_pool_age_seconds`}
+	if err = callCollector(ctx, collector, []byte(result), metricNames); err != nil {
+		t.Fatal(err)
+	}
+}
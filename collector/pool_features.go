@@ -0,0 +1,91 @@
+package collector
+
+import (
+	"log/slog"
+	"slices"
+	"strings"
+
+	"github.com/jmcgover/zfs_exporter/v2/zfs"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const poolFeaturePrefix = `feature@`
+
+// poolFeatureStates are the states of a feature flag, exported one-hot so that pools with features still disabled can
+// be found with a single selector
+var poolFeatureStates = []string{`disabled`, `enabled`, `active`}
+
+func init() {
+	registerCollector(`pool-features`, defaultDisabled, ``, newPoolFeaturesCollector)
+}
+
+// poolFeaturesCollector exports the state of the feature flags of each pool, as reported by `zpool get all`, so that
+// pools that still need `zpool upgrade` after an update of OpenZFS can be found. The text output of `zpool get` is
+// parsed, so the collector does not depend on JSON support.
+type poolFeaturesCollector struct {
+	log      *slog.Logger
+	client   zfs.Client
+	feature  property
+	disabled property
+}
+
+func (c *poolFeaturesCollector) describe(ch chan<- *prometheus.Desc) {
+	ch <- c.feature.desc
+	ch <- c.disabled.desc
+}
+
+func (c *poolFeaturesCollector) update(ch chan<- metric, pools []string, excludes regexpCollection) error {
+	return updatePools(pools, func(pool string) error {
+		props, err := c.client.Pool(pool).Properties(`all`)
+		if err != nil {
+			return err
+		}
+
+		disabled := 0
+		for k, state := range props.Properties() {
+			feature, ok := strings.CutPrefix(k, poolFeaturePrefix)
+			if !ok {
+				continue
+			}
+			if !slices.Contains(poolFeatureStates, state) {
+				c.log.Warn("Unknown feature state", "collector", "pool-features", "pool", pool, "feature", feature, "state", state)
+				continue
+			}
+			if state == `disabled` {
+				disabled++
+			}
+			for _, s := range poolFeatureStates {
+				v := 0.0
+				if s == state {
+					v = 1
+				}
+				c.feature.send(ch, v, pool, feature, s)
+			}
+		}
+		c.disabled.send(ch, float64(disabled), pool)
+		return nil
+	})
+}
+
+func newPoolFeaturesCollector(l *slog.Logger, c zfs.Client, props []string) (Collector, error) {
+	return &poolFeaturesCollector{
+		log:    l,
+		client: c,
+		feature: newProperty(
+			subsystemPool,
+			`feature`,
+			`Whether the feature flag of the pool is in the state given by the state label (1) or not (0). Disabled features are enabled by zpool upgrade, unless excluded by the compatibility property of the pool.`,
+			transformNumeric,
+			prometheus.GaugeValue,
+			`pool`, `feature`, `state`,
+		),
+		disabled: newProperty(
+			subsystemPool,
+			`features_disabled`,
+			`Number of feature flags supported by the installed ZFS version that are disabled on the pool.`,
+			transformNumeric,
+			prometheus.GaugeValue,
+			poolLabels...,
+		),
+	}, nil
+}
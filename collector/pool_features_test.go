@@ -0,0 +1,64 @@
+package collector
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jmcgover/zfs_exporter/v2/zfs/mock_zfs"
+	"go.uber.org/mock/gomock"
+)
+
+func TestPoolFeaturesMetrics(t *testing.T) {
+	const result = `# HELP zfs_pool_feature Whether the feature flag of the pool is in the state given by the state label (1) or not (0). Disabled features are enabled by zpool upgrade, unless excluded by the compatibility property of the pool.
+# TYPE zfs_pool_feature gauge
+zfs_pool_feature{feature="async_destroy",pool="testpool",state="active"} 0
+zfs_pool_feature{feature="async_destroy",pool="testpool",state="disabled"} 0
+zfs_pool_feature{feature="async_destroy",pool="testpool",state="enabled"} 1
+zfs_pool_feature{feature="block_cloning",pool="testpool",state="active"} 0
+zfs_pool_feature{feature="block_cloning",pool="testpool",state="disabled"} 1
+zfs_pool_feature{feature="block_cloning",pool="testpool",state="enabled"} 0
+zfs_pool_feature{feature="raidz_expansion",pool="testpool",state="active"} 0
+zfs_pool_feature{feature="raidz_expansion",pool="testpool",state="disabled"} 1
+zfs_pool_feature{feature="raidz_expansion",pool="testpool",state="enabled"} 0
+zfs_pool_feature{feature="spacemap_histogram",pool="testpool",state="active"} 1
+zfs_pool_feature{feature="spacemap_histogram",pool="testpool",state="disabled"} 0
+zfs_pool_feature{feature="spacemap_histogram",pool="testpool",state="enabled"} 0
+# HELP zfs_pool_features_disabled Number of feature flags supported by the installed ZFS version that are disabled on the pool.
+# TYPE zfs_pool_features_disabled gauge
+zfs_pool_features_disabled{pool="testpool"} 2
+`
+
+	ctrl, ctx := gomock.WithContext(context.Background(), t)
+	zfsClient := mock_zfs.NewMockClient(ctrl)
+	zfsClient.EXPECT().PoolNames().Return([]string{`testpool`}, nil).Times(1)
+	props := mock_zfs.NewMockPoolProperties(ctrl)
+	props.EXPECT().Properties().Return(map[string]string{
+		`size`:                       `3985729650688`,
+		`compatibility`:              `off`,
+		`feature@async_destroy`:      `enabled`,
+		`feature@spacemap_histogram`: `active`,
+		`feature@block_cloning`:      `disabled`,
+		`feature@raidz_expansion`:    `disabled`,
+		`unsupported@com.example:x`:  `inactive`,
+	}).Times(1)
+	pool := mock_zfs.NewMockPool(ctrl)
+	pool.EXPECT().Properties([]string{`all`}).Return(props, nil).Times(1)
+	zfsClient.EXPECT().Pool(`testpool`).Return(pool).Times(1)
+
+	collector, err := NewZFS(defaultConfig(zfsClient))
+	if err != nil {
+		t.Fatal(err)
+	}
+	collector.Collectors = map[string]State{
+		`pool-features`: {
+			Name:       "pool-features",
+			Enabled:    boolPointer(true),
+			Properties: stringPointer(``),
+			factory:    newPoolFeaturesCollector,
+		},
+	}
+
+	if err = callCollector(ctx, collector, []byte(result), []string{`zfs_pool_feature`, `zfs_pool_features_disabled`}); err != nil {
+		t.Fatal(err)
+	}
+}
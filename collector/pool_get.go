@@ -0,0 +1,87 @@
+package collector
+
+import (
+	"log/slog"
+
+	"github.com/jmcgover/zfs_exporter/v2/zfs"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	defaultPoolGetProps = `ashift,autotrim,delegation,freeing,leaked,multihost`
+)
+
+func init() {
+	registerCollector(`pool-get`, defaultDisabled, defaultPoolGetProps, newPoolGetCollector)
+}
+
+// poolGetCollector exports arbitrary pool properties from a single `zpool get` invocation. Unlike the pool and
+// pool-list collectors, properties need not be known in advance: numeric and boolean values are exported as gauges,
+// and all other values as info metrics.
+type poolGetCollector struct {
+	log    *slog.Logger
+	client zfs.Client
+	props  []string
+	value  property
+	info   property
+}
+
+func (c *poolGetCollector) describe(ch chan<- *prometheus.Desc) {
+	ch <- c.value.desc
+	ch <- c.info.desc
+}
+
+func (c *poolGetCollector) update(ch chan<- metric, pools []string, excludes regexpCollection) error {
+	list, err := c.client.PoolGet(c.props...)
+	if err != nil {
+		return err
+	}
+
+	for _, pool := range pools {
+		p, ok := list[pool]
+		if !ok {
+			c.log.Warn("Pool missing from get output", "collector", "pool-get", "pool", pool)
+			continue
+		}
+		for k, v := range p.Properties {
+			value := string(v.Value)
+			// Unset properties, e.g. an empty comment, are reported as "-"
+			if k == `name` || value == `-` {
+				continue
+			}
+			if f, err := transformNumeric(value); err == nil {
+				c.value.send(ch, f, pool, k)
+			} else if f, err := transformBool(value); err == nil {
+				c.value.send(ch, f, pool, k)
+			} else {
+				c.info.send(ch, 1, pool, k, value)
+			}
+		}
+	}
+
+	return nil
+}
+
+func newPoolGetCollector(l *slog.Logger, c zfs.Client, props []string) (Collector, error) {
+	return &poolGetCollector{
+		log:    l,
+		client: c,
+		props:  props,
+		value: newProperty(
+			subsystemPool,
+			`property`,
+			`Value of a numeric or on/off pool property reported by zpool get, where on is 1 and off is 0.`,
+			transformNumeric,
+			prometheus.GaugeValue,
+			`pool`, `property`,
+		),
+		info: newProperty(
+			subsystemPool,
+			`property_info`,
+			`Value of a pool property reported by zpool get that is neither numeric nor on/off, given by the value label.`,
+			transformNumeric,
+			prometheus.GaugeValue,
+			`pool`, `property`, `value`,
+		),
+	}, nil
+}
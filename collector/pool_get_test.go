@@ -0,0 +1,56 @@
+package collector
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jmcgover/zfs_exporter/v2/zfs"
+	"github.com/jmcgover/zfs_exporter/v2/zfs/mock_zfs"
+	"go.uber.org/mock/gomock"
+)
+
+func TestPoolGetMetrics(t *testing.T) {
+	const result = `# HELP zfs_pool_property Value of a numeric or on/off pool property reported by zpool get, where on is 1 and off is 0.
+# TYPE zfs_pool_property gauge
+zfs_pool_property{pool="testpool",property="ashift"} 12
+zfs_pool_property{pool="testpool",property="autotrim"} 1
+zfs_pool_property{pool="testpool",property="multihost"} 0
+# HELP zfs_pool_property_info Value of a pool property reported by zpool get that is neither numeric nor on/off, given by the value label.
+# TYPE zfs_pool_property_info gauge
+zfs_pool_property_info{pool="testpool",property="failmode",value="wait"} 1
+`
+
+	ctrl, ctx := gomock.WithContext(context.Background(), t)
+	zfsClient := mock_zfs.NewMockClient(ctrl)
+	zfsClient.EXPECT().PoolNames().Return([]string{`testpool`}, nil).Times(1)
+	zfsClient.EXPECT().PoolGet(`ashift`, `autotrim`, `multihost`, `failmode`, `comment`).Return(map[string]zfs.PoolListT{
+		`testpool`: {
+			Name: `testpool`,
+			Properties: map[string]zfs.PropertyT{
+				`ashift`:    {Value: `12`},
+				`autotrim`:  {Value: `on`},
+				`multihost`: {Value: `off`},
+				`failmode`:  {Value: `wait`},
+				`comment`:   {Value: `-`},
+			},
+		},
+	}, nil).Times(1)
+
+	collector, err := NewZFS(defaultConfig(zfsClient))
+	if err != nil {
+		t.Fatal(err)
+	}
+	collector.Collectors = map[string]State{
+		`pool-get`: {
+			Name:       "pool-get",
+			Enabled:    boolPointer(true),
+			Properties: stringPointer(`ashift,autotrim,multihost,failmode,comment`),
+			factory:    newPoolGetCollector,
+		},
+	}
+
+	metricNames := []string{`zfs_pool_property`, `zfs_pool_property_info`}
+	if err = callCollector(ctx, collector, []byte(result), metricNames); err != nil {
+		t.Fatal(err)
+	}
+}
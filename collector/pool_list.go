@@ -0,0 +1,78 @@
+package collector
+
+import (
+	"log/slog"
+
+	"github.com/jmcgover/zfs_exporter/v2/zfs"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	defaultPoolListProps = `allocated,capacity,dedupratio,fragmentation,free,health,size`
+)
+
+func init() {
+	registerCollector(`pool-list`, defaultDisabled, defaultPoolListProps, newPoolListCollector)
+}
+
+// poolListCollector exports the same metrics as the pool collector, but collects all pools in a single `zpool list`
+// invocation, so the two are mutually exclusive.
+type poolListCollector struct {
+	log    *slog.Logger
+	client zfs.Client
+	props  []string
+}
+
+func (c *poolListCollector) describe(ch chan<- *prometheus.Desc) {
+	for _, k := range c.props {
+		if k == `health` {
+			poolHealth.describe(ch)
+			continue
+		}
+		prop, err := poolProperties.find(k)
+		if err != nil {
+			c.log.Warn(propertyUnsupportedMsg, `help`, helpIssue, `collector`, `pool-list`, `property`, k, `err`, err)
+		}
+		ch <- prop.desc
+	}
+}
+
+func (c *poolListCollector) update(ch chan<- metric, pools []string, excludes regexpCollection) error {
+	list, err := c.client.PoolList(c.props...)
+	if err != nil {
+		return err
+	}
+
+	for _, pool := range pools {
+		p, ok := list[pool]
+		if !ok {
+			c.log.Warn("Pool missing from list output", "collector", "pool-list", "pool", pool)
+			continue
+		}
+		labelValues := []string{pool}
+		for k, v := range p.Properties {
+			if k == `name` {
+				continue
+			}
+			if k == `health` {
+				if err := poolHealth.send(ch, string(v.Value), labelValues...); err != nil {
+					return err
+				}
+				continue
+			}
+			prop, err := poolProperties.find(k)
+			if err != nil {
+				c.log.Warn(propertyUnsupportedMsg, `help`, helpIssue, `collector`, `pool-list`, `property`, k, `err`, err)
+			}
+			if err = prop.push(ch, string(v.Value), labelValues...); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func newPoolListCollector(l *slog.Logger, c zfs.Client, props []string) (Collector, error) {
+	return &poolListCollector{log: l, client: c, props: props}, nil
+}
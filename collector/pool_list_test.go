@@ -0,0 +1,66 @@
+package collector
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/jmcgover/zfs_exporter/v2/zfs"
+	"github.com/jmcgover/zfs_exporter/v2/zfs/mock_zfs"
+	"go.uber.org/mock/gomock"
+)
+
+func TestPoolListMetrics(t *testing.T) {
+	const result = `# HELP zfs_pool_allocated_bytes Amount of storage in bytes used within the pool.
+# TYPE zfs_pool_allocated_bytes gauge
+zfs_pool_allocated_bytes{pool="testpool1"} 1024
+# HELP zfs_pool_capacity_ratio Ratio of pool space used.
+# TYPE zfs_pool_capacity_ratio gauge
+zfs_pool_capacity_ratio{pool="testpool1"} 0.5
+# HELP zfs_pool_health Health status code for the pool [0: ONLINE, 1: DEGRADED, 2: FAULTED, 3: OFFLINE, 4: UNAVAIL, 5: REMOVED, 6: SUSPENDED].
+# TYPE zfs_pool_health gauge
+zfs_pool_health{pool="testpool1"} 1
+`
+	propsRequested := []string{`allocated`, `capacity`, `health`}
+
+	ctrl, ctx := gomock.WithContext(context.Background(), t)
+	zfsClient := mock_zfs.NewMockClient(ctrl)
+	zfsClient.EXPECT().PoolNames().Return([]string{`testpool1`, `testpool2`}, nil).Times(1)
+	zfsClient.EXPECT().PoolList(propsRequested).Return(map[string]zfs.PoolListT{
+		`testpool1`: {
+			Name: `testpool1`,
+			Properties: map[string]zfs.PropertyT{
+				`name`:      {Value: `testpool1`},
+				`allocated`: {Value: `1024`},
+				`capacity`:  {Value: `50`},
+				`health`:    {Value: `DEGRADED`},
+			},
+		},
+		`testpool2`: {
+			Name: `testpool2`,
+			Properties: map[string]zfs.PropertyT{
+				`allocated`: {Value: `2048`},
+			},
+		},
+	}, nil).Times(1)
+
+	config := defaultConfig(zfsClient)
+	config.Pools = []string{`testpool1`}
+	collector, err := NewZFS(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	collector.Collectors = map[string]State{
+		`pool-list`: {
+			Name:       "pool-list",
+			Enabled:    boolPointer(true),
+			Properties: stringPointer(strings.Join(propsRequested, `,`)),
+			factory:    newPoolListCollector,
+		},
+	}
+
+	metricNames := []string{`zfs_pool_allocated_bytes`, `zfs_pool_capacity_ratio`, `zfs_pool_health`}
+	if err = callCollector(ctx, collector, []byte(result), metricNames); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,71 @@
+package collector
+
+import (
+	"log/slog"
+
+	"github.com/jmcgover/zfs_exporter/v2/zfs"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	readonlyUnexpectedDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, subsystemDataset, `readonly_unexpected`),
+		`Whether a dataset that is configured to be writable is read-only (1) or not (0), e.g. following a remount or a read-only import of the pool after errors.`,
+		datasetLabels,
+		nil,
+	)
+	// readonlyKinds are the kinds of dataset that may be written to
+	readonlyKinds = []zfs.DatasetKind{zfs.DatasetFilesystem, zfs.DatasetVolume}
+)
+
+// ReadonlyCollector exports whether datasets that are expected to be writable have become read-only, whether through
+// the readonly property, a read-only mount, or a read-only import of the pool
+type ReadonlyCollector struct {
+	logger   *slog.Logger
+	client   zfs.Client
+	writable regexpCollection
+}
+
+// NewReadonlyCollector instantiates a collector for the datasets matching any of the writable regexes
+func NewReadonlyCollector(logger *slog.Logger, client zfs.Client, writable []string) (*ReadonlyCollector, error) {
+	patterns, err := compileRegexps(writable)
+	if err != nil {
+		return nil, err
+	}
+	return &ReadonlyCollector{logger: logger, client: client, writable: patterns}, nil
+}
+
+// Describe implements the prometheus.Collector interface.
+func (c *ReadonlyCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- readonlyUnexpectedDesc
+}
+
+// Collect implements the prometheus.Collector interface. Pools whose datasets cannot be listed are omitted.
+func (c *ReadonlyCollector) Collect(ch chan<- prometheus.Metric) {
+	pools, err := c.client.PoolGet(`readonly`)
+	if err != nil {
+		c.logger.Error("Error getting pool properties for read-only datasets", "err", err)
+		return
+	}
+
+	for pool, props := range pools {
+		poolReadonly := props.Properties[`readonly`].Value == `on`
+		// The readonly property reports the effective state of mounted filesystems, with a temporary source where it
+		// differs from the property due to the mount options
+		datasets, err := c.client.DatasetGet(pool, readonlyKinds, `readonly`)
+		if err != nil {
+			c.logger.Error("Error getting read-only datasets", "pool", pool, "err", err)
+			continue
+		}
+		for name, dataset := range datasets {
+			if !c.writable.MatchString(name) {
+				continue
+			}
+			unexpected := 0.0
+			if poolReadonly || dataset.Properties[`readonly`].Value == `on` {
+				unexpected = 1
+			}
+			ch <- prometheus.MustNewConstMetric(readonlyUnexpectedDesc, prometheus.GaugeValue, unexpected, name, pool, string(dataset.Kind()))
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package collector
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/jmcgover/zfs_exporter/v2/zfs"
+	"github.com/jmcgover/zfs_exporter/v2/zfs/mock_zfs"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"go.uber.org/mock/gomock"
+)
+
+func TestReadonlyCollector(t *testing.T) {
+	const result = `# HELP zfs_dataset_readonly_unexpected Whether a dataset that is configured to be writable is read-only (1) or not (0), e.g. following a remount or a read-only import of the pool after errors.
+# TYPE zfs_dataset_readonly_unexpected gauge
+zfs_dataset_readonly_unexpected{name="backup/data",pool="backup",type="filesystem"} 1
+zfs_dataset_readonly_unexpected{name="tank/db",pool="tank",type="filesystem"} 1
+zfs_dataset_readonly_unexpected{name="tank/home",pool="tank",type="filesystem"} 0
+`
+
+	ctrl, _ := gomock.WithContext(context.Background(), t)
+	zfsClient := mock_zfs.NewMockClient(ctrl)
+	zfsClient.EXPECT().PoolGet(`readonly`).Return(map[string]zfs.PoolListT{
+		`tank`:   {Name: `tank`, Properties: map[string]zfs.PropertyT{`readonly`: {Value: `off`}}},
+		`backup`: {Name: `backup`, Properties: map[string]zfs.PropertyT{`readonly`: {Value: `on`}}},
+	}, nil).Times(1)
+	zfsClient.EXPECT().DatasetGet(`tank`, readonlyKinds, `readonly`).Return(map[string]zfs.DatasetListT{
+		`tank/home`: {Name: `tank/home`, Type: `FILESYSTEM`, Properties: map[string]zfs.PropertyT{
+			`readonly`: {Value: `off`},
+		}},
+		// Remounted read-only, as reported with a temporary source
+		`tank/db`: {Name: `tank/db`, Type: `FILESYSTEM`, Properties: map[string]zfs.PropertyT{
+			`readonly`: {Value: `on`, Source: zfs.PropertySourceT{Type: `TEMPORARY`}},
+		}},
+		// Read-only by design, and not configured to be writable
+		`tank/archive`: {Name: `tank/archive`, Type: `FILESYSTEM`, Properties: map[string]zfs.PropertyT{
+			`readonly`: {Value: `on`},
+		}},
+	}, nil).Times(1)
+	zfsClient.EXPECT().DatasetGet(`backup`, readonlyKinds, `readonly`).Return(map[string]zfs.DatasetListT{
+		`backup/data`: {Name: `backup/data`, Type: `FILESYSTEM`, Properties: map[string]zfs.PropertyT{
+			`readonly`: {Value: `off`},
+		}},
+	}, nil).Times(1)
+
+	c, err := NewReadonlyCollector(logger, zfsClient, []string{`^tank/(home|db)$`, `^backup/`})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = testutil.CollectAndCompare(c, bytes.NewBufferString(result)); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = NewReadonlyCollector(logger, zfsClient, []string{`^tank/(`}); err == nil {
+		t.Error(`Expected error for an invalid regex`)
+	}
+}
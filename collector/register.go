@@ -0,0 +1,109 @@
+package collector
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"sort"
+
+	"github.com/jmcgover/zfs_exporter/v2/zfs"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+var (
+	collectorNameRe = regexp.MustCompile(`^[a-z0-9][a-z0-9-]*$`)
+
+	errInvalidCollectorName = errors.New(`invalid collector name`)
+	errDuplicateCollector   = errors.New(`collector already registered`)
+)
+
+// Matcher reports whether a dataset, snapshot or volume name matches the configured exclusions
+type Matcher interface {
+	MatchString(name string) bool
+}
+
+// PluginCollector is implemented by collectors registered outside of this package via Register.
+//
+// A new PluginCollector is created by its Factory for every collection, so any state that must persist between
+// collections should be held by the Factory. Update is called concurrently with other collectors, and may continue
+// to run after the collection deadline, in which case its metrics are cached for the next scrape.
+type PluginCollector interface {
+	// Describe sends the descriptors of every metric that Update may send
+	Describe(ch chan<- *prometheus.Desc)
+	// Update sends the metrics for the given pools, skipping datasets that match excludes. Returning an error marks
+	// the collector as failed in zfs_scrape_collector_success, and counts towards the circuit breaker.
+	Update(ch chan<- prometheus.Metric, pools []string, excludes Matcher) error
+}
+
+// Factory creates a PluginCollector, given the properties configured for it via the configuration file
+type Factory func(logger *slog.Logger, client zfs.Client, properties []string) (PluginCollector, error)
+
+// Register adds a collector, which is disabled by default and may be enabled by the --collector.<name> flag or the
+// collectors section of the configuration file, like those built in. Register must be called before the command line
+// is parsed, typically from the init function of the package that implements the collector, and returns an error if
+// the name is invalid or already registered.
+func Register(name string, factory Factory) error {
+	if !collectorNameRe.MatchString(name) {
+		return fmt.Errorf("%w: %q must consist of lowercase letters, digits and hyphens", errInvalidCollectorName, name)
+	}
+	if _, ok := collectorStates[name]; ok {
+		return fmt.Errorf("%w: %s", errDuplicateCollector, name)
+	}
+
+	registerCollector(name, defaultDisabled, ``, func(l *slog.Logger, c zfs.Client, props []string) (Collector, error) {
+		collector, err := factory(l, c, props)
+		if err != nil {
+			return nil, err
+		}
+		return pluginCollector{collector}, nil
+	})
+
+	return nil
+}
+
+// pluginCollector adapts a PluginCollector to the Collector interface
+type pluginCollector struct {
+	collector PluginCollector
+}
+
+func (c pluginCollector) describe(ch chan<- *prometheus.Desc) {
+	c.collector.Describe(ch)
+}
+
+func (c pluginCollector) update(ch chan<- metric, pools []string, excludes regexpCollection) error {
+	metrics := make(chan prometheus.Metric)
+	done := make(chan error, 1)
+	// Update runs in its own goroutine so that its metrics may be forwarded as they are sent, and so panics must be
+	// recovered here rather than by the caller.
+	go func() {
+		var err error
+		defer func() {
+			done <- err
+			close(metrics)
+		}()
+		defer recoverPanic(&err)
+		err = c.collector.Update(metrics, pools, excludes)
+	}()
+	for m := range metrics {
+		ch <- metric{name: pluginMetricName(m), prometheus: m}
+	}
+
+	return <-done
+}
+
+// pluginMetricName identifies a metric for caching, by its descriptor and label values
+func pluginMetricName(m prometheus.Metric) string {
+	var pb dto.Metric
+	if err := m.Write(&pb); err != nil {
+		return m.Desc().String()
+	}
+	labels := make([]string, 0, len(pb.GetLabel()))
+	for _, label := range pb.GetLabel() {
+		labels = append(labels, label.GetName()+`=`+label.GetValue())
+	}
+	sort.Strings(labels)
+
+	return expandMetricName(m.Desc().String(), labels...)
+}
@@ -0,0 +1,95 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"testing"
+
+	"github.com/jmcgover/zfs_exporter/v2/zfs"
+	"github.com/jmcgover/zfs_exporter/v2/zfs/mock_zfs"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/mock/gomock"
+)
+
+var enclosureTempDesc = prometheus.NewDesc(`zfs_enclosure_temperature_celsius`, `Enclosure temperature.`, []string{`pool`}, nil)
+
+type enclosureCollector struct {
+	panics bool
+}
+
+func (c enclosureCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- enclosureTempDesc
+}
+
+func (c enclosureCollector) Update(ch chan<- prometheus.Metric, pools []string, excludes Matcher) error {
+	if c.panics {
+		panic(`enclosure unavailable`)
+	}
+	for _, pool := range pools {
+		ch <- prometheus.MustNewConstMetric(enclosureTempDesc, prometheus.GaugeValue, 35, pool)
+	}
+	return nil
+}
+
+func TestRegister(t *testing.T) {
+	factory := func(l *slog.Logger, c zfs.Client, props []string) (PluginCollector, error) {
+		return enclosureCollector{}, nil
+	}
+	for _, name := range []string{``, `Enclosure`, `-enclosure`, `enclosure temp`, `pool`} {
+		if err := Register(name, factory); err == nil {
+			t.Errorf("Expected error registering collector %q", name)
+		}
+	}
+	if err := Register(`test-enclosure`, factory); err != nil {
+		t.Fatal(err)
+	}
+	if err := Register(`test-enclosure`, factory); !errors.Is(err, errDuplicateCollector) {
+		t.Errorf("Expected duplicate collector error, got %v", err)
+	}
+	if *collectorStates[`test-enclosure`].Enabled {
+		t.Error("Expected registered collector to be disabled by default")
+	}
+
+	const result = `# HELP zfs_enclosure_temperature_celsius Enclosure temperature.
+# TYPE zfs_enclosure_temperature_celsius gauge
+zfs_enclosure_temperature_celsius{pool="testpool1"} 35
+zfs_enclosure_temperature_celsius{pool="testpool2"} 35
+`
+
+	ctrl, ctx := gomock.WithContext(context.Background(), t)
+	zfsClient := mock_zfs.NewMockClient(ctrl)
+	zfsClient.EXPECT().PoolNames().Return([]string{`testpool1`, `testpool2`}, nil).Times(1)
+
+	collector, err := NewZFS(defaultConfig(zfsClient))
+	if err != nil {
+		t.Fatal(err)
+	}
+	collector.Collectors = map[string]State{
+		`test-enclosure`: {
+			Name:       "test-enclosure",
+			Enabled:    boolPointer(true),
+			Properties: stringPointer(``),
+			factory:    collectorStates[`test-enclosure`].factory,
+		},
+	}
+
+	if err = callCollector(ctx, collector, []byte(result), []string{`zfs_enclosure_temperature_celsius`}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPluginCollectorPanic(t *testing.T) {
+	ch := make(chan metric)
+	go func() {
+		for range ch {
+		}
+	}()
+	defer close(ch)
+
+	err := pluginCollector{enclosureCollector{panics: true}}.update(ch, []string{`testpool`}, nil)
+	var pe *panicError
+	if !errors.As(err, &pe) {
+		t.Fatalf("Expected panic error, got %v", err)
+	}
+}
@@ -0,0 +1,83 @@
+package collector
+
+import (
+	"log/slog"
+
+	"github.com/jmcgover/zfs_exporter/v2/zfs"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func init() {
+	registerCollector(`removal`, defaultDisabled, ``, newRemovalCollector)
+}
+
+// removalCollector exports the indirect and hole vdevs left in place of top-level vdevs removed from each pool, and
+// the memory used to remap blocks of the removed vdevs, which persist for the life of the pool
+type removalCollector struct {
+	log           *slog.Logger
+	client        zfs.Client
+	indirectVdevs property
+	indirectBytes property
+	holeVdevs     property
+	mappingMemory property
+}
+
+func (c *removalCollector) describe(ch chan<- *prometheus.Desc) {
+	ch <- c.indirectVdevs.desc
+	ch <- c.indirectBytes.desc
+	ch <- c.holeVdevs.desc
+	ch <- c.mappingMemory.desc
+}
+
+func (c *removalCollector) update(ch chan<- metric, pools []string, excludes regexpCollection) error {
+	return updatePools(pools, func(pool string) error {
+		stats, err := c.client.RemovalStats(pool)
+		if err != nil {
+			return err
+		}
+		c.indirectVdevs.send(ch, float64(stats.IndirectVdevs), pool)
+		c.indirectBytes.send(ch, float64(stats.IndirectBytes), pool)
+		c.holeVdevs.send(ch, float64(stats.HoleVdevs), pool)
+		c.mappingMemory.send(ch, float64(stats.MappingMemoryBytes), pool)
+		return nil
+	})
+}
+
+func newRemovalCollector(l *slog.Logger, c zfs.Client, props []string) (Collector, error) {
+	return &removalCollector{
+		log:    l,
+		client: c,
+		indirectVdevs: newProperty(
+			subsystemPool,
+			`indirect_vdevs`,
+			`Number of indirect vdevs in the pool, left in place of data vdevs removed with zpool remove.`,
+			transformNumeric,
+			prometheus.GaugeValue,
+			poolLabels...,
+		),
+		indirectBytes: newProperty(
+			subsystemPool,
+			`indirect_vdev_bytes`,
+			`Total size of the data vdevs removed from the pool in bytes, whose blocks are remapped by indirect vdevs.`,
+			transformNumeric,
+			prometheus.GaugeValue,
+			poolLabels...,
+		),
+		holeVdevs: newProperty(
+			subsystemPool,
+			`hole_vdevs`,
+			`Number of hole vdevs in the pool, left in place of log vdevs removed with zpool remove.`,
+			transformNumeric,
+			prometheus.GaugeValue,
+			poolLabels...,
+		),
+		mappingMemory: newProperty(
+			subsystemPool,
+			`removal_mapping_memory_bytes`,
+			`Memory used for the mappings of vdevs removed from the pool in bytes, as reported by zpool status.`,
+			transformNumeric,
+			prometheus.GaugeValue,
+			poolLabels...,
+		),
+	}, nil
+}
@@ -0,0 +1,59 @@
+package collector
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jmcgover/zfs_exporter/v2/zfs"
+	"github.com/jmcgover/zfs_exporter/v2/zfs/mock_zfs"
+	"go.uber.org/mock/gomock"
+)
+
+func TestRemovalMetrics(t *testing.T) {
+	const result = `# HELP zfs_pool_hole_vdevs Number of hole vdevs in the pool, left in place of log vdevs removed with zpool remove.
+# TYPE zfs_pool_hole_vdevs gauge
+zfs_pool_hole_vdevs{pool="testpool1"} 1
+zfs_pool_hole_vdevs{pool="testpool2"} 0
+# HELP zfs_pool_indirect_vdev_bytes Total size of the data vdevs removed from the pool in bytes, whose blocks are remapped by indirect vdevs.
+# TYPE zfs_pool_indirect_vdev_bytes gauge
+zfs_pool_indirect_vdev_bytes{pool="testpool1"} 1.999307276288e+12
+zfs_pool_indirect_vdev_bytes{pool="testpool2"} 0
+# HELP zfs_pool_indirect_vdevs Number of indirect vdevs in the pool, left in place of data vdevs removed with zpool remove.
+# TYPE zfs_pool_indirect_vdevs gauge
+zfs_pool_indirect_vdevs{pool="testpool1"} 1
+zfs_pool_indirect_vdevs{pool="testpool2"} 0
+# HELP zfs_pool_removal_mapping_memory_bytes Memory used for the mappings of vdevs removed from the pool in bytes, as reported by zpool status.
+# TYPE zfs_pool_removal_mapping_memory_bytes gauge
+zfs_pool_removal_mapping_memory_bytes{pool="testpool1"} 1.9293798e+07
+zfs_pool_removal_mapping_memory_bytes{pool="testpool2"} 0
+`
+
+	ctrl, ctx := gomock.WithContext(context.Background(), t)
+	zfsClient := mock_zfs.NewMockClient(ctrl)
+	zfsClient.EXPECT().PoolNames().Return([]string{`testpool1`, `testpool2`}, nil).Times(1)
+	zfsClient.EXPECT().RemovalStats(`testpool1`).Return(zfs.RemovalStatsT{
+		IndirectVdevs:      1,
+		IndirectBytes:      1999307276288,
+		HoleVdevs:          1,
+		MappingMemoryBytes: 19293798,
+	}, nil).Times(1)
+	zfsClient.EXPECT().RemovalStats(`testpool2`).Return(zfs.RemovalStatsT{}, nil).Times(1)
+
+	collector, err := NewZFS(defaultConfig(zfsClient))
+	if err != nil {
+		t.Fatal(err)
+	}
+	collector.Collectors = map[string]State{
+		`removal`: {
+			Name:       "removal",
+			Enabled:    boolPointer(true),
+			Properties: stringPointer(``),
+			factory:    newRemovalCollector,
+		},
+	}
+
+	metricNames := []string{`zfs_pool_hole_vdevs`, `zfs_pool_indirect_vdev_bytes`, `zfs_pool_indirect_vdevs`, `zfs_pool_removal_mapping_memory_bytes`}
+	if err = callCollector(ctx, collector, []byte(result), metricNames); err != nil {
+		t.Fatal(err)
+	}
+}
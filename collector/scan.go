@@ -0,0 +1,247 @@
+package collector
+
+import (
+	"log/slog"
+	"strings"
+
+	"github.com/jmcgover/zfs_exporter/v2/zfs"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// scanStates are the states of a scrub or resilver, exported one-hot so that each is present with a value of zero
+// unless active
+var scanStates = []string{`SCANNING`, `FINISHED`, `CANCELED`}
+
+var (
+	scanLabels        = []string{`pool`, `function`}
+	raidzExpandLabels = []string{`pool`, `vdev`}
+)
+
+func init() {
+	registerCollector(`scan`, defaultDisabled, ``, newScanCollector)
+}
+
+type scanCollector struct {
+	log       *slog.Logger
+	client    zfs.Client
+	examined  property
+	toExamine property
+	issued    property
+	state     property
+	startTime property
+	endTime   property
+	errors    property
+	lastScrub property
+
+	expandReflowed  property
+	expandToReflow  property
+	expandState     property
+	expandStartTime property
+	expandEndTime   property
+	expandWaiting   property
+}
+
+func (c *scanCollector) describe(ch chan<- *prometheus.Desc) {
+	for _, p := range c.properties() {
+		ch <- p.desc
+	}
+}
+
+func (c *scanCollector) properties() []property {
+	return []property{
+		c.examined, c.toExamine, c.issued, c.state, c.startTime, c.endTime, c.errors, c.lastScrub,
+		c.expandReflowed, c.expandToReflow, c.expandState, c.expandStartTime, c.expandEndTime, c.expandWaiting,
+	}
+}
+
+func (c *scanCollector) update(ch chan<- metric, pools []string, excludes regexpCollection) error {
+	status, err := c.client.PoolStatus(false)
+	// Where only some pools could be queried, the others are reported before the error is returned
+	if err != nil && len(status) == 0 {
+		return err
+	}
+
+	for _, pool := range pools {
+		poolStatus, ok := status[pool]
+		if !ok {
+			if err == nil {
+				c.log.Warn("Pool missing from status output", "collector", "scan", "pool", pool)
+			}
+			continue
+		}
+		if expand := poolStatus.RaidzExpandStats; expand != nil && expand.State != `` && expand.State != `NONE` {
+			c.sendRaidzExpand(ch, pool, expand)
+		}
+		scan := poolStatus.ScanStats
+		// Pools that have never been scrubbed or resilvered report no scan
+		if scan.Function == `` || scan.State == `NONE` {
+			continue
+		}
+
+		function := strings.ToLower(scan.Function)
+		c.examined.send(ch, float64(scan.Examined), pool, function)
+		c.toExamine.send(ch, float64(scan.ToExamine), pool, function)
+		c.issued.send(ch, float64(scan.Issued), pool, function)
+		c.startTime.send(ch, float64(scan.StartTime), pool, function)
+		if scan.State != `SCANNING` {
+			c.endTime.send(ch, float64(scan.EndTime), pool, function)
+		}
+		c.errors.send(ch, float64(scan.Errors), pool, function)
+		for _, state := range scanStates {
+			v := 0.0
+			if scan.State == state {
+				v = 1
+			}
+			c.state.send(ch, v, pool, function, state)
+		}
+		// Only the most recent scan is retained, so the time of the last scrub is unknown once a resilver has run
+		if scan.Function == `SCRUB` && scan.State == `FINISHED` {
+			c.lastScrub.send(ch, float64(scan.EndTime), pool)
+		}
+	}
+
+	return err
+}
+
+// sendRaidzExpand exports the progress of a raidz expansion, which copies existing data to the wider layout much as a
+// scan examines it
+func (c *scanCollector) sendRaidzExpand(ch chan<- metric, pool string, expand *zfs.RaidzExpandStatsT) {
+	vdev := expand.ExpandingVdev
+	c.expandReflowed.send(ch, float64(expand.Reflowed), pool, vdev)
+	c.expandToReflow.send(ch, float64(expand.ToReflow), pool, vdev)
+	c.expandStartTime.send(ch, float64(expand.StartTime), pool, vdev)
+	if expand.State != `SCANNING` {
+		c.expandEndTime.send(ch, float64(expand.EndTime), pool, vdev)
+	}
+	waiting := 0.0
+	if expand.WaitingForResilver != 0 {
+		waiting = 1
+	}
+	c.expandWaiting.send(ch, waiting, pool, vdev)
+	for _, state := range scanStates {
+		v := 0.0
+		if expand.State == state {
+			v = 1
+		}
+		c.expandState.send(ch, v, pool, vdev, state)
+	}
+}
+
+func newScanCollector(l *slog.Logger, c zfs.Client, props []string) (Collector, error) {
+	return &scanCollector{
+		log:    l,
+		client: c,
+		examined: newProperty(
+			subsystemScan,
+			`examined_bytes`,
+			`Number of bytes examined by the current or most recent scrub or resilver.`,
+			transformNumeric,
+			prometheus.GaugeValue,
+			scanLabels...,
+		),
+		toExamine: newProperty(
+			subsystemScan,
+			`to_examine_bytes`,
+			`Total number of bytes to be examined by the current or most recent scrub or resilver.`,
+			transformNumeric,
+			prometheus.GaugeValue,
+			scanLabels...,
+		),
+		issued: newProperty(
+			subsystemScan,
+			`issued_bytes`,
+			`Number of bytes issued for verification by the current or most recent scrub or resilver.`,
+			transformNumeric,
+			prometheus.GaugeValue,
+			scanLabels...,
+		),
+		state: newProperty(
+			subsystemScan,
+			`state`,
+			`Whether the current or most recent scrub or resilver is in the state given by the state label (1) or not (0).`,
+			transformNumeric,
+			prometheus.GaugeValue,
+			append(scanLabels, `state`)...,
+		),
+		startTime: newProperty(
+			subsystemScan,
+			`start_time_seconds`,
+			`Time at which the current or most recent scrub or resilver started, in seconds since the epoch.`,
+			transformNumeric,
+			prometheus.GaugeValue,
+			scanLabels...,
+		),
+		endTime: newProperty(
+			subsystemScan,
+			`end_time_seconds`,
+			`Time at which the most recent scrub or resilver finished or was canceled, in seconds since the epoch.`,
+			transformNumeric,
+			prometheus.GaugeValue,
+			scanLabels...,
+		),
+		errors: newProperty(
+			subsystemScan,
+			`errors_total`,
+			`Number of errors encountered by the current or most recent scrub or resilver, reset when a new scan starts.`,
+			transformNumeric,
+			prometheus.CounterValue,
+			scanLabels...,
+		),
+		lastScrub: newProperty(
+			subsystemPool,
+			`last_scrub_timestamp_seconds`,
+			`Time at which the most recent scrub of the pool completed, in seconds since the epoch. Absent if the most recent scan was not a completed scrub.`,
+			transformNumeric,
+			prometheus.GaugeValue,
+			poolLabels...,
+		),
+		expandReflowed: newProperty(
+			subsystemVdev,
+			`raidz_expand_reflowed_bytes`,
+			`Number of bytes copied to the new layout by the current or most recent expansion of a raidz vdev.`,
+			transformNumeric,
+			prometheus.GaugeValue,
+			raidzExpandLabels...,
+		),
+		expandToReflow: newProperty(
+			subsystemVdev,
+			`raidz_expand_to_reflow_bytes`,
+			`Total number of bytes to be copied to the new layout by the current or most recent expansion of a raidz vdev.`,
+			transformNumeric,
+			prometheus.GaugeValue,
+			raidzExpandLabels...,
+		),
+		expandState: newProperty(
+			subsystemVdev,
+			`raidz_expand_state`,
+			`Whether the current or most recent expansion of a raidz vdev is in the state given by the state label (1) or not (0).`,
+			transformNumeric,
+			prometheus.GaugeValue,
+			append(raidzExpandLabels, `state`)...,
+		),
+		expandStartTime: newProperty(
+			subsystemVdev,
+			`raidz_expand_start_time_seconds`,
+			`Time at which the current or most recent expansion of a raidz vdev started, in seconds since the epoch.`,
+			transformNumeric,
+			prometheus.GaugeValue,
+			raidzExpandLabels...,
+		),
+		expandEndTime: newProperty(
+			subsystemVdev,
+			`raidz_expand_end_time_seconds`,
+			`Time at which the most recent expansion of a raidz vdev finished or was canceled, in seconds since the epoch.`,
+			transformNumeric,
+			prometheus.GaugeValue,
+			raidzExpandLabels...,
+		),
+		expandWaiting: newProperty(
+			subsystemVdev,
+			`raidz_expand_waiting_for_resilver`,
+			`Whether the expansion of a raidz vdev is paused until a resilver completes (1) or not (0).`,
+			transformNumeric,
+			prometheus.GaugeValue,
+			raidzExpandLabels...,
+		),
+	}, nil
+}
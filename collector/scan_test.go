@@ -0,0 +1,212 @@
+package collector
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jmcgover/zfs_exporter/v2/zfs"
+	"github.com/jmcgover/zfs_exporter/v2/zfs/mock_zfs"
+	"go.uber.org/mock/gomock"
+)
+
+func TestScanMetrics(t *testing.T) {
+	const result = `# HELP zfs_pool_last_scrub_timestamp_seconds Time at which the most recent scrub of the pool completed, in seconds since the epoch. Absent if the most recent scan was not a completed scrub.
+# TYPE zfs_pool_last_scrub_timestamp_seconds gauge
+zfs_pool_last_scrub_timestamp_seconds{pool="testpool3"} 1.6e+09
+# HELP zfs_scan_end_time_seconds Time at which the most recent scrub or resilver finished or was canceled, in seconds since the epoch.
+# TYPE zfs_scan_end_time_seconds gauge
+zfs_scan_end_time_seconds{function="resilver",pool="testpool2"} 1.7000036e+09
+zfs_scan_end_time_seconds{function="scrub",pool="testpool3"} 1.6e+09
+# HELP zfs_scan_errors_total Number of errors encountered by the current or most recent scrub or resilver, reset when a new scan starts.
+# TYPE zfs_scan_errors_total counter
+zfs_scan_errors_total{function="resilver",pool="testpool2"} 2
+zfs_scan_errors_total{function="scrub",pool="testpool1"} 0
+zfs_scan_errors_total{function="scrub",pool="testpool3"} 0
+# HELP zfs_scan_examined_bytes Number of bytes examined by the current or most recent scrub or resilver.
+# TYPE zfs_scan_examined_bytes gauge
+zfs_scan_examined_bytes{function="resilver",pool="testpool2"} 2048
+zfs_scan_examined_bytes{function="scrub",pool="testpool1"} 512
+zfs_scan_examined_bytes{function="scrub",pool="testpool3"} 4096
+# HELP zfs_scan_issued_bytes Number of bytes issued for verification by the current or most recent scrub or resilver.
+# TYPE zfs_scan_issued_bytes gauge
+zfs_scan_issued_bytes{function="resilver",pool="testpool2"} 2048
+zfs_scan_issued_bytes{function="scrub",pool="testpool1"} 256
+zfs_scan_issued_bytes{function="scrub",pool="testpool3"} 4096
+# HELP zfs_scan_start_time_seconds Time at which the current or most recent scrub or resilver started, in seconds since the epoch.
+# TYPE zfs_scan_start_time_seconds gauge
+zfs_scan_start_time_seconds{function="resilver",pool="testpool2"} 1.7e+09
+zfs_scan_start_time_seconds{function="scrub",pool="testpool1"} 1.71e+09
+zfs_scan_start_time_seconds{function="scrub",pool="testpool3"} 1.5999964e+09
+# HELP zfs_scan_state Whether the current or most recent scrub or resilver is in the state given by the state label (1) or not (0).
+# TYPE zfs_scan_state gauge
+zfs_scan_state{function="resilver",pool="testpool2",state="CANCELED"} 0
+zfs_scan_state{function="resilver",pool="testpool2",state="FINISHED"} 1
+zfs_scan_state{function="resilver",pool="testpool2",state="SCANNING"} 0
+zfs_scan_state{function="scrub",pool="testpool1",state="CANCELED"} 0
+zfs_scan_state{function="scrub",pool="testpool1",state="FINISHED"} 0
+zfs_scan_state{function="scrub",pool="testpool1",state="SCANNING"} 1
+zfs_scan_state{function="scrub",pool="testpool3",state="CANCELED"} 0
+zfs_scan_state{function="scrub",pool="testpool3",state="FINISHED"} 1
+zfs_scan_state{function="scrub",pool="testpool3",state="SCANNING"} 0
+# HELP zfs_scan_to_examine_bytes Total number of bytes to be examined by the current or most recent scrub or resilver.
+# TYPE zfs_scan_to_examine_bytes gauge
+zfs_scan_to_examine_bytes{function="resilver",pool="testpool2"} 2048
+zfs_scan_to_examine_bytes{function="scrub",pool="testpool1"} 1024
+zfs_scan_to_examine_bytes{function="scrub",pool="testpool3"} 4096
+`
+
+	ctrl, ctx := gomock.WithContext(context.Background(), t)
+	zfsClient := mock_zfs.NewMockClient(ctrl)
+	zfsClient.EXPECT().PoolNames().Return([]string{`testpool1`, `testpool2`, `testpool3`, `testpool4`}, nil).Times(1)
+	zfsClient.EXPECT().PoolStatus(false).Return(map[string]zfs.PoolStatusT{
+		`testpool1`: {
+			Name: `testpool1`,
+			ScanStats: zfs.ScanStatsT{
+				Function:  `SCRUB`,
+				State:     `SCANNING`,
+				StartTime: 1710000000,
+				ToExamine: 1024,
+				Examined:  512,
+				Issued:    256,
+			},
+		},
+		`testpool2`: {
+			Name: `testpool2`,
+			ScanStats: zfs.ScanStatsT{
+				Function:  `RESILVER`,
+				State:     `FINISHED`,
+				StartTime: 1700000000,
+				EndTime:   1700003600,
+				ToExamine: 2048,
+				Examined:  2048,
+				Issued:    2048,
+				Errors:    2,
+			},
+		},
+		`testpool3`: {
+			Name: `testpool3`,
+			ScanStats: zfs.ScanStatsT{
+				Function:  `SCRUB`,
+				State:     `FINISHED`,
+				StartTime: 1599996400,
+				EndTime:   1600000000,
+				ToExamine: 4096,
+				Examined:  4096,
+				Issued:    4096,
+			},
+		},
+		`testpool4`: {Name: `testpool4`, ScanStats: zfs.ScanStatsT{Function: `NONE`, State: `NONE`}},
+	}, nil).Times(1)
+
+	collector, err := NewZFS(defaultConfig(zfsClient))
+	if err != nil {
+		t.Fatal(err)
+	}
+	collector.Collectors = map[string]State{
+		`scan`: {
+			Name:       "scan",
+			Enabled:    boolPointer(true),
+			Properties: stringPointer(``),
+			factory:    newScanCollector,
+		},
+	}
+
+	metricNames := []string{
+		`zfs_pool_last_scrub_timestamp_seconds`,
+		`zfs_scan_end_time_seconds`,
+		`zfs_scan_errors_total`,
+		`zfs_scan_examined_bytes`,
+		`zfs_scan_issued_bytes`,
+		`zfs_scan_start_time_seconds`,
+		`zfs_scan_state`,
+		`zfs_scan_to_examine_bytes`,
+	}
+	if err = callCollector(ctx, collector, []byte(result), metricNames); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestScanRaidzExpandMetrics(t *testing.T) {
+	const result = `# HELP zfs_vdev_raidz_expand_end_time_seconds Time at which the most recent expansion of a raidz vdev finished or was canceled, in seconds since the epoch.
+# TYPE zfs_vdev_raidz_expand_end_time_seconds gauge
+zfs_vdev_raidz_expand_end_time_seconds{pool="testpool2",vdev="raidz2-0"} 1.7000036e+09
+# HELP zfs_vdev_raidz_expand_reflowed_bytes Number of bytes copied to the new layout by the current or most recent expansion of a raidz vdev.
+# TYPE zfs_vdev_raidz_expand_reflowed_bytes gauge
+zfs_vdev_raidz_expand_reflowed_bytes{pool="testpool1",vdev="raidz1-0"} 1024
+zfs_vdev_raidz_expand_reflowed_bytes{pool="testpool2",vdev="raidz2-0"} 8192
+# HELP zfs_vdev_raidz_expand_start_time_seconds Time at which the current or most recent expansion of a raidz vdev started, in seconds since the epoch.
+# TYPE zfs_vdev_raidz_expand_start_time_seconds gauge
+zfs_vdev_raidz_expand_start_time_seconds{pool="testpool1",vdev="raidz1-0"} 1.71e+09
+zfs_vdev_raidz_expand_start_time_seconds{pool="testpool2",vdev="raidz2-0"} 1.7e+09
+# HELP zfs_vdev_raidz_expand_state Whether the current or most recent expansion of a raidz vdev is in the state given by the state label (1) or not (0).
+# TYPE zfs_vdev_raidz_expand_state gauge
+zfs_vdev_raidz_expand_state{pool="testpool1",state="CANCELED",vdev="raidz1-0"} 0
+zfs_vdev_raidz_expand_state{pool="testpool1",state="FINISHED",vdev="raidz1-0"} 0
+zfs_vdev_raidz_expand_state{pool="testpool1",state="SCANNING",vdev="raidz1-0"} 1
+zfs_vdev_raidz_expand_state{pool="testpool2",state="CANCELED",vdev="raidz2-0"} 0
+zfs_vdev_raidz_expand_state{pool="testpool2",state="FINISHED",vdev="raidz2-0"} 1
+zfs_vdev_raidz_expand_state{pool="testpool2",state="SCANNING",vdev="raidz2-0"} 0
+# HELP zfs_vdev_raidz_expand_to_reflow_bytes Total number of bytes to be copied to the new layout by the current or most recent expansion of a raidz vdev.
+# TYPE zfs_vdev_raidz_expand_to_reflow_bytes gauge
+zfs_vdev_raidz_expand_to_reflow_bytes{pool="testpool1",vdev="raidz1-0"} 4096
+zfs_vdev_raidz_expand_to_reflow_bytes{pool="testpool2",vdev="raidz2-0"} 8192
+# HELP zfs_vdev_raidz_expand_waiting_for_resilver Whether the expansion of a raidz vdev is paused until a resilver completes (1) or not (0).
+# TYPE zfs_vdev_raidz_expand_waiting_for_resilver gauge
+zfs_vdev_raidz_expand_waiting_for_resilver{pool="testpool1",vdev="raidz1-0"} 1
+zfs_vdev_raidz_expand_waiting_for_resilver{pool="testpool2",vdev="raidz2-0"} 0
+`
+
+	ctrl, ctx := gomock.WithContext(context.Background(), t)
+	zfsClient := mock_zfs.NewMockClient(ctrl)
+	zfsClient.EXPECT().PoolNames().Return([]string{`testpool1`, `testpool2`, `testpool3`}, nil).Times(1)
+	zfsClient.EXPECT().PoolStatus(false).Return(map[string]zfs.PoolStatusT{
+		`testpool1`: {
+			Name: `testpool1`,
+			RaidzExpandStats: &zfs.RaidzExpandStatsT{
+				State:              `SCANNING`,
+				ExpandingVdev:      `raidz1-0`,
+				StartTime:          1710000000,
+				ToReflow:           4096,
+				Reflowed:           1024,
+				WaitingForResilver: 1,
+			},
+		},
+		`testpool2`: {
+			Name: `testpool2`,
+			RaidzExpandStats: &zfs.RaidzExpandStatsT{
+				State:         `FINISHED`,
+				ExpandingVdev: `raidz2-0`,
+				StartTime:     1700000000,
+				EndTime:       1700003600,
+				ToReflow:      8192,
+				Reflowed:      8192,
+			},
+		},
+		`testpool3`: {Name: `testpool3`},
+	}, nil).Times(1)
+
+	collector, err := NewZFS(defaultConfig(zfsClient))
+	if err != nil {
+		t.Fatal(err)
+	}
+	collector.Collectors = map[string]State{
+		`scan`: {
+			Name:       "scan",
+			Enabled:    boolPointer(true),
+			Properties: stringPointer(``),
+			factory:    newScanCollector,
+		},
+	}
+
+	metricNames := []string{
+		`zfs_vdev_raidz_expand_end_time_seconds`,
+		`zfs_vdev_raidz_expand_reflowed_bytes`,
+		`zfs_vdev_raidz_expand_start_time_seconds`,
+		`zfs_vdev_raidz_expand_state`,
+		`zfs_vdev_raidz_expand_to_reflow_bytes`,
+		`zfs_vdev_raidz_expand_waiting_for_resilver`,
+	}
+	if err = callCollector(ctx, collector, []byte(result), metricNames); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,128 @@
+package collector
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// timeWindow is a daily period in local time, as offsets from midnight. Windows whose end precedes their start span
+// midnight.
+type timeWindow struct {
+	start, end time.Duration
+}
+
+// contains reports whether the time of day of t falls within the window
+func (w timeWindow) contains(t time.Time) bool {
+	offset := time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute + time.Duration(t.Second())*time.Second
+	if w.start <= w.end {
+		return offset >= w.start && offset < w.end
+	}
+	return offset >= w.start || offset < w.end
+}
+
+// parseSchedule parses a collector schedule of the form COLLECTOR=HH:MM-HH:MM
+func parseSchedule(spec string) (string, timeWindow, error) {
+	name, window, ok := strings.Cut(spec, `=`)
+	if !ok || name == `` {
+		return ``, timeWindow{}, fmt.Errorf("invalid schedule '%s': expected COLLECTOR=HH:MM-HH:MM", spec)
+	}
+	start, end, ok := strings.Cut(window, `-`)
+	if !ok {
+		return ``, timeWindow{}, fmt.Errorf("invalid schedule '%s': expected COLLECTOR=HH:MM-HH:MM", spec)
+	}
+	var (
+		w   timeWindow
+		err error
+	)
+	if w.start, err = parseTimeOfDay(start); err != nil {
+		return ``, timeWindow{}, fmt.Errorf("invalid schedule '%s': %w", spec, err)
+	}
+	if w.end, err = parseTimeOfDay(end); err != nil {
+		return ``, timeWindow{}, fmt.Errorf("invalid schedule '%s': %w", spec, err)
+	}
+	if w.start == w.end {
+		return ``, timeWindow{}, fmt.Errorf("invalid schedule '%s': window is empty", spec)
+	}
+
+	return name, w, nil
+}
+
+func parseTimeOfDay(s string) (time.Duration, error) {
+	t, err := time.Parse(`15:04`, s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time of day '%s'", s)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// scheduler restricts collectors to their configured time windows, retaining the metrics of their last run so that
+// they may be served outside the windows
+type scheduler struct {
+	windows map[string][]timeWindow
+	now     func() time.Time
+	mu      sync.Mutex
+	held    map[string][]metric
+}
+
+func newScheduler(specs []string) (*scheduler, error) {
+	s := &scheduler{windows: make(map[string][]timeWindow), now: time.Now, held: make(map[string][]metric)}
+	for _, spec := range specs {
+		name, window, err := parseSchedule(spec)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := collectorStates[name]; !ok {
+			return nil, fmt.Errorf("invalid schedule '%s': unknown collector", spec)
+		}
+		s.windows[name] = append(s.windows[name], window)
+	}
+
+	return s, nil
+}
+
+// scheduled reports whether the collector is restricted to time windows
+func (s *scheduler) scheduled(name string) bool {
+	_, ok := s.windows[name]
+	return ok
+}
+
+// due reports whether the collector should run now, which is always the case for collectors without a schedule, and
+// for scheduled collectors that have not yet run
+func (s *scheduler) due(name string) bool {
+	windows, ok := s.windows[name]
+	if !ok {
+		return true
+	}
+	s.mu.Lock()
+	_, ran := s.held[name]
+	s.mu.Unlock()
+	if !ran {
+		return true
+	}
+	now := s.now()
+	for _, w := range windows {
+		if w.contains(now) {
+			return true
+		}
+	}
+	return false
+}
+
+// hold records the metrics of the latest run of the collector
+func (s *scheduler) hold(name string, metrics []metric) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.held[name] = metrics
+}
+
+// replay sends the metrics of the latest run of the collector
+func (s *scheduler) replay(name string, ch chan<- metric) {
+	s.mu.Lock()
+	metrics := s.held[name]
+	s.mu.Unlock()
+	for _, m := range metrics {
+		ch <- m
+	}
+}
@@ -0,0 +1,104 @@
+package collector
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jmcgover/zfs_exporter/v2/zfs"
+	"github.com/jmcgover/zfs_exporter/v2/zfs/mock_zfs"
+	"go.uber.org/mock/gomock"
+)
+
+func TestParseSchedule(t *testing.T) {
+	testCases := []struct {
+		spec    string
+		name    string
+		inside  []string
+		outside []string
+		wantErr bool
+	}{
+		{spec: `snapshot-summary=01:00-05:30`, name: `snapshot-summary`, inside: []string{`01:00`, `05:29`}, outside: []string{`00:59`, `05:30`, `12:00`}},
+		{spec: `ddt=22:00-06:00`, name: `ddt`, inside: []string{`22:00`, `23:59`, `00:00`, `05:59`}, outside: []string{`06:00`, `21:59`}},
+		{spec: `ddt`, wantErr: true},
+		{spec: `=01:00-02:00`, wantErr: true},
+		{spec: `ddt=01:00`, wantErr: true},
+		{spec: `ddt=25:00-02:00`, wantErr: true},
+		{spec: `ddt=01:00-01:00`, wantErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.spec, func(t *testing.T) {
+			name, window, err := parseSchedule(tc.spec)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatal(`Expected error, got nil`)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if name != tc.name {
+				t.Errorf("Expected collector %s, got %s", tc.name, name)
+			}
+			at := func(clock string) time.Time {
+				tm, err := time.Parse(`15:04`, clock)
+				if err != nil {
+					t.Fatal(err)
+				}
+				return time.Date(2025, 1, 1, tm.Hour(), tm.Minute(), 0, 0, time.Local)
+			}
+			for _, clock := range tc.inside {
+				if !window.contains(at(clock)) {
+					t.Errorf("Expected %s to be inside the window", clock)
+				}
+			}
+			for _, clock := range tc.outside {
+				if window.contains(at(clock)) {
+					t.Errorf("Expected %s to be outside the window", clock)
+				}
+			}
+		})
+	}
+
+	if _, err := newScheduler([]string{`missing=01:00-02:00`}); err == nil {
+		t.Error(`Expected error for an unknown collector`)
+	}
+}
+
+func TestScheduledCollector(t *testing.T) {
+	const result = `# HELP zfs_pool_ddt_entries Number of unique blocks tracked by the dedup table (DDT) of the pool.
+# TYPE zfs_pool_ddt_entries gauge
+zfs_pool_ddt_entries{pool="testpool"} 100
+`
+
+	ctrl, ctx := gomock.WithContext(context.Background(), t)
+	zfsClient := mock_zfs.NewMockClient(ctrl)
+	zfsClient.EXPECT().PoolNames().Return([]string{`testpool`}, nil).Times(3)
+	// Only run once the collector has no metrics to serve, and again within the window
+	zfsClient.EXPECT().DDTStats(`testpool`).Return(zfs.DDTStatsT{Entries: 100}, nil).Times(2)
+
+	config := defaultConfig(zfsClient)
+	config.Schedules = []string{`ddt=01:00-05:00`}
+	collector, err := NewZFS(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	collector.Collectors = map[string]State{
+		`ddt`: {
+			Name:       "ddt",
+			Enabled:    boolPointer(true),
+			Properties: stringPointer(``),
+			factory:    newDDTCollector,
+		},
+	}
+
+	metricNames := []string{`zfs_pool_ddt_entries`}
+	for _, hour := range []int{12, 13, 2} {
+		collector.scheduler.now = func() time.Time { return time.Date(2025, 1, 1, hour, 0, 0, 0, time.Local) }
+		if err = callCollector(ctx, collector, []byte(result), metricNames); err != nil {
+			t.Fatalf("At %02d:00: %s", hour, err)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/jmcgover/zfs_exporter/v2/zfs"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// ReplicationPair identifies the snapshot or bookmark last replicated to a target
+type ReplicationPair struct {
+	Name string
+	// From is the full name of the snapshot or bookmark last replicated
+	From string
+	// Snapshot is the snapshot to be sent, or the most recent snapshot of the dataset of From if empty
+	Snapshot string
+}
+
+// dataset returns the name of the dataset of the snapshot or bookmark the pair is replicated from
+func (p ReplicationPair) dataset() string {
+	if i := strings.IndexAny(p.From, `@#`); i >= 0 {
+		return p.From[:i]
+	}
+	return p.From
+}
+
+// SendEstimateCollector exports the estimated size of the next incremental send of each replication pair, which it
+// estimates in the background, as a dry run of zfs send may take some time on large datasets
+type SendEstimateCollector struct {
+	logger   *slog.Logger
+	client   zfs.Client
+	pairs    []ReplicationPair
+	estimate *prometheus.GaugeVec
+}
+
+// NewSendEstimateCollector instantiates a collector for the send estimates of the replication pairs
+func NewSendEstimateCollector(logger *slog.Logger, client zfs.Client, pairs []ReplicationPair) *SendEstimateCollector {
+	return &SendEstimateCollector{
+		logger: logger,
+		client: client,
+		pairs:  pairs,
+		estimate: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: `replication`,
+			Name:      `send_estimate_bytes`,
+			Help:      `Estimated size of the incremental send from the snapshot or bookmark last replicated, as reported by a dry run of zfs send.`,
+		}, []string{`replication`, `dataset`}),
+	}
+}
+
+// Run estimates the size of each pair until ctx is done, waiting for the duration returned by interval between
+// estimates
+func (c *SendEstimateCollector) Run(ctx context.Context, interval func() time.Duration) {
+	for {
+		if err := c.Poll(); err != nil {
+			c.logger.Error("Error estimating replication sends", "err", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval()):
+		}
+	}
+}
+
+// Poll estimates the size of the next send of every pair. Pairs that cannot be estimated, e.g. as the bookmark has been
+// destroyed, are removed until they can be estimated again.
+func (c *SendEstimateCollector) Poll() error {
+	var errs []error
+	for _, pair := range c.pairs {
+		dataset := pair.dataset()
+		size, err := c.estimatePair(pair)
+		if err != nil {
+			c.estimate.DeleteLabelValues(pair.Name, dataset)
+			errs = append(errs, fmt.Errorf("replication %s: %w", pair.Name, err))
+			continue
+		}
+		c.estimate.WithLabelValues(pair.Name, dataset).Set(float64(size))
+	}
+
+	return errors.Join(errs...)
+}
+
+func (c *SendEstimateCollector) estimatePair(pair ReplicationPair) (uint64, error) {
+	snapshot := pair.Snapshot
+	if snapshot == `` {
+		var err error
+		if snapshot, err = c.client.LatestSnapshot(pair.dataset()); err != nil {
+			return 0, err
+		}
+	}
+	// Nothing remains to be sent once the latest snapshot has been replicated
+	if snapshot == pair.From {
+		return 0, nil
+	}
+	return c.client.SendEstimate(snapshot, pair.From)
+}
+
+// Describe implements the prometheus.Collector interface.
+func (c *SendEstimateCollector) Describe(ch chan<- *prometheus.Desc) {
+	c.estimate.Describe(ch)
+}
+
+// Collect implements the prometheus.Collector interface.
+func (c *SendEstimateCollector) Collect(ch chan<- prometheus.Metric) {
+	c.estimate.Collect(ch)
+}
@@ -0,0 +1,43 @@
+package collector
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/jmcgover/zfs_exporter/v2/zfs/mock_zfs"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"go.uber.org/mock/gomock"
+)
+
+func TestSendEstimateCollector(t *testing.T) {
+	const result = `# HELP zfs_replication_send_estimate_bytes Estimated size of the incremental send from the snapshot or bookmark last replicated, as reported by a dry run of zfs send.
+# TYPE zfs_replication_send_estimate_bytes gauge
+zfs_replication_send_estimate_bytes{dataset="tank/data",replication="offsite"} 1.048576e+06
+zfs_replication_send_estimate_bytes{dataset="tank/home",replication="nas"} 0
+zfs_replication_send_estimate_bytes{dataset="tank/vm",replication="dr"} 4096
+`
+
+	ctrl, _ := gomock.WithContext(context.Background(), t)
+	zfsClient := mock_zfs.NewMockClient(ctrl)
+	zfsClient.EXPECT().LatestSnapshot(`tank/data`).Return(`tank/data@daily-2`, nil)
+	zfsClient.EXPECT().SendEstimate(`tank/data@daily-2`, `tank/data#offsite`).Return(uint64(1048576), nil)
+	zfsClient.EXPECT().SendEstimate(`tank/vm@pinned`, `tank/vm@base`).Return(uint64(4096), nil)
+	// The latest snapshot has already been replicated
+	zfsClient.EXPECT().LatestSnapshot(`tank/home`).Return(`tank/home@daily-2`, nil)
+	zfsClient.EXPECT().LatestSnapshot(`tank/gone`).Return(``, errors.New(`dataset does not exist`))
+
+	c := NewSendEstimateCollector(logger, zfsClient, []ReplicationPair{
+		{Name: `offsite`, From: `tank/data#offsite`},
+		{Name: `dr`, From: `tank/vm@base`, Snapshot: `tank/vm@pinned`},
+		{Name: `nas`, From: `tank/home@daily-2`},
+		{Name: `missing`, From: `tank/gone#offsite`},
+	})
+	if err := c.Poll(); err == nil {
+		t.Error(`Expected error estimating a pair whose dataset does not exist`)
+	}
+	if err := testutil.CollectAndCompare(c, bytes.NewBufferString(result)); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,181 @@
+package collector
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/jmcgover/zfs_exporter/v2/zfs"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// slogStates are the states exported for every log vdev, those of vdevStates other than the states of hot spares
+var slogStates = []string{
+	string(zfs.PoolOnline),
+	string(zfs.PoolDegraded),
+	string(zfs.PoolFaulted),
+	string(zfs.PoolOffline),
+	string(zfs.PoolUnavail),
+	string(zfs.PoolRemoved),
+}
+
+func init() {
+	registerCollector(`slog`, defaultDisabled, ``, newSlogCollector)
+}
+
+// slogCollector exports the log vdevs (SLOG) of each pool separately from its data vdevs, as a failed SLOG risks the
+// loss of recent synchronous writes on a crash rather than the pool itself, and its latency bounds that of sync writes
+type slogCollector struct {
+	log            *slog.Logger
+	client         zfs.Client
+	hasSlog        property
+	state          stateProperty
+	size           property
+	allocated      property
+	writeOps       property
+	writeWait      property
+	diskWriteWait  property
+	syncqWriteWait property
+}
+
+func (c *slogCollector) describe(ch chan<- *prometheus.Desc) {
+	ch <- c.hasSlog.desc
+	c.state.describe(ch)
+	ch <- c.size.desc
+	ch <- c.allocated.desc
+	ch <- c.writeOps.desc
+	ch <- c.writeWait.desc
+	ch <- c.diskWriteWait.desc
+	ch <- c.syncqWriteWait.desc
+}
+
+func (c *slogCollector) update(ch chan<- metric, pools []string, excludes regexpCollection) error {
+	stats, err := c.client.PoolIostats(true)
+	if err != nil {
+		return err
+	}
+
+	for _, pool := range pools {
+		poolStats, ok := stats[pool]
+		if !ok {
+			c.log.Warn("Pool missing from iostat output", "collector", "slog", "pool", pool)
+			continue
+		}
+		hasSlog := 0.0
+		if len(poolStats.Logs) > 0 {
+			hasSlog = 1
+		}
+		c.hasSlog.send(ch, hasSlog, pool)
+
+		// Space is allocated from top-level vdevs, while latency is that of the devices servicing the writes
+		for _, vdev := range poolStats.Logs {
+			c.size.send(ch, float64(vdev.TotalSpace), pool, vdev.Name)
+			c.allocated.send(ch, float64(vdev.AllocSpace), pool, vdev.Name)
+			if err = c.updateVdev(ch, pool, vdev); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (c *slogCollector) updateVdev(ch chan<- metric, pool string, vdev zfs.VdevIostatT) error {
+	if err := c.state.send(ch, vdev.State, pool, vdev.Name); err != nil {
+		return err
+	}
+	if len(vdev.Vdevs) == 0 {
+		c.writeOps.send(ch, float64(vdev.WriteOps), pool, vdev.Name)
+		c.writeWait.send(ch, time.Duration(vdev.TotalWriteWait).Seconds(), pool, vdev.Name)
+		c.diskWriteWait.send(ch, time.Duration(vdev.DiskWriteWait).Seconds(), pool, vdev.Name)
+		c.syncqWriteWait.send(ch, time.Duration(vdev.SyncqWriteWait).Seconds(), pool, vdev.Name)
+	}
+	for _, child := range vdev.Vdevs {
+		if err := c.updateVdev(ch, pool, child); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func newSlogCollector(l *slog.Logger, c zfs.Client, props []string) (Collector, error) {
+	return &slogCollector{
+		log:    l,
+		client: c,
+		hasSlog: newProperty(
+			subsystemPool,
+			`has_slog`,
+			`Whether the pool has a separate intent log (SLOG) device (1) or not (0).`,
+			transformNumeric,
+			prometheus.GaugeValue,
+			poolLabels...,
+		),
+		state: stateProperty{
+			code: newProperty(
+				subsystemSlog,
+				`health`,
+				`Health status code for the log vdev `+stateCodesHelp(slogStates)+`, as reported by zpool iostat.`,
+				transformHealthCode,
+				prometheus.GaugeValue,
+				vdevLabels...,
+			),
+			oneHot: newProperty(
+				subsystemSlog,
+				`state`,
+				`Whether the log vdev is in the state given by the state label (1) or not (0), as reported by zpool iostat.`,
+				transformNumeric,
+				prometheus.GaugeValue,
+				`pool`, `vdev`, `state`,
+			),
+			states: slogStates,
+		},
+		size: newProperty(
+			subsystemSlog,
+			`size_bytes`,
+			`Size of the top-level log vdev in bytes.`,
+			transformNumeric,
+			prometheus.GaugeValue,
+			vdevLabels...,
+		),
+		allocated: newProperty(
+			subsystemSlog,
+			`allocated_bytes`,
+			`Space allocated on the top-level log vdev in bytes, i.e. synchronous writes not yet committed to the pool.`,
+			transformNumeric,
+			prometheus.GaugeValue,
+			vdevLabels...,
+		),
+		writeOps: newProperty(
+			subsystemSlog,
+			`write_ops_per_second`,
+			`Average write operations per second for the log device over --zfs.iostat-interval.`,
+			transformNumeric,
+			prometheus.GaugeValue,
+			vdevLabels...,
+		),
+		writeWait: newProperty(
+			subsystemSlog,
+			`write_wait_seconds`,
+			`Average total wait time for write operations on the log device over --zfs.iostat-interval.`,
+			transformNumeric,
+			prometheus.GaugeValue,
+			vdevLabels...,
+		),
+		diskWriteWait: newProperty(
+			subsystemSlog,
+			`disk_write_wait_seconds`,
+			`Average time write operations spent waiting on the disk on the log device over --zfs.iostat-interval.`,
+			transformNumeric,
+			prometheus.GaugeValue,
+			vdevLabels...,
+		),
+		syncqWriteWait: newProperty(
+			subsystemSlog,
+			`syncq_write_wait_seconds`,
+			`Average time synchronous write operations spent in the vdev queue on the log device over --zfs.iostat-interval.`,
+			transformNumeric,
+			prometheus.GaugeValue,
+			vdevLabels...,
+		),
+	}, nil
+}
@@ -0,0 +1,74 @@
+package collector
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jmcgover/zfs_exporter/v2/zfs"
+	"github.com/jmcgover/zfs_exporter/v2/zfs/mock_zfs"
+	"go.uber.org/mock/gomock"
+)
+
+func TestSlogMetrics(t *testing.T) {
+	const result = `# HELP zfs_pool_has_slog Whether the pool has a separate intent log (SLOG) device (1) or not (0).
+# TYPE zfs_pool_has_slog gauge
+zfs_pool_has_slog{pool="backup"} 0
+zfs_pool_has_slog{pool="testpool"} 1
+# HELP zfs_slog_allocated_bytes Space allocated on the top-level log vdev in bytes, i.e. synchronous writes not yet committed to the pool.
+# TYPE zfs_slog_allocated_bytes gauge
+zfs_slog_allocated_bytes{pool="testpool",vdev="mirror-1"} 1.048576e+06
+# HELP zfs_slog_health Health status code for the log vdev [0: ONLINE, 1: DEGRADED, 2: FAULTED, 3: OFFLINE, 4: UNAVAIL, 5: REMOVED], as reported by zpool iostat.
+# TYPE zfs_slog_health gauge
+zfs_slog_health{pool="testpool",vdev="mirror-1"} 1
+zfs_slog_health{pool="testpool",vdev="nvme0n1"} 0
+zfs_slog_health{pool="testpool",vdev="nvme1n1"} 2
+# HELP zfs_slog_size_bytes Size of the top-level log vdev in bytes.
+# TYPE zfs_slog_size_bytes gauge
+zfs_slog_size_bytes{pool="testpool",vdev="mirror-1"} 1.6106127360e+10
+# HELP zfs_slog_write_wait_seconds Average total wait time for write operations on the log device over --zfs.iostat-interval.
+# TYPE zfs_slog_write_wait_seconds gauge
+zfs_slog_write_wait_seconds{pool="testpool",vdev="nvme0n1"} 5e-05
+zfs_slog_write_wait_seconds{pool="testpool",vdev="nvme1n1"} 0
+`
+
+	ctrl, ctx := gomock.WithContext(context.Background(), t)
+	zfsClient := mock_zfs.NewMockClient(ctrl)
+	zfsClient.EXPECT().PoolNames().Return([]string{`backup`, `testpool`}, nil).Times(1)
+	zfsClient.EXPECT().PoolIostats(true).Return(map[string]zfs.PoolIostatT{
+		`backup`: {Name: `backup`},
+		`testpool`: {
+			Name: `testpool`,
+			Logs: map[string]zfs.VdevIostatT{
+				`mirror-1`: {
+					Name:       `mirror-1`,
+					VdevType:   `mirror`,
+					State:      `DEGRADED`,
+					AllocSpace: 1048576,
+					TotalSpace: 16106127360,
+					Vdevs: map[string]zfs.VdevIostatT{
+						`nvme0n1`: {Name: `nvme0n1`, VdevType: `disk`, State: `ONLINE`, WriteOps: 12, TotalWriteWait: 50000},
+						`nvme1n1`: {Name: `nvme1n1`, VdevType: `disk`, State: `FAULTED`},
+					},
+				},
+			},
+		},
+	}, nil).Times(1)
+
+	collector, err := NewZFS(defaultConfig(zfsClient))
+	if err != nil {
+		t.Fatal(err)
+	}
+	collector.Collectors = map[string]State{
+		`slog`: {
+			Name:       "slog",
+			Enabled:    boolPointer(true),
+			Properties: stringPointer(``),
+			factory:    newSlogCollector,
+		},
+	}
+
+	metricNames := []string{`zfs_pool_has_slog`, `zfs_slog_allocated_bytes`, `zfs_slog_health`, `zfs_slog_size_bytes`, `zfs_slog_write_wait_seconds`}
+	if err = callCollector(ctx, collector, []byte(result), metricNames); err != nil {
+		t.Fatal(err)
+	}
+}
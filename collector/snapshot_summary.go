@@ -0,0 +1,125 @@
+package collector
+
+import (
+	"log/slog"
+	"strings"
+
+	"github.com/jmcgover/zfs_exporter/v2/zfs"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	snapshotSummaryKinds  = []zfs.DatasetKind{zfs.DatasetSnapshot}
+	snapshotSummaryProps  = []string{`used`, `creation`}
+	snapshotSummaryLabels = []string{`name`, `pool`}
+	snapshotCount         = newProperty(
+		subsystemDataset,
+		`snapshot_count`,
+		`The number of snapshots of this dataset.`,
+		transformNumeric,
+		prometheus.GaugeValue,
+		snapshotSummaryLabels...,
+	)
+	snapshotUsed = newProperty(
+		subsystemDataset,
+		`snapshot_used_bytes`,
+		`The sum of space in bytes uniquely consumed by each snapshot of this dataset.`,
+		transformNumeric,
+		prometheus.GaugeValue,
+		snapshotSummaryLabels...,
+	)
+	snapshotOldest = newProperty(
+		subsystemDataset,
+		`snapshot_oldest_timestamp_seconds`,
+		`The unix timestamp when the oldest snapshot of this dataset was created.`,
+		transformNumeric,
+		prometheus.GaugeValue,
+		snapshotSummaryLabels...,
+	)
+	snapshotNewest = newProperty(
+		subsystemDataset,
+		`snapshot_newest_timestamp_seconds`,
+		`The unix timestamp when the newest snapshot of this dataset was created.`,
+		transformNumeric,
+		prometheus.GaugeValue,
+		snapshotSummaryLabels...,
+	)
+)
+
+func init() {
+	registerCollector(`snapshot-summary`, defaultDisabled, ``, newSnapshotSummaryCollector)
+}
+
+type snapshotSummary struct {
+	count  float64
+	used   float64
+	oldest float64
+	newest float64
+}
+
+type snapshotSummaryCollector struct {
+	log    *slog.Logger
+	client zfs.Client
+}
+
+func (c *snapshotSummaryCollector) describe(ch chan<- *prometheus.Desc) {
+	ch <- snapshotCount.desc
+	ch <- snapshotUsed.desc
+	ch <- snapshotOldest.desc
+	ch <- snapshotNewest.desc
+}
+
+func (c *snapshotSummaryCollector) update(ch chan<- metric, pools []string, excludes regexpCollection) error {
+	return updatePools(pools, func(pool string) error {
+		return c.updatePoolMetrics(ch, pool, excludes)
+	})
+}
+
+func (c *snapshotSummaryCollector) updatePoolMetrics(ch chan<- metric, pool string, excludes regexpCollection) error {
+	snapshots, err := c.client.DatasetList(pool, -1, snapshotSummaryKinds, snapshotSummaryProps...)
+	if err != nil {
+		return err
+	}
+
+	summaries := make(map[string]*snapshotSummary)
+	for name, snapshot := range snapshots {
+		if excludes.MatchString(name) {
+			continue
+		}
+		dataset, _, found := strings.Cut(name, `@`)
+		if !found {
+			continue
+		}
+		used, err := transformNumeric(string(snapshot.Properties[`used`].Value))
+		if err != nil {
+			return err
+		}
+		creation, err := transformNumeric(string(snapshot.Properties[`creation`].Value))
+		if err != nil {
+			return err
+		}
+
+		summary, ok := summaries[dataset]
+		if !ok {
+			summary = &snapshotSummary{oldest: creation, newest: creation}
+			summaries[dataset] = summary
+		}
+		summary.count++
+		summary.used += used
+		summary.oldest = min(summary.oldest, creation)
+		summary.newest = max(summary.newest, creation)
+	}
+
+	for dataset, summary := range summaries {
+		snapshotCount.send(ch, summary.count, dataset, pool)
+		snapshotUsed.send(ch, summary.used, dataset, pool)
+		snapshotOldest.send(ch, summary.oldest, dataset, pool)
+		snapshotNewest.send(ch, summary.newest, dataset, pool)
+	}
+
+	return nil
+}
+
+func newSnapshotSummaryCollector(l *slog.Logger, c zfs.Client, props []string) (Collector, error) {
+	return &snapshotSummaryCollector{log: l, client: c}, nil
+}
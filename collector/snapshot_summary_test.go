@@ -0,0 +1,63 @@
+package collector
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jmcgover/zfs_exporter/v2/zfs"
+	"github.com/jmcgover/zfs_exporter/v2/zfs/mock_zfs"
+	"go.uber.org/mock/gomock"
+)
+
+func TestSnapshotSummaryMetrics(t *testing.T) {
+	const result = `# HELP zfs_dataset_snapshot_count The number of snapshots of this dataset.
+# TYPE zfs_dataset_snapshot_count gauge
+zfs_dataset_snapshot_count{name="testpool/data",pool="testpool"} 2
+zfs_dataset_snapshot_count{name="testpool/home",pool="testpool"} 1
+# HELP zfs_dataset_snapshot_newest_timestamp_seconds The unix timestamp when the newest snapshot of this dataset was created.
+# TYPE zfs_dataset_snapshot_newest_timestamp_seconds gauge
+zfs_dataset_snapshot_newest_timestamp_seconds{name="testpool/data",pool="testpool"} 1756033200
+zfs_dataset_snapshot_newest_timestamp_seconds{name="testpool/home",pool="testpool"} 1756033000
+# HELP zfs_dataset_snapshot_oldest_timestamp_seconds The unix timestamp when the oldest snapshot of this dataset was created.
+# TYPE zfs_dataset_snapshot_oldest_timestamp_seconds gauge
+zfs_dataset_snapshot_oldest_timestamp_seconds{name="testpool/data",pool="testpool"} 1756033100
+zfs_dataset_snapshot_oldest_timestamp_seconds{name="testpool/home",pool="testpool"} 1756033000
+# HELP zfs_dataset_snapshot_used_bytes The sum of space in bytes uniquely consumed by each snapshot of this dataset.
+# TYPE zfs_dataset_snapshot_used_bytes gauge
+zfs_dataset_snapshot_used_bytes{name="testpool/data",pool="testpool"} 3072
+zfs_dataset_snapshot_used_bytes{name="testpool/home",pool="testpool"} 512
+`
+
+	ctrl, ctx := gomock.WithContext(context.Background(), t)
+	zfsClient := mock_zfs.NewMockClient(ctrl)
+	zfsClient.EXPECT().PoolNames().Return([]string{`testpool`}, nil).Times(1)
+	zfsClient.EXPECT().DatasetList(`testpool`, -1, snapshotSummaryKinds, snapshotSummaryProps).Return(map[string]zfs.DatasetListT{
+		`testpool/data@daily-1`: {
+			Properties: map[string]zfs.PropertyT{`used`: {Value: `1024`}, `creation`: {Value: `1756033100`}},
+		},
+		`testpool/data@daily-2`: {
+			Properties: map[string]zfs.PropertyT{`used`: {Value: `2048`}, `creation`: {Value: `1756033200`}},
+		},
+		`testpool/home@daily-1`: {
+			Properties: map[string]zfs.PropertyT{`used`: {Value: `512`}, `creation`: {Value: `1756033000`}},
+		},
+	}, nil).Times(1)
+
+	collector, err := NewZFS(defaultConfig(zfsClient))
+	if err != nil {
+		t.Fatal(err)
+	}
+	collector.Collectors = map[string]State{
+		`snapshot-summary`: {
+			Name:       "snapshot-summary",
+			Enabled:    boolPointer(true),
+			Properties: stringPointer(``),
+			factory:    newSnapshotSummaryCollector,
+		},
+	}
+
+	metricNames := []string{`zfs_dataset_snapshot_count`, `zfs_dataset_snapshot_newest_timestamp_seconds`, `zfs_dataset_snapshot_oldest_timestamp_seconds`, `zfs_dataset_snapshot_used_bytes`}
+	if err = callCollector(ctx, collector, []byte(result), metricNames); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,127 @@
+package collector
+
+import (
+	"errors"
+	"log/slog"
+
+	"github.com/jmcgover/zfs_exporter/v2/zfs"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	vdevStateAvail = `AVAIL`
+	vdevTypeSpare  = `spare`
+)
+
+func init() {
+	registerCollector(`spares`, defaultDisabled, ``, newSparesCollector)
+}
+
+type sparesCollector struct {
+	log           *slog.Logger
+	client        zfs.Client
+	available     property
+	unspared      property
+	attachAdvised property
+}
+
+func (c *sparesCollector) describe(ch chan<- *prometheus.Desc) {
+	ch <- c.available.desc
+	ch <- c.unspared.desc
+	ch <- c.attachAdvised.desc
+}
+
+func (c *sparesCollector) update(ch chan<- metric, pools []string, excludes regexpCollection) error {
+	status, err := c.client.PoolStatus(false)
+	// Where only some pools could be queried, the others are reported before the error is returned
+	if err != nil && len(status) == 0 {
+		return err
+	}
+
+	return errors.Join(err, updatePools(pools, func(pool string) error {
+		poolStatus, ok := status[pool]
+		if !ok {
+			if err == nil {
+				c.log.Warn("Pool missing from status output", "collector", "spares", "pool", pool)
+			}
+			return nil
+		}
+		return c.updatePoolMetrics(ch, poolStatus)
+	}))
+}
+
+func (c *sparesCollector) updatePoolMetrics(ch chan<- metric, status zfs.PoolStatusT) error {
+	props, err := c.client.Pool(status.Name).Properties(`autoreplace`)
+	if err != nil {
+		return err
+	}
+	autoreplace := props.Properties()[`autoreplace`] == `on`
+
+	available := 0
+	for _, spare := range status.Spares {
+		if spare.State == vdevStateAvail {
+			available++
+		}
+	}
+	unspared := unsparedFaults(status.Vdevs, false)
+
+	advised := 0.0
+	if unspared > 0 && available > 0 && !autoreplace {
+		advised = 1
+		c.log.Warn("Faulted device requires manual spare attachment", "pool", status.Name, "faulted", unspared, "spares_available", available)
+	}
+
+	c.available.send(ch, float64(available), status.Name)
+	c.unspared.send(ch, float64(unspared), status.Name)
+	c.attachAdvised.send(ch, advised, status.Name)
+
+	return nil
+}
+
+// unsparedFaults counts the faulted devices that have not been replaced by a hot spare, where spared indicates that
+// the vdevs are children of a spare vdev
+func unsparedFaults(vdevs map[string]zfs.VdevStatusT, spared bool) int {
+	count := 0
+	for _, vdev := range vdevs {
+		if len(vdev.Vdevs) > 0 {
+			count += unsparedFaults(vdev.Vdevs, spared || vdev.VdevType == vdevTypeSpare)
+			continue
+		}
+		if !spared && zfs.PoolStatus(vdev.State) == zfs.PoolFaulted {
+			count++
+		}
+	}
+
+	return count
+}
+
+func newSparesCollector(l *slog.Logger, c zfs.Client, props []string) (Collector, error) {
+	return &sparesCollector{
+		log:    l,
+		client: c,
+		available: newProperty(
+			subsystemPool,
+			`spares_available`,
+			`Number of hot spares available to the pool.`,
+			transformNumeric,
+			prometheus.GaugeValue,
+			poolLabels...,
+		),
+		unspared: newProperty(
+			subsystemPool,
+			`faulted_unspared_vdevs`,
+			`Number of faulted devices in the pool that have not been replaced by a hot spare.`,
+			transformNumeric,
+			prometheus.GaugeValue,
+			poolLabels...,
+		),
+		attachAdvised: newProperty(
+			subsystemPool,
+			`spare_attach_advised`,
+			`Whether a faulted device requires a hot spare to be attached manually, as spares are available but autoreplace is off.`,
+			transformNumeric,
+			prometheus.GaugeValue,
+			poolLabels...,
+		),
+	}, nil
+}
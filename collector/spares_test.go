@@ -0,0 +1,89 @@
+package collector
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jmcgover/zfs_exporter/v2/zfs"
+	"github.com/jmcgover/zfs_exporter/v2/zfs/mock_zfs"
+	"go.uber.org/mock/gomock"
+)
+
+func TestSparesMetrics(t *testing.T) {
+	const result = `# HELP zfs_pool_faulted_unspared_vdevs Number of faulted devices in the pool that have not been replaced by a hot spare.
+# TYPE zfs_pool_faulted_unspared_vdevs gauge
+zfs_pool_faulted_unspared_vdevs{pool="backup"} 0
+zfs_pool_faulted_unspared_vdevs{pool="tank"} 1
+# HELP zfs_pool_spare_attach_advised Whether a faulted device requires a hot spare to be attached manually, as spares are available but autoreplace is off.
+# TYPE zfs_pool_spare_attach_advised gauge
+zfs_pool_spare_attach_advised{pool="backup"} 0
+zfs_pool_spare_attach_advised{pool="tank"} 1
+# HELP zfs_pool_spares_available Number of hot spares available to the pool.
+# TYPE zfs_pool_spares_available gauge
+zfs_pool_spares_available{pool="backup"} 0
+zfs_pool_spares_available{pool="tank"} 1
+`
+
+	ctrl, ctx := gomock.WithContext(context.Background(), t)
+	zfsClient := mock_zfs.NewMockClient(ctrl)
+	zfsClient.EXPECT().PoolNames().Return([]string{`backup`, `tank`}, nil).Times(1)
+	zfsClient.EXPECT().PoolStatus(false).Return(map[string]zfs.PoolStatusT{
+		`tank`: {
+			Name: `tank`,
+			Vdevs: map[string]zfs.VdevStatusT{
+				`tank`: {Name: `tank`, VdevType: `root`, Vdevs: map[string]zfs.VdevStatusT{
+					`mirror-0`: {Name: `mirror-0`, VdevType: `mirror`, State: `DEGRADED`, Vdevs: map[string]zfs.VdevStatusT{
+						`sda`: {Name: `sda`, VdevType: `disk`, State: `ONLINE`},
+						`sdb`: {Name: `sdb`, VdevType: `disk`, State: `FAULTED`},
+					}},
+				}},
+			},
+			Spares: map[string]zfs.VdevStatusT{
+				`sdc`: {Name: `sdc`, VdevType: `disk`, State: `AVAIL`},
+			},
+		},
+		// The faulted device has already been replaced by the spare.
+		`backup`: {
+			Name: `backup`,
+			Vdevs: map[string]zfs.VdevStatusT{
+				`backup`: {Name: `backup`, VdevType: `root`, Vdevs: map[string]zfs.VdevStatusT{
+					`mirror-0`: {Name: `mirror-0`, VdevType: `mirror`, State: `DEGRADED`, Vdevs: map[string]zfs.VdevStatusT{
+						`sdd`: {Name: `sdd`, VdevType: `disk`, State: `ONLINE`},
+						`spare-1`: {Name: `spare-1`, VdevType: `spare`, State: `DEGRADED`, Vdevs: map[string]zfs.VdevStatusT{
+							`sde`: {Name: `sde`, VdevType: `disk`, State: `FAULTED`},
+							`sdf`: {Name: `sdf`, VdevType: `disk`, State: `ONLINE`},
+						}},
+					}},
+				}},
+			},
+			Spares: map[string]zfs.VdevStatusT{
+				`sdf`: {Name: `sdf`, VdevType: `disk`, State: `INUSE`},
+			},
+		},
+	}, nil).Times(1)
+	for _, pool := range []string{`backup`, `tank`} {
+		props := mock_zfs.NewMockPoolProperties(ctrl)
+		props.EXPECT().Properties().Return(map[string]string{`autoreplace`: `off`}).Times(1)
+		zfsPool := mock_zfs.NewMockPool(ctrl)
+		zfsPool.EXPECT().Properties(`autoreplace`).Return(props, nil).Times(1)
+		zfsClient.EXPECT().Pool(pool).Return(zfsPool).Times(1)
+	}
+
+	collector, err := NewZFS(defaultConfig(zfsClient))
+	if err != nil {
+		t.Fatal(err)
+	}
+	collector.Collectors = map[string]State{
+		`spares`: {
+			Name:       "spares",
+			Enabled:    boolPointer(true),
+			Properties: stringPointer(``),
+			factory:    newSparesCollector,
+		},
+	}
+
+	metricNames := []string{`zfs_pool_faulted_unspared_vdevs`, `zfs_pool_spare_attach_advised`, `zfs_pool_spares_available`}
+	if err = callCollector(ctx, collector, []byte(result), metricNames); err != nil {
+		t.Fatal(err)
+	}
+}
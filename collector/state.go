@@ -0,0 +1,121 @@
+package collector
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/jmcgover/zfs_exporter/v2/zfs"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// StateFormat selects how pool and vdev states are exported: as a numeric code, which suits alerting thresholds, as
+// one series per state with a value of 1 for the current state, which suits dashboards, or both
+type StateFormat string
+
+const (
+	StateFormatNumeric StateFormat = `numeric`
+	StateFormatOneHot  StateFormat = `one-hot`
+	StateFormatBoth    StateFormat = `both`
+)
+
+var (
+	// StateFormats are the supported state formats
+	StateFormats = []string{string(StateFormatNumeric), string(StateFormatOneHot), string(StateFormatBoth)}
+
+	stateFormat = StateFormatBoth
+
+	// poolStates are the states of a pool, in the order of their codes
+	poolStates = []string{
+		string(zfs.PoolOnline),
+		string(zfs.PoolDegraded),
+		string(zfs.PoolFaulted),
+		string(zfs.PoolOffline),
+		string(zfs.PoolUnavail),
+		string(zfs.PoolRemoved),
+		string(zfs.PoolSuspended),
+	}
+
+	// stateCodes is the canonical mapping of pool and vdev states to the codes exported by numeric state metrics
+	stateCodes = map[string]poolHealthCode{
+		string(zfs.PoolOnline):    poolOnline,
+		string(zfs.PoolDegraded):  poolDegraded,
+		string(zfs.PoolFaulted):   poolFaulted,
+		string(zfs.PoolOffline):   poolOffline,
+		string(zfs.PoolUnavail):   poolUnavail,
+		string(zfs.PoolRemoved):   poolRemoved,
+		string(zfs.PoolSuspended): poolSuspended,
+		`AVAIL`:                   spareAvail,
+		`INUSE`:                   spareInuse,
+	}
+)
+
+// SetStateFormat selects how states are exported by all collectors. It must be called before collection starts.
+func SetStateFormat(format StateFormat) error {
+	if !slices.Contains(StateFormats, string(format)) {
+		return fmt.Errorf("unknown state format '%s', expected one of %s", format, strings.Join(StateFormats, `, `))
+	}
+	stateFormat = format
+	return nil
+}
+
+func (f StateFormat) numeric() bool {
+	return f == StateFormatNumeric || f == StateFormatBoth
+}
+
+func (f StateFormat) oneHot() bool {
+	return f == StateFormatOneHot || f == StateFormatBoth
+}
+
+// stateCodesHelp describes the codes of the given states, for the help text of numeric state metrics
+func stateCodesHelp(states []string) string {
+	codes := make([]string, len(states))
+	for i, state := range states {
+		codes[i] = fmt.Sprintf("%d: %s", stateCodes[state], state)
+	}
+	return `[` + strings.Join(codes, `, `) + `]`
+}
+
+// stateProperty exports a state as a numeric code, and as one series for each known state with a state label, as
+// selected by the state format
+type stateProperty struct {
+	code   property
+	oneHot property
+	states []string
+}
+
+func (p stateProperty) describe(ch chan<- *prometheus.Desc) {
+	if stateFormat.numeric() {
+		ch <- p.code.desc
+	}
+	if stateFormat.oneHot() {
+		ch <- p.oneHot.desc
+	}
+}
+
+// send exports the state. Unknown states are only exported in the one-hot format, and reported as an error.
+func (p stateProperty) send(ch chan<- metric, state string, labelValues ...string) error {
+	if stateFormat.oneHot() {
+		labelValues := slices.Clip(labelValues)
+		known := false
+		for _, s := range p.states {
+			v := 0.0
+			if s == state {
+				v, known = 1, true
+			}
+			p.oneHot.send(ch, v, append(labelValues, s)...)
+		}
+		if !known && state != `` {
+			p.oneHot.send(ch, 1, append(labelValues, state)...)
+		}
+	}
+	if stateFormat.numeric() && state != `` {
+		code, err := transformHealthCode(state)
+		if err != nil {
+			return err
+		}
+		p.code.send(ch, code, labelValues...)
+	}
+
+	return nil
+}
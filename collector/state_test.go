@@ -0,0 +1,107 @@
+package collector
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jmcgover/zfs_exporter/v2/zfs"
+	"github.com/jmcgover/zfs_exporter/v2/zfs/mock_zfs"
+	"go.uber.org/mock/gomock"
+)
+
+func TestStateFormat(t *testing.T) {
+	defer func() { stateFormat = StateFormatBoth }()
+
+	testCases := []struct {
+		name          string
+		format        StateFormat
+		metricResults string
+	}{
+		{
+			name:   `one-hot`,
+			format: StateFormatOneHot,
+			metricResults: `# HELP zfs_pool_state Whether the pool is in the state given by the state label (1) or not (0).
+# TYPE zfs_pool_state gauge
+zfs_pool_state{pool="testpool",state="DEGRADED"} 1
+zfs_pool_state{pool="testpool",state="FAULTED"} 0
+zfs_pool_state{pool="testpool",state="OFFLINE"} 0
+zfs_pool_state{pool="testpool",state="ONLINE"} 0
+zfs_pool_state{pool="testpool",state="REMOVED"} 0
+zfs_pool_state{pool="testpool",state="SUSPENDED"} 0
+zfs_pool_state{pool="testpool",state="UNAVAIL"} 0
+# HELP zfs_vdev_state Whether the vdev is in the state given by the state label (1) or not (0), as reported by zpool status. The class label is the allocation class of the vdev, e.g. normal, log or special.
+# TYPE zfs_vdev_state gauge
+zfs_vdev_state{class="normal",pool="testpool",state="AVAIL",vdev="sda"} 0
+zfs_vdev_state{class="normal",pool="testpool",state="DEGRADED",vdev="sda"} 0
+zfs_vdev_state{class="normal",pool="testpool",state="FAULTED",vdev="sda"} 1
+zfs_vdev_state{class="normal",pool="testpool",state="INUSE",vdev="sda"} 0
+zfs_vdev_state{class="normal",pool="testpool",state="OFFLINE",vdev="sda"} 0
+zfs_vdev_state{class="normal",pool="testpool",state="ONLINE",vdev="sda"} 0
+zfs_vdev_state{class="normal",pool="testpool",state="REMOVED",vdev="sda"} 0
+zfs_vdev_state{class="normal",pool="testpool",state="UNAVAIL",vdev="sda"} 0
+`,
+		},
+		{
+			name:   `numeric`,
+			format: StateFormatNumeric,
+			metricResults: `# HELP zfs_pool_health Health status code for the pool [0: ONLINE, 1: DEGRADED, 2: FAULTED, 3: OFFLINE, 4: UNAVAIL, 5: REMOVED, 6: SUSPENDED].
+# TYPE zfs_pool_health gauge
+zfs_pool_health{pool="testpool"} 1
+# HELP zfs_vdev_health Health status code for the vdev [0: ONLINE, 1: DEGRADED, 2: FAULTED, 3: OFFLINE, 4: UNAVAIL, 5: REMOVED, 7: AVAIL, 8: INUSE], as reported by zpool status. The class label is the allocation class of the vdev, e.g. normal, log or special.
+# TYPE zfs_vdev_health gauge
+zfs_vdev_health{class="normal",pool="testpool",vdev="sda"} 2
+`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := SetStateFormat(tc.format); err != nil {
+				t.Fatal(err)
+			}
+			ctrl, ctx := gomock.WithContext(context.Background(), t)
+			zfsClient := mock_zfs.NewMockClient(ctrl)
+			zfsClient.EXPECT().PoolNames().Return([]string{`testpool`}, nil).Times(1)
+			zfsPoolProperties := mock_zfs.NewMockPoolProperties(ctrl)
+			zfsPoolProperties.EXPECT().Properties().Return(map[string]string{`health`: `DEGRADED`}).Times(1)
+			zfsPool := mock_zfs.NewMockPool(ctrl)
+			zfsPool.EXPECT().Properties([]string{`health`}).Return(zfsPoolProperties, nil).Times(1)
+			zfsClient.EXPECT().Pool(`testpool`).Return(zfsPool).Times(1)
+			zfsClient.EXPECT().PoolStatus(false).Return(map[string]zfs.PoolStatusT{
+				`testpool`: {Name: `testpool`, State: `DEGRADED`, Vdevs: map[string]zfs.VdevStatusT{
+					`testpool`: {Name: `testpool`, VdevType: `root`, State: `DEGRADED`, Vdevs: map[string]zfs.VdevStatusT{
+						`sda`: {Name: `sda`, VdevType: `disk`, Class: `normal`, State: `FAULTED`},
+					}},
+				}},
+			}, nil).Times(1)
+
+			collector, err := NewZFS(defaultConfig(zfsClient))
+			if err != nil {
+				t.Fatal(err)
+			}
+			collector.Collectors = map[string]State{
+				`pool`: {
+					Name:       "pool",
+					Enabled:    boolPointer(true),
+					Properties: stringPointer(`health`),
+					factory:    newPoolCollector,
+				},
+				`status`: {
+					Name:       "status",
+					Enabled:    boolPointer(true),
+					Properties: stringPointer(``),
+					factory:    newStatusCollector,
+				},
+			}
+
+			metricNames := []string{`zfs_pool_health`, `zfs_pool_state`, `zfs_vdev_health`, `zfs_vdev_state`}
+			if err = callCollector(ctx, collector, []byte(tc.metricResults), metricNames); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+
+	if err := SetStateFormat(`binary`); err == nil {
+		t.Error(`Expected error for an unknown state format`)
+	}
+}
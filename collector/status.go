@@ -0,0 +1,149 @@
+package collector
+
+import (
+	"log/slog"
+
+	"github.com/jmcgover/zfs_exporter/v2/zfs"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// vdevStates are the states exported for every vdev, so that each is present with a value of zero unless active.
+// AVAIL and INUSE are only reported for hot spares.
+var vdevStates = []string{
+	string(zfs.PoolOnline),
+	string(zfs.PoolDegraded),
+	string(zfs.PoolFaulted),
+	string(zfs.PoolOffline),
+	string(zfs.PoolUnavail),
+	string(zfs.PoolRemoved),
+	`AVAIL`,
+	`INUSE`,
+}
+
+func init() {
+	registerCollector(`status`, defaultDisabled, ``, newStatusCollector)
+}
+
+type statusCollector struct {
+	log        *slog.Logger
+	client     zfs.Client
+	info       property
+	vdevState  stateProperty
+	leafCounts property
+	upgrade    property
+}
+
+func (c *statusCollector) describe(ch chan<- *prometheus.Desc) {
+	ch <- c.info.desc
+	c.vdevState.describe(ch)
+	ch <- c.leafCounts.desc
+	ch <- c.upgrade.desc
+}
+
+func (c *statusCollector) update(ch chan<- metric, pools []string, excludes regexpCollection) error {
+	status, err := c.client.PoolStatus(false)
+	// Where only some pools could be queried, the others are reported before the error is returned
+	if err != nil && len(status) == 0 {
+		return err
+	}
+
+	for _, pool := range pools {
+		poolStatus, ok := status[pool]
+		if !ok {
+			if err == nil {
+				c.log.Warn("Pool missing from status output", "collector", "status", "pool", pool)
+			}
+			continue
+		}
+		c.info.send(ch, 1, pool, poolStatus.Status, poolStatus.Action)
+		upgrade := 0.0
+		if poolStatus.UpgradeAvailable() {
+			upgrade = 1
+		}
+		c.upgrade.send(ch, upgrade, pool)
+
+		// A spare that is in use appears both in the config tree and in the spares list, where its state is INUSE.
+		seen := make(map[string]bool)
+		// leaves counts the leaf vdevs of each allocation class by state
+		leaves := make(map[string]map[string]int)
+		for _, vdev := range poolStatus.AllVdevs() {
+			if seen[vdev.Name] {
+				continue
+			}
+			seen[vdev.Name] = true
+			if err := c.vdevState.send(ch, vdev.State, pool, vdev.Name, vdev.Class); err != nil {
+				c.log.Warn("Unknown vdev state", "collector", "status", "pool", pool, "vdev", vdev.Name, "err", err)
+			}
+			if len(vdev.Vdevs) == 0 {
+				if leaves[vdev.Class] == nil {
+					leaves[vdev.Class] = make(map[string]int)
+				}
+				leaves[vdev.Class][vdev.State]++
+			}
+		}
+
+		for class, states := range leaves {
+			for _, state := range vdevStates {
+				c.leafCounts.send(ch, float64(states[state]), pool, class, state)
+				delete(states, state)
+			}
+			for state, count := range states {
+				if state != `` {
+					c.leafCounts.send(ch, float64(count), pool, class, state)
+				}
+			}
+		}
+	}
+
+	return err
+}
+
+func newStatusCollector(l *slog.Logger, c zfs.Client, props []string) (Collector, error) {
+	return &statusCollector{
+		log:    l,
+		client: c,
+		info: newProperty(
+			subsystemPool,
+			`status_info`,
+			`Status and recommended action reported by zpool status for the pool, empty while the pool is healthy.`,
+			transformNumeric,
+			prometheus.GaugeValue,
+			`pool`, `status`, `action`,
+		),
+		vdevState: stateProperty{
+			code: newProperty(
+				subsystemVdev,
+				`health`,
+				`Health status code for the vdev `+stateCodesHelp(vdevStates)+`, as reported by zpool status. The class label is the allocation class of the vdev, e.g. normal, log or special.`,
+				transformHealthCode,
+				prometheus.GaugeValue,
+				`pool`, `vdev`, `class`,
+			),
+			oneHot: newProperty(
+				subsystemVdev,
+				`state`,
+				`Whether the vdev is in the state given by the state label (1) or not (0), as reported by zpool status. The class label is the allocation class of the vdev, e.g. normal, log or special.`,
+				transformNumeric,
+				prometheus.GaugeValue,
+				`pool`, `vdev`, `class`, `state`,
+			),
+			states: vdevStates,
+		},
+		leafCounts: newProperty(
+			subsystemPool,
+			`leaf_vdevs`,
+			`Number of leaf vdevs (devices) in the pool by allocation class and state.`,
+			transformNumeric,
+			prometheus.GaugeValue,
+			`pool`, `class`, `state`,
+		),
+		upgrade: newProperty(
+			subsystemPool,
+			`upgrade_available`,
+			`Whether zpool status reports that the on-disk format or features of the pool may be upgraded with zpool upgrade (1) or not (0).`,
+			transformNumeric,
+			prometheus.GaugeValue,
+			poolLabels...,
+		),
+	}, nil
+}
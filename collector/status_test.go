@@ -0,0 +1,219 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/jmcgover/zfs_exporter/v2/zfs"
+	"github.com/jmcgover/zfs_exporter/v2/zfs/mock_zfs"
+	"go.uber.org/mock/gomock"
+)
+
+func TestStatusMetrics(t *testing.T) {
+	const result = `# HELP zfs_pool_leaf_vdevs Number of leaf vdevs (devices) in the pool by allocation class and state.
+# TYPE zfs_pool_leaf_vdevs gauge
+zfs_pool_leaf_vdevs{class="log",pool="testpool",state="AVAIL"} 0
+zfs_pool_leaf_vdevs{class="log",pool="testpool",state="DEGRADED"} 0
+zfs_pool_leaf_vdevs{class="log",pool="testpool",state="FAULTED"} 1
+zfs_pool_leaf_vdevs{class="log",pool="testpool",state="INUSE"} 0
+zfs_pool_leaf_vdevs{class="log",pool="testpool",state="OFFLINE"} 0
+zfs_pool_leaf_vdevs{class="log",pool="testpool",state="ONLINE"} 0
+zfs_pool_leaf_vdevs{class="log",pool="testpool",state="REMOVED"} 0
+zfs_pool_leaf_vdevs{class="log",pool="testpool",state="UNAVAIL"} 0
+zfs_pool_leaf_vdevs{class="normal",pool="testpool",state="AVAIL"} 0
+zfs_pool_leaf_vdevs{class="normal",pool="testpool",state="DEGRADED"} 0
+zfs_pool_leaf_vdevs{class="normal",pool="testpool",state="FAULTED"} 1
+zfs_pool_leaf_vdevs{class="normal",pool="testpool",state="INUSE"} 0
+zfs_pool_leaf_vdevs{class="normal",pool="testpool",state="OFFLINE"} 0
+zfs_pool_leaf_vdevs{class="normal",pool="testpool",state="ONLINE"} 2
+zfs_pool_leaf_vdevs{class="normal",pool="testpool",state="REMOVED"} 0
+zfs_pool_leaf_vdevs{class="normal",pool="testpool",state="UNAVAIL"} 0
+# HELP zfs_pool_status_info Status and recommended action reported by zpool status for the pool, empty while the pool is healthy.
+# TYPE zfs_pool_status_info gauge
+zfs_pool_status_info{action="Replace the faulted device.",pool="testpool",status="One or more devices are faulted."} 1
+# HELP zfs_vdev_state Whether the vdev is in the state given by the state label (1) or not (0), as reported by zpool status. The class label is the allocation class of the vdev, e.g. normal, log or special.
+# TYPE zfs_vdev_state gauge
+zfs_vdev_state{class="log",pool="testpool",state="AVAIL",vdev="nvme0n1"} 0
+zfs_vdev_state{class="log",pool="testpool",state="DEGRADED",vdev="nvme0n1"} 0
+zfs_vdev_state{class="log",pool="testpool",state="FAULTED",vdev="nvme0n1"} 1
+zfs_vdev_state{class="log",pool="testpool",state="INUSE",vdev="nvme0n1"} 0
+zfs_vdev_state{class="log",pool="testpool",state="OFFLINE",vdev="nvme0n1"} 0
+zfs_vdev_state{class="log",pool="testpool",state="ONLINE",vdev="nvme0n1"} 0
+zfs_vdev_state{class="log",pool="testpool",state="REMOVED",vdev="nvme0n1"} 0
+zfs_vdev_state{class="log",pool="testpool",state="UNAVAIL",vdev="nvme0n1"} 0
+zfs_vdev_state{class="normal",pool="testpool",state="AVAIL",vdev="mirror-0"} 0
+zfs_vdev_state{class="normal",pool="testpool",state="AVAIL",vdev="sda"} 0
+zfs_vdev_state{class="normal",pool="testpool",state="AVAIL",vdev="sdb"} 0
+zfs_vdev_state{class="normal",pool="testpool",state="AVAIL",vdev="sdc"} 0
+zfs_vdev_state{class="normal",pool="testpool",state="AVAIL",vdev="spare-1"} 0
+zfs_vdev_state{class="normal",pool="testpool",state="DEGRADED",vdev="mirror-0"} 1
+zfs_vdev_state{class="normal",pool="testpool",state="DEGRADED",vdev="sda"} 0
+zfs_vdev_state{class="normal",pool="testpool",state="DEGRADED",vdev="sdb"} 0
+zfs_vdev_state{class="normal",pool="testpool",state="DEGRADED",vdev="sdc"} 0
+zfs_vdev_state{class="normal",pool="testpool",state="DEGRADED",vdev="spare-1"} 1
+zfs_vdev_state{class="normal",pool="testpool",state="FAULTED",vdev="mirror-0"} 0
+zfs_vdev_state{class="normal",pool="testpool",state="FAULTED",vdev="sda"} 0
+zfs_vdev_state{class="normal",pool="testpool",state="FAULTED",vdev="sdb"} 1
+zfs_vdev_state{class="normal",pool="testpool",state="FAULTED",vdev="sdc"} 0
+zfs_vdev_state{class="normal",pool="testpool",state="FAULTED",vdev="spare-1"} 0
+zfs_vdev_state{class="normal",pool="testpool",state="INUSE",vdev="mirror-0"} 0
+zfs_vdev_state{class="normal",pool="testpool",state="INUSE",vdev="sda"} 0
+zfs_vdev_state{class="normal",pool="testpool",state="INUSE",vdev="sdb"} 0
+zfs_vdev_state{class="normal",pool="testpool",state="INUSE",vdev="sdc"} 0
+zfs_vdev_state{class="normal",pool="testpool",state="INUSE",vdev="spare-1"} 0
+zfs_vdev_state{class="normal",pool="testpool",state="OFFLINE",vdev="mirror-0"} 0
+zfs_vdev_state{class="normal",pool="testpool",state="OFFLINE",vdev="sda"} 0
+zfs_vdev_state{class="normal",pool="testpool",state="OFFLINE",vdev="sdb"} 0
+zfs_vdev_state{class="normal",pool="testpool",state="OFFLINE",vdev="sdc"} 0
+zfs_vdev_state{class="normal",pool="testpool",state="OFFLINE",vdev="spare-1"} 0
+zfs_vdev_state{class="normal",pool="testpool",state="ONLINE",vdev="mirror-0"} 0
+zfs_vdev_state{class="normal",pool="testpool",state="ONLINE",vdev="sda"} 1
+zfs_vdev_state{class="normal",pool="testpool",state="ONLINE",vdev="sdb"} 0
+zfs_vdev_state{class="normal",pool="testpool",state="ONLINE",vdev="sdc"} 1
+zfs_vdev_state{class="normal",pool="testpool",state="ONLINE",vdev="spare-1"} 0
+zfs_vdev_state{class="normal",pool="testpool",state="REMOVED",vdev="mirror-0"} 0
+zfs_vdev_state{class="normal",pool="testpool",state="REMOVED",vdev="sda"} 0
+zfs_vdev_state{class="normal",pool="testpool",state="REMOVED",vdev="sdb"} 0
+zfs_vdev_state{class="normal",pool="testpool",state="REMOVED",vdev="sdc"} 0
+zfs_vdev_state{class="normal",pool="testpool",state="REMOVED",vdev="spare-1"} 0
+zfs_vdev_state{class="normal",pool="testpool",state="UNAVAIL",vdev="mirror-0"} 0
+zfs_vdev_state{class="normal",pool="testpool",state="UNAVAIL",vdev="sda"} 0
+zfs_vdev_state{class="normal",pool="testpool",state="UNAVAIL",vdev="sdb"} 0
+zfs_vdev_state{class="normal",pool="testpool",state="UNAVAIL",vdev="sdc"} 0
+zfs_vdev_state{class="normal",pool="testpool",state="UNAVAIL",vdev="spare-1"} 0
+`
+
+	ctrl, ctx := gomock.WithContext(context.Background(), t)
+	zfsClient := mock_zfs.NewMockClient(ctrl)
+	zfsClient.EXPECT().PoolNames().Return([]string{`testpool`}, nil).Times(1)
+	zfsClient.EXPECT().PoolStatus(false).Return(map[string]zfs.PoolStatusT{
+		`testpool`: {
+			Name:   `testpool`,
+			State:  `DEGRADED`,
+			Status: `One or more devices are faulted.`,
+			Action: `Replace the faulted device.`,
+			Vdevs: map[string]zfs.VdevStatusT{
+				`testpool`: {
+					Name:     `testpool`,
+					VdevType: `root`,
+					State:    `DEGRADED`,
+					Vdevs: map[string]zfs.VdevStatusT{
+						`mirror-0`: {
+							Name:     `mirror-0`,
+							VdevType: `mirror`,
+							Class:    `normal`,
+							State:    `DEGRADED`,
+							Vdevs: map[string]zfs.VdevStatusT{
+								`sda`: {Name: `sda`, VdevType: `disk`, Class: `normal`, State: `ONLINE`},
+								`spare-1`: {
+									Name:     `spare-1`,
+									VdevType: `spare`,
+									Class:    `normal`,
+									State:    `DEGRADED`,
+									Vdevs: map[string]zfs.VdevStatusT{
+										`sdb`: {Name: `sdb`, VdevType: `disk`, Class: `normal`, State: `FAULTED`},
+										`sdc`: {Name: `sdc`, VdevType: `disk`, Class: `normal`, State: `ONLINE`},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+			Logs: map[string]zfs.VdevStatusT{
+				`nvme0n1`: {Name: `nvme0n1`, VdevType: `disk`, Class: `log`, State: `FAULTED`},
+			},
+			Spares: map[string]zfs.VdevStatusT{
+				`sdc`: {Name: `sdc`, VdevType: `disk`, Class: `spare`, State: `INUSE`},
+			},
+		},
+	}, nil).Times(1)
+
+	collector, err := NewZFS(defaultConfig(zfsClient))
+	if err != nil {
+		t.Fatal(err)
+	}
+	collector.Collectors = map[string]State{
+		`status`: {
+			Name:       "status",
+			Enabled:    boolPointer(true),
+			Properties: stringPointer(``),
+			factory:    newStatusCollector,
+		},
+	}
+
+	if err = callCollector(ctx, collector, []byte(result), []string{`zfs_pool_leaf_vdevs`, `zfs_pool_status_info`, `zfs_vdev_state`}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestStatusUpgradeAvailableMetrics(t *testing.T) {
+	const result = `# HELP zfs_pool_upgrade_available Whether zpool status reports that the on-disk format or features of the pool may be upgraded with zpool upgrade (1) or not (0).
+# TYPE zfs_pool_upgrade_available gauge
+zfs_pool_upgrade_available{pool="current"} 0
+zfs_pool_upgrade_available{pool="outdated"} 1
+`
+
+	ctrl, ctx := gomock.WithContext(context.Background(), t)
+	zfsClient := mock_zfs.NewMockClient(ctrl)
+	zfsClient.EXPECT().PoolNames().Return([]string{`current`, `outdated`}, nil).Times(1)
+	zfsClient.EXPECT().PoolStatus(false).Return(map[string]zfs.PoolStatusT{
+		`current`: {Name: `current`, State: `ONLINE`},
+		`outdated`: {
+			Name:   `outdated`,
+			State:  `ONLINE`,
+			Status: `Some supported and requested features are not enabled on the pool. The pool can still be used, but some features are unavailable.`,
+			Action: `Enable all features using 'zpool upgrade'. Once this is done, the pool may no longer be accessible by software that does not support the features.`,
+		},
+	}, nil).Times(1)
+
+	collector, err := NewZFS(defaultConfig(zfsClient))
+	if err != nil {
+		t.Fatal(err)
+	}
+	collector.Collectors = map[string]State{
+		`status`: {
+			Name:       "status",
+			Enabled:    boolPointer(true),
+			Properties: stringPointer(``),
+			factory:    newStatusCollector,
+		},
+	}
+
+	if err = callCollector(ctx, collector, []byte(result), []string{`zfs_pool_upgrade_available`}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestStatusMetricsPartial(t *testing.T) {
+	const result = `# HELP zfs_pool_status_info Status and recommended action reported by zpool status for the pool, empty while the pool is healthy.
+# TYPE zfs_pool_status_info gauge
+zfs_pool_status_info{action="",pool="healthy",status=""} 1
+`
+
+	ctrl, ctx := gomock.WithContext(context.Background(), t)
+	zfsClient := mock_zfs.NewMockClient(ctrl)
+	zfsClient.EXPECT().PoolNames().Return([]string{`healthy`, `suspended`}, nil).Times(1)
+	// The status of the suspended pool could not be retrieved, while that of the other pool is still reported
+	zfsClient.EXPECT().PoolStatus(false).Return(map[string]zfs.PoolStatusT{
+		`healthy`: {Name: `healthy`, State: `ONLINE`},
+	}, errors.New(`pool suspended: signal: killed`)).Times(1)
+
+	collector, err := NewZFS(defaultConfig(zfsClient))
+	if err != nil {
+		t.Fatal(err)
+	}
+	collector.Collectors = map[string]State{
+		`status`: {
+			Name:       "status",
+			Enabled:    boolPointer(true),
+			Properties: stringPointer(``),
+			factory:    newStatusCollector,
+		},
+	}
+
+	if err = callCollector(ctx, collector, []byte(result), []string{`zfs_pool_status_info`}); err != nil {
+		t.Fatal(err)
+	}
+}
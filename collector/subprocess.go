@@ -0,0 +1,141 @@
+package collector
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/jmcgover/zfs_exporter/v2/zfs"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const procSelfFD = `/proc/self/fd`
+
+var (
+	subprocessStartedDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(exporterNamespace, `subprocess`, `started_total`),
+		`zfs_exporter: Number of zpool/zfs subprocesses started.`,
+		nil,
+		nil,
+	)
+	subprocessFailedDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(exporterNamespace, `subprocess`, `failed_total`),
+		`zfs_exporter: Number of zpool/zfs subprocesses that failed to start or exited unsuccessfully.`,
+		nil,
+		nil,
+	)
+	subprocessRunningDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(exporterNamespace, `subprocess`, `running`),
+		`zfs_exporter: Number of zpool/zfs subprocesses currently running.`,
+		nil,
+		nil,
+	)
+	subprocessCPUDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(exporterNamespace, `subprocess`, `cpu_seconds_total`),
+		`zfs_exporter: Total user and system CPU time consumed by exited zpool/zfs subprocesses.`,
+		nil,
+		nil,
+	)
+	subprocessMaxRSSDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(exporterNamespace, `subprocess`, `max_resident_memory_bytes`),
+		`zfs_exporter: Largest peak resident memory size of any exited zpool/zfs subprocess.`,
+		nil,
+		nil,
+	)
+	queueWaitDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(exporterNamespace, `command`, `queue_wait_seconds`),
+		`zfs_exporter: Time spent by requests waiting behind other executions of the same operation, such as a shared zpool status, rather than running their own commands.`,
+		[]string{`operation`},
+		nil,
+	)
+	commandDurationDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(exporterNamespace, `command`, `duration_seconds`),
+		`zfs_exporter: Time spent executing zpool/zfs commands, by command and subcommand.`,
+		[]string{`command`},
+		nil,
+	)
+	commandErrorsDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(exporterNamespace, `command`, `errors_total`),
+		`zfs_exporter: Number of zpool/zfs commands that failed or timed out, by command and subcommand.`,
+		[]string{`command`},
+		nil,
+	)
+	jsonParseErrorsDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(exporterNamespace, ``, `json_parse_errors_total`),
+		`zfs_exporter: Number of zpool/zfs command outputs that could not be parsed as JSON.`,
+		nil,
+		nil,
+	)
+	openPipesDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(exporterNamespace, ``, `open_pipe_fds`),
+		`zfs_exporter: Number of open pipe file descriptors held by the exporter.`,
+		nil,
+		nil,
+	)
+)
+
+// SubprocessCollector exports resource usage of the zpool/zfs subprocesses spawned by the exporter
+type SubprocessCollector struct {
+	stats      func() zfs.ExecStatsT
+	queueWaits func() map[string]zfs.QueueWaitT
+	commands   func() map[string]zfs.CommandStatsT
+	jsonErrors func() uint64
+	procFD     string
+}
+
+// Describe implements the prometheus.Collector interface.
+func (c *SubprocessCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- subprocessStartedDesc
+	ch <- subprocessFailedDesc
+	ch <- subprocessRunningDesc
+	ch <- subprocessCPUDesc
+	ch <- subprocessMaxRSSDesc
+	ch <- queueWaitDesc
+	ch <- commandDurationDesc
+	ch <- commandErrorsDesc
+	ch <- jsonParseErrorsDesc
+	ch <- openPipesDesc
+}
+
+// Collect implements the prometheus.Collector interface.
+func (c *SubprocessCollector) Collect(ch chan<- prometheus.Metric) {
+	stats := c.stats()
+	ch <- prometheus.MustNewConstMetric(subprocessStartedDesc, prometheus.CounterValue, float64(stats.Started))
+	ch <- prometheus.MustNewConstMetric(subprocessFailedDesc, prometheus.CounterValue, float64(stats.Failed))
+	ch <- prometheus.MustNewConstMetric(subprocessRunningDesc, prometheus.GaugeValue, float64(stats.Running))
+	ch <- prometheus.MustNewConstMetric(subprocessCPUDesc, prometheus.CounterValue, stats.CPUTime.Seconds())
+	ch <- prometheus.MustNewConstMetric(subprocessMaxRSSDesc, prometheus.GaugeValue, float64(stats.MaxRSS))
+	for operation, wait := range c.queueWaits() {
+		ch <- prometheus.MustNewConstSummary(queueWaitDesc, wait.Count, wait.Total.Seconds(), nil, operation)
+	}
+	for command, stats := range c.commands() {
+		ch <- prometheus.MustNewConstSummary(commandDurationDesc, stats.Count, stats.Duration.Seconds(), nil, command)
+		ch <- prometheus.MustNewConstMetric(commandErrorsDesc, prometheus.CounterValue, float64(stats.Errors), command)
+	}
+	ch <- prometheus.MustNewConstMetric(jsonParseErrorsDesc, prometheus.CounterValue, float64(c.jsonErrors()))
+	if pipes, ok := c.openPipes(); ok {
+		ch <- prometheus.MustNewConstMetric(openPipesDesc, prometheus.GaugeValue, float64(pipes))
+	}
+}
+
+// openPipes counts the pipe file descriptors held by the process, which is only supported where procfs is available
+func (c *SubprocessCollector) openPipes() (int, bool) {
+	entries, err := os.ReadDir(c.procFD)
+	if err != nil {
+		return 0, false
+	}
+	pipes := 0
+	for _, entry := range entries {
+		target, err := os.Readlink(filepath.Join(c.procFD, entry.Name()))
+		if err == nil && strings.HasPrefix(target, `pipe:`) {
+			pipes++
+		}
+	}
+	return pipes, true
+}
+
+// NewSubprocessCollector instantiates a collector for the resource usage of zpool/zfs subprocesses
+func NewSubprocessCollector() *SubprocessCollector {
+	return &SubprocessCollector{stats: zfs.ExecStats, queueWaits: zfs.QueueWaits,
+		commands: zfs.CommandStats, jsonErrors: zfs.JSONParseErrors, procFD: procSelfFD}
+}
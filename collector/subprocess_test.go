@@ -0,0 +1,72 @@
+package collector
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jmcgover/zfs_exporter/v2/zfs"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestSubprocessCollector(t *testing.T) {
+	const result = `# HELP zfs_exporter_command_duration_seconds zfs_exporter: Time spent executing zpool/zfs commands, by command and subcommand.
+# TYPE zfs_exporter_command_duration_seconds summary
+zfs_exporter_command_duration_seconds_sum{command="zpool status"} 3
+zfs_exporter_command_duration_seconds_count{command="zpool status"} 6
+# HELP zfs_exporter_command_errors_total zfs_exporter: Number of zpool/zfs commands that failed or timed out, by command and subcommand.
+# TYPE zfs_exporter_command_errors_total counter
+zfs_exporter_command_errors_total{command="zpool status"} 1
+# HELP zfs_exporter_command_queue_wait_seconds zfs_exporter: Time spent by requests waiting behind other executions of the same operation, such as a shared zpool status, rather than running their own commands.
+# TYPE zfs_exporter_command_queue_wait_seconds summary
+zfs_exporter_command_queue_wait_seconds_sum{operation="PoolStatus"} 2.5
+zfs_exporter_command_queue_wait_seconds_count{operation="PoolStatus"} 4
+# HELP zfs_exporter_json_parse_errors_total zfs_exporter: Number of zpool/zfs command outputs that could not be parsed as JSON.
+# TYPE zfs_exporter_json_parse_errors_total counter
+zfs_exporter_json_parse_errors_total 3
+# HELP zfs_exporter_open_pipe_fds zfs_exporter: Number of open pipe file descriptors held by the exporter.
+# TYPE zfs_exporter_open_pipe_fds gauge
+zfs_exporter_open_pipe_fds 2
+# HELP zfs_exporter_subprocess_cpu_seconds_total zfs_exporter: Total user and system CPU time consumed by exited zpool/zfs subprocesses.
+# TYPE zfs_exporter_subprocess_cpu_seconds_total counter
+zfs_exporter_subprocess_cpu_seconds_total 1.5
+# HELP zfs_exporter_subprocess_failed_total zfs_exporter: Number of zpool/zfs subprocesses that failed to start or exited unsuccessfully.
+# TYPE zfs_exporter_subprocess_failed_total counter
+zfs_exporter_subprocess_failed_total 2
+# HELP zfs_exporter_subprocess_max_resident_memory_bytes zfs_exporter: Largest peak resident memory size of any exited zpool/zfs subprocess.
+# TYPE zfs_exporter_subprocess_max_resident_memory_bytes gauge
+zfs_exporter_subprocess_max_resident_memory_bytes 8.388608e+06
+# HELP zfs_exporter_subprocess_running zfs_exporter: Number of zpool/zfs subprocesses currently running.
+# TYPE zfs_exporter_subprocess_running gauge
+zfs_exporter_subprocess_running 1
+# HELP zfs_exporter_subprocess_started_total zfs_exporter: Number of zpool/zfs subprocesses started.
+# TYPE zfs_exporter_subprocess_started_total counter
+zfs_exporter_subprocess_started_total 10
+`
+
+	procFD := t.TempDir()
+	for name, target := range map[string]string{`0`: `/dev/null`, `3`: `pipe:[1234]`, `4`: `pipe:[1235]`, `5`: `socket:[42]`} {
+		if err := os.Symlink(target, filepath.Join(procFD, name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	c := &SubprocessCollector{
+		stats: func() zfs.ExecStatsT {
+			return zfs.ExecStatsT{Started: 10, Failed: 2, Running: 1, CPUTime: 1500 * time.Millisecond, MaxRSS: 8 << 20}
+		},
+		queueWaits: func() map[string]zfs.QueueWaitT {
+			return map[string]zfs.QueueWaitT{`PoolStatus`: {Count: 4, Total: 2500 * time.Millisecond}}
+		},
+		commands: func() map[string]zfs.CommandStatsT {
+			return map[string]zfs.CommandStatsT{`zpool status`: {Count: 6, Errors: 1, Duration: 3 * time.Second}}
+		},
+		jsonErrors: func() uint64 { return 3 },
+		procFD:     procFD,
+	}
+	if err := testutil.CollectAndCompare(c, bytes.NewBufferString(result)); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -3,8 +3,6 @@ package collector
 import (
 	"fmt"
 	"strconv"
-
-	"github.com/jmcgover/zfs_exporter/v2/zfs"
 )
 
 type poolHealthCode int
@@ -17,6 +15,8 @@ const (
 	poolUnavail
 	poolRemoved
 	poolSuspended
+	spareAvail
+	spareInuse
 )
 
 func transformNumeric(value string) (float64, error) {
@@ -27,27 +27,12 @@ func transformNumeric(value string) (float64, error) {
 }
 
 func transformHealthCode(status string) (float64, error) {
-	var result poolHealthCode
-	switch zfs.PoolStatus(status) {
-	case zfs.PoolOnline:
-		result = poolOnline
-	case zfs.PoolDegraded:
-		result = poolDegraded
-	case zfs.PoolFaulted:
-		result = poolFaulted
-	case zfs.PoolOffline:
-		result = poolOffline
-	case zfs.PoolUnavail:
-		result = poolUnavail
-	case zfs.PoolRemoved:
-		result = poolRemoved
-	case zfs.PoolSuspended:
-		result = poolSuspended
-	default:
+	code, ok := stateCodes[status]
+	if !ok {
 		return -1, fmt.Errorf(`unknown pool heath status: %s`, status)
 	}
 
-	return float64(result), nil
+	return float64(code), nil
 }
 
 func transformBool(value string) (float64, error) {
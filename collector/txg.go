@@ -0,0 +1,85 @@
+package collector
+
+import (
+	"log/slog"
+	"sync"
+
+	"github.com/jmcgover/zfs_exporter/v2/zfs"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var txgSyncMetricName = prometheus.BuildFQName(namespace, subsystemPool, `txg_sync_duration_seconds`)
+
+// txgHistory accumulates transaction group timings across scrapes, as the kstat only retains recent history.
+var txgHistory = struct {
+	sync.Mutex
+	once     sync.Once
+	syncTime *prometheus.HistogramVec
+	lastTxg  map[string]uint64
+}{
+	lastTxg: make(map[string]uint64),
+}
+
+func init() {
+	registerCollector(`txg`, defaultDisabled, ``, newTxgCollector)
+}
+
+type txgCollector struct {
+	log      *slog.Logger
+	client   zfs.Client
+	syncTime *prometheus.HistogramVec
+}
+
+func (c *txgCollector) describe(ch chan<- *prometheus.Desc) {
+	c.syncTime.Describe(ch)
+}
+
+func (c *txgCollector) update(ch chan<- metric, pools []string, excludes regexpCollection) error {
+	txgHistory.Lock()
+	defer txgHistory.Unlock()
+
+	seen := make(map[string]struct{}, len(pools))
+	for _, pool := range pools {
+		seen[pool] = struct{}{}
+		txgs, err := c.client.Txgs(pool)
+		if err != nil {
+			return err
+		}
+
+		histogram := c.syncTime.WithLabelValues(pool)
+		last := txgHistory.lastTxg[pool]
+		for _, txg := range txgs {
+			if !txg.Committed() || txg.Txg <= last {
+				continue
+			}
+			histogram.Observe(txg.SyncTime.Seconds())
+			txgHistory.lastTxg[pool] = max(txgHistory.lastTxg[pool], txg.Txg)
+		}
+		ch <- metric{
+			name:       expandMetricName(txgSyncMetricName, pool),
+			prometheus: histogram.(prometheus.Metric),
+		}
+	}
+
+	// Forget pools that have been exported.
+	for pool := range txgHistory.lastTxg {
+		if _, ok := seen[pool]; !ok {
+			c.syncTime.DeleteLabelValues(pool)
+			delete(txgHistory.lastTxg, pool)
+		}
+	}
+
+	return nil
+}
+
+func newTxgCollector(l *slog.Logger, c zfs.Client, props []string) (Collector, error) {
+	txgHistory.once.Do(func() {
+		txgHistory.syncTime = newLatencyHistogramVec(
+			subsystemPool,
+			`txg_sync_duration_seconds`,
+			`Time taken to sync transaction groups to disk.`,
+			poolLabels...,
+		)
+	})
+	return &txgCollector{log: l, client: c, syncTime: txgHistory.syncTime}, nil
+}
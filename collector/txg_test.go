@@ -0,0 +1,64 @@
+package collector
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jmcgover/zfs_exporter/v2/zfs"
+	"github.com/jmcgover/zfs_exporter/v2/zfs/mock_zfs"
+	"go.uber.org/mock/gomock"
+)
+
+func TestTxgMetrics(t *testing.T) {
+	const firstResult = `# HELP zfs_pool_txg_sync_duration_seconds Time taken to sync transaction groups to disk.
+# TYPE zfs_pool_txg_sync_duration_seconds histogram
+zfs_pool_txg_sync_duration_seconds_bucket{pool="testpool",le="+Inf"} 2
+zfs_pool_txg_sync_duration_seconds_sum{pool="testpool"} 0.75
+zfs_pool_txg_sync_duration_seconds_count{pool="testpool"} 2
+`
+	// Only transaction groups committed since the previous scrape are observed.
+	const secondResult = `# HELP zfs_pool_txg_sync_duration_seconds Time taken to sync transaction groups to disk.
+# TYPE zfs_pool_txg_sync_duration_seconds histogram
+zfs_pool_txg_sync_duration_seconds_bucket{pool="testpool",le="+Inf"} 3
+zfs_pool_txg_sync_duration_seconds_sum{pool="testpool"} 1.75
+zfs_pool_txg_sync_duration_seconds_count{pool="testpool"} 3
+`
+
+	ctrl, ctx := gomock.WithContext(context.Background(), t)
+	zfsClient := mock_zfs.NewMockClient(ctrl)
+	zfsClient.EXPECT().PoolNames().Return([]string{`testpool`}, nil).Times(2)
+	gomock.InOrder(
+		zfsClient.EXPECT().Txgs(`testpool`).Return([]zfs.TxgT{
+			{Txg: 10, State: `C`, SyncTime: 250 * time.Millisecond},
+			{Txg: 11, State: `C`, SyncTime: 500 * time.Millisecond},
+			{Txg: 12, State: `S`},
+		}, nil),
+		zfsClient.EXPECT().Txgs(`testpool`).Return([]zfs.TxgT{
+			{Txg: 11, State: `C`, SyncTime: 500 * time.Millisecond},
+			{Txg: 12, State: `C`, SyncTime: time.Second},
+			{Txg: 13, State: `O`},
+		}, nil),
+	)
+
+	collector, err := NewZFS(defaultConfig(zfsClient))
+	if err != nil {
+		t.Fatal(err)
+	}
+	collector.Collectors = map[string]State{
+		`txg`: {
+			Name:       "txg",
+			Enabled:    boolPointer(true),
+			Properties: stringPointer(``),
+			factory:    newTxgCollector,
+		},
+	}
+
+	metricNames := []string{`zfs_pool_txg_sync_duration_seconds`}
+	if err = callCollector(ctx, collector, []byte(firstResult), metricNames); err != nil {
+		t.Fatal(err)
+	}
+	if err = callCollector(ctx, collector, []byte(secondResult), metricNames); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,206 @@
+package collector
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/jmcgover/zfs_exporter/v2/zfs"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+type vdevIOStat struct {
+	prop  property
+	value func(zfs.VdevIostatT) float64
+}
+
+// poolLatencyStat is a pool-wide latency, averaged across the leaf vdevs of the pool weighted by their operations
+type poolLatencyStat struct {
+	prop property
+	ops  func(zfs.VdevIostatT) zfs.Uint64
+	wait func(zfs.VdevIostatT) zfs.Uint64
+}
+
+var (
+	vdevLabels  = []string{`pool`, `vdev`}
+	vdevIOStats = []vdevIOStat{
+		newVdevIOStat(
+			`read_ops_per_second`,
+			`Average read operations per second for the vdev over --zfs.iostat-interval.`,
+			func(s zfs.VdevIostatT) float64 { return float64(s.ReadOps) },
+		),
+		newVdevIOStat(
+			`write_ops_per_second`,
+			`Average write operations per second for the vdev over --zfs.iostat-interval.`,
+			func(s zfs.VdevIostatT) float64 { return float64(s.WriteOps) },
+		),
+		newVdevIOStat(
+			`read_bytes_per_second`,
+			`Average bytes read per second for the vdev over --zfs.iostat-interval.`,
+			func(s zfs.VdevIostatT) float64 { return float64(s.ReadBytes) },
+		),
+		newVdevIOStat(
+			`write_bytes_per_second`,
+			`Average bytes written per second for the vdev over --zfs.iostat-interval.`,
+			func(s zfs.VdevIostatT) float64 { return float64(s.WriteBytes) },
+		),
+		newVdevIOStat(
+			`read_wait_seconds`,
+			`Average total wait time for read operations on the vdev over --zfs.iostat-interval.`,
+			func(s zfs.VdevIostatT) float64 { return time.Duration(s.TotalReadWait).Seconds() },
+		),
+		newVdevIOStat(
+			`write_wait_seconds`,
+			`Average total wait time for write operations on the vdev over --zfs.iostat-interval.`,
+			func(s zfs.VdevIostatT) float64 { return time.Duration(s.TotalWriteWait).Seconds() },
+		),
+		newVdevIOStat(
+			`disk_read_wait_seconds`,
+			`Average time read operations spent waiting on the disk on the vdev over --zfs.iostat-interval.`,
+			func(s zfs.VdevIostatT) float64 { return time.Duration(s.DiskReadWait).Seconds() },
+		),
+		newVdevIOStat(
+			`disk_write_wait_seconds`,
+			`Average time write operations spent waiting on the disk on the vdev over --zfs.iostat-interval.`,
+			func(s zfs.VdevIostatT) float64 { return time.Duration(s.DiskWriteWait).Seconds() },
+		),
+		newVdevIOStat(
+			`syncq_read_wait_seconds`,
+			`Average time synchronous read operations spent in the vdev queue on the vdev over --zfs.iostat-interval.`,
+			func(s zfs.VdevIostatT) float64 { return time.Duration(s.SyncqReadWait).Seconds() },
+		),
+		newVdevIOStat(
+			`syncq_write_wait_seconds`,
+			`Average time synchronous write operations spent in the vdev queue on the vdev over --zfs.iostat-interval.`,
+			func(s zfs.VdevIostatT) float64 { return time.Duration(s.SyncqWriteWait).Seconds() },
+		),
+		newVdevIOStat(
+			`asyncq_read_wait_seconds`,
+			`Average time asynchronous read operations spent in the vdev queue on the vdev over --zfs.iostat-interval.`,
+			func(s zfs.VdevIostatT) float64 { return time.Duration(s.AsyncqReadWait).Seconds() },
+		),
+		newVdevIOStat(
+			`asyncq_write_wait_seconds`,
+			`Average time asynchronous write operations spent in the vdev queue on the vdev over --zfs.iostat-interval.`,
+			func(s zfs.VdevIostatT) float64 { return time.Duration(s.AsyncqWriteWait).Seconds() },
+		),
+		newVdevIOStat(
+			`scrub_wait_seconds`,
+			`Average wait time for scrub operations on the vdev over --zfs.iostat-interval.`,
+			func(s zfs.VdevIostatT) float64 { return time.Duration(s.ScrubWait).Seconds() },
+		),
+		newVdevIOStat(
+			`trim_wait_seconds`,
+			`Average wait time for trim operations on the vdev over --zfs.iostat-interval.`,
+			func(s zfs.VdevIostatT) float64 { return time.Duration(s.TrimWait).Seconds() },
+		),
+		newVdevIOStat(
+			`rebuild_wait_seconds`,
+			`Average wait time for rebuild operations on the vdev over --zfs.iostat-interval.`,
+			func(s zfs.VdevIostatT) float64 { return time.Duration(s.RebuildWait).Seconds() },
+		),
+	}
+)
+
+var poolLatencyStats = []poolLatencyStat{
+	{
+		prop: newProperty(
+			subsystemPool,
+			`weighted_read_wait_seconds`,
+			`Average total wait time for read operations on the pool over --zfs.iostat-interval, weighted by the read operations of each leaf vdev.`,
+			transformNumeric,
+			prometheus.GaugeValue,
+			poolLabels...,
+		),
+		ops:  func(s zfs.VdevIostatT) zfs.Uint64 { return s.ReadOps },
+		wait: func(s zfs.VdevIostatT) zfs.Uint64 { return s.TotalReadWait },
+	},
+	{
+		prop: newProperty(
+			subsystemPool,
+			`weighted_write_wait_seconds`,
+			`Average total wait time for write operations on the pool over --zfs.iostat-interval, weighted by the write operations of each leaf vdev.`,
+			transformNumeric,
+			prometheus.GaugeValue,
+			poolLabels...,
+		),
+		ops:  func(s zfs.VdevIostatT) zfs.Uint64 { return s.WriteOps },
+		wait: func(s zfs.VdevIostatT) zfs.Uint64 { return s.TotalWriteWait },
+	},
+}
+
+func init() {
+	registerCollector(`vdev-io`, defaultDisabled, ``, newVdevIOCollector)
+}
+
+type vdevIOCollector struct {
+	log    *slog.Logger
+	client zfs.Client
+}
+
+func (c *vdevIOCollector) describe(ch chan<- *prometheus.Desc) {
+	for _, stat := range vdevIOStats {
+		ch <- stat.prop.desc
+	}
+	for _, stat := range poolLatencyStats {
+		ch <- stat.prop.desc
+	}
+}
+
+func (c *vdevIOCollector) update(ch chan<- metric, pools []string, excludes regexpCollection) error {
+	stats, err := c.client.PoolIostats(true)
+	if err != nil {
+		return err
+	}
+
+	for _, pool := range pools {
+		poolStats, ok := stats[pool]
+		if !ok {
+			c.log.Warn("Pool missing from iostat output", "collector", "vdev-io", "pool", pool)
+			continue
+		}
+		for _, vdev := range poolStats.AllVdevs() {
+			for _, stat := range vdevIOStats {
+				stat.prop.send(ch, stat.value(vdev), pool, vdev.Name)
+			}
+		}
+		leaves := poolStats.LeafVdevs()
+		for _, stat := range poolLatencyStats {
+			stat.prop.send(ch, stat.value(leaves), pool)
+		}
+	}
+
+	return nil
+}
+
+// value returns the average wait of the vdevs weighted by their operations, or zero when there have been none
+func (s poolLatencyStat) value(vdevs []zfs.VdevIostatT) float64 {
+	var ops, weighted float64
+	for _, vdev := range vdevs {
+		o := float64(s.ops(vdev))
+		ops += o
+		weighted += o * time.Duration(s.wait(vdev)).Seconds()
+	}
+	if ops == 0 {
+		return 0
+	}
+
+	return weighted / ops
+}
+
+func newVdevIOStat(metricName, helpText string, value func(zfs.VdevIostatT) float64) vdevIOStat {
+	return vdevIOStat{
+		prop: newProperty(
+			subsystemVdev,
+			metricName,
+			helpText,
+			transformNumeric,
+			prometheus.GaugeValue,
+			vdevLabels...,
+		),
+		value: value,
+	}
+}
+
+func newVdevIOCollector(l *slog.Logger, c zfs.Client, props []string) (Collector, error) {
+	return &vdevIOCollector{log: l, client: c}, nil
+}
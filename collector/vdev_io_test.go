@@ -0,0 +1,88 @@
+package collector
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jmcgover/zfs_exporter/v2/zfs"
+	"github.com/jmcgover/zfs_exporter/v2/zfs/mock_zfs"
+	"go.uber.org/mock/gomock"
+)
+
+func TestVdevIOMetrics(t *testing.T) {
+	const result = `# HELP zfs_pool_weighted_read_wait_seconds Average total wait time for read operations on the pool over --zfs.iostat-interval, weighted by the read operations of each leaf vdev.
+# TYPE zfs_pool_weighted_read_wait_seconds gauge
+zfs_pool_weighted_read_wait_seconds{pool="testpool"} 0.0025
+# HELP zfs_pool_weighted_write_wait_seconds Average total wait time for write operations on the pool over --zfs.iostat-interval, weighted by the write operations of each leaf vdev.
+# TYPE zfs_pool_weighted_write_wait_seconds gauge
+zfs_pool_weighted_write_wait_seconds{pool="testpool"} 0
+# HELP zfs_vdev_disk_write_wait_seconds Average time write operations spent waiting on the disk on the vdev over --zfs.iostat-interval.
+# TYPE zfs_vdev_disk_write_wait_seconds gauge
+zfs_vdev_disk_write_wait_seconds{pool="testpool",vdev="mirror-0"} 0.0015
+zfs_vdev_disk_write_wait_seconds{pool="testpool",vdev="nvme0n1"} 2e-05
+zfs_vdev_disk_write_wait_seconds{pool="testpool",vdev="sda"} 0.0015
+zfs_vdev_disk_write_wait_seconds{pool="testpool",vdev="sdb"} 0.0015
+# HELP zfs_vdev_read_ops_per_second Average read operations per second for the vdev over --zfs.iostat-interval.
+# TYPE zfs_vdev_read_ops_per_second gauge
+zfs_vdev_read_ops_per_second{pool="testpool",vdev="mirror-0"} 10
+zfs_vdev_read_ops_per_second{pool="testpool",vdev="nvme0n1"} 2
+zfs_vdev_read_ops_per_second{pool="testpool",vdev="sda"} 6
+zfs_vdev_read_ops_per_second{pool="testpool",vdev="sdb"} 4
+# HELP zfs_vdev_write_wait_seconds Average total wait time for write operations on the vdev over --zfs.iostat-interval.
+# TYPE zfs_vdev_write_wait_seconds gauge
+zfs_vdev_write_wait_seconds{pool="testpool",vdev="mirror-0"} 0.002
+zfs_vdev_write_wait_seconds{pool="testpool",vdev="nvme0n1"} 5e-05
+zfs_vdev_write_wait_seconds{pool="testpool",vdev="sda"} 0.002
+zfs_vdev_write_wait_seconds{pool="testpool",vdev="sdb"} 0.002
+`
+
+	ctrl, ctx := gomock.WithContext(context.Background(), t)
+	zfsClient := mock_zfs.NewMockClient(ctrl)
+	zfsClient.EXPECT().PoolNames().Return([]string{`testpool`}, nil).Times(1)
+	zfsClient.EXPECT().PoolIostats(true).Return(map[string]zfs.PoolIostatT{
+		`testpool`: {
+			Name: `testpool`,
+			Vdevs: map[string]zfs.VdevIostatT{
+				`testpool`: {
+					Name:     `testpool`,
+					VdevType: `root`,
+					ReadOps:  10,
+					Vdevs: map[string]zfs.VdevIostatT{
+						`mirror-0`: {
+							Name:           `mirror-0`,
+							VdevType:       `mirror`,
+							ReadOps:        10,
+							TotalWriteWait: 2000000,
+							DiskWriteWait:  1500000,
+							Vdevs: map[string]zfs.VdevIostatT{
+								`sda`: {Name: `sda`, VdevType: `disk`, ReadOps: 6, TotalReadWait: 3000000, TotalWriteWait: 2000000, DiskWriteWait: 1500000},
+								`sdb`: {Name: `sdb`, VdevType: `disk`, ReadOps: 4, TotalReadWait: 1000000, TotalWriteWait: 2000000, DiskWriteWait: 1500000},
+							},
+						},
+					},
+				},
+			},
+			Logs: map[string]zfs.VdevIostatT{
+				`nvme0n1`: {Name: `nvme0n1`, VdevType: `disk`, ReadOps: 2, TotalReadWait: 4000000, TotalWriteWait: 50000, DiskWriteWait: 20000},
+			},
+		},
+	}, nil).Times(1)
+
+	collector, err := NewZFS(defaultConfig(zfsClient))
+	if err != nil {
+		t.Fatal(err)
+	}
+	collector.Collectors = map[string]State{
+		`vdev-io`: {
+			Name:       "vdev-io",
+			Enabled:    boolPointer(true),
+			Properties: stringPointer(``),
+			factory:    newVdevIOCollector,
+		},
+	}
+
+	metricNames := []string{`zfs_pool_weighted_read_wait_seconds`, `zfs_pool_weighted_write_wait_seconds`, `zfs_vdev_disk_write_wait_seconds`, `zfs_vdev_read_ops_per_second`, `zfs_vdev_write_wait_seconds`}
+	if err = callCollector(ctx, collector, []byte(result), metricNames); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,66 @@
+package collector
+
+import (
+	"log/slog"
+
+	"github.com/jmcgover/zfs_exporter/v2/zfs"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func init() {
+	registerCollector(`vdev-power`, defaultDisabled, ``, newVdevPowerCollector)
+}
+
+type vdevPowerCollector struct {
+	log     *slog.Logger
+	client  zfs.Client
+	powered property
+}
+
+func (c *vdevPowerCollector) describe(ch chan<- *prometheus.Desc) {
+	ch <- c.powered.desc
+}
+
+func (c *vdevPowerCollector) update(ch chan<- metric, pools []string, excludes regexpCollection) error {
+	status, err := c.client.PoolStatus(true)
+	// Where only some pools could be queried, the others are reported before the error is returned
+	if err != nil && len(status) == 0 {
+		return err
+	}
+
+	for _, pool := range pools {
+		poolStatus, ok := status[pool]
+		if !ok {
+			if err == nil {
+				c.log.Warn("Pool missing from status output", "collector", "vdev-power", "pool", pool)
+			}
+			continue
+		}
+		for _, vdev := range poolStatus.AllVdevs() {
+			// Interior vdevs, and devices whose enclosure slot power cannot be determined, do not report a state.
+			switch vdev.PowerState {
+			case `on`:
+				c.powered.send(ch, 1, pool, vdev.Name)
+			case `off`:
+				c.powered.send(ch, 0, pool, vdev.Name)
+			}
+		}
+	}
+
+	return err
+}
+
+func newVdevPowerCollector(l *slog.Logger, c zfs.Client, props []string) (Collector, error) {
+	return &vdevPowerCollector{
+		log:    l,
+		client: c,
+		powered: newProperty(
+			subsystemVdev,
+			`powered_on`,
+			`Whether the enclosure slot of the vdev is powered on (1) or off (0), as reported by zpool status --power.`,
+			transformNumeric,
+			prometheus.GaugeValue,
+			vdevLabels...,
+		),
+	}, nil
+}
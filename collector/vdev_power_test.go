@@ -0,0 +1,63 @@
+package collector
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jmcgover/zfs_exporter/v2/zfs"
+	"github.com/jmcgover/zfs_exporter/v2/zfs/mock_zfs"
+	"go.uber.org/mock/gomock"
+)
+
+func TestVdevPowerMetrics(t *testing.T) {
+	const result = `# HELP zfs_vdev_powered_on Whether the enclosure slot of the vdev is powered on (1) or off (0), as reported by zpool status --power.
+# TYPE zfs_vdev_powered_on gauge
+zfs_vdev_powered_on{pool="testpool",vdev="sda"} 1
+zfs_vdev_powered_on{pool="testpool",vdev="sdb"} 0
+`
+
+	ctrl, ctx := gomock.WithContext(context.Background(), t)
+	zfsClient := mock_zfs.NewMockClient(ctrl)
+	zfsClient.EXPECT().PoolNames().Return([]string{`testpool`}, nil).Times(1)
+	zfsClient.EXPECT().PoolStatus(true).Return(map[string]zfs.PoolStatusT{
+		`testpool`: {
+			Name: `testpool`,
+			Vdevs: map[string]zfs.VdevStatusT{
+				`testpool`: {
+					Name:     `testpool`,
+					VdevType: `root`,
+					Vdevs: map[string]zfs.VdevStatusT{
+						`mirror-0`: {
+							Name:     `mirror-0`,
+							VdevType: `mirror`,
+							Vdevs: map[string]zfs.VdevStatusT{
+								`sda`: {Name: `sda`, VdevType: `disk`, State: `ONLINE`, PowerState: `on`},
+								`sdb`: {Name: `sdb`, VdevType: `disk`, State: `UNAVAIL`, PowerState: `off`},
+							},
+						},
+					},
+				},
+			},
+			Spares: map[string]zfs.VdevStatusT{
+				`sdc`: {Name: `sdc`, VdevType: `disk`, State: `AVAIL`, PowerState: `-`},
+			},
+		},
+	}, nil).Times(1)
+
+	collector, err := NewZFS(defaultConfig(zfsClient))
+	if err != nil {
+		t.Fatal(err)
+	}
+	collector.Collectors = map[string]State{
+		`vdev-power`: {
+			Name:       "vdev-power",
+			Enabled:    boolPointer(true),
+			Properties: stringPointer(``),
+			factory:    newVdevPowerCollector,
+		},
+	}
+
+	if err = callCollector(ctx, collector, []byte(result), []string{`zfs_vdev_powered_on`}); err != nil {
+		t.Fatal(err)
+	}
+}
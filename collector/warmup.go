@@ -0,0 +1,85 @@
+package collector
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// FirstScrape selects how scrapes are served while the warm-up collection started by Warmup is running: by
+// collecting synchronously as usual, which runs no warm-up, by blocking until the warm-up completes, or by serving
+// whatever has been collected so far, which is nothing until the deadline of the warm-up passes
+type FirstScrape string
+
+const (
+	FirstScrapeCollect FirstScrape = `collect`
+	FirstScrapeBlock   FirstScrape = `block`
+	FirstScrapeServe   FirstScrape = `serve`
+)
+
+var (
+	// FirstScrapeModes are the supported first scrape modes
+	FirstScrapeModes = []string{string(FirstScrapeCollect), string(FirstScrapeBlock), string(FirstScrapeServe)}
+
+	warmingUpDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(exporterNamespace, ``, `warming_up`),
+		`zfs_exporter: Whether the initial collection following startup is still running (1) or not (0).`,
+		nil,
+		nil,
+	)
+)
+
+// Warmup runs a full collection, filling the cache served to scrapes received in the meantime, and returns once the
+// collection has completed. It must be called once, and only when a first scrape mode other than collect is
+// configured.
+func (c *ZFS) Warmup() {
+	begin := time.Now()
+	discard := make(chan prometheus.Metric)
+	go func() {
+		for range discard {
+		}
+	}()
+	c.collect(discard)
+	close(discard)
+	// Collection continues in the background beyond the deadline, and signals readiness once the cache is complete
+	<-c.ready
+	c.ready <- struct{}{}
+	close(c.warmed)
+	c.logger.Info("Initial collection complete", "durationSeconds", time.Since(begin).Seconds())
+}
+
+// serveWarming serves the cache to scrapes received before the warm-up completes, waiting for it up to the warm-up
+// timeout in block mode, and reports whether it did. Scrapes received after the warm-up collect as usual.
+func (c *ZFS) serveWarming(ch chan<- prometheus.Metric) bool {
+	select {
+	case <-c.warmed:
+		ch <- prometheus.MustNewConstMetric(warmingUpDesc, prometheus.GaugeValue, 0)
+		return false
+	default:
+	}
+
+	if c.firstScrape == FirstScrapeBlock {
+		var timeout <-chan time.Time
+		if c.warmupTimeout > 0 {
+			timer := time.NewTimer(c.warmupTimeout)
+			defer timer.Stop()
+			timeout = timer.C
+		}
+		select {
+		case <-c.warmed:
+		case <-timeout:
+			c.logger.Warn("Initial collection still running, serving cached metrics", "timeout", c.warmupTimeout)
+		}
+	}
+
+	var warming float64
+	select {
+	case <-c.warmed:
+	default:
+		warming = 1
+	}
+	ch <- prometheus.MustNewConstMetric(warmingUpDesc, prometheus.GaugeValue, warming)
+	c.sendCached(ch, make(map[string]struct{}))
+	c.sendDataAge(ch)
+	return true
+}
@@ -0,0 +1,126 @@
+package collector
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/jmcgover/zfs_exporter/v2/zfs"
+	"github.com/jmcgover/zfs_exporter/v2/zfs/mock_zfs"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/mock/gomock"
+)
+
+var gatedDesc = prometheus.NewDesc(`zfs_test_gated`, `Test metric.`, nil, nil)
+
+// gatedCollector exports a single metric once released
+type gatedCollector struct {
+	release chan struct{}
+}
+
+func (c gatedCollector) describe(ch chan<- *prometheus.Desc) {
+	ch <- gatedDesc
+}
+
+func (c gatedCollector) update(ch chan<- metric, pools []string, excludes regexpCollection) error {
+	<-c.release
+	ch <- metric{name: `zfs_test_gated`, prometheus: prometheus.MustNewConstMetric(gatedDesc, prometheus.GaugeValue, 1)}
+	return nil
+}
+
+func newWarmupCollector(t *testing.T, mode FirstScrape, timeout time.Duration) (*ZFS, chan struct{}, context.Context) {
+	ctrl, ctx := gomock.WithContext(context.Background(), t)
+	zfsClient := mock_zfs.NewMockClient(ctrl)
+	zfsClient.EXPECT().PoolNames().Return([]string{`testpool`}, nil).AnyTimes()
+
+	config := defaultConfig(zfsClient)
+	config.FirstScrape = mode
+	config.WarmupTimeout = timeout
+	collector, err := NewZFS(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	release := make(chan struct{})
+	collector.Collectors = map[string]State{
+		`gated`: {
+			Name:       `gated`,
+			Enabled:    boolPointer(true),
+			Properties: stringPointer(``),
+			factory: func(l *slog.Logger, c zfs.Client, properties []string) (Collector, error) {
+				return gatedCollector{release: release}, nil
+			},
+		},
+	}
+	return collector, release, ctx
+}
+
+func TestZFSWarmupServe(t *testing.T) {
+	const (
+		warming = `# HELP zfs_exporter_warming_up zfs_exporter: Whether the initial collection following startup is still running (1) or not (0).
+# TYPE zfs_exporter_warming_up gauge
+zfs_exporter_warming_up 1
+`
+		warmed = `# HELP zfs_exporter_warming_up zfs_exporter: Whether the initial collection following startup is still running (1) or not (0).
+# TYPE zfs_exporter_warming_up gauge
+zfs_exporter_warming_up 0
+# HELP zfs_test_gated Test metric.
+# TYPE zfs_test_gated gauge
+zfs_test_gated 1
+`
+	)
+
+	collector, release, ctx := newWarmupCollector(t, FirstScrapeServe, 0)
+	go collector.Warmup()
+
+	if err := callCollector(ctx, collector, []byte(warming), []string{`zfs_exporter_warming_up`, `zfs_test_gated`}); err != nil {
+		t.Fatal(err)
+	}
+	close(release)
+	<-collector.warmed
+	if err := callCollector(ctx, collector, []byte(warmed), []string{`zfs_exporter_warming_up`, `zfs_test_gated`}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestZFSWarmupBlock(t *testing.T) {
+	const result = `# HELP zfs_exporter_warming_up zfs_exporter: Whether the initial collection following startup is still running (1) or not (0).
+# TYPE zfs_exporter_warming_up gauge
+zfs_exporter_warming_up 0
+# HELP zfs_test_gated Test metric.
+# TYPE zfs_test_gated gauge
+zfs_test_gated 1
+`
+
+	collector, release, ctx := newWarmupCollector(t, FirstScrapeBlock, 0)
+	go collector.Warmup()
+	time.AfterFunc(10*time.Millisecond, func() { close(release) })
+
+	if err := callCollector(ctx, collector, []byte(result), []string{`zfs_exporter_warming_up`, `zfs_test_gated`}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestZFSWarmupBlockTimeout(t *testing.T) {
+	const result = `# HELP zfs_exporter_warming_up zfs_exporter: Whether the initial collection following startup is still running (1) or not (0).
+# TYPE zfs_exporter_warming_up gauge
+zfs_exporter_warming_up 1
+`
+
+	collector, release, ctx := newWarmupCollector(t, FirstScrapeBlock, 10*time.Millisecond)
+	t.Cleanup(func() { close(release) })
+	go collector.Warmup()
+
+	if err := callCollector(ctx, collector, []byte(result), []string{`zfs_exporter_warming_up`, `zfs_test_gated`}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNewZFSInvalidFirstScrape(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	config := defaultConfig(mock_zfs.NewMockClient(ctrl))
+	config.FirstScrape = `eventually`
+	if _, err := NewZFS(config); err == nil {
+		t.Fatal("expected error for unknown first scrape mode")
+	}
+}
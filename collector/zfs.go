@@ -2,6 +2,8 @@ package collector
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log/slog"
 	"regexp"
 	"sort"
@@ -25,6 +27,19 @@ func (c regexpCollection) MatchString(input string) bool {
 	return false
 }
 
+func compileRegexps(patterns []string) (regexpCollection, error) {
+	result := make(regexpCollection, len(patterns))
+	for i, pattern := range patterns {
+		r, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regex '%s': %w", pattern, err)
+		}
+		result[i] = r
+	}
+
+	return result, nil
+}
+
 // ZFSConfig configures a ZFS collector
 type ZFSConfig struct {
 	DisableMetrics bool
@@ -33,6 +48,26 @@ type ZFSConfig struct {
 	Excludes       []string
 	Logger         *slog.Logger
 	ZFSClient      zfs.Client
+	// PoolIncludes restricts collection to pools matching any of the regexes, if set
+	PoolIncludes []string
+	// PoolExcludes skips collection of pools matching any of the regexes
+	PoolExcludes []string
+	// CircuitThreshold is the number of consecutive failures after which a collector is skipped, zero disables
+	CircuitThreshold int
+	// CircuitCooldown is the duration for which a failing collector is skipped
+	CircuitCooldown time.Duration
+	// Capabilities of the installed ZFS userland, collectors requiring unsupported capabilities are disabled. Nil
+	// assumes all capabilities are supported.
+	Capabilities zfs.Capabilities
+	// Schedules restrict collectors to daily time windows, as COLLECTOR=HH:MM-HH:MM in local time, outside of which the
+	// metrics of their last run are served
+	Schedules []string
+	// Remote disables collectors that read local kstats, for clients that run commands on another host
+	Remote bool
+	// FirstScrape selects how scrapes received before Warmup completes are served, collect by default
+	FirstScrape FirstScrape
+	// WarmupTimeout limits how long a scrape waits for Warmup to complete in block mode, zero waits indefinitely
+	WarmupTimeout time.Duration
 }
 
 // ZFS collector
@@ -46,6 +81,21 @@ type ZFS struct {
 	ready          chan struct{}
 	logger         *slog.Logger
 	excludes       regexpCollection
+	poolIncludes   regexpCollection
+	poolExcludes   regexpCollection
+
+	circuitThreshold int
+	circuitCooldown  time.Duration
+	breakers         map[string]*circuitBreaker
+	breakersMu       sync.Mutex
+
+	scheduler *scheduler
+	origins   *metricOrigins
+
+	firstScrape   FirstScrape
+	warmupTimeout time.Duration
+	// warmed is closed once Warmup completes, and is nil in collect mode
+	warmed chan struct{}
 }
 
 // Describe implements the prometheus.Collector interface.
@@ -53,6 +103,13 @@ func (c *ZFS) Describe(ch chan<- *prometheus.Desc) {
 	if !c.disableMetrics {
 		ch <- scrapeDurationDesc
 		ch <- scrapeSuccessDesc
+		ch <- collectorDurationDesc
+		ch <- collectorSuccessDesc
+		ch <- circuitOpenDesc
+	}
+	ch <- dataAgeDesc
+	if c.warmed != nil {
+		ch <- warmingUpDesc
 	}
 
 	for _, state := range c.Collectors {
@@ -60,7 +117,7 @@ func (c *ZFS) Describe(ch chan<- *prometheus.Desc) {
 			continue
 		}
 
-		collector, err := state.factory(c.logger, c.client, strings.Split(*state.Properties, `,`))
+		collector, err := state.factory(c.logger, c.client, state.properties())
 		if err != nil {
 			continue
 		}
@@ -70,6 +127,14 @@ func (c *ZFS) Describe(ch chan<- *prometheus.Desc) {
 
 // Collect implements the prometheus.Collector interface.
 func (c *ZFS) Collect(ch chan<- prometheus.Metric) {
+	if c.warmed != nil && c.serveWarming(ch) {
+		return
+	}
+	c.collect(ch)
+}
+
+func (c *ZFS) collect(ch chan<- prometheus.Metric) {
+	defer c.sendDataAge(ch)
 	select {
 	case <-c.ready:
 	default:
@@ -133,14 +198,35 @@ func (c *ZFS) Collect(ch chan<- prometheus.Metric) {
 			continue
 		}
 
-		collector, err := state.factory(c.logger, c.client, strings.Split(*state.Properties, `,`))
+		if !c.scheduler.due(name) {
+			c.logger.Debug("Executing collector", "status", "skipped", "collector", name, "reason", "outside schedule")
+			go func(name string) {
+				c.scheduler.replay(name, proxy)
+				wg.Done()
+			}(name)
+			continue
+		}
+
+		breaker := c.breaker(name)
+		if !breaker.allow() {
+			c.logger.Debug("Executing collector", "status", "skipped", "collector", name, "reason", "circuit open")
+			c.publishCircuitMetric(name, true, proxy)
+			wg.Done()
+			continue
+		}
+
+		collector, err := state.factory(c.logger, c.client, state.properties())
 		if err != nil {
 			c.logger.Error("Error instantiating collector", "collector", name, "err", err)
 			wg.Done()
 			continue
 		}
 		go func(name string, collector Collector) {
-			c.execute(ctx, name, collector, proxy, pools)
+			if c.scheduler.scheduled(name) {
+				c.executeScheduled(ctx, name, collector, breaker, proxy, pools)
+			} else {
+				c.execute(ctx, name, collector, breaker, proxy, pools)
+			}
 			wg.Done()
 		}(name, collector)
 	}
@@ -161,6 +247,30 @@ func (c *ZFS) Collect(ch chan<- prometheus.Metric) {
 	<-finalized
 }
 
+// MetricOrigins returns the name of the collector from which each metric was most recently collected, keyed by metric
+// name. Metrics of the exporter itself, such as zfs_scrape_collector_success, are not included.
+func (c *ZFS) MetricOrigins() map[string]string {
+	return c.origins.snapshot()
+}
+
+// Updated returns the completion time of the most recent full collection, or the zero time if none has completed.
+func (c *ZFS) Updated() time.Time {
+	return c.cache.lastUpdated()
+}
+
+func (c *ZFS) sendDataAge(ch chan<- prometheus.Metric) {
+	var age float64
+	if updated := c.Updated(); !updated.IsZero() {
+		age = time.Since(updated).Seconds()
+	}
+	ch <- prometheus.MustNewConstMetric(dataAgeDesc, prometheus.GaugeValue, age)
+}
+
+// Generation returns a counter that changes whenever the collected data changes, suitable for use as an entity tag.
+func (c *ZFS) Generation() uint64 {
+	return c.cache.gen()
+}
+
 // sendCached values that do not appear in the current cacheIndex.
 func (c *ZFS) sendCached(ch chan<- prometheus.Metric, cacheIndex map[string]struct{}) {
 	c.cache.RLock()
@@ -180,7 +290,7 @@ func (c *ZFS) getPools(pools []string) ([]string, error) {
 	}
 	// Return all pools if not explicitly configured.
 	if len(pools) == 0 {
-		return poolNames, nil
+		return c.filterPools(poolNames), nil
 	}
 
 	// Configured pools may not exist, so append available pools as they're found, rather than allocating up front.
@@ -199,15 +309,105 @@ func (c *ZFS) getPools(pools []string) ([]string, error) {
 		}
 	}
 
-	return result, nil
+	return c.filterPools(result), nil
 }
 
-func (c *ZFS) execute(ctx context.Context, name string, collector Collector, ch chan<- metric, pools []string) {
+// filterPools applies the pool include and exclude regexes
+func (c *ZFS) filterPools(pools []string) []string {
+	if len(c.poolIncludes) == 0 && len(c.poolExcludes) == 0 {
+		return pools
+	}
+
+	result := make([]string, 0, len(pools))
+	for _, pool := range pools {
+		if (len(c.poolIncludes) > 0 && !c.poolIncludes.MatchString(pool)) || c.poolExcludes.MatchString(pool) {
+			c.logger.Debug("Pool filtered", "pool", pool)
+			continue
+		}
+		result = append(result, pool)
+	}
+
+	return result
+}
+
+func collectorEnabled(name string) bool {
+	state, ok := collectorStates[name]
+	return ok && *state.Enabled
+}
+
+func (c *ZFS) execute(ctx context.Context, name string, collector Collector, breaker *circuitBreaker, ch chan<- metric, pools []string) error {
 	begin := time.Now()
-	err := collector.update(ch, pools, c.excludes)
+	tracked, done := c.origins.track(name, ch)
+	err := c.update(collector, tracked, pools)
+	close(tracked)
+	<-done
 	duration := time.Since(begin)
 
+	var panicErr *panicError
+	if errors.As(err, &panicErr) {
+		c.logger.Error("Collector panicked", "collector", name, "panic", panicErr.value, "stack", string(panicErr.stack))
+	}
+
+	if breaker.record(err) {
+		c.logger.Warn("Skipping failing collector", "collector", name, "failures", c.circuitThreshold, "cooldown", c.circuitCooldown)
+	}
 	c.publishCollectorMetrics(ctx, name, err, duration, ch)
+	c.publishCircuitMetric(name, breaker.isOpen(), ch)
+
+	return err
+}
+
+// executeScheduled executes a collector restricted to time windows, retaining the metrics of successful runs so that
+// they may be served outside the windows
+func (c *ZFS) executeScheduled(ctx context.Context, name string, collector Collector, breaker *circuitBreaker, ch chan<- metric, pools []string) {
+	tee := make(chan metric)
+	done := make(chan struct{})
+	var metrics []metric
+	go func() {
+		for m := range tee {
+			metrics = append(metrics, m)
+			ch <- m
+		}
+		close(done)
+	}()
+	err := c.execute(ctx, name, collector, breaker, tee, pools)
+	close(tee)
+	<-done
+	if err == nil {
+		c.scheduler.hold(name, metrics)
+	}
+}
+
+// update executes the collector, isolating any panic so that a single collector cannot take down the endpoint
+func (c *ZFS) update(collector Collector, ch chan<- metric, pools []string) (err error) {
+	defer recoverPanic(&err)
+	return collector.update(ch, pools, c.excludes)
+}
+
+// breaker returns the circuit breaker for the named collector, creating it if necessary
+func (c *ZFS) breaker(name string) *circuitBreaker {
+	c.breakersMu.Lock()
+	defer c.breakersMu.Unlock()
+	b, ok := c.breakers[name]
+	if !ok {
+		b = newCircuitBreaker(c.circuitThreshold, c.circuitCooldown)
+		c.breakers[name] = b
+	}
+	return b
+}
+
+func (c *ZFS) publishCircuitMetric(name string, open bool, ch chan<- metric) {
+	if c.disableMetrics {
+		return
+	}
+	var value float64
+	if open {
+		value = 1
+	}
+	ch <- metric{
+		name:       expandMetricName(circuitOpenDescName, name),
+		prometheus: prometheus.MustNewConstMetric(circuitOpenDesc, prometheus.GaugeValue, value, name),
+	}
 }
 
 func (c *ZFS) publishCollectorMetrics(ctx context.Context, name string, err error, duration time.Duration, ch chan<- metric) {
@@ -243,15 +443,66 @@ func (c *ZFS) publishCollectorMetrics(ctx context.Context, name string, err erro
 		name:       scrapeSuccessDescName,
 		prometheus: prometheus.MustNewConstMetric(scrapeSuccessDesc, prometheus.GaugeValue, success, name),
 	}
+	ch <- metric{
+		name:       expandMetricName(collectorDurationDescName, name),
+		prometheus: prometheus.MustNewConstMetric(collectorDurationDesc, prometheus.GaugeValue, duration.Seconds(), name),
+	}
+	ch <- metric{
+		name:       expandMetricName(collectorSuccessDescName, name),
+		prometheus: prometheus.MustNewConstMetric(collectorSuccessDesc, prometheus.GaugeValue, success, name),
+	}
 }
 
 // NewZFS instantiates a ZFS collector with the provided ZFSConfig
 func NewZFS(config ZFSConfig) (*ZFS, error) {
+	for name, conflicts := range collectorConflicts {
+		for _, conflict := range conflicts {
+			if collectorEnabled(name) && collectorEnabled(conflict) {
+				return nil, fmt.Errorf("the %s and %s collectors export the same metrics, and may not both be enabled", name, conflict)
+			}
+		}
+	}
+	collectors := make(map[string]State, len(collectorStates))
+	for name, state := range collectorStates {
+		if missing := config.Capabilities.Missing(collectorRequirements[name]...); config.Capabilities != nil && len(missing) > 0 && *state.Enabled {
+			config.Logger.Warn("Disabling collector unsupported by the installed ZFS version", "collector", name, "missing_capabilities", missing)
+			state.Enabled = new(bool)
+		}
+		if config.Remote && kstatCollectors[name] && *state.Enabled {
+			config.Logger.Debug("Disabling collector that reads local kstats for a remote host", "collector", name)
+			state.Enabled = new(bool)
+		}
+		collectors[name] = state
+	}
+
 	sort.Strings(config.Pools)
 	sort.Strings(config.Excludes)
-	excludes := make(regexpCollection, len(config.Excludes))
-	for i, v := range config.Excludes {
-		excludes[i] = regexp.MustCompile(v)
+	excludes, err := compileRegexps(config.Excludes)
+	if err != nil {
+		return nil, fmt.Errorf("dataset exclude: %w", err)
+	}
+	poolIncludes, err := compileRegexps(config.PoolIncludes)
+	if err != nil {
+		return nil, fmt.Errorf("pool include: %w", err)
+	}
+	poolExcludes, err := compileRegexps(config.PoolExcludes)
+	if err != nil {
+		return nil, fmt.Errorf("pool exclude: %w", err)
+	}
+	scheduler, err := newScheduler(config.Schedules)
+	if err != nil {
+		return nil, err
+	}
+	var warmed chan struct{}
+	switch config.FirstScrape {
+	case ``, FirstScrapeCollect:
+	case FirstScrapeBlock, FirstScrapeServe:
+		warmed = make(chan struct{})
+	default:
+		return nil, fmt.Errorf("unknown first scrape mode '%s', expected one of %s", config.FirstScrape, strings.Join(FirstScrapeModes, `, `))
+	}
+	if config.WarmupTimeout < 0 {
+		return nil, fmt.Errorf("warm-up timeout must not be negative: %s", config.WarmupTimeout)
 	}
 	ready := make(chan struct{}, 1)
 	ready <- struct{}{}
@@ -260,10 +511,23 @@ func NewZFS(config ZFSConfig) (*ZFS, error) {
 		client:         config.ZFSClient,
 		deadline:       config.Deadline,
 		Pools:          config.Pools,
-		Collectors:     collectorStates,
+		Collectors:     collectors,
 		excludes:       excludes,
+		poolIncludes:   poolIncludes,
+		poolExcludes:   poolExcludes,
 		cache:          newMetricCache(),
 		ready:          ready,
 		logger:         config.Logger,
+
+		circuitThreshold: config.CircuitThreshold,
+		circuitCooldown:  config.CircuitCooldown,
+		breakers:         make(map[string]*circuitBreaker),
+
+		scheduler: scheduler,
+		origins:   newMetricOrigins(),
+
+		firstScrape:   config.FirstScrape,
+		warmupTimeout: config.WarmupTimeout,
+		warmed:        warmed,
 	}, nil
 }
@@ -3,14 +3,27 @@ package collector
 import (
 	"context"
 	"errors"
+	"log/slog"
+	"reflect"
+	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 
+	"github.com/jmcgover/zfs_exporter/v2/zfs"
 	"github.com/jmcgover/zfs_exporter/v2/zfs/mock_zfs"
+	"github.com/prometheus/client_golang/prometheus"
 	"go.uber.org/mock/gomock"
 )
 
 func TestZFSCollectInvalidPools(t *testing.T) {
-	const result = `# HELP zfs_scrape_collector_duration_seconds zfs_exporter: Duration of a collector scrape.
+	const result = `# HELP zfs_exporter_collector_duration_seconds zfs_exporter: Duration of the most recent run of a collector.
+# TYPE zfs_exporter_collector_duration_seconds gauge
+zfs_exporter_collector_duration_seconds{collector="pool"} 0
+# HELP zfs_exporter_collector_success zfs_exporter: Whether the most recent run of a collector succeeded (1) or failed (0).
+# TYPE zfs_exporter_collector_success gauge
+zfs_exporter_collector_success{collector="pool"} 0
+# HELP zfs_scrape_collector_duration_seconds zfs_exporter: Duration of a collector scrape.
 # TYPE zfs_scrape_collector_duration_seconds gauge
 zfs_scrape_collector_duration_seconds{collector="pool"} 0
 # HELP zfs_scrape_collector_success zfs_exporter: Whether a collector succeeded.
@@ -37,7 +50,352 @@ zfs_scrape_collector_success{collector="pool"} 0
 		t.Fatal(err)
 	}
 
-	if err = callCollector(ctx, collector, []byte(result), []string{`zfs_scrape_collector_duration_seconds`, `zfs_scrape_collector_success`}); err != nil {
+	if err = callCollector(ctx, collector, []byte(result), []string{`zfs_scrape_collector_duration_seconds`, `zfs_scrape_collector_success`, `zfs_exporter_collector_duration_seconds`, `zfs_exporter_collector_success`}); err != nil {
 		t.Fatal(err)
 	}
 }
+
+type panicCollector struct {
+	perPool bool
+}
+
+func (c panicCollector) describe(ch chan<- *prometheus.Desc) {}
+
+func (c panicCollector) update(ch chan<- metric, pools []string, excludes regexpCollection) error {
+	if c.perPool {
+		return updatePools(pools, func(pool string) error {
+			var props map[string]string
+			props[pool] = `nil map`
+			return nil
+		})
+	}
+	panic(`bad parse`)
+}
+
+func TestZFSCollectPanic(t *testing.T) {
+	const result = `# HELP zfs_scrape_collector_success zfs_exporter: Whether a collector succeeded.
+# TYPE zfs_scrape_collector_success gauge
+zfs_scrape_collector_success{collector="panic"} 0
+`
+
+	for _, perPool := range []bool{false, true} {
+		ctrl, ctx := gomock.WithContext(context.Background(), t)
+		zfsClient := mock_zfs.NewMockClient(ctrl)
+		zfsClient.EXPECT().PoolNames().Return([]string{`testpool`}, nil).Times(1)
+
+		config := defaultConfig(zfsClient)
+		config.DisableMetrics = false
+		collector, err := NewZFS(config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		collector.Collectors = map[string]State{
+			`panic`: {
+				Name:       `panic`,
+				Enabled:    boolPointer(true),
+				Properties: stringPointer(``),
+				factory: func(l *slog.Logger, c zfs.Client, properties []string) (Collector, error) {
+					return panicCollector{perPool: perPool}, nil
+				},
+			},
+		}
+
+		if err = callCollector(ctx, collector, []byte(result), []string{`zfs_scrape_collector_success`}); err != nil {
+			t.Fatalf("perPool=%t: %v", perPool, err)
+		}
+	}
+}
+
+func TestNewZFSCapabilities(t *testing.T) {
+	state := collectorStates[`io`]
+	prev := *state.Enabled
+	*state.Enabled = true
+	t.Cleanup(func() { *state.Enabled = prev })
+
+	testCases := []struct {
+		name         string
+		capabilities zfs.Capabilities
+		want         bool
+	}{
+		{name: `unknown`, capabilities: nil, want: true},
+		{name: `supported`, capabilities: zfs.Capabilities{zfs.CapabilityIostatJSON: true}, want: true},
+		{name: `unsupported`, capabilities: zfs.Capabilities{zfs.CapabilityIostatJSON: false}, want: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			config := defaultConfig(nil)
+			config.Capabilities = tc.capabilities
+			collector, err := NewZFS(config)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := *collector.Collectors[`io`].Enabled; got != tc.want {
+				t.Errorf("Expected io collector enabled=%t, got %t", tc.want, got)
+			}
+			if !*state.Enabled {
+				t.Error(`Expected flag value to be left unchanged`)
+			}
+		})
+	}
+}
+
+func TestNewZFSRemote(t *testing.T) {
+	state := collectorStates[`arcstats`]
+	prev := *state.Enabled
+	*state.Enabled = true
+	t.Cleanup(func() { *state.Enabled = prev })
+
+	for _, remote := range []bool{false, true} {
+		config := defaultConfig(nil)
+		config.Remote = remote
+		collector, err := NewZFS(config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := *collector.Collectors[`arcstats`].Enabled; got == remote {
+			t.Errorf("Expected arcstats collector enabled=%t for remote=%t, got %t", !remote, remote, got)
+		}
+	}
+}
+
+// sleepCollector exports nothing, taking delay to do so
+type sleepCollector struct {
+	delay *atomic.Int64
+}
+
+func (c sleepCollector) describe(ch chan<- *prometheus.Desc) {}
+
+func (c sleepCollector) update(ch chan<- metric, pools []string, excludes regexpCollection) error {
+	time.Sleep(time.Duration(c.delay.Load()))
+	return nil
+}
+
+func TestZFSCollectDeadline(t *testing.T) {
+	const result = `# HELP zfs_exporter_collector_success zfs_exporter: Whether the most recent run of a collector succeeded (1) or failed (0).
+# TYPE zfs_exporter_collector_success gauge
+zfs_exporter_collector_success{collector="fast"} 1
+zfs_exporter_collector_success{collector="slow"} 1
+`
+
+	ctrl, ctx := gomock.WithContext(context.Background(), t)
+	zfsClient := mock_zfs.NewMockClient(ctrl)
+	zfsClient.EXPECT().PoolNames().Return([]string{`testpool`}, nil).AnyTimes()
+
+	config := defaultConfig(zfsClient)
+	config.DisableMetrics = false
+	config.Deadline = 100 * time.Millisecond
+	collector, err := NewZFS(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fast, slow atomic.Int64
+	collector.Collectors = map[string]State{}
+	for name, delay := range map[string]*atomic.Int64{`fast`: &fast, `slow`: &slow} {
+		collector.Collectors[name] = State{
+			Name:       name,
+			Enabled:    boolPointer(true),
+			Properties: stringPointer(``),
+			factory: func(l *slog.Logger, c zfs.Client, props []string) (Collector, error) {
+				return sleepCollector{delay: delay}, nil
+			},
+		}
+	}
+
+	// Both collectors complete within the deadline of the first scrape
+	if err = callCollector(ctx, collector, []byte(result), []string{`zfs_exporter_collector_success`}); err != nil {
+		t.Fatal(err)
+	}
+
+	// The success of the collector exceeding the deadline is served from the previous scrape
+	slow.Store(int64(3 * config.Deadline))
+	if err = callCollector(ctx, collector, []byte(result), []string{`zfs_exporter_collector_success`}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// kstatClient records whether kstats were read, panicking on any other call
+type kstatClient struct {
+	zfs.Client
+	read atomic.Bool
+}
+
+func (c *kstatClient) ArcStats() (map[string]string, error) {
+	c.read.Store(true)
+	return nil, errors.New(`no kstats`)
+}
+
+func (c *kstatClient) Txgs(pool string) ([]zfs.TxgT, error) {
+	c.read.Store(true)
+	return nil, errors.New(`no kstats`)
+}
+
+func (c *kstatClient) ObjsetStats(pool string) (map[string]zfs.ObjsetStatsT, error) {
+	c.read.Store(true)
+	return nil, errors.New(`no kstats`)
+}
+
+// TestKstatCollectors checks that exactly the collectors reading local kstats are disabled for remote hosts
+func TestKstatCollectors(t *testing.T) {
+	for name, state := range collectorStates {
+		client := &kstatClient{}
+		c, err := state.factory(logger, client, strings.Split(*state.Properties, `,`))
+		if err != nil {
+			continue
+		}
+		ch := make(chan metric, 1024)
+		func() {
+			defer func() { _ = recover() }()
+			_ = c.update(ch, []string{`testpool`}, nil)
+		}()
+		if got := client.read.Load(); got != kstatCollectors[name] {
+			t.Errorf("Collector %s reads kstats=%t, but kstatCollectors has %t", name, got, kstatCollectors[name])
+		}
+	}
+}
+
+func TestDisableDefaultCollectors(t *testing.T) {
+	enabled := make(map[string]bool, len(collectorStates))
+	for name, state := range collectorStates {
+		enabled[name] = *state.Enabled
+	}
+	t.Cleanup(func() {
+		for name, state := range collectorStates {
+			*state.Enabled = enabled[name]
+			delete(forcedCollectors, name)
+		}
+	})
+
+	*collectorStates[`arcstats`].Enabled = true
+	if err := collectorFlagAction(`arcstats`)(nil); err != nil {
+		t.Fatal(err)
+	}
+	DisableDefaultCollectors()
+
+	for name, state := range collectorStates {
+		if want := name == `arcstats`; *state.Enabled != want {
+			t.Errorf("collector %s enabled = %t, want %t", name, *state.Enabled, want)
+		}
+	}
+}
+
+func TestZFSPoolFilters(t *testing.T) {
+	testCases := []struct {
+		name     string
+		pools    []string
+		includes []string
+		excludes []string
+		want     []string
+	}{
+		{
+			name: `unfiltered`,
+			want: []string{`backup`, `rpool`, `tank`, `tank-scratch`},
+		},
+		{
+			name:     `include`,
+			includes: []string{`^tank`},
+			want:     []string{`tank`, `tank-scratch`},
+		},
+		{
+			name:     `exclude`,
+			excludes: []string{`-scratch$`, `^backup$`},
+			want:     []string{`rpool`, `tank`},
+		},
+		{
+			name:     `include and exclude`,
+			includes: []string{`^tank`},
+			excludes: []string{`-scratch$`},
+			want:     []string{`tank`},
+		},
+		{
+			name:     `explicit pools`,
+			pools:    []string{`rpool`, `tank-scratch`},
+			excludes: []string{`-scratch$`},
+			want:     []string{`rpool`},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			zfsClient := mock_zfs.NewMockClient(ctrl)
+			zfsClient.EXPECT().PoolNames().Return([]string{`backup`, `rpool`, `tank`, `tank-scratch`}, nil).Times(1)
+
+			config := defaultConfig(zfsClient)
+			config.Pools = tc.pools
+			config.PoolIncludes = tc.includes
+			config.PoolExcludes = tc.excludes
+			collector, err := NewZFS(config)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := collector.getPools(collector.Pools)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("Expected pools %v, got %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestNewZFSInvalidRegex(t *testing.T) {
+	config := defaultConfig(nil)
+	config.PoolExcludes = []string{`(`}
+	if _, err := NewZFS(config); err == nil {
+		t.Fatal(`Expected error for invalid pool exclude regex`)
+	}
+}
+
+type staticCollector struct {
+	prop property
+}
+
+func (c staticCollector) describe(ch chan<- *prometheus.Desc) {
+	ch <- c.prop.desc
+}
+
+func (c staticCollector) update(ch chan<- metric, pools []string, excludes regexpCollection) error {
+	for _, pool := range pools {
+		c.prop.send(ch, 1, pool)
+	}
+	return nil
+}
+
+func TestZFSMetricOrigins(t *testing.T) {
+	const result = `# HELP zfs_pool_static Static metric.
+# TYPE zfs_pool_static gauge
+zfs_pool_static{pool="test-pool"} 1
+`
+
+	ctrl, ctx := gomock.WithContext(context.Background(), t)
+	zfsClient := mock_zfs.NewMockClient(ctrl)
+	zfsClient.EXPECT().PoolNames().Return([]string{`test-pool`}, nil).Times(1)
+
+	config := defaultConfig(zfsClient)
+	config.DisableMetrics = false
+	collector, err := NewZFS(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	collector.Collectors = map[string]State{
+		`static`: {
+			Name:       `static`,
+			Enabled:    boolPointer(true),
+			Properties: stringPointer(``),
+			factory: func(l *slog.Logger, c zfs.Client, properties []string) (Collector, error) {
+				return staticCollector{prop: newProperty(subsystemPool, `static`, `Static metric.`, transformNumeric, prometheus.GaugeValue, `pool`)}, nil
+			},
+		},
+	}
+
+	if err = callCollector(ctx, collector, []byte(result), []string{`zfs_pool_static`}); err != nil {
+		t.Fatal(err)
+	}
+	// Label values may contain the separator of expanded metric names, and exporter metrics have no collector
+	if origins := collector.MetricOrigins(); !reflect.DeepEqual(origins, map[string]string{`zfs_pool_static`: `static`}) {
+		t.Errorf("Unexpected metric origins %v", origins)
+	}
+}
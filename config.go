@@ -0,0 +1,79 @@
+package main
+
+import (
+	"time"
+
+	"github.com/alecthomas/kingpin/v2"
+	"github.com/jmcgover/zfs_exporter/v2/collector"
+	"github.com/jmcgover/zfs_exporter/v2/config"
+)
+
+// settings holds the flag values that may also be supplied via the configuration file
+type settings struct {
+	listenAddresses *[]string
+	webConfigFile   *string
+	pools           *[]string
+	excludes        *[]string
+	deadline        *time.Duration
+	commandTimeout  *time.Duration
+	cacheTTL        *time.Duration
+}
+
+// explicitFlags returns the names of the flags that were set on the command line
+func explicitFlags(app *kingpin.Application, args []string) (map[string]bool, error) {
+	ctx, err := app.ParseContext(args)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]bool)
+	for _, element := range ctx.Elements {
+		if flag, ok := element.Clause.(*kingpin.FlagClause); ok {
+			result[flag.Model().Name] = true
+		}
+	}
+
+	return result, nil
+}
+
+// applyConfig applies the configuration file to s and the collector flags. Flags set on the command line take
+// precedence over the file.
+func applyConfig(cfg *config.Config, s settings, explicit map[string]bool) error {
+	if len(cfg.ListenAddresses) > 0 && !explicit[`web.listen-address`] {
+		*s.listenAddresses = cfg.ListenAddresses
+	}
+	if cfg.WebConfigFile != `` && !explicit[`web.config.file`] {
+		*s.webConfigFile = cfg.WebConfigFile
+	}
+	if len(cfg.Pools) > 0 && !explicit[`pool`] {
+		*s.pools = cfg.Pools
+	}
+	if len(cfg.Excludes) > 0 && !explicit[`exclude`] {
+		*s.excludes = cfg.Excludes
+	}
+	if cfg.Deadline > 0 && !explicit[`deadline`] {
+		*s.deadline = cfg.Deadline
+	}
+	if cfg.CommandTimeout > 0 && !explicit[`zfs.command-timeout`] {
+		*s.commandTimeout = cfg.CommandTimeout
+	}
+	if cfg.CacheTTL > 0 && !explicit[`zfs.cache-ttl`] {
+		*s.cacheTTL = cfg.CacheTTL
+	}
+
+	for _, name := range cfg.CollectorNames() {
+		c := cfg.Collectors[name]
+		enabled, properties := c.Enabled, c.Properties
+		if explicit[`collector.`+name] {
+			enabled = nil
+		}
+		if explicit[`properties.`+name] {
+			properties = nil
+		}
+		if err := collector.Configure(name, enabled, properties); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
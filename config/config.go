@@ -0,0 +1,328 @@
+// Package config loads the zfs_exporter YAML configuration file.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"go.yaml.in/yaml/v2"
+)
+
+// CurrentVersion is the schema version produced by Load. Older schemas are migrated to this version on load.
+const CurrentVersion = 2
+
+var (
+	// ErrUnsupportedVersion is returned when a configuration file declares a schema version newer than CurrentVersion
+	ErrUnsupportedVersion = errors.New(`unsupported config version`)
+
+	labelNameRe = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+	// HealthScoreComponents are the components of the health score that may be weighted
+	HealthScoreComponents = []string{`state`, `errors`, `capacity`, `scrub`}
+
+	// migrations upgrade a raw configuration document from the schema version at index+1 to the next version.
+	migrations = []func(data []byte) ([]byte, error){
+		migrateV1,
+	}
+)
+
+// Config holds settings that may be supplied via file instead of on the command line
+type Config struct {
+	Version         int                        `yaml:"version"`
+	ListenAddresses []string                   `yaml:"listen_addresses,omitempty"`
+	WebConfigFile   string                     `yaml:"web_config_file,omitempty"`
+	Pools           []string                   `yaml:"pools,omitempty"`
+	Excludes        []string                   `yaml:"excludes,omitempty"`
+	Deadline        time.Duration              `yaml:"deadline,omitempty"`
+	CommandTimeout  time.Duration              `yaml:"command_timeout,omitempty"`
+	CacheTTL        time.Duration              `yaml:"cache_ttl,omitempty"`
+	Collectors      map[string]CollectorConfig `yaml:"collectors,omitempty"`
+	// HealthPolicies maps policy names to CEL expressions that classify the health of each pool
+	HealthPolicies map[string]string `yaml:"health_policies,omitempty"`
+	// Writable lists regexes of the datasets expected to be writable, which are reported if they become read-only
+	Writable []string `yaml:"writable,omitempty"`
+	// PoolLabels adds constant labels to the series of matching pools, applied in order
+	PoolLabels []PoolLabels `yaml:"pool_labels,omitempty"`
+	// Replication lists the replication pairs whose next incremental send is estimated
+	Replication []Replication `yaml:"replication,omitempty"`
+	// HealthScore enables zfs_host_health_score, combining the health of the pools into a single score
+	HealthScore *HealthScore `yaml:"health_score,omitempty"`
+	// SSHHosts holds the ssh settings of hosts that may be probed, which are allowed in addition to
+	// --probe.allowed-target
+	SSHHosts []SSHHost `yaml:"ssh_hosts,omitempty"`
+}
+
+// SSHHost holds the ssh settings used to probe a host, overriding those of the command-line flags
+type SSHHost struct {
+	// Host is the name or address of the host, as given in the probe target without a user
+	Host string `yaml:"host"`
+	// User is the login user, unless the probe target names one
+	User string `yaml:"user,omitempty"`
+	Port int    `yaml:"port,omitempty"`
+	// IdentityFile is the private key used to authenticate to the host
+	IdentityFile string `yaml:"identity_file,omitempty"`
+	// Options are passed to ssh with -o, taking precedence over those of --probe.ssh-option
+	Options []string `yaml:"options,omitempty"`
+}
+
+// HealthScore weights the components of the health score of each pool, and sets the thresholds at which they fall.
+// Omitted settings take their defaults.
+type HealthScore struct {
+	// Weights of the components, relative to their sum. A weight of zero ignores the component.
+	Weights map[string]float64 `yaml:"weights,omitempty"`
+	// CapacityThreshold is the fraction of the pool allocated above which the capacity component falls, reaching zero
+	// once the pool is full
+	CapacityThreshold float64 `yaml:"capacity_threshold,omitempty"`
+	// ErrorThreshold is the number of read, write, checksum and data errors at which the errors component reaches zero
+	ErrorThreshold int `yaml:"error_threshold,omitempty"`
+	// ScrubMaxAge is the age of the last completed scrub at which the scrub component reaches zero
+	ScrubMaxAge time.Duration `yaml:"scrub_max_age,omitempty"`
+}
+
+// Replication identifies the snapshot or bookmark last replicated to a target, from which the size of the next
+// incremental send is estimated
+type Replication struct {
+	Name string `yaml:"name"`
+	// From is the full name of the snapshot or bookmark last replicated, e.g. tank/data#offsite
+	From string `yaml:"from"`
+	// Snapshot is the snapshot to be sent, or the most recent snapshot of the dataset of From if empty
+	Snapshot string `yaml:"snapshot,omitempty"`
+}
+
+// PoolLabels holds the labels added to every series of the pools whose name matches Pool
+type PoolLabels struct {
+	// Pool is a regex matched against the whole pool name, so that a plain name matches only that pool
+	Pool   string            `yaml:"pool"`
+	Labels map[string]string `yaml:"labels"`
+}
+
+// CollectorConfig holds the settings for a single collector
+type CollectorConfig struct {
+	Enabled    *bool    `yaml:"enabled,omitempty"`
+	Properties []string `yaml:"properties,omitempty"`
+}
+
+// configV1 is the original schema, which mirrors the command-line flags. Files that do not declare a version are
+// treated as version 1.
+type configV1 struct {
+	Version    int               `yaml:"version"`
+	Pools      []string          `yaml:"pools,omitempty"`
+	Excludes   []string          `yaml:"excludes,omitempty"`
+	Deadline   time.Duration     `yaml:"deadline,omitempty"`
+	Collectors map[string]bool   `yaml:"collectors,omitempty"`
+	Properties map[string]string `yaml:"properties,omitempty"`
+}
+
+// validSSHName reports whether s may be used as the user or host of an ssh destination, without being mistaken for an
+// option of the ssh client
+func validSSHName(s string) bool {
+	return s != `` && !strings.HasPrefix(s, `-`) && !strings.ContainsFunc(s, func(r rune) bool { return r <= ' ' || r == 0x7f || r == '@' })
+}
+
+// Load reads the configuration file at path, migrating it to CurrentVersion if required
+func Load(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return cfg, nil
+}
+
+// Parse decodes a configuration document, migrating it to CurrentVersion if required. Unknown keys are rejected.
+func Parse(data []byte) (*Config, error) {
+	var header struct {
+		Version int `yaml:"version"`
+	}
+	if err := yaml.Unmarshal(data, &header); err != nil {
+		return nil, err
+	}
+
+	version := header.Version
+	if version == 0 {
+		version = 1
+	}
+	if version < 1 || version > CurrentVersion {
+		return nil, fmt.Errorf("%w: %d (supported: 1-%d)", ErrUnsupportedVersion, header.Version, CurrentVersion)
+	}
+
+	for ; version < CurrentVersion; version++ {
+		var err error
+		if data, err = migrations[version-1](data); err != nil {
+			return nil, fmt.Errorf("migrating config from version %d: %w", version, err)
+		}
+	}
+
+	cfg := new(Config)
+	if err := yaml.UnmarshalStrict(data, cfg); err != nil {
+		return nil, err
+	}
+	cfg.Version = CurrentVersion
+
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+// validate rejects values that would otherwise only fail once the exporter is running
+func (c *Config) validate() error {
+	if c.Deadline < 0 {
+		return fmt.Errorf("deadline must not be negative: %s", c.Deadline)
+	}
+	if c.CommandTimeout < 0 {
+		return fmt.Errorf("command_timeout must not be negative: %s", c.CommandTimeout)
+	}
+	if c.CacheTTL < 0 {
+		return fmt.Errorf("cache_ttl must not be negative: %s", c.CacheTTL)
+	}
+	for _, addr := range c.ListenAddresses {
+		if addr == `` {
+			return errors.New(`listen_addresses must not contain empty addresses`)
+		}
+	}
+	for _, pool := range c.Pools {
+		if pool == `` {
+			return errors.New(`pools must not contain empty names`)
+		}
+	}
+	for name, expression := range c.HealthPolicies {
+		if name == `` || expression == `` {
+			return errors.New(`health_policies must not contain empty names or expressions`)
+		}
+	}
+	for _, exclude := range c.Excludes {
+		if _, err := regexp.Compile(exclude); err != nil {
+			return fmt.Errorf("invalid exclude '%s': %w", exclude, err)
+		}
+	}
+	for _, writable := range c.Writable {
+		if _, err := regexp.Compile(writable); err != nil {
+			return fmt.Errorf("invalid writable '%s': %w", writable, err)
+		}
+	}
+	names := make(map[string]bool)
+	for _, r := range c.Replication {
+		if r.Name == `` || names[r.Name] {
+			return fmt.Errorf("replication names must be unique and not empty: '%s'", r.Name)
+		}
+		names[r.Name] = true
+		if i := strings.IndexAny(r.From, `@#`); i < 1 {
+			return fmt.Errorf("invalid replication from '%s': must be a snapshot or bookmark, e.g. tank/data#offsite", r.From)
+		}
+		if r.Snapshot != `` && strings.IndexByte(r.Snapshot, '@') < 1 {
+			return fmt.Errorf("invalid replication snapshot '%s'", r.Snapshot)
+		}
+	}
+	hosts := make(map[string]bool)
+	for _, h := range c.SSHHosts {
+		if !validSSHName(h.Host) || hosts[h.Host] {
+			return fmt.Errorf("ssh_hosts hosts must be unique and valid host names: '%s'", h.Host)
+		}
+		hosts[h.Host] = true
+		if h.User != `` && !validSSHName(h.User) {
+			return fmt.Errorf("invalid ssh_hosts user '%s' for host '%s'", h.User, h.Host)
+		}
+		if h.Port < 0 || h.Port > 65535 {
+			return fmt.Errorf("invalid ssh_hosts port %d for host '%s'", h.Port, h.Host)
+		}
+	}
+	if hs := c.HealthScore; hs != nil {
+		for name, weight := range hs.Weights {
+			if !slices.Contains(HealthScoreComponents, name) {
+				return fmt.Errorf("unknown health_score weight '%s', must be one of %s", name, strings.Join(HealthScoreComponents, `, `))
+			}
+			if weight < 0 {
+				return fmt.Errorf("health_score weight '%s' must not be negative: %g", name, weight)
+			}
+		}
+		if hs.CapacityThreshold < 0 || hs.CapacityThreshold >= 1 {
+			return fmt.Errorf("health_score capacity_threshold must be at least 0 and less than 1: %g", hs.CapacityThreshold)
+		}
+		if hs.ErrorThreshold < 0 {
+			return fmt.Errorf("health_score error_threshold must not be negative: %d", hs.ErrorThreshold)
+		}
+		if hs.ScrubMaxAge < 0 {
+			return fmt.Errorf("health_score scrub_max_age must not be negative: %s", hs.ScrubMaxAge)
+		}
+	}
+	for _, pl := range c.PoolLabels {
+		if pl.Pool == `` {
+			return errors.New(`pool_labels must not contain empty pools`)
+		}
+		if _, err := regexp.Compile(pl.Pool); err != nil {
+			return fmt.Errorf("invalid pool_labels pool '%s': %w", pl.Pool, err)
+		}
+		for name := range pl.Labels {
+			if !labelNameRe.MatchString(name) || strings.HasPrefix(name, `__`) || name == `pool` {
+				return fmt.Errorf("invalid pool_labels label name '%s'", name)
+			}
+		}
+	}
+
+	return nil
+}
+
+// migrateV1 groups the separate collector and properties maps of the version 1 schema by collector
+func migrateV1(data []byte) ([]byte, error) {
+	var v1 configV1
+	if err := yaml.UnmarshalStrict(data, &v1); err != nil {
+		return nil, err
+	}
+
+	v2 := Config{
+		Version:  2,
+		Pools:    v1.Pools,
+		Excludes: v1.Excludes,
+		Deadline: v1.Deadline,
+	}
+	if len(v1.Collectors) > 0 || len(v1.Properties) > 0 {
+		v2.Collectors = make(map[string]CollectorConfig)
+	}
+	for name, enabled := range v1.Collectors {
+		c := v2.Collectors[name]
+		c.Enabled = &enabled
+		v2.Collectors[name] = c
+	}
+	for name, props := range v1.Properties {
+		c := v2.Collectors[name]
+		c.Properties = splitProperties(props)
+		v2.Collectors[name] = c
+	}
+
+	return yaml.Marshal(v2)
+}
+
+// CollectorNames returns the names of the configured collectors in sorted order
+func (c *Config) CollectorNames() []string {
+	names := make([]string, 0, len(c.Collectors))
+	for name := range c.Collectors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+func splitProperties(props string) []string {
+	result := make([]string, 0)
+	for _, prop := range strings.Split(props, `,`) {
+		if prop = strings.TrimSpace(prop); prop != `` {
+			result = append(result, prop)
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,185 @@
+package config
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	enabled, disabled := true, false
+	want := &Config{
+		Version:  CurrentVersion,
+		Pools:    []string{`tank`},
+		Deadline: 5 * time.Second,
+		Collectors: map[string]CollectorConfig{
+			`pool`:     {Enabled: &disabled},
+			`arcstats`: {Enabled: &enabled, Properties: []string{`hits`, `misses`}},
+		},
+	}
+
+	testCases := []struct {
+		name string
+		data string
+	}{
+		{
+			name: `current`,
+			data: `version: 2
+pools: [tank]
+deadline: 5s
+collectors:
+  pool:
+    enabled: false
+  arcstats:
+    enabled: true
+    properties: [hits, misses]
+`,
+		},
+		{
+			name: `v1`,
+			data: `version: 1
+pools: [tank]
+deadline: 5s
+collectors:
+  pool: false
+  arcstats: true
+properties:
+  arcstats: hits, misses
+`,
+		},
+		{
+			name: `unversioned`,
+			data: `pools: [tank]
+deadline: 5s
+collectors:
+  pool: false
+  arcstats: true
+properties:
+  arcstats: hits,misses
+`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := Parse([]byte(tc.data))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("Expected %+v, got %+v", want, got)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	testCases := []struct {
+		name    string
+		data    string
+		wantErr error
+	}{
+		{
+			name: `unknown key`,
+			data: "version: 2\npool: [tank]\n",
+		},
+		{
+			name: `unknown v1 key`,
+			data: "exclude: ['^tank/docker/']\n",
+		},
+		{
+			name: `v1 layout with v2 version`,
+			data: "version: 2\ncollectors:\n  pool: false\n",
+		},
+		{
+			name: `negative deadline`,
+			data: "version: 2\ndeadline: -5s\n",
+		},
+		{
+			name: `negative command timeout`,
+			data: "version: 2\ncommand_timeout: -1m\n",
+		},
+		{
+			name: `negative cache ttl`,
+			data: "version: 2\ncache_ttl: -10s\n",
+		},
+		{
+			name: `empty listen address`,
+			data: "version: 2\nlisten_addresses: ['']\n",
+		},
+		{
+			name: `empty health policy`,
+			data: "version: 2\nhealth_policies:\n  strict: ''\n",
+		},
+		{
+			name: `invalid exclude`,
+			data: "version: 2\nexcludes: ['^tank/(']\n",
+		},
+		{
+			name: `invalid writable`,
+			data: "version: 2\nwritable: ['^tank/(']\n",
+		},
+		{
+			name: `invalid pool labels regex`,
+			data: "version: 2\npool_labels:\n  - pool: '^archive-('\n    labels: {tier: archive}\n",
+		},
+		{
+			name: `invalid pool label name`,
+			data: "version: 2\npool_labels:\n  - pool: tank\n    labels: {site-name: berlin}\n",
+		},
+		{
+			name: `reserved pool label name`,
+			data: "version: 2\npool_labels:\n  - pool: tank\n    labels: {pool: other}\n",
+		},
+		{
+			name: `duplicate replication name`,
+			data: "version: 2\nreplication:\n  - {name: offsite, from: 'tank/a#offsite'}\n  - {name: offsite, from: 'tank/b#offsite'}\n",
+		},
+		{
+			name: `relative replication from`,
+			data: "version: 2\nreplication:\n  - {name: offsite, from: '#offsite'}\n",
+		},
+		{
+			name: `duplicate ssh host`,
+			data: "version: 2\nssh_hosts:\n  - {host: nas1}\n  - {host: nas1, port: 2222}\n",
+		},
+		{
+			name: `ssh host with user`,
+			data: "version: 2\nssh_hosts:\n  - {host: monitor@nas1}\n",
+		},
+		{
+			name: `unknown health score weight`,
+			data: "version: 2\nhealth_score:\n  weights: {temperature: 10}\n",
+		},
+		{
+			name: `negative health score weight`,
+			data: "version: 2\nhealth_score:\n  weights: {scrub: -1}\n",
+		},
+		{
+			name: `health score capacity threshold out of range`,
+			data: "version: 2\nhealth_score:\n  capacity_threshold: 1.5\n",
+		},
+		{
+			name: `invalid v1 exclude`,
+			data: "excludes: ['^tank/(']\n",
+		},
+		{
+			name:    `future version`,
+			data:    "version: 99\n",
+			wantErr: ErrUnsupportedVersion,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := Parse([]byte(tc.data))
+			if err == nil {
+				t.Fatal(`Expected error, got nil`)
+			}
+			if tc.wantErr != nil && !errors.Is(err, tc.wantErr) {
+				t.Errorf("Expected %v, got %v", tc.wantErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/alecthomas/kingpin/v2"
+	"github.com/jmcgover/zfs_exporter/v2/config"
+)
+
+func TestExplicitFlags(t *testing.T) {
+	app := kingpin.New(`test`, ``)
+	app.Flag(`collector.pool`, ``).Default(`true`).Bool()
+	app.Flag(`collector.arcstats`, ``).Default(`false`).Bool()
+	app.Flag(`pool`, ``).Strings()
+	app.Flag(`deadline`, ``).Default(`8s`).Duration()
+
+	got, err := explicitFlags(app, []string{`--no-collector.pool`, `--pool=tank`})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{`collector.pool`, `pool`} {
+		if !got[name] {
+			t.Errorf("Expected %s to be explicit", name)
+		}
+	}
+	for _, name := range []string{`collector.arcstats`, `deadline`} {
+		if got[name] {
+			t.Errorf("Expected %s not to be explicit", name)
+		}
+	}
+}
+
+func TestApplyConfig(t *testing.T) {
+	cfg := &config.Config{
+		Version:         config.CurrentVersion,
+		ListenAddresses: []string{`:9135`},
+		WebConfigFile:   `web-config.yml`,
+		Pools:           []string{`tank`},
+		Excludes:        []string{`^tank/docker/`},
+		Deadline:        5 * time.Second,
+		CommandTimeout:  30 * time.Second,
+		CacheTTL:        10 * time.Second,
+	}
+
+	listenAddresses, pools, excludes := []string{`:9134`}, []string{`rpool`}, []string(nil)
+	deadline, commandTimeout, cacheTTL := 8*time.Second, time.Minute, time.Duration(0)
+	webConfigFile := ``
+	s := settings{
+		listenAddresses: &listenAddresses,
+		webConfigFile:   &webConfigFile,
+		pools:           &pools,
+		excludes:        &excludes,
+		deadline:        &deadline,
+		commandTimeout:  &commandTimeout,
+		cacheTTL:        &cacheTTL,
+	}
+
+	if err := applyConfig(cfg, s, map[string]bool{`pool`: true, `zfs.command-timeout`: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(listenAddresses, cfg.ListenAddresses) {
+		t.Errorf("Expected listen addresses %v, got %v", cfg.ListenAddresses, listenAddresses)
+	}
+	if webConfigFile != cfg.WebConfigFile {
+		t.Errorf("Expected web config file %s, got %s", cfg.WebConfigFile, webConfigFile)
+	}
+	if !reflect.DeepEqual(pools, []string{`rpool`}) {
+		t.Errorf("Expected pools set on the command line to be kept, got %v", pools)
+	}
+	if !reflect.DeepEqual(excludes, cfg.Excludes) {
+		t.Errorf("Expected excludes %v, got %v", cfg.Excludes, excludes)
+	}
+	if deadline != cfg.Deadline {
+		t.Errorf("Expected deadline %s, got %s", cfg.Deadline, deadline)
+	}
+	if cacheTTL != cfg.CacheTTL {
+		t.Errorf("Expected cache TTL %s, got %s", cfg.CacheTTL, cacheTTL)
+	}
+	if commandTimeout != time.Minute {
+		t.Errorf("Expected command timeout set on the command line to be kept, got %s", commandTimeout)
+	}
+}
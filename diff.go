@@ -0,0 +1,251 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"slices"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// diffLive selects the current state of the host, rather than a saved inventory, as the target of a diff
+const diffLive = `live`
+
+var (
+	// diffProperties are the properties compared for each kind of object. Snapshots are only counted.
+	diffProperties = map[string][]string{
+		`pool`:       {`health`, `size`, `allocated`, `free`},
+		`vdev`:       {`state`, `vdev_type`, `class`, `parent`, `path`},
+		`filesystem`: {`used`, `available`, `quota`, `refquota`, `mountpoint`},
+		`volume`:     {`used`, `volsize`, `refreservation`},
+	}
+	// diffSizeProperties are reported with the change in size
+	diffSizeProperties = map[string]bool{
+		`size`: true, `allocated`: true, `free`: true, `used`: true, `available`: true, `quota`: true, `refquota`: true,
+		`volsize`: true, `refreservation`: true,
+	}
+	diffKindOrder = []string{`pool`, `vdev`, `filesystem`, `volume`}
+)
+
+// inventoryObject identifies a row group of an inventory export
+type inventoryObject struct {
+	kind, pool, name string
+}
+
+// inventorySnapshot is an inventory export read back into memory
+type inventorySnapshot struct {
+	timestamp string
+	objects   map[inventoryObject]map[string]string
+}
+
+// readInventory parses an inventory export written by the inventory command
+func readInventory(r io.Reader) (inventorySnapshot, error) {
+	s := inventorySnapshot{objects: make(map[inventoryObject]map[string]string)}
+	records, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return s, err
+	}
+	if len(records) == 0 || !slices.Equal(records[0], inventoryHeader) {
+		return s, errors.New(`not an inventory export: unexpected header`)
+	}
+	for _, record := range records[1:] {
+		// timestamp, kind, pool, name, property, value, source
+		s.timestamp = record[0]
+		object := inventoryObject{kind: record[1], pool: record[2], name: record[3]}
+		if s.objects[object] == nil {
+			s.objects[object] = make(map[string]string)
+		}
+		s.objects[object][record[4]] = record[5]
+	}
+
+	return s, nil
+}
+
+func readInventoryFile(path string) (inventorySnapshot, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return inventorySnapshot{}, err
+	}
+	defer f.Close()
+	s, err := readInventory(f)
+	if err != nil {
+		return s, fmt.Errorf("%s: %w", path, err)
+	}
+	return s, nil
+}
+
+// writeDiff reports the objects added to or removed from the inventory, and changes to their key properties, such as
+// capacity growth and vdev state. Snapshots are summarised by the number added and removed in each pool.
+func writeDiff(w io.Writer, from, to inventorySnapshot) error {
+	var lines []string
+	report := func(format string, args ...any) {
+		lines = append(lines, fmt.Sprintf(format, args...))
+	}
+	sorted := func(objects map[inventoryObject]map[string]string, kind string) []inventoryObject {
+		var result []inventoryObject
+		for o := range objects {
+			if o.kind == kind {
+				result = append(result, o)
+			}
+		}
+		sort.Slice(result, func(i, j int) bool {
+			if result[i].pool != result[j].pool {
+				return result[i].pool < result[j].pool
+			}
+			return result[i].name < result[j].name
+		})
+		return result
+	}
+
+	for _, kind := range diffKindOrder {
+		for _, o := range sorted(from.objects, kind) {
+			if _, ok := to.objects[o]; !ok {
+				report("%s %s: removed", kind, objectName(o))
+			}
+		}
+		for _, o := range sorted(to.objects, kind) {
+			before, ok := from.objects[o]
+			if !ok {
+				report("%s %s: added", kind, objectName(o))
+				continue
+			}
+			after := to.objects[o]
+			for _, prop := range diffProperties[kind] {
+				if before[prop] == after[prop] {
+					continue
+				}
+				if diffSizeProperties[prop] {
+					report("%s %s: %s %s -> %s (%s)", kind, objectName(o), prop, formatSize(before[prop]), formatSize(after[prop]), formatSizeChange(before[prop], after[prop]))
+				} else {
+					report("%s %s: %s %s -> %s", kind, objectName(o), prop, quoteEmpty(before[prop]), quoteEmpty(after[prop]))
+				}
+			}
+		}
+	}
+
+	added, removed := make(map[string]int), make(map[string]int)
+	for o := range to.objects {
+		if _, ok := from.objects[o]; !ok && o.kind == `snapshot` {
+			added[o.pool]++
+		}
+	}
+	for o := range from.objects {
+		if _, ok := to.objects[o]; !ok && o.kind == `snapshot` {
+			removed[o.pool]++
+		}
+	}
+	var pools []string
+	for pool := range added {
+		pools = append(pools, pool)
+	}
+	for pool := range removed {
+		if _, ok := added[pool]; !ok {
+			pools = append(pools, pool)
+		}
+	}
+	sort.Strings(pools)
+	for _, pool := range pools {
+		report("snapshots %s: %d added, %d removed", pool, added[pool], removed[pool])
+	}
+
+	if _, err := fmt.Fprintf(w, "Comparing %s with %s: %d changes\n", from.timestamp, to.timestamp, len(lines)); err != nil {
+		return err
+	}
+	for _, line := range lines {
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// objectName qualifies vdev names with their pool, as vdev names are only unique within a pool
+func objectName(o inventoryObject) string {
+	if o.kind == `vdev` {
+		return o.pool + `/` + o.name
+	}
+	return o.name
+}
+
+func quoteEmpty(v string) string {
+	if v == `` {
+		return `""`
+	}
+	return v
+}
+
+// formatSize formats a size in bytes with a binary unit, leaving values that are not numeric, such as "none",
+// unchanged
+func formatSize(v string) string {
+	n, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return quoteEmpty(v)
+	}
+	return humanBytes(n)
+}
+
+func formatSizeChange(before, after string) string {
+	b, errB := strconv.ParseFloat(before, 64)
+	a, errA := strconv.ParseFloat(after, 64)
+	if errB != nil || errA != nil {
+		return `changed`
+	}
+	sign := `+`
+	if a < b {
+		sign = `-`
+	}
+	change := sign + humanBytes(math.Abs(a-b))
+	if b > 0 {
+		change += fmt.Sprintf(", %s%.1f%%", sign, math.Abs(a-b)/b*100)
+	}
+	return change
+}
+
+func humanBytes(n float64) string {
+	const units = `KMGTPE`
+	if n < 1024 {
+		return fmt.Sprintf("%.0f B", n)
+	}
+	i := -1
+	for n >= 1024 && i < len(units)-1 {
+		n /= 1024
+		i++
+	}
+	return fmt.Sprintf("%.1f %ciB", n, units[i])
+}
+
+// diffFiles reports the changes between two saved inventories on stdout
+func diffFiles(fromPath, toPath string) error {
+	from, err := readInventoryFile(fromPath)
+	if err != nil {
+		return err
+	}
+	to, err := readInventoryFile(toPath)
+	if err != nil {
+		return err
+	}
+	return writeDiff(os.Stdout, from, to)
+}
+
+// diffLiveInventory reports the changes between a saved inventory and the current state of the host on stdout
+func diffLiveInventory(fromPath string, inv inventory) error {
+	from, err := readInventoryFile(fromPath)
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	if err = inv.write(&buf, time.Now()); err != nil {
+		return err
+	}
+	to, err := readInventory(&buf)
+	if err != nil {
+		return err
+	}
+	return writeDiff(os.Stdout, from, to)
+}
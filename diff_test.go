@@ -0,0 +1,62 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestWriteDiff(t *testing.T) {
+	const before = `timestamp,kind,pool,name,property,value,source
+2025-01-01T00:00:00Z,pool,tank,tank,allocated,1073741824,NONE
+2025-01-01T00:00:00Z,pool,tank,tank,health,ONLINE,NONE
+2025-01-01T00:00:00Z,vdev,tank,sda,state,ONLINE,
+2025-01-01T00:00:00Z,vdev,tank,sdb,state,ONLINE,
+2025-01-01T00:00:00Z,filesystem,tank,tank/old,used,1024,NONE
+2025-01-01T00:00:00Z,filesystem,tank,tank/home,quota,none,LOCAL
+2025-01-01T00:00:00Z,snapshot,tank,tank/home@1,used,0,NONE
+2025-01-01T00:00:00Z,snapshot,tank,tank/home@2,used,0,NONE
+`
+	const after = `timestamp,kind,pool,name,property,value,source
+2025-02-01T00:00:00Z,pool,tank,tank,allocated,1610612736,NONE
+2025-02-01T00:00:00Z,pool,tank,tank,health,DEGRADED,NONE
+2025-02-01T00:00:00Z,vdev,tank,sda,state,ONLINE,
+2025-02-01T00:00:00Z,vdev,tank,sdb,state,FAULTED,
+2025-02-01T00:00:00Z,vdev,tank,sdc,state,ONLINE,
+2025-02-01T00:00:00Z,filesystem,tank,tank/home,quota,10737418240,LOCAL
+2025-02-01T00:00:00Z,filesystem,tank,tank/new,used,1024,NONE
+2025-02-01T00:00:00Z,snapshot,tank,tank/home@2,used,0,NONE
+2025-02-01T00:00:00Z,snapshot,tank,tank/home@3,used,0,NONE
+2025-02-01T00:00:00Z,snapshot,tank,tank/home@4,used,0,NONE
+`
+	const expected = `Comparing 2025-01-01T00:00:00Z with 2025-02-01T00:00:00Z: 8 changes
+pool tank: health ONLINE -> DEGRADED
+pool tank: allocated 1.0 GiB -> 1.5 GiB (+512.0 MiB, +50.0%)
+vdev tank/sdb: state ONLINE -> FAULTED
+vdev tank/sdc: added
+filesystem tank/old: removed
+filesystem tank/home: quota none -> 10.0 GiB (changed)
+filesystem tank/new: added
+snapshots tank: 2 added, 1 removed
+`
+
+	from, err := readInventory(strings.NewReader(before))
+	if err != nil {
+		t.Fatal(err)
+	}
+	to, err := readInventory(strings.NewReader(after))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err = writeDiff(&buf, from, to); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != expected {
+		t.Errorf("Unexpected diff:\n%s", buf.String())
+	}
+
+	if _, err = readInventory(strings.NewReader("name,value\n")); err == nil {
+		t.Error("Expected error reading a file that is not an inventory export")
+	}
+}
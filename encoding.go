@@ -0,0 +1,136 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/munnerz/goautoneg"
+	"github.com/vmihailenco/msgpack/v5"
+	"go.yaml.in/yaml/v2"
+)
+
+// apiEncodeFunc encodes a response of the API
+type apiEncodeFunc func(v any) ([]byte, error)
+
+var (
+	// apiEncoders are the encoders of API responses, keyed by media type
+	apiEncoders = make(map[string]apiEncodeFunc)
+	// apiMediaTypes are the media types of apiEncoders in order of preference, the first being the default
+	apiMediaTypes []string
+)
+
+func init() {
+	registerAPIEncoder(encodeJSON, `application/json`)
+	registerAPIEncoder(encodeYAML, `application/yaml`, `application/x-yaml`, `text/yaml`)
+	registerAPIEncoder(encodeMsgpack, `application/msgpack`, `application/x-msgpack`, `application/vnd.msgpack`)
+}
+
+// registerAPIEncoder registers an encoder of API responses for the media types, of which the first is preferred where
+// the client accepts several
+func registerAPIEncoder(encode apiEncodeFunc, mediaTypes ...string) {
+	for _, mediaType := range mediaTypes {
+		apiEncoders[mediaType] = encode
+		apiMediaTypes = append(apiMediaTypes, mediaType)
+	}
+}
+
+// negotiateAPIMediaType returns the media type of the response to a request with the Accept header, JSON where the
+// header is absent, and false where none of the media types is acceptable
+func negotiateAPIMediaType(accept string) (string, bool) {
+	if accept == `` {
+		return apiMediaTypes[0], true
+	}
+	mediaType := goautoneg.Negotiate(accept, apiMediaTypes)
+	return mediaType, mediaType != ``
+}
+
+// writeAPI writes a response of the API in the media type negotiated with the Accept header of the request
+func writeAPI(w http.ResponseWriter, r *http.Request, v any) {
+	w.Header().Add(`Vary`, `Accept`)
+	mediaType, ok := negotiateAPIMediaType(r.Header.Get(`Accept`))
+	if !ok {
+		http.Error(w, `Accept must include one of the media types: `+strings.Join(apiMediaTypes, `, `), http.StatusNotAcceptable)
+		return
+	}
+	data, err := apiEncoders[mediaType](v)
+	if err != nil {
+		http.Error(w, `error encoding response: `+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set(`Content-Type`, mediaType)
+	_, _ = w.Write(data)
+}
+
+func encodeJSON(v any) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+func encodeYAML(v any) ([]byte, error) {
+	doc, err := apiDocument(v)
+	if err != nil {
+		return nil, err
+	}
+	return yaml.Marshal(doc)
+}
+
+func encodeMsgpack(v any) ([]byte, error) {
+	doc, err := apiDocument(v)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	enc := msgpack.NewEncoder(&buf)
+	enc.SetSortMapKeys(true)
+	if err = enc.Encode(doc); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// apiDocument returns the JSON encoding of v as maps, slices and scalars, so that every format encodes the same
+// document, with the field names, omitted fields and string-encoded GUIDs of the JSON encoding
+func apiDocument(v any) (any, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var doc any
+	if err = dec.Decode(&doc); err != nil {
+		return nil, err
+	}
+	return apiNumbers(doc), nil
+}
+
+// apiNumbers replaces the numbers of a decoded JSON document with integers where they are integral, rather than
+// floats, which would lose precision and be encoded in exponent form
+func apiNumbers(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for k, e := range v {
+			v[k] = apiNumbers(e)
+		}
+	case []any:
+		for i, e := range v {
+			v[i] = apiNumbers(e)
+		}
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+		if u, err := strconv.ParseUint(v.String(), 10, 64); err == nil {
+			return u
+		}
+		f, _ := v.Float64()
+		return f
+	}
+	return v
+}
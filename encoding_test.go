@@ -0,0 +1,74 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/vmihailenco/msgpack/v5"
+	"go.yaml.in/yaml/v2"
+)
+
+func TestWriteAPI(t *testing.T) {
+	resolved := resolvedGUID{GUID: 0x5d7d4a1b2c3e4f60, Kind: `vdev`, Pool: `tank`, Name: `sda`, State: `FAULTED`}
+	serve := func(accept string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, `/api/v1/resolve`, nil)
+		if accept != `` {
+			r.Header.Set(`Accept`, accept)
+		}
+		rec := httptest.NewRecorder()
+		writeAPI(rec, r, resolved)
+		return rec
+	}
+
+	const expectedJSON = `{"guid":"6736622098182983520","kind":"vdev","pool":"tank","name":"sda","state":"FAULTED"}` + "\n"
+	for _, accept := range []string{``, `*/*`, `application/json`, `text/html, application/json;q=0.9`} {
+		rec := serve(accept)
+		if rec.Code != http.StatusOK || rec.Header().Get(`Content-Type`) != `application/json` || rec.Body.String() != expectedJSON {
+			t.Errorf("Expected JSON for Accept '%s', got %d %s: %s", accept, rec.Code, rec.Header().Get(`Content-Type`), rec.Body.String())
+		}
+	}
+
+	// Other formats encode the document of the JSON encoding, keeping its field names and string GUIDs
+	expected := map[string]any{`guid`: `6736622098182983520`, `kind`: `vdev`, `pool`: `tank`, `name`: `sda`, `state`: `FAULTED`}
+	rec := serve(`application/json;q=0.5, application/yaml`)
+	if rec.Header().Get(`Content-Type`) != `application/yaml` {
+		t.Fatalf("Expected YAML, got %s", rec.Header().Get(`Content-Type`))
+	}
+	var doc map[string]any
+	if err := yaml.Unmarshal(rec.Body.Bytes(), &doc); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(doc, expected) {
+		t.Errorf("Expected %v in YAML, got %v", expected, doc)
+	}
+
+	rec = serve(`application/x-msgpack`)
+	if rec.Header().Get(`Content-Type`) != `application/x-msgpack` {
+		t.Fatalf("Expected msgpack, got %s", rec.Header().Get(`Content-Type`))
+	}
+	doc = nil
+	if err := msgpack.Unmarshal(rec.Body.Bytes(), &doc); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(doc, expected) {
+		t.Errorf("Expected %v in msgpack, got %v", expected, doc)
+	}
+
+	rec = serve(`text/html`)
+	if rec.Code != http.StatusNotAcceptable {
+		t.Errorf("Expected status %d for unsupported Accept, got %d", http.StatusNotAcceptable, rec.Code)
+	}
+}
+
+func TestAPINumbers(t *testing.T) {
+	doc, err := apiDocument(map[string]any{`small`: 3, `large`: uint64(18446744073709551615), `ratio`: 1.5, `list`: []int{1}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]any{`small`: int64(3), `large`: uint64(18446744073709551615), `ratio`: 1.5, `list`: []any{int64(1)}}
+	if !reflect.DeepEqual(doc, expected) {
+		t.Errorf("Expected %v, got %v", expected, doc)
+	}
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"bytes"
+	"hash/fnv"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/andybalholm/brotli"
+)
+
+const (
+	encodingBrotli = `br`
+	headerDataAge  = `X-Data-Age-Seconds`
+)
+
+// metricsHandler wraps the metrics handler, adding brotli compression on top of the encodings offered by promhttp,
+// conditional responses keyed on the collection generation, and headers describing the age of the collected data.
+type metricsHandler struct {
+	handler     http.Handler
+	generation  func() uint64
+	updated     func() time.Time
+	conditional bool
+}
+
+// freshnessWriter sets headers describing the age of the collected data immediately before the response is sent,
+// once collection for the request has completed.
+type freshnessWriter struct {
+	http.ResponseWriter
+	updated     func() time.Time
+	wroteHeader bool
+}
+
+func (w *freshnessWriter) WriteHeader(status int) {
+	if !w.wroteHeader {
+		w.wroteHeader = true
+		if updated := w.updated(); !updated.IsZero() {
+			w.Header().Set(`Last-Modified`, updated.UTC().Format(http.TimeFormat))
+			w.Header().Set(headerDataAge, strconv.FormatFloat(time.Since(updated).Seconds(), 'f', 3, 64))
+		}
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *freshnessWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// bufferedResponse captures a response so that it may be inspected or re-encoded before being sent.
+type bufferedResponse struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (r *bufferedResponse) Header() http.Header {
+	return r.header
+}
+
+func (r *bufferedResponse) Write(b []byte) (int, error) {
+	return r.body.Write(b)
+}
+
+func (r *bufferedResponse) WriteHeader(status int) {
+	r.status = status
+}
+
+func (h *metricsHandler) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	w := &freshnessWriter{ResponseWriter: rw, updated: h.updated}
+	useBrotli := acceptsEncoding(r.Header.Get(`Accept-Encoding`), encodingBrotli)
+	if !useBrotli && !h.conditional {
+		h.handler.ServeHTTP(w, r)
+		return
+	}
+
+	if useBrotli {
+		// Request an uncompressed response, we'll handle encoding ourselves.
+		r = r.Clone(r.Context())
+		r.Header.Del(`Accept-Encoding`)
+	}
+
+	resp := &bufferedResponse{header: make(http.Header), status: http.StatusOK}
+	h.handler.ServeHTTP(resp, r)
+	for k, v := range resp.header {
+		w.Header()[k] = v
+	}
+	if resp.status != http.StatusOK {
+		w.WriteHeader(resp.status)
+		_, _ = w.Write(resp.body.Bytes())
+		return
+	}
+
+	if h.conditional {
+		etag := entityTag(h.generation(), w.Header())
+		w.Header().Set(`ETag`, etag)
+		if matchesETag(r.Header.Get(`If-None-Match`), etag) {
+			w.Header().Del(`Content-Encoding`)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+	}
+
+	if !useBrotli {
+		_, _ = w.Write(resp.body.Bytes())
+		return
+	}
+
+	w.Header().Set(`Content-Encoding`, encodingBrotli)
+	w.Header().Add(`Vary`, `Accept-Encoding`)
+	bw := brotli.NewWriterLevel(w, brotli.DefaultCompression)
+	_, _ = bw.Write(resp.body.Bytes())
+	_ = bw.Close()
+}
+
+// entityTag returns a weak entity tag for the collection generation, distinguishing between exposition formats.
+func entityTag(generation uint64, header http.Header) string {
+	format := fnv.New32a()
+	_, _ = format.Write([]byte(header.Get(`Content-Type`)))
+	return `W/"` + strconv.FormatUint(generation, 10) + `-` + strconv.FormatUint(uint64(format.Sum32()), 16) + `"`
+}
+
+// acceptsEncoding reports whether the Accept-Encoding header value includes the encoding with a non-zero quality.
+func acceptsEncoding(header, encoding string) bool {
+	for _, part := range strings.Split(header, `,`) {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), `;`)
+		if !strings.EqualFold(strings.TrimSpace(name), encoding) {
+			continue
+		}
+		q, found := strings.CutPrefix(strings.TrimSpace(params), `q=`)
+		if !found {
+			return true
+		}
+		v, err := strconv.ParseFloat(q, 64)
+		return err == nil && v > 0
+	}
+
+	return false
+}
+
+// matchesETag reports whether the If-None-Match header value matches the entity tag, using weak comparison.
+func matchesETag(header, etag string) bool {
+	etag = strings.TrimPrefix(etag, `W/`)
+	for _, candidate := range strings.Split(header, `,`) {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == `*` || strings.TrimPrefix(candidate, `W/`) == etag {
+			return true
+		}
+	}
+
+	return false
+}
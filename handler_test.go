@@ -0,0 +1,147 @@
+package main
+
+import (
+	"bufio"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/andybalholm/brotli"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	dto "github.com/prometheus/client_model/go"
+	"google.golang.org/protobuf/encoding/protodelim"
+
+	"github.com/prometheus/common/expfmt"
+)
+
+func TestMetricsHandler(t *testing.T) {
+	const body = "zfs_pool_health{pool=\"testpool\"} 0\n"
+	var generation uint64 = 1
+	h := &metricsHandler{
+		handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if enc := r.Header.Get(`Accept-Encoding`); enc != `` {
+				t.Errorf("Expected Accept-Encoding to be stripped, got %q", enc)
+			}
+			_, _ = io.WriteString(w, body)
+		}),
+		generation:  func() uint64 { return generation },
+		updated:     func() time.Time { return time.Now().Add(-time.Minute) },
+		conditional: true,
+	}
+
+	req := httptest.NewRequest(http.MethodGet, `/metrics`, nil)
+	req.Header.Set(`Accept-Encoding`, `gzip, br;q=0.9`)
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, rec.Code)
+	}
+	if age := rec.Header().Get(headerDataAge); !strings.HasPrefix(age, `60.`) {
+		t.Errorf("Expected data age of 60s, got %q", age)
+	}
+	if enc := rec.Header().Get(`Content-Encoding`); enc != encodingBrotli {
+		t.Fatalf("Expected brotli encoding, got %q", enc)
+	}
+	decoded, err := io.ReadAll(brotli.NewReader(rec.Body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(decoded) != body {
+		t.Fatalf("Expected body %q, got %q", body, decoded)
+	}
+
+	etag := rec.Header().Get(`ETag`)
+	req = httptest.NewRequest(http.MethodGet, `/metrics`, nil)
+	req.Header.Set(`If-None-Match`, etag)
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNotModified {
+		t.Fatalf("Expected status %d, got %d", http.StatusNotModified, rec.Code)
+	}
+
+	generation++
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK || rec.Body.String() != body {
+		t.Fatalf("Expected fresh response after generation change, got %d %q", rec.Code, rec.Body.String())
+	}
+}
+
+func TestAcceptsEncoding(t *testing.T) {
+	testCases := []struct {
+		header string
+		want   bool
+	}{
+		{header: `br`, want: true},
+		{header: `gzip, br;q=0.5`, want: true},
+		{header: `gzip, br;q=0`, want: false},
+		{header: `gzip`, want: false},
+		{header: ``, want: false},
+	}
+
+	for _, tc := range testCases {
+		if got := acceptsEncoding(tc.header, encodingBrotli); got != tc.want {
+			t.Errorf("acceptsEncoding(%q) = %t, want %t", tc.header, got, tc.want)
+		}
+	}
+}
+
+func TestMetricsHandlerProtobuf(t *testing.T) {
+	desc := prometheus.NewDesc(`zfs_test_latency_seconds`, `Test latency.`, nil, nil)
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(constCollector{
+		desc: desc,
+		metric: prometheus.MustNewConstNativeHistogram(
+			desc, 3, 0.75, map[int]int64{-2: 1, 0: 2}, nil, 0, 0, 0, time.Time{},
+		),
+	})
+
+	h := &metricsHandler{
+		handler:     promhttp.HandlerFor(registry, promhttp.HandlerOpts{}),
+		generation:  func() uint64 { return 1 },
+		updated:     func() time.Time { return time.Time{} },
+		conditional: true,
+	}
+
+	req := httptest.NewRequest(http.MethodGet, `/metrics`, nil)
+	req.Header.Set(`Accept`, string(expfmt.NewFormat(expfmt.TypeProtoDelim)))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, rec.Code)
+	}
+
+	family := &dto.MetricFamily{}
+	if err := protodelim.UnmarshalFrom(bufio.NewReader(rec.Body), family); err != nil {
+		t.Fatal(err)
+	}
+	histogram := family.GetMetric()[0].GetHistogram()
+	if histogram.GetSampleCount() != 3 || len(histogram.GetPositiveSpan()) == 0 {
+		t.Fatalf("Expected native histogram, got %v", histogram)
+	}
+
+	// Text and protobuf representations must not share an entity tag.
+	textReq := httptest.NewRequest(http.MethodGet, `/metrics`, nil)
+	textRec := httptest.NewRecorder()
+	h.ServeHTTP(textRec, textReq)
+	if textRec.Header().Get(`ETag`) == rec.Header().Get(`ETag`) {
+		t.Fatalf("Expected distinct entity tags per format, got %q", rec.Header().Get(`ETag`))
+	}
+}
+
+type constCollector struct {
+	desc   *prometheus.Desc
+	metric prometheus.Metric
+}
+
+func (c constCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.desc
+}
+
+func (c constCollector) Collect(ch chan<- prometheus.Metric) {
+	ch <- c.metric
+}
@@ -0,0 +1,252 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// historySample is a single recorded value of a metric, stored as one JSON object per line
+type historySample struct {
+	Timestamp time.Time         `json:"timestamp"`
+	Metric    string            `json:"metric"`
+	Labels    map[string]string `json:"labels,omitempty"`
+	Value     float64           `json:"value"`
+}
+
+// history periodically records selected metrics to a local file, so that recent trends may be queried on hosts that
+// are not scraped by a time series database. Samples older than the retention period are discarded.
+type history struct {
+	logger    *slog.Logger
+	gatherer  prometheus.Gatherer
+	path      string
+	metrics   map[string]bool
+	retention time.Duration
+
+	mu      sync.Mutex
+	samples []historySample
+}
+
+// newHistory returns a history recording the named metrics to path, loading any samples previously recorded there
+func newHistory(logger *slog.Logger, gatherer prometheus.Gatherer, path string, metrics []string, retention time.Duration) (*history, error) {
+	h := &history{
+		logger:    logger,
+		gatherer:  gatherer,
+		path:      path,
+		metrics:   make(map[string]bool, len(metrics)),
+		retention: retention,
+	}
+	for _, metric := range metrics {
+		h.metrics[metric] = true
+	}
+
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return h, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var s historySample
+		// A partially written line, e.g. following a crash, is skipped rather than discarding the whole history
+		if err := json.Unmarshal(scanner.Bytes(), &s); err != nil {
+			logger.Warn("Skipping invalid history sample", "file", path, "err", err)
+			continue
+		}
+		h.samples = append(h.samples, s)
+	}
+
+	return h, scanner.Err()
+}
+
+// Run records samples until ctx is done, waiting for the duration returned by interval before each sample
+func (h *history) Run(ctx context.Context, interval func() time.Duration) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval()):
+		}
+		if err := h.record(time.Now()); err != nil {
+			h.logger.Error("Error recording history", "err", err)
+		}
+	}
+}
+
+// record gathers the selected metrics, appends them to the history and discards expired samples
+func (h *history) record(now time.Time) error {
+	families, err := h.gatherer.Gather()
+	if err != nil {
+		// Gather returns whatever could be collected along with the error
+		h.logger.Warn("Error gathering metrics for history", "err", err)
+	}
+
+	var samples []historySample
+	for _, family := range families {
+		if !h.metrics[family.GetName()] {
+			continue
+		}
+		for _, m := range family.GetMetric() {
+			v, ok := sampleValue(family.GetType(), m)
+			if !ok {
+				continue
+			}
+			s := historySample{Timestamp: now.UTC(), Metric: family.GetName(), Value: v}
+			if len(m.GetLabel()) > 0 {
+				s.Labels = make(map[string]string, len(m.GetLabel()))
+				for _, l := range m.GetLabel() {
+					s.Labels[l.GetName()] = l.GetValue()
+				}
+			}
+			samples = append(samples, s)
+		}
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.samples = append(h.samples, samples...)
+	cutoff := now.Add(-h.retention)
+	expired := 0
+	for expired < len(h.samples) && h.samples[expired].Timestamp.Before(cutoff) {
+		expired++
+	}
+	h.samples = h.samples[expired:]
+
+	if expired > 0 {
+		return h.rewrite()
+	}
+	return h.append(samples)
+}
+
+// sampleValue returns the value of a gauge, counter or untyped metric. Histograms and summaries are not recorded.
+func sampleValue(kind dto.MetricType, m *dto.Metric) (float64, bool) {
+	switch kind {
+	case dto.MetricType_GAUGE:
+		return m.GetGauge().GetValue(), true
+	case dto.MetricType_COUNTER:
+		return m.GetCounter().GetValue(), true
+	case dto.MetricType_UNTYPED:
+		return m.GetUntyped().GetValue(), true
+	}
+	return 0, false
+}
+
+// append adds samples to the end of the history file
+func (h *history) append(samples []historySample) error {
+	f, err := os.OpenFile(h.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return err
+	}
+	if err = writeSamples(f, samples); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// rewrite replaces the history file with the retained samples, via a temporary file so that the history is not lost
+// if the exporter stops part way through
+func (h *history) rewrite() error {
+	f, err := os.CreateTemp(filepath.Dir(h.path), filepath.Base(h.path)+`.*.tmp`)
+	if err != nil {
+		return err
+	}
+	if err = writeSamples(f, h.samples); err != nil {
+		_ = f.Close()
+		_ = os.Remove(f.Name())
+		return err
+	}
+	if err = f.Close(); err != nil {
+		_ = os.Remove(f.Name())
+		return err
+	}
+	return os.Rename(f.Name(), h.path)
+}
+
+func writeSamples(f *os.File, samples []historySample) error {
+	w := bufio.NewWriter(f)
+	enc := json.NewEncoder(w)
+	for _, s := range samples {
+		if err := enc.Encode(s); err != nil {
+			return err
+		}
+	}
+	return w.Flush()
+}
+
+// query returns the samples of the metric recorded within [from, to] whose labels include all of matchers. An empty
+// metric matches all metrics.
+func (h *history) query(metric string, matchers map[string]string, from, to time.Time) []historySample {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	result := make([]historySample, 0)
+	for _, s := range h.samples {
+		if (metric != `` && s.Metric != metric) || s.Timestamp.Before(from) || s.Timestamp.After(to) {
+			continue
+		}
+		matched := true
+		for k, v := range matchers {
+			if s.Labels[k] != v {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			result = append(result, s)
+		}
+	}
+	return result
+}
+
+// ServeHTTP lists recorded samples as JSON. The metric, from and to query parameters select the metric and time range,
+// where times are RFC 3339 or Unix timestamps, and any other parameters must match the labels of the samples, e.g.
+// ?metric=zfs_pool_allocated_bytes&pool=tank&from=2025-01-01T00:00:00Z
+func (h *history) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set(`Allow`, `GET`)
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	params := r.URL.Query()
+	from, to := time.Time{}, time.Now()
+	for name, dst := range map[string]*time.Time{`from`: &from, `to`: &to} {
+		if v := params.Get(name); v != `` {
+			t, err := parseHistoryTime(v)
+			if err != nil {
+				http.Error(w, name+` must be an RFC 3339 or Unix timestamp`, http.StatusBadRequest)
+				return
+			}
+			*dst = t
+		}
+	}
+	matchers := make(map[string]string)
+	for k := range params {
+		if k != `metric` && k != `from` && k != `to` {
+			matchers[k] = params.Get(k)
+		}
+	}
+
+	writeAPI(w, r, h.query(params.Get(`metric`), matchers, from, to))
+}
+
+func parseHistoryTime(v string) (time.Time, error) {
+	if secs, err := strconv.ParseInt(v, 10, 64); err == nil {
+		return time.Unix(secs, 0), nil
+	}
+	return time.Parse(time.RFC3339, v)
+}
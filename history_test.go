@@ -0,0 +1,63 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestHistory(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	allocated := prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: `zfs_pool_allocated_bytes`}, []string{`pool`})
+	other := prometheus.NewGauge(prometheus.GaugeOpts{Name: `zfs_other`})
+	registry.MustRegister(allocated, other)
+
+	path := filepath.Join(t.TempDir(), `history.jsonl`)
+	h, err := newHistory(discardLogger, registry, path, []string{`zfs_pool_allocated_bytes`}, 2*time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := range 4 {
+		allocated.WithLabelValues(`tank`).Set(float64(100 * (i + 1)))
+		allocated.WithLabelValues(`backup`).Set(1)
+		if err = h.record(start.Add(time.Duration(i) * time.Hour)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Samples older than the retention period are discarded from the file as well as from memory
+	h, err = newHistory(discardLogger, registry, path, nil, 2*time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(h.samples) != 6 {
+		t.Fatalf("Expected 6 retained samples, got %d", len(h.samples))
+	}
+
+	req := httptest.NewRequest(http.MethodGet, `/api/v1/history?metric=zfs_pool_allocated_bytes&pool=tank&from=2025-01-01T02:00:00Z&to=4102444800`, nil)
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Unexpected status %d: %s", rec.Code, rec.Body.String())
+	}
+	var samples []historySample
+	if err = json.Unmarshal(rec.Body.Bytes(), &samples); err != nil {
+		t.Fatal(err)
+	}
+	if len(samples) != 2 || samples[0].Value != 300 || samples[1].Value != 400 {
+		t.Errorf("Unexpected samples: %+v", samples)
+	}
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, `/api/v1/history?from=yesterday`, nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d for an invalid time, got %d", http.StatusBadRequest, rec.Code)
+	}
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/jmcgover/zfs_exporter/v2/zfs"
+)
+
+// inventoryHeader names the columns of an inventory export. Every property of every object is a separate row, so that
+// the export may be loaded into a single table regardless of which properties each release of ZFS reports.
+var inventoryHeader = []string{`timestamp`, `kind`, `pool`, `name`, `property`, `value`, `source`}
+
+// inventoryDatasetKinds are the kinds of dataset included in an inventory export
+var inventoryDatasetKinds = []zfs.DatasetKind{zfs.DatasetFilesystem, zfs.DatasetVolume, zfs.DatasetSnapshot}
+
+// inventory writes a point-in-time export of pools, vdevs, datasets and snapshots, along with all of their properties,
+// for ingestion into data warehouses used for capacity planning
+type inventory struct {
+	client zfs.Client
+	status map[string]zfs.PoolStatusT
+	// pools limits the export to the named pools, all pools are exported if empty
+	pools []string
+}
+
+// write exports the inventory as CSV, with rows ordered by pool, kind and name
+func (inv inventory) write(w io.Writer, now time.Time) error {
+	pools := inv.pools
+	if len(pools) == 0 {
+		for pool := range inv.status {
+			pools = append(pools, pool)
+		}
+		sort.Strings(pools)
+	}
+
+	poolProps, err := inv.client.PoolGet(`all`)
+	if err != nil {
+		return fmt.Errorf("pool properties: %w", err)
+	}
+
+	out := csv.NewWriter(w)
+	timestamp := now.UTC().Format(time.RFC3339)
+	if err = out.Write(inventoryHeader); err != nil {
+		return err
+	}
+	writeProps := func(kind, pool, name string, props map[string]zfs.PropertyT) {
+		for _, k := range sortedKeys(props) {
+			_ = out.Write([]string{timestamp, kind, pool, name, k, string(props[k].Value), props[k].Source.Type})
+		}
+	}
+
+	for _, pool := range pools {
+		p, ok := poolProps[pool]
+		if !ok {
+			return fmt.Errorf("pool %s missing from zpool get output", pool)
+		}
+		writeProps(`pool`, pool, pool, p.Properties)
+
+		seen := make(map[string]bool)
+		for _, vdev := range inv.status[pool].AllVdevs() {
+			if seen[vdev.Name] {
+				continue
+			}
+			seen[vdev.Name] = true
+			writeProps(`vdev`, pool, vdev.Name, vdevProperties(vdev))
+		}
+
+		datasets, err := inv.client.DatasetGet(pool, inventoryDatasetKinds, `all`)
+		if err != nil {
+			return fmt.Errorf("dataset properties of pool %s: %w", pool, err)
+		}
+		for _, name := range sortedKeys(datasets) {
+			writeProps(string(datasets[name].Kind()), pool, name, datasets[name].Properties)
+		}
+	}
+
+	out.Flush()
+	return out.Error()
+}
+
+// vdevProperties returns the fields reported for the vdev by zpool status in the same form as pool and dataset
+// properties, for which the source is left empty
+func vdevProperties(vdev zfs.VdevStatusT) map[string]zfs.PropertyT {
+	fields := map[string]string{
+		`vdev_type`:       vdev.VdevType,
+		`class`:           vdev.Class,
+		`parent`:          vdev.Parent,
+		`state`:           vdev.State,
+		`path`:            vdev.Path,
+		`read_errors`:     strconv.FormatUint(uint64(vdev.ReadErrors), 10),
+		`write_errors`:    strconv.FormatUint(uint64(vdev.WriteErrors), 10),
+		`checksum_errors`: strconv.FormatUint(uint64(vdev.ChecksumErrors), 10),
+	}
+	// GUIDs are only reported by JSON output
+	if vdev.Guid != 0 {
+		fields[`guid`] = strconv.FormatUint(uint64(vdev.Guid), 10)
+	}
+	props := make(map[string]zfs.PropertyT, len(fields))
+	for k, v := range fields {
+		if v != `` {
+			props[k] = zfs.PropertyT{Value: zfs.PropertyValue(v)}
+		}
+	}
+
+	return props
+}
+
+func sortedKeys[T any](m map[string]T) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+// writeInventoryFile writes the inventory to path, or to stdout if path is empty or "-"
+func writeInventoryFile(path string, inv inventory, now time.Time) error {
+	if path == `` || path == `-` {
+		return inv.write(os.Stdout, now)
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+	if err = inv.write(f, now); err != nil {
+		_ = f.Close()
+		return err
+	}
+
+	return f.Close()
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/jmcgover/zfs_exporter/v2/zfs"
+	"github.com/jmcgover/zfs_exporter/v2/zfs/mock_zfs"
+	"go.uber.org/mock/gomock"
+)
+
+func TestInventory(t *testing.T) {
+	const expected = `timestamp,kind,pool,name,property,value,source
+2026-01-02T03:04:05Z,pool,tank,tank,ashift,12,LOCAL
+2026-01-02T03:04:05Z,pool,tank,tank,comment,"a,b",LOCAL
+2026-01-02T03:04:05Z,vdev,tank,sda,checksum_errors,2,
+2026-01-02T03:04:05Z,vdev,tank,sda,class,normal,
+2026-01-02T03:04:05Z,vdev,tank,sda,guid,7,
+2026-01-02T03:04:05Z,vdev,tank,sda,parent,tank,
+2026-01-02T03:04:05Z,vdev,tank,sda,path,/dev/sda1,
+2026-01-02T03:04:05Z,vdev,tank,sda,read_errors,0,
+2026-01-02T03:04:05Z,vdev,tank,sda,state,ONLINE,
+2026-01-02T03:04:05Z,vdev,tank,sda,vdev_type,disk,
+2026-01-02T03:04:05Z,vdev,tank,sda,write_errors,0,
+2026-01-02T03:04:05Z,filesystem,tank,tank,used,1024,NONE
+2026-01-02T03:04:05Z,snapshot,tank,tank@daily,used,0,NONE
+`
+
+	ctrl := gomock.NewController(t)
+	client := mock_zfs.NewMockClient(ctrl)
+	client.EXPECT().PoolGet(`all`).Return(map[string]zfs.PoolListT{
+		`tank`: {Name: `tank`, Properties: map[string]zfs.PropertyT{
+			`ashift`:  {Value: `12`, Source: zfs.PropertySourceT{Type: `LOCAL`}},
+			`comment`: {Value: `a,b`, Source: zfs.PropertySourceT{Type: `LOCAL`}},
+		}},
+	}, nil)
+	client.EXPECT().DatasetGet(`tank`, inventoryDatasetKinds, `all`).Return(map[string]zfs.DatasetListT{
+		`tank@daily`: {Name: `tank@daily`, Type: `SNAPSHOT`, Properties: map[string]zfs.PropertyT{
+			`used`: {Value: `0`, Source: zfs.PropertySourceT{Type: `NONE`}},
+		}},
+		`tank`: {Name: `tank`, Type: `FILESYSTEM`, Properties: map[string]zfs.PropertyT{
+			`used`: {Value: `1024`, Source: zfs.PropertySourceT{Type: `NONE`}},
+		}},
+	}, nil)
+
+	inv := inventory{
+		client: client,
+		status: map[string]zfs.PoolStatusT{
+			`tank`: {Name: `tank`, Vdevs: map[string]zfs.VdevStatusT{
+				`tank`: {Name: `tank`, VdevType: `root`, State: `ONLINE`, Guid: 42, Vdevs: map[string]zfs.VdevStatusT{
+					`sda`: {Name: `sda`, Guid: 7, VdevType: `disk`, Class: `normal`, Parent: `tank`, State: `ONLINE`, Path: `/dev/sda1`, ChecksumErrors: 2},
+				}},
+			}},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := inv.write(&buf, time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != expected {
+		t.Errorf("Unexpected inventory:\n%s", buf.String())
+	}
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"log/syslog"
+	"os"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Destinations of log messages
+const (
+	logOutputStderr = `stderr`
+	logOutputFile   = `file`
+	logOutputSyslog = `syslog`
+)
+
+// logWriter returns the destination of log messages for the output. Files are appended to, so that the log survives a
+// restart. Messages are sent to syslog with the daemon facility at info priority, as each message includes its level.
+func logWriter(output, path string) (io.Writer, error) {
+	switch output {
+	case logOutputStderr:
+		return os.Stderr, nil
+	case logOutputFile:
+		if path == `` {
+			return nil, errors.New("--log.file is required with --log.output=file")
+		}
+		return os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	case logOutputSyslog:
+		return syslog.New(syslog.LOG_DAEMON|syslog.LOG_INFO, `zfs_exporter`)
+	}
+	return nil, fmt.Errorf("unknown log output: %s", output)
+}
+
+// countedLevels are the levels for which log messages are counted, so that degradations that are only logged may be
+// alerted on
+var countedLevels = []slog.Level{slog.LevelWarn, slog.LevelError}
+
+// countingHandler wraps a slog.Handler, counting messages logged at warning level or above. Such messages are counted
+// even when the wrapped handler is configured to discard them.
+type countingHandler struct {
+	slog.Handler
+	messages *prometheus.CounterVec
+}
+
+func newCountingHandler(h slog.Handler) countingHandler {
+	messages := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: `zfs_exporter`,
+		Name:      `log_messages_total`,
+		Help:      `Number of log messages emitted at warning level or above, by level.`,
+	}, []string{`level`})
+	for _, level := range countedLevels {
+		messages.WithLabelValues(levelLabel(level))
+	}
+
+	return countingHandler{Handler: h, messages: messages}
+}
+
+func (h countingHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return level >= slog.LevelWarn || h.Handler.Enabled(ctx, level)
+}
+
+func (h countingHandler) Handle(ctx context.Context, r slog.Record) error {
+	if r.Level >= slog.LevelWarn {
+		level := slog.LevelWarn
+		if r.Level >= slog.LevelError {
+			level = slog.LevelError
+		}
+		h.messages.WithLabelValues(levelLabel(level)).Inc()
+	}
+	if !h.Handler.Enabled(ctx, r.Level) {
+		return nil
+	}
+
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h countingHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return countingHandler{Handler: h.Handler.WithAttrs(attrs), messages: h.messages}
+}
+
+func (h countingHandler) WithGroup(name string) slog.Handler {
+	return countingHandler{Handler: h.Handler.WithGroup(name), messages: h.messages}
+}
+
+// Describe implements the prometheus.Collector interface.
+func (h countingHandler) Describe(ch chan<- *prometheus.Desc) {
+	h.messages.Describe(ch)
+}
+
+// Collect implements the prometheus.Collector interface.
+func (h countingHandler) Collect(ch chan<- prometheus.Metric) {
+	h.messages.Collect(ch)
+}
+
+func levelLabel(level slog.Level) string {
+	return strings.ToLower(level.String())
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestCountingHandler(t *testing.T) {
+	const result = `# HELP zfs_exporter_log_messages_total Number of log messages emitted at warning level or above, by level.
+# TYPE zfs_exporter_log_messages_total counter
+zfs_exporter_log_messages_total{level="error"} 1
+zfs_exporter_log_messages_total{level="warn"} 2
+`
+
+	var buf bytes.Buffer
+	handler := newCountingHandler(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelError}))
+	logger := slog.New(handler).With("collector", "pool")
+
+	logger.Info("Ignored")
+	logger.Warn("Pool unavailable", "pool", "tank")
+	logger.WithGroup("zfs").Warn("Command slow")
+	logger.Error("Command failed")
+
+	if err := testutil.CollectAndCompare(handler, strings.NewReader(result)); err != nil {
+		t.Error(err)
+	}
+	if out := buf.String(); strings.Count(out, "\n") != 1 || !strings.Contains(out, `msg="Command failed" collector=pool`) {
+		t.Errorf("Expected only the error to be written, got %q", out)
+	}
+}
+
+func TestLogWriter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), `zfs_exporter.log`)
+	for _, line := range []string{"first\n", "second\n"} {
+		w, err := logWriter(logOutputFile, path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err = io.WriteString(w, line); err != nil {
+			t.Fatal(err)
+		}
+		_ = w.(io.Closer).Close()
+	}
+	if data, err := os.ReadFile(path); err != nil || string(data) != "first\nsecond\n" {
+		t.Errorf("Expected the log file to be appended to, got %q (%v)", data, err)
+	}
+
+	if w, err := logWriter(logOutputStderr, ``); err != nil || w != os.Stderr {
+		t.Errorf("Expected stderr, got %v (%v)", w, err)
+	}
+	if _, err := logWriter(logOutputFile, ``); err == nil {
+		t.Error("Expected error for a file output without a path")
+	}
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// hostMaintenance is the pool name under which maintenance windows covering the whole host are recorded
+const hostMaintenance = ``
+
+var maintenanceActiveDesc = prometheus.NewDesc(
+	`zfs_maintenance_active`,
+	`Whether a maintenance window is active for the pool (1) or not (0). An empty pool label denotes the whole host.`,
+	[]string{`pool`},
+	nil,
+)
+
+// maintenanceWindow is the JSON representation of a maintenance window
+type maintenanceWindow struct {
+	Pool string    `json:"pool"`
+	End  time.Time `json:"end"`
+}
+
+// maintenance records the pools, or the host, that are undergoing planned maintenance. Metrics continue to be
+// collected during maintenance, but zfs_maintenance_active allows alerts to be silenced, and remediation hooks may be
+// suppressed.
+type maintenance struct {
+	logger *slog.Logger
+	now    func() time.Time
+	mu     sync.Mutex
+	// windows maps pool names to the end of their maintenance window
+	windows map[string]time.Time
+}
+
+func newMaintenance(logger *slog.Logger) *maintenance {
+	return &maintenance{logger: logger, now: time.Now, windows: make(map[string]time.Time)}
+}
+
+// start begins a maintenance window of the given duration, replacing any existing window for the pool
+func (m *maintenance) start(pool string, duration time.Duration) maintenanceWindow {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	end := m.now().Add(duration)
+	m.windows[pool] = end
+	m.logger.Info("Maintenance window started", "pool", pool, "end", end)
+
+	return maintenanceWindow{Pool: pool, End: end}
+}
+
+// end finishes the maintenance window of the pool, if any
+func (m *maintenance) end(pool string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.windows[pool]; ok {
+		delete(m.windows, pool)
+		m.logger.Info("Maintenance window ended", "pool", pool)
+	}
+}
+
+// active reports whether the pool, or the whole host, is in a maintenance window
+func (m *maintenance) active(pool string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.prune()
+	_, host := m.windows[hostMaintenance]
+	_, ok := m.windows[pool]
+
+	return host || ok
+}
+
+// list returns the active maintenance windows, ordered by pool
+func (m *maintenance) list() []maintenanceWindow {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.prune()
+	windows := make([]maintenanceWindow, 0, len(m.windows))
+	for pool, end := range m.windows {
+		windows = append(windows, maintenanceWindow{Pool: pool, End: end})
+	}
+	sort.Slice(windows, func(i, j int) bool { return windows[i].Pool < windows[j].Pool })
+
+	return windows
+}
+
+// prune removes expired windows, the caller must hold the lock
+func (m *maintenance) prune() {
+	now := m.now()
+	for pool, end := range m.windows {
+		if !now.Before(end) {
+			delete(m.windows, pool)
+			m.logger.Info("Maintenance window expired", "pool", pool)
+		}
+	}
+}
+
+// Describe implements the prometheus.Collector interface.
+func (m *maintenance) Describe(ch chan<- *prometheus.Desc) {
+	ch <- maintenanceActiveDesc
+}
+
+// Collect implements the prometheus.Collector interface. The host is always reported, pools only while in maintenance.
+func (m *maintenance) Collect(ch chan<- prometheus.Metric) {
+	host := 0.0
+	for _, w := range m.list() {
+		if w.Pool == hostMaintenance {
+			host = 1
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(maintenanceActiveDesc, prometheus.GaugeValue, 1, w.Pool)
+	}
+	ch <- prometheus.MustNewConstMetric(maintenanceActiveDesc, prometheus.GaugeValue, host, hostMaintenance)
+}
+
+// ServeHTTP lists maintenance windows on GET, starts a window on POST with the pool and duration query parameters, and
+// ends a window on DELETE with the pool query parameter. Omitting the pool applies to the whole host.
+func (m *maintenance) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	pool := r.URL.Query().Get(`pool`)
+	switch r.Method {
+	case http.MethodGet:
+		writeAPI(w, r, m.list())
+	case http.MethodPost:
+		duration, err := time.ParseDuration(r.URL.Query().Get(`duration`))
+		if err != nil || duration <= 0 {
+			http.Error(w, `duration must be a positive duration, e.g. 2h`, http.StatusBadRequest)
+			return
+		}
+		writeAPI(w, r, m.start(pool, duration))
+	case http.MethodDelete:
+		m.end(pool)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.Header().Set(`Allow`, `GET, POST, DELETE`)
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+	}
+}
+
+// parseMaintenanceWindow parses a maintenance window of the form [POOL:]DURATION. Pool names may contain colons, so
+// the duration follows the last.
+func parseMaintenanceWindow(spec string) (string, time.Duration, error) {
+	pool, value := hostMaintenance, spec
+	if i := strings.LastIndex(spec, `:`); i >= 0 {
+		pool, value = spec[:i], spec[i+1:]
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		return ``, 0, fmt.Errorf("invalid maintenance window '%s': %w", spec, err)
+	}
+	if duration <= 0 {
+		return ``, 0, fmt.Errorf("invalid maintenance window '%s': duration must be positive", spec)
+	}
+
+	return pool, duration, nil
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+var discardLogger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+func TestMaintenance(t *testing.T) {
+	now := time.Date(2025, 7, 1, 12, 0, 0, 0, time.UTC)
+	m := newMaintenance(discardLogger)
+	m.now = func() time.Time { return now }
+
+	m.start(`tank`, time.Hour)
+	if !m.active(`tank`) || m.active(`backup`) {
+		t.Fatal("Expected only tank to be in maintenance")
+	}
+	m.start(hostMaintenance, 30*time.Minute)
+	if !m.active(`backup`) {
+		t.Fatal("Expected host maintenance to cover every pool")
+	}
+
+	const result = `# HELP zfs_maintenance_active Whether a maintenance window is active for the pool (1) or not (0). An empty pool label denotes the whole host.
+# TYPE zfs_maintenance_active gauge
+zfs_maintenance_active{pool=""} 0
+zfs_maintenance_active{pool="tank"} 1
+`
+	now = now.Add(45 * time.Minute)
+	if err := testutil.CollectAndCompare(m, bytes.NewBufferString(result)); err != nil {
+		t.Fatal(err)
+	}
+
+	m.end(`tank`)
+	if m.active(`tank`) {
+		t.Error("Expected tank maintenance to have ended")
+	}
+}
+
+func TestMaintenanceHandler(t *testing.T) {
+	m := newMaintenance(discardLogger)
+
+	rec := httptest.NewRecorder()
+	m.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, `/api/v1/maintenance?pool=tank&duration=2h`, nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	m.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, `/api/v1/maintenance`, nil))
+	var windows []maintenanceWindow
+	if err := json.NewDecoder(rec.Body).Decode(&windows); err != nil {
+		t.Fatal(err)
+	}
+	if len(windows) != 1 || windows[0].Pool != `tank` {
+		t.Errorf("Expected a window for tank, got %+v", windows)
+	}
+
+	rec = httptest.NewRecorder()
+	m.ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, `/api/v1/maintenance?pool=tank`, nil))
+	if rec.Code != http.StatusNoContent || m.active(`tank`) {
+		t.Errorf("Expected tank maintenance to have ended, got status %d", rec.Code)
+	}
+
+	for _, duration := range []string{``, `-1h`, `soon`} {
+		rec = httptest.NewRecorder()
+		m.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, `/api/v1/maintenance?duration=`+duration, nil))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("Expected status %d for duration %q, got %d", http.StatusBadRequest, duration, rec.Code)
+		}
+	}
+}
+
+func TestParseMaintenanceWindow(t *testing.T) {
+	testCases := []struct {
+		spec     string
+		pool     string
+		duration time.Duration
+		err      bool
+	}{
+		{spec: `2h`, pool: hostMaintenance, duration: 2 * time.Hour},
+		{spec: `tank:30m`, pool: `tank`, duration: 30 * time.Minute},
+		{spec: `tank:backup:1h`, pool: `tank:backup`, duration: time.Hour},
+		{spec: `tank`, err: true},
+		{spec: `tank:0s`, err: true},
+	}
+
+	for _, tc := range testCases {
+		pool, duration, err := parseMaintenanceWindow(tc.spec)
+		if (err != nil) != tc.err {
+			t.Errorf("%s: unexpected error state: %v", tc.spec, err)
+			continue
+		}
+		if pool != tc.pool || duration != tc.duration {
+			t.Errorf("%s: expected %q %s, got %q %s", tc.spec, tc.pool, tc.duration, pool, duration)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"log/slog"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// metricMetadata describes a metric served by the exporter
+type metricMetadata struct {
+	Name   string   `json:"name"`
+	Type   string   `json:"type"`
+	Help   string   `json:"help"`
+	Labels []string `json:"labels"`
+	// Collector is the ZFS collector that exported the metric, empty for metrics of the exporter itself
+	Collector string `json:"collector,omitempty"`
+}
+
+// metadataHandler serves the metadata of every metric currently served, for dashboard generators and documentation
+// tooling
+type metadataHandler struct {
+	logger   *slog.Logger
+	gatherer prometheus.Gatherer
+	origins  func() map[string]string
+}
+
+// ServeHTTP lists the metadata of the metrics as JSON, sorted by name, on GET. Metrics are gathered as for a scrape, so
+// metrics without series, such as those of pools that are not imported, are omitted.
+func (h metadataHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set(`Allow`, `GET`)
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	families, err := h.gatherer.Gather()
+	if err != nil {
+		// Gather returns whatever could be collected along with the error
+		h.logger.Warn("Error gathering metrics for metadata", "err", err)
+	}
+	origins := h.origins()
+	result := make([]metricMetadata, 0, len(families))
+	for _, family := range families {
+		seen := make(map[string]bool)
+		labels := make([]string, 0)
+		for _, m := range family.GetMetric() {
+			for _, label := range m.GetLabel() {
+				if !seen[label.GetName()] {
+					seen[label.GetName()] = true
+					labels = append(labels, label.GetName())
+				}
+			}
+		}
+		sort.Strings(labels)
+		result = append(result, metricMetadata{
+			Name:      family.GetName(),
+			Type:      strings.ToLower(family.GetType().String()),
+			Help:      family.GetHelp(),
+			Labels:    labels,
+			Collector: origins[family.GetName()],
+		})
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	writeAPI(w, r, result)
+}
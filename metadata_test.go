@@ -0,0 +1,48 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestMetadataHandler(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	health := prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: `zfs_pool_health`, Help: `Health of the pool.`}, []string{`pool`})
+	health.WithLabelValues(`tank`).Set(0)
+	messages := prometheus.NewCounterVec(prometheus.CounterOpts{Name: `zfs_exporter_log_messages_total`, Help: `Log messages.`}, []string{`level`})
+	messages.WithLabelValues(`warn`)
+	registry.MustRegister(health, messages)
+	h := metadataHandler{
+		logger:   discardLogger,
+		gatherer: registry,
+		origins:  func() map[string]string { return map[string]string{`zfs_pool_health`: `pool`} },
+	}
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, `/api/v1/metrics-metadata`, nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, rec.Code)
+	}
+	var metadata []metricMetadata
+	if err := json.NewDecoder(rec.Body).Decode(&metadata); err != nil {
+		t.Fatal(err)
+	}
+	want := []metricMetadata{
+		{Name: `zfs_exporter_log_messages_total`, Type: `counter`, Help: `Log messages.`, Labels: []string{`level`}},
+		{Name: `zfs_pool_health`, Type: `gauge`, Help: `Health of the pool.`, Labels: []string{`pool`}, Collector: `pool`},
+	}
+	if !reflect.DeepEqual(metadata, want) {
+		t.Errorf("Expected %+v, got %+v", want, metadata)
+	}
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, `/api/v1/metrics-metadata`, nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected status %d for POST, got %d", http.StatusMethodNotAllowed, rec.Code)
+	}
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"io"
+	"sort"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/expfmt"
+)
+
+// collectorSuccessMetric reports the outcome of each collector, from which the exit status of the once command is
+// determined
+const collectorSuccessMetric = `zfs_exporter_collector_success`
+
+// writeOnce performs a single collection, writing the metrics to w in the text exposition format, and returns the
+// names of the collectors that failed
+func writeOnce(w io.Writer, gatherer prometheus.Gatherer) ([]string, error) {
+	families, err := gatherer.Gather()
+	if err != nil {
+		return nil, err
+	}
+
+	var failed []string
+	enc := expfmt.NewEncoder(w, expfmt.NewFormat(expfmt.TypeTextPlain))
+	for _, mf := range families {
+		if err = enc.Encode(mf); err != nil {
+			return nil, err
+		}
+		if mf.GetName() != collectorSuccessMetric {
+			continue
+		}
+		for _, m := range mf.Metric {
+			if m.GetGauge().GetValue() != 0 {
+				continue
+			}
+			if name, ok := labelValue(m, `collector`); ok {
+				failed = append(failed, name)
+			}
+		}
+	}
+	sort.Strings(failed)
+
+	return failed, nil
+}
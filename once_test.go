@@ -0,0 +1,42 @@
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestWriteOnce(t *testing.T) {
+	const expected = `# HELP zfs_exporter_collector_success Whether the most recent run of a collector succeeded (1) or failed (0).
+# TYPE zfs_exporter_collector_success gauge
+zfs_exporter_collector_success{collector="arcstats"} 1
+zfs_exporter_collector_success{collector="pool"} 0
+zfs_exporter_collector_success{collector="vdev-io"} 0
+# HELP zfs_pool_health Health.
+# TYPE zfs_pool_health gauge
+zfs_pool_health{pool="tank"} 0
+`
+
+	registry := prometheus.NewRegistry()
+	success := prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: collectorSuccessMetric, Help: `Whether the most recent run of a collector succeeded (1) or failed (0).`}, []string{`collector`})
+	health := prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: `zfs_pool_health`, Help: `Health.`}, []string{`pool`})
+	registry.MustRegister(success, health)
+	success.WithLabelValues(`arcstats`).Set(1)
+	success.WithLabelValues(`vdev-io`).Set(0)
+	success.WithLabelValues(`pool`).Set(0)
+	health.WithLabelValues(`tank`)
+
+	var buf bytes.Buffer
+	failed, err := writeOnce(&buf, registry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != expected {
+		t.Errorf("Unexpected output:\n%s", buf.String())
+	}
+	if want := []string{`pool`, `vdev-io`}; !reflect.DeepEqual(failed, want) {
+		t.Errorf("Expected failed collectors %v, got %v", want, failed)
+	}
+}
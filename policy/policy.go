@@ -0,0 +1,138 @@
+// Package policy evaluates user-supplied CEL expressions that classify the health of pools.
+package policy
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/types"
+	"github.com/jmcgover/zfs_exporter/v2/zfs"
+)
+
+// Policy is a compiled health policy
+type Policy struct {
+	Name    string
+	program cel.Program
+}
+
+var env = newEnv()
+
+func newEnv() *cel.Env {
+	e, err := cel.NewEnv(
+		cel.Variable(`pool`, cel.MapType(cel.StringType, cel.DynType)),
+		cel.Variable(`now`, cel.IntType),
+	)
+	if err != nil {
+		panic(err)
+	}
+	return e
+}
+
+// Compile compiles the expression of a policy. The expression is evaluated for each pool, with the variables `pool`,
+// describing the status of the pool, and `now`, the current time in seconds since the epoch, and must return a number
+// or a bool, which is exported as the health of the pool.
+func Compile(name, expression string) (*Policy, error) {
+	ast, issues := env.Compile(expression)
+	if issues != nil && issues.Err() != nil {
+		return nil, fmt.Errorf("policy %s: %w", name, issues.Err())
+	}
+	switch ast.OutputType() {
+	case cel.IntType, cel.UintType, cel.DoubleType, cel.BoolType, cel.DynType:
+	default:
+		return nil, fmt.Errorf("policy %s: expression must return a number or bool, not %s", name, ast.OutputType())
+	}
+	program, err := env.Program(ast)
+	if err != nil {
+		return nil, fmt.Errorf("policy %s: %w", name, err)
+	}
+
+	return &Policy{Name: name, program: program}, nil
+}
+
+// CompileAll compiles the policies keyed by name, returning them ordered by name
+func CompileAll(expressions map[string]string) ([]*Policy, error) {
+	names := make([]string, 0, len(expressions))
+	for name := range expressions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	policies := make([]*Policy, 0, len(names))
+	for _, name := range names {
+		p, err := Compile(name, expressions[name])
+		if err != nil {
+			return nil, err
+		}
+		policies = append(policies, p)
+	}
+
+	return policies, nil
+}
+
+// Evaluate returns the health of the pool according to the policy
+func (p *Policy) Evaluate(pool zfs.PoolStatusT, now time.Time) (float64, error) {
+	out, _, err := p.program.Eval(map[string]any{
+		`pool`: poolInput(pool),
+		`now`:  now.Unix(),
+	})
+	if err != nil {
+		return 0, fmt.Errorf("policy %s: %w", p.Name, err)
+	}
+
+	switch v := out.(type) {
+	case types.Int:
+		return float64(v), nil
+	case types.Uint:
+		return float64(v), nil
+	case types.Double:
+		return float64(v), nil
+	case types.Bool:
+		if v {
+			return 1, nil
+		}
+		return 0, nil
+	default:
+		return 0, fmt.Errorf("policy %s: expression returned %s, not a number or bool", p.Name, out.Type())
+	}
+}
+
+// poolInput describes the pool to policies, using the field names of `zpool status --json`
+func poolInput(pool zfs.PoolStatusT) map[string]any {
+	vdevs := make([]any, 0)
+	for _, vdev := range pool.AllVdevs() {
+		vdevs = append(vdevs, map[string]any{
+			`name`:            vdev.Name,
+			`vdev_type`:       vdev.VdevType,
+			`class`:           vdev.Class,
+			`state`:           vdev.State,
+			`path`:            vdev.Path,
+			`leaf`:            len(vdev.Vdevs) == 0,
+			`read_errors`:     int64(vdev.ReadErrors),
+			`write_errors`:    int64(vdev.WriteErrors),
+			`checksum_errors`: int64(vdev.ChecksumErrors),
+		})
+	}
+	scan := pool.ScanStats
+
+	return map[string]any{
+		`name`:           pool.Name,
+		`state`:          pool.State,
+		`status`:         pool.Status,
+		`action`:         pool.Action,
+		`msgid`:          pool.Msgid,
+		`classification`: string(pool.Classify()),
+		`error_count`:    int64(pool.ErrorCount),
+		`vdevs`:          vdevs,
+		`scan_stats`: map[string]any{
+			`function`:   scan.Function,
+			`state`:      scan.State,
+			`start_time`: int64(scan.StartTime),
+			`end_time`:   int64(scan.EndTime),
+			`to_examine`: int64(scan.ToExamine),
+			`examined`:   int64(scan.Examined),
+			`errors`:     int64(scan.Errors),
+		},
+	}
+}
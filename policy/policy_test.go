@@ -0,0 +1,99 @@
+package policy
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jmcgover/zfs_exporter/v2/zfs"
+)
+
+var testPool = zfs.PoolStatusT{
+	Name:  `tank`,
+	State: `ONLINE`,
+	Vdevs: map[string]zfs.VdevStatusT{
+		`tank`: {Name: `tank`, VdevType: `root`, Vdevs: map[string]zfs.VdevStatusT{
+			`mirror-0`: {Name: `mirror-0`, VdevType: `mirror`, Class: `normal`, State: `ONLINE`, Vdevs: map[string]zfs.VdevStatusT{
+				`sda`: {Name: `sda`, VdevType: `disk`, Class: `normal`, State: `ONLINE`},
+				`sdb`: {Name: `sdb`, VdevType: `disk`, Class: `normal`, State: `ONLINE`, ChecksumErrors: 3},
+			}},
+		}},
+	},
+	ScanStats: zfs.ScanStatsT{Function: `SCRUB`, State: `FINISHED`, EndTime: 1700000000},
+}
+
+func TestEvaluate(t *testing.T) {
+	now := time.Unix(1700000000+40*86400, 0)
+	testCases := []struct {
+		name       string
+		expression string
+		want       float64
+	}{
+		{
+			name:       `int`,
+			expression: `pool.state != "ONLINE" ? 2 : pool.vdevs.exists(v, v.leaf && v.checksum_errors > 0) ? 1 : 0`,
+			want:       1,
+		},
+		{
+			name:       `bool`,
+			expression: `now - pool.scan_stats.end_time > 35 * 86400`,
+			want:       1,
+		},
+		{
+			name:       `double`,
+			expression: `double(pool.vdevs.filter(v, v.leaf && v.state == "ONLINE").size()) / 4.0`,
+			want:       0.5,
+		},
+		{
+			name:       `classification`,
+			expression: `pool.classification == "ok" && pool.name == "tank"`,
+			want:       1,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			p, err := Compile(tc.name, tc.expression)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := p.Evaluate(testPool, now)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tc.want {
+				t.Errorf("Expected %v, got %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestCompileInvalid(t *testing.T) {
+	for _, expression := range []string{`pool.state ==`, `"healthy"`, `unknown > 0`} {
+		if _, err := Compile(`invalid`, expression); err == nil {
+			t.Errorf("Expected error compiling %q", expression)
+		}
+	}
+}
+
+func TestEvaluateInvalidResult(t *testing.T) {
+	p, err := Compile(`dynamic`, `pool.state`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = p.Evaluate(testPool, time.Now()); err == nil {
+		t.Error("Expected error for a string result")
+	}
+}
+
+func TestCompileAll(t *testing.T) {
+	policies, err := CompileAll(map[string]string{`b`: `0`, `a`: `1`})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(policies) != 2 || policies[0].Name != `a` || policies[1].Name != `b` {
+		t.Errorf("Expected policies ordered by name, got %v", policies)
+	}
+	if _, err = CompileAll(map[string]string{`a`: `1`, `b`: `)`}); err == nil {
+		t.Error("Expected error for an invalid policy")
+	}
+}
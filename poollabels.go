@@ -0,0 +1,118 @@
+package main
+
+import (
+	"regexp"
+	"sort"
+
+	"github.com/jmcgover/zfs_exporter/v2/config"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"google.golang.org/protobuf/proto"
+)
+
+// poolLabel is the label identifying the pool of a series
+const poolLabel = `pool`
+
+type poolLabelRule struct {
+	pool   *regexp.Regexp
+	labels map[string]string
+}
+
+// poolLabelGatherer adds the labels configured for a pool to every gathered series with that pool label, so that
+// metadata such as the tier or site of a pool is maintained alongside the exporter rather than in relabelling rules.
+// Labels already present on a series are left unchanged.
+type poolLabelGatherer struct {
+	prometheus.Gatherer
+	rules []poolLabelRule
+}
+
+func newPoolLabelGatherer(g prometheus.Gatherer, pools []config.PoolLabels) (*poolLabelGatherer, error) {
+	rules := make([]poolLabelRule, 0, len(pools))
+	for _, pl := range pools {
+		re, err := regexp.Compile(`^(?:` + pl.Pool + `)$`)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, poolLabelRule{pool: re, labels: pl.Labels})
+	}
+
+	return &poolLabelGatherer{Gatherer: g, rules: rules}, nil
+}
+
+// labels returns the labels of the pool, where later rules take precedence over earlier ones
+func (g *poolLabelGatherer) labels(pool string) map[string]string {
+	var result map[string]string
+	for _, rule := range g.rules {
+		if !rule.pool.MatchString(pool) {
+			continue
+		}
+		if result == nil {
+			result = make(map[string]string)
+		}
+		for name, value := range rule.labels {
+			result[name] = value
+		}
+	}
+	return result
+}
+
+// Gather implements the prometheus.Gatherer interface
+func (g *poolLabelGatherer) Gather() ([]*dto.MetricFamily, error) {
+	families, err := g.Gatherer.Gather()
+	byPool := make(map[string]map[string]string)
+	for _, mf := range families {
+		labelled := false
+		for _, m := range mf.Metric {
+			pool, ok := labelValue(m, poolLabel)
+			if !ok {
+				continue
+			}
+			labels, ok := byPool[pool]
+			if !ok {
+				labels = g.labels(pool)
+				byPool[pool] = labels
+			}
+			if len(labels) == 0 {
+				continue
+			}
+			for name, value := range labels {
+				if _, exists := labelValue(m, name); !exists {
+					m.Label = append(m.Label, &dto.LabelPair{Name: proto.String(name), Value: proto.String(value)})
+				}
+			}
+			sort.Slice(m.Label, func(i, j int) bool { return m.Label[i].GetName() < m.Label[j].GetName() })
+			labelled = true
+		}
+		if labelled {
+			sortMetrics(mf.Metric)
+		}
+	}
+
+	return families, err
+}
+
+// sortMetrics restores the order of a family in which only some series were labelled, as sorted by the registry: by
+// the number of labels, then by label values
+func sortMetrics(metrics []*dto.Metric) {
+	sort.SliceStable(metrics, func(i, j int) bool {
+		a, b := metrics[i].Label, metrics[j].Label
+		if len(a) != len(b) {
+			return len(a) < len(b)
+		}
+		for n := range a {
+			if a[n].GetValue() != b[n].GetValue() {
+				return a[n].GetValue() < b[n].GetValue()
+			}
+		}
+		return false
+	})
+}
+
+func labelValue(m *dto.Metric, name string) (string, bool) {
+	for _, label := range m.Label {
+		if label.GetName() == name {
+			return label.GetValue(), true
+		}
+	}
+	return ``, false
+}
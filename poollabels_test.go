@@ -0,0 +1,52 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jmcgover/zfs_exporter/v2/config"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestPoolLabelGatherer(t *testing.T) {
+	const result = `# HELP zfs_pool_health Health.
+# TYPE zfs_pool_health gauge
+zfs_pool_health{pool="archive-1",site="berlin",tier="cold"} 0
+zfs_pool_health{pool="archive10",site="berlin"} 0
+zfs_pool_health{pool="tank",site="berlin",tier="hot"} 0
+# HELP zfs_vdev_health Health.
+# TYPE zfs_vdev_health gauge
+zfs_vdev_health{pool="tank",site="berlin",tier="ssd",vdev="sda"} 0
+# HELP zfs_exporter_build_info Build.
+# TYPE zfs_exporter_build_info gauge
+zfs_exporter_build_info 1
+`
+
+	registry := prometheus.NewRegistry()
+	health := prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: `zfs_pool_health`, Help: `Health.`}, []string{`pool`})
+	vdev := prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: `zfs_vdev_health`, Help: `Health.`}, []string{`pool`, `vdev`, `tier`})
+	build := prometheus.NewGauge(prometheus.GaugeOpts{Name: `zfs_exporter_build_info`, Help: `Build.`})
+	registry.MustRegister(health, vdev, build)
+	for _, pool := range []string{`archive-1`, `archive10`, `tank`} {
+		health.WithLabelValues(pool)
+	}
+	// Labels already present on a series are not overridden
+	vdev.WithLabelValues(`tank`, `sda`, `ssd`)
+	build.Set(1)
+
+	gatherer, err := newPoolLabelGatherer(registry, []config.PoolLabels{
+		{Pool: `.*`, Labels: map[string]string{`site`: `berlin`}},
+		// Pool regexes match the whole name, so archive10 is not matched
+		{Pool: `archive-\d`, Labels: map[string]string{`tier`: `archive`}},
+		{Pool: `tank`, Labels: map[string]string{`tier`: `hot`}},
+		// Later rules take precedence
+		{Pool: `archive-1`, Labels: map[string]string{`tier`: `cold`}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = testutil.GatherAndCompare(gatherer, strings.NewReader(result)); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,19 @@
+package main
+
+import (
+	"net/http"
+	"net/http/pprof"
+)
+
+const pprofPath = `/debug/pprof/`
+
+// registerPprof serves the Go runtime profiles of the exporter under pprofPath, for investigating leaks and CPU usage
+// of a long-running exporter. Importing net/http/pprof also registers these handlers on http.DefaultServeMux, so the
+// exporter serves its own mux to keep them behind --web.enable-pprof.
+func registerPprof(mux *http.ServeMux) {
+	mux.HandleFunc(pprofPath, pprof.Index)
+	mux.HandleFunc(pprofPath+`cmdline`, pprof.Cmdline)
+	mux.HandleFunc(pprofPath+`profile`, pprof.Profile)
+	mux.HandleFunc(pprofPath+`symbol`, pprof.Symbol)
+	mux.HandleFunc(pprofPath+`trace`, pprof.Trace)
+}
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRegisterPprof(t *testing.T) {
+	mux := http.NewServeMux()
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, pprofPath+`goroutine?debug=1`, nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("Expected status %d before registering, got %d", http.StatusNotFound, rec.Code)
+	}
+
+	registerPprof(mux)
+	for _, target := range []string{pprofPath, pprofPath + `goroutine?debug=1`, pprofPath + `cmdline`} {
+		rec = httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		if rec.Code != http.StatusOK {
+			t.Errorf("Expected status %d for %s, got %d", http.StatusOK, target, rec.Code)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"log/slog"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jmcgover/zfs_exporter/v2/collector"
+	"github.com/jmcgover/zfs_exporter/v2/config"
+	"github.com/jmcgover/zfs_exporter/v2/zfs"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const probePath = `/probe`
+
+// probeHandler collects the metrics of the remote host given by the target query parameter on each request, running
+// commands via ssh, so that one exporter may monitor appliances on which it cannot be installed
+type probeHandler struct {
+	logger *slog.Logger
+	// allowed restricts the targets that may be probed, no targets are allowed if both allowed and hosts are empty
+	allowed []*regexp.Regexp
+	// hosts holds the ssh settings of configured hosts, which may be probed regardless of allowed
+	hosts map[string]config.SSHHost
+	// runner returns the runner of commands on the target
+	runner         func(target string) zfs.Runner
+	commandTimeout time.Duration
+	iostatInterval time.Duration
+	// collector returns the ZFS collector of a target, given a client running commands on it
+	collector func(logger *slog.Logger, client zfs.Client, capabilities zfs.Capabilities) (prometheus.Collector, error)
+}
+
+// ServeHTTP probes the target, serving zfs_exporter_probe_success along with the metrics of the target where its ZFS
+// version could be determined
+func (h probeHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	target := r.URL.Query().Get(`target`)
+	if err := zfs.ValidateSSHTarget(target); err != nil {
+		http.Error(w, `target must be given as [USER@]HOST`, http.StatusBadRequest)
+		return
+	}
+	if !h.allow(target) {
+		http.Error(w, `target not allowed by --probe.allowed-target or ssh_hosts`, http.StatusForbidden)
+		return
+	}
+
+	logger := h.logger.With("target", target)
+	runner := h.runner(target)
+	registry := prometheus.NewRegistry()
+	success := prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: `zfs_exporter`,
+		Name:      `probe_success`,
+		Help:      `Whether the ZFS version of the target could be determined over ssh (1) or not (0).`,
+	})
+	registry.MustRegister(success)
+
+	ctx, cancel := zfs.CommandContext(h.commandTimeout)
+	_, capabilities, err := zfs.DetectCapabilities(ctx, runner, logger)
+	cancel()
+	if err == nil {
+		client := zfs.New(zfs.Config{Logger: logger, CommandTimeout: h.commandTimeout, Runner: runner, TextOutput: zfs.TextOutputRequired(capabilities), IostatInterval: h.iostatInterval})
+		var c prometheus.Collector
+		if c, err = h.collector(logger, client, capabilities); err == nil {
+			err = registry.Register(c)
+		}
+	}
+	if err != nil {
+		logger.Error("Error probing target", "err", err)
+	} else {
+		success.Set(1)
+	}
+
+	promhttp.HandlerFor(registry, promhttp.HandlerOpts{
+		ErrorLog:      slog.NewLogLogger(logger.Handler(), slog.LevelError),
+		ErrorHandling: promhttp.ContinueOnError,
+	}).ServeHTTP(w, r)
+}
+
+func (h probeHandler) allow(target string) bool {
+	if _, ok := h.hosts[probeHost(target)]; ok {
+		return true
+	}
+	for _, re := range h.allowed {
+		if re.MatchString(target) {
+			return true
+		}
+	}
+	return false
+}
+
+// newProbeCollector returns the collector of a target, with the given config applied to its client, which runs commands
+// on a remote host
+func newProbeCollector(config collector.ZFSConfig) func(*slog.Logger, zfs.Client, zfs.Capabilities) (prometheus.Collector, error) {
+	return func(l *slog.Logger, client zfs.Client, capabilities zfs.Capabilities) (prometheus.Collector, error) {
+		c := config
+		c.Logger = l
+		c.Capabilities = capabilities
+		c.ZFSClient = client
+		c.Remote = true
+		return collector.NewZFS(c)
+	}
+}
+
+// probeHost returns the host of a target given as [USER@]HOST
+func probeHost(target string) string {
+	return target[strings.LastIndexByte(target, '@')+1:]
+}
+
+// probeSSHRunner returns the runner of commands on the target, applying the settings of its host where configured
+func probeSSHRunner(base zfs.SSHRunner, hosts map[string]config.SSHHost, target string) zfs.SSHRunner {
+	base.Target = target
+	host, ok := hosts[probeHost(target)]
+	if !ok {
+		return base
+	}
+	if host.User != `` && !strings.Contains(target, `@`) {
+		base.Target = host.User + `@` + target
+	}
+	if host.Port != 0 {
+		base.Port = host.Port
+	}
+	if host.IdentityFile != `` {
+		base.IdentityFile = host.IdentityFile
+	}
+	// ssh uses the first value of an option, so the options of the host are given precedence
+	base.Options = append(slices.Clone(host.Options), base.Options...)
+	return base
+}
+
+// compileProbeTargets compiles the allowed probe target patterns, which must match the whole target
+func compileProbeTargets(patterns []string) ([]*regexp.Regexp, error) {
+	result := make([]*regexp.Regexp, len(patterns))
+	for i, pattern := range patterns {
+		re, err := regexp.Compile(`^(?:` + pattern + `)$`)
+		if err != nil {
+			return nil, err
+		}
+		result[i] = re
+	}
+	return result, nil
+}
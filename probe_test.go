@@ -0,0 +1,156 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jmcgover/zfs_exporter/v2/collector"
+	"github.com/jmcgover/zfs_exporter/v2/config"
+	"github.com/jmcgover/zfs_exporter/v2/zfs"
+	"github.com/jmcgover/zfs_exporter/v2/zfs/mock_zfs"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/mock/gomock"
+)
+
+// versionRunner reports a ZFS version without JSON support, or fails every command if down
+type versionRunner struct {
+	down bool
+}
+
+func (r versionRunner) Run(ctx context.Context, name string, args ...string) ([]byte, []byte, error) {
+	if r.down || len(args) != 1 {
+		return nil, nil, errors.New(`exit status 255`)
+	}
+	return []byte("zfs-2.1.5-1\nzfs-kmod-2.1.5-1\n"), nil, nil
+}
+
+func TestProbeHandler(t *testing.T) {
+	allowed, err := compileProbeTargets([]string{`nas[0-9]+`, `monitor@nas[0-9]+`})
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := probeHandler{
+		logger:  discardLogger,
+		allowed: allowed,
+		hosts:   map[string]config.SSHHost{`db1.example.com`: {Host: `db1.example.com`}},
+		runner: func(target string) zfs.Runner {
+			return versionRunner{down: target == `nas2`}
+		},
+		collector: func(l *slog.Logger, client zfs.Client, capabilities zfs.Capabilities) (prometheus.Collector, error) {
+			if capabilities[zfs.CapabilityJSON] {
+				t.Error("Expected capabilities of the target")
+			}
+			return prometheus.NewGaugeFunc(prometheus.GaugeOpts{Name: `zfs_pool_health`, Help: `.`}, func() float64 { return 0 }), nil
+		},
+	}
+
+	for _, tc := range []struct {
+		target string
+		code   int
+		want   []string
+	}{
+		{target: `monitor@nas1`, code: http.StatusOK, want: []string{`zfs_exporter_probe_success 1`, `zfs_pool_health 0`}},
+		{target: `nas2`, code: http.StatusOK, want: []string{`zfs_exporter_probe_success 0`}},
+		{target: `-oProxyCommand=sh`, code: http.StatusBadRequest},
+		{target: `db1`, code: http.StatusForbidden},
+		{target: `root@db1.example.com`, code: http.StatusOK, want: []string{`zfs_exporter_probe_success 1`}},
+	} {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, `/probe?target=`+tc.target, nil))
+		if rec.Code != tc.code {
+			t.Errorf("Expected status %d for %s, got %d", tc.code, tc.target, rec.Code)
+		}
+		for _, want := range tc.want {
+			if !strings.Contains(rec.Body.String(), want) {
+				t.Errorf("Expected %q for %s, got:\n%s", want, tc.target, rec.Body.String())
+			}
+		}
+		if tc.target == `nas2` && strings.Contains(rec.Body.String(), `zfs_pool_health`) {
+			t.Errorf("Expected no metrics of an unreachable target, got:\n%s", rec.Body.String())
+		}
+	}
+
+	// Without an allow-list, no target is allowed
+	h.allowed, h.hosts = nil, nil
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, `/probe?target=monitor@nas1`, nil))
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("Expected status %d without an allow-list, got %d", http.StatusForbidden, rec.Code)
+	}
+}
+
+func TestProbeSSHRunner(t *testing.T) {
+	base := zfs.SSHRunner{Path: `ssh`, IdentityFile: `/etc/zfs_exporter/id_ed25519`, Options: []string{`ConnectTimeout=5`}}
+	hosts := map[string]config.SSHHost{
+		`nas1`: {Host: `nas1`, User: `monitor`, Port: 2222, IdentityFile: `/etc/zfs_exporter/nas1`, Options: []string{`ConnectTimeout=30`}},
+	}
+
+	for _, tc := range []struct {
+		target string
+		want   zfs.SSHRunner
+	}{
+		{
+			target: `nas1`,
+			want: zfs.SSHRunner{Path: `ssh`, Target: `monitor@nas1`, Port: 2222, IdentityFile: `/etc/zfs_exporter/nas1`,
+				Options: []string{`ConnectTimeout=30`, `ConnectTimeout=5`}},
+		},
+		{
+			// The user of the target takes precedence
+			target: `root@nas1`,
+			want: zfs.SSHRunner{Path: `ssh`, Target: `root@nas1`, Port: 2222, IdentityFile: `/etc/zfs_exporter/nas1`,
+				Options: []string{`ConnectTimeout=30`, `ConnectTimeout=5`}},
+		},
+		{
+			target: `nas2`,
+			want:   zfs.SSHRunner{Path: `ssh`, Target: `nas2`, IdentityFile: `/etc/zfs_exporter/id_ed25519`, Options: []string{`ConnectTimeout=5`}},
+		},
+	} {
+		if got := probeSSHRunner(base, hosts, tc.target); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("Expected %+v for %s, got %+v", tc.want, tc.target, got)
+		}
+	}
+}
+
+func TestProbeKstatCollectors(t *testing.T) {
+	enabled := true
+	if err := collector.Configure(`l2arc`, &enabled, []string{`l2_size`}); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		disabled := false
+		_ = collector.Configure(`l2arc`, &disabled, []string{})
+	})
+
+	// The kstats of the exporter's own host must not be reported as those of the target
+	ctrl := gomock.NewController(t)
+	client := mock_zfs.NewMockClient(ctrl)
+	client.EXPECT().PoolNames().Return(nil, nil).AnyTimes()
+	client.EXPECT().ArcStats().Return(map[string]string{`l2_size`: `1024`}, nil).AnyTimes()
+
+	newCollector := newProbeCollector(collector.ZFSConfig{Deadline: time.Minute})
+	h := probeHandler{
+		logger:  discardLogger,
+		allowed: []*regexp.Regexp{regexp.MustCompile(`^nas1$`)},
+		runner:  func(target string) zfs.Runner { return versionRunner{} },
+		collector: func(l *slog.Logger, _ zfs.Client, capabilities zfs.Capabilities) (prometheus.Collector, error) {
+			return newCollector(l, client, capabilities)
+		},
+	}
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, `/probe?target=nas1`, nil))
+	if !strings.Contains(rec.Body.String(), `zfs_exporter_probe_success 1`) {
+		t.Fatalf("Expected a successful probe, got:\n%s", rec.Body.String())
+	}
+	if strings.Contains(rec.Body.String(), `zfs_l2arc_`) {
+		t.Errorf("Expected no L2ARC metrics of a remote target, got:\n%s", rec.Body.String())
+	}
+}
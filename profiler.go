@@ -0,0 +1,123 @@
+package main
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"runtime/pprof"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	profilesPath = `/debug/profiles/`
+	// maxProfileDuration bounds the CPU profile of a scrape that never completes
+	maxProfileDuration = time.Minute
+)
+
+// scrapeProfile is a CPU profile captured during a slow scrape
+type scrapeProfile struct {
+	ID       int           `json:"id"`
+	Start    time.Time     `json:"start"`
+	Duration time.Duration `json:"duration_ns"`
+	data     []byte
+}
+
+// profileSession is a CPU profile in progress
+type profileSession struct {
+	buf   bytes.Buffer
+	start time.Time
+	timer *time.Timer
+	once  sync.Once
+}
+
+// slowScrapeProfiler captures a CPU profile of scrapes that run for longer than the threshold, retaining the most
+// recent profiles. As the duration of a scrape is only known once it completes, profiling starts when a scrape
+// exceeds the threshold and stops when it completes, covering the slow tail of the scrape. Only one CPU profile may
+// run at a time, so concurrent slow scrapes share a profile, and none is captured while another profile is running.
+type slowScrapeProfiler struct {
+	logger    *slog.Logger
+	threshold time.Duration
+	retain    int
+	mu        sync.Mutex
+	running   bool
+	nextID    int
+	profiles  []scrapeProfile
+}
+
+func newSlowScrapeProfiler(logger *slog.Logger, threshold time.Duration, retain int) *slowScrapeProfiler {
+	return &slowScrapeProfiler{logger: logger, threshold: threshold, retain: max(1, retain), nextID: 1}
+}
+
+// wrap profiles requests to next that run for longer than the threshold
+func (p *slowScrapeProfiler) wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		started := make(chan *profileSession, 1)
+		timer := time.AfterFunc(p.threshold, func() { started <- p.start() })
+		next.ServeHTTP(w, r)
+		if !timer.Stop() {
+			if s := <-started; s != nil {
+				p.stop(s)
+			}
+		}
+	})
+}
+
+func (p *slowScrapeProfiler) start() *profileSession {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.running {
+		return nil
+	}
+	s := &profileSession{start: time.Now()}
+	if err := pprof.StartCPUProfile(&s.buf); err != nil {
+		p.logger.Warn("Unable to profile slow scrape", "err", err)
+		return nil
+	}
+	p.running = true
+	s.timer = time.AfterFunc(maxProfileDuration, func() { p.stop(s) })
+
+	return s
+}
+
+func (p *slowScrapeProfiler) stop(s *profileSession) {
+	s.once.Do(func() {
+		s.timer.Stop()
+		pprof.StopCPUProfile()
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		p.running = false
+		profile := scrapeProfile{ID: p.nextID, Start: s.start, Duration: time.Since(s.start), data: s.buf.Bytes()}
+		p.nextID++
+		p.profiles = append(p.profiles, profile)
+		if len(p.profiles) > p.retain {
+			p.profiles = p.profiles[len(p.profiles)-p.retain:]
+		}
+		p.logger.Info("Captured CPU profile of slow scrape", "id", profile.ID, "threshold", p.threshold, "duration", profile.Duration)
+	})
+}
+
+// ServeHTTP lists the retained profiles as JSON, or serves the profile with the ID given in the path in the format
+// read by `go tool pprof`.
+func (p *slowScrapeProfiler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimPrefix(r.URL.Path, profilesPath)
+	p.mu.Lock()
+	profiles := append([]scrapeProfile(nil), p.profiles...)
+	p.mu.Unlock()
+
+	if id == `` {
+		writeAPI(w, r, profiles)
+		return
+	}
+	for _, profile := range profiles {
+		if strconv.Itoa(profile.ID) == id {
+			w.Header().Set(`Content-Type`, `application/octet-stream`)
+			w.Header().Set(`Content-Disposition`, `attachment; filename="scrape-`+id+`.pprof"`)
+			_, _ = w.Write(profile.data)
+			return
+		}
+	}
+	http.NotFound(w, r)
+}
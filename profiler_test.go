@@ -0,0 +1,48 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestSlowScrapeProfiler(t *testing.T) {
+	p := newSlowScrapeProfiler(discardLogger, 10*time.Millisecond, 1)
+	h := p.wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get(`slow`) != `` {
+			time.Sleep(50 * time.Millisecond)
+		}
+	}))
+
+	for _, target := range []string{`/metrics`, `/metrics?slow=1`, `/metrics?slow=1`} {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, target, nil))
+	}
+
+	rec := httptest.NewRecorder()
+	p.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, profilesPath, nil))
+	var profiles []scrapeProfile
+	if err := json.NewDecoder(rec.Body).Decode(&profiles); err != nil {
+		t.Fatal(err)
+	}
+	// Only the most recent profile is retained
+	if len(profiles) != 1 || profiles[0].ID != 2 {
+		t.Fatalf("Expected profile 2 to be retained, got %+v", profiles)
+	}
+	if profiles[0].Duration <= 0 {
+		t.Errorf("Expected a positive duration, got %s", profiles[0].Duration)
+	}
+
+	rec = httptest.NewRecorder()
+	p.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, profilesPath+`2`, nil))
+	if rec.Code != http.StatusOK || rec.Body.Len() == 0 {
+		t.Errorf("Expected profile data, got status %d with %d bytes", rec.Code, rec.Body.Len())
+	}
+
+	rec = httptest.NewRecorder()
+	p.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, profilesPath+`1`, nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("Expected status %d for an evicted profile, got %d", http.StatusNotFound, rec.Code)
+	}
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	fixtureManifest = `fixture.json`
+	fixtureKstatDir = `kstat`
+)
+
+// fixtureCommand is the output of a command recorded in a fixture bundle
+type fixtureCommand struct {
+	Args   []string `json:"args"`
+	Stdout string   `json:"stdout"`
+	Stderr string   `json:"stderr,omitempty"`
+	Error  string   `json:"error,omitempty"`
+}
+
+// fixtureKstats returns the kstat files read by the exporter for the pools, relative to kstatPath
+func fixtureKstats(kstatPath string, pools []string) []string {
+	result := []string{`arcstats`}
+	sort.Strings(pools)
+	for _, pool := range pools {
+		result = append(result, filepath.Join(pool, `txgs`))
+		objsets, _ := filepath.Glob(filepath.Join(kstatPath, pool, `objset-*`))
+		for _, path := range objsets {
+			result = append(result, filepath.Join(pool, filepath.Base(path)))
+		}
+	}
+
+	return result
+}
+
+// writeFixture writes a gzipped tarball of every command recorded and the kstats of the pools to w, which may be
+// replayed with --zfs.replay
+func writeFixture(w io.Writer, recorder *recordingRunner, kstatPath string, pools []string, now time.Time) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	add := func(name string, data []byte) error {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0o644, Size: int64(len(data)), ModTime: now}); err != nil {
+			return err
+		}
+		_, err := tw.Write(data)
+		return err
+	}
+
+	var commands []fixtureCommand
+	for _, cmd := range recorder.recorded() {
+		recorded := fixtureCommand{Args: cmd.Args, Stdout: string(cmd.Stdout), Stderr: string(cmd.Stderr)}
+		if cmd.Err != nil {
+			recorded.Error = cmd.Err.Error()
+		}
+		commands = append(commands, recorded)
+	}
+	manifest, err := json.MarshalIndent(commands, ``, `  `)
+	if err != nil {
+		return err
+	}
+	if err = add(fixtureManifest, append(manifest, '\n')); err != nil {
+		return err
+	}
+
+	// Kstats that are missing, e.g. on platforms without procfs, are omitted rather than recorded as empty
+	for _, name := range fixtureKstats(kstatPath, pools) {
+		data, err := os.ReadFile(filepath.Join(kstatPath, name))
+		if err != nil {
+			continue
+		}
+		if err = add(fixtureKstatDir+`/`+filepath.ToSlash(name), data); err != nil {
+			return err
+		}
+	}
+
+	if err = tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// writeFixtureFile writes the fixture bundle to path, or to a file named for the time of recording if path is empty,
+// and returns the path written
+func writeFixtureFile(path string, recorder *recordingRunner, kstatPath string, pools []string, now time.Time) (string, error) {
+	if path == `` {
+		path = `zfs_exporter-fixture-` + now.UTC().Format(`20060102T150405Z`) + `.tar.gz`
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return ``, err
+	}
+	if err = writeFixture(f, recorder, kstatPath, pools, now); err != nil {
+		_ = f.Close()
+		return ``, err
+	}
+
+	return path, f.Close()
+}
+
+// replayRunner serves the output of the commands recorded in a fixture bundle, keyed by command line. Where a command
+// was recorded more than once, the last output is served.
+type replayRunner map[string]fixtureCommand
+
+func (r replayRunner) Run(ctx context.Context, name string, args ...string) ([]byte, []byte, error) {
+	cmd, ok := r[strings.Join(append([]string{name}, args...), ` `)]
+	if !ok {
+		return nil, nil, fmt.Errorf("command not recorded in fixture bundle: %s", strings.Join(append([]string{name}, args...), ` `))
+	}
+	var err error
+	if cmd.Error != `` {
+		err = errors.New(cmd.Error)
+	}
+
+	return []byte(cmd.Stdout), []byte(cmd.Stderr), err
+}
+
+// readFixture reads a fixture bundle written by writeFixture, extracting its kstats below kstatPath
+func readFixture(r io.Reader, kstatPath string) (replayRunner, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	var runner replayRunner
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, err
+		}
+
+		if hdr.Name == fixtureManifest {
+			var commands []fixtureCommand
+			if err = json.Unmarshal(data, &commands); err != nil {
+				return nil, fmt.Errorf("%s: %w", fixtureManifest, err)
+			}
+			runner = make(replayRunner, len(commands))
+			for _, cmd := range commands {
+				runner[strings.Join(cmd.Args, ` `)] = cmd
+			}
+			continue
+		}
+		name, ok := strings.CutPrefix(hdr.Name, fixtureKstatDir+`/`)
+		if !ok || !filepath.IsLocal(name) {
+			return nil, fmt.Errorf("unexpected file in fixture bundle: %s", hdr.Name)
+		}
+		path := filepath.Join(kstatPath, filepath.FromSlash(name))
+		if err = os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return nil, err
+		}
+		if err = os.WriteFile(path, data, 0o644); err != nil {
+			return nil, err
+		}
+	}
+	if runner == nil {
+		return nil, fmt.Errorf("not a fixture bundle: %s not found", fixtureManifest)
+	}
+
+	return runner, nil
+}
+
+// readFixtureFile reads the fixture bundle at path, extracting its kstats to a temporary directory whose path is
+// returned
+func readFixtureFile(path string) (replayRunner, string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, ``, err
+	}
+	defer f.Close()
+	kstatPath, err := os.MkdirTemp(``, `zfs_exporter-replay-`)
+	if err != nil {
+		return nil, ``, err
+	}
+	runner, err := readFixture(f, kstatPath)
+	if err != nil {
+		_ = os.RemoveAll(kstatPath)
+		return nil, ``, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return runner, kstatPath, nil
+}
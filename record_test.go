@@ -0,0 +1,64 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFixtureRoundTrip(t *testing.T) {
+	kstatPath := t.TempDir()
+	for name, data := range map[string]string{
+		`arcstats`:          `hits 4 1234`,
+		`tank/txgs`:         `txg birth state`,
+		`tank/objset-0x36`:  `dataset_name 7 tank/home`,
+		`backup/objset-0x1`: `dataset_name 7 backup`,
+	} {
+		path := filepath.Join(kstatPath, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	recorder := &recordingRunner{Runner: stubRunner{failures: map[string]bool{`zpool status missing`: true}}}
+	for _, args := range [][]string{{`status`, `-p`}, {`status`, `missing`}} {
+		_, _, _ = recorder.Run(context.Background(), `zpool`, args...)
+	}
+	var buf bytes.Buffer
+	if err := writeFixture(&buf, recorder, kstatPath, []string{`tank`}, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+
+	replayPath := t.TempDir()
+	replay, err := readFixture(&buf, replayPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout, _, err := replay.Run(context.Background(), `zpool`, `status`, `-p`)
+	if err != nil || string(stdout) != `output of zpool status -p` {
+		t.Errorf("Unexpected replay of zpool status -p: %q, %v", stdout, err)
+	}
+	if _, stderr, err := replay.Run(context.Background(), `zpool`, `status`, `missing`); err == nil || string(stderr) != `cannot open 'missing': no such pool` {
+		t.Errorf("Expected replayed failure of zpool status missing, got %q, %v", stderr, err)
+	}
+	if _, _, err = replay.Run(context.Background(), `zfs`, `list`); err == nil {
+		t.Error("Expected error replaying a command that was not recorded")
+	}
+
+	for name, want := range map[string]string{`arcstats`: `hits 4 1234`, `tank/objset-0x36`: `dataset_name 7 tank/home`} {
+		data, err := os.ReadFile(filepath.Join(replayPath, name))
+		if err != nil || string(data) != want {
+			t.Errorf("Unexpected kstat %s: %q, %v", name, data, err)
+		}
+	}
+	// Only the kstats of pools that were found are recorded
+	if _, err = os.Stat(filepath.Join(replayPath, `backup`)); !os.IsNotExist(err) {
+		t.Errorf("Expected kstats of backup to be omitted, got %v", err)
+	}
+}
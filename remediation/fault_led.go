@@ -0,0 +1,177 @@
+// Package remediation implements optional hooks that act upon changes in ZFS state.
+package remediation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/jmcgover/zfs_exporter/v2/zfs"
+)
+
+// PathPlaceholder is replaced by the device path of the vdev in fault LED commands
+const PathPlaceholder = `{path}`
+
+// FaultLEDConfig configures a FaultLED hook
+type FaultLEDConfig struct {
+	Logger *slog.Logger
+	Client zfs.Client
+	Runner zfs.Runner
+	// SetCommand and ClearCommand are the commands that turn the fault LED of a device on and off, e.g.
+	// `ledctl failure={path}`. Arguments are split on whitespace.
+	SetCommand   string
+	ClearCommand string
+	// ReadOnly logs the commands that would be run, without running them
+	ReadOnly bool
+	// CommandTimeout limits the runtime of each command, zero disables the limit
+	CommandTimeout time.Duration
+	// Suppressed, if set, reports whether changes to the pool should be deferred, e.g. during planned maintenance.
+	// Deferred changes are made once the pool is no longer suppressed, if still required.
+	Suppressed func(pool string) bool
+}
+
+// FaultLED lights the enclosure fault LED of vdevs that enter the FAULTED state, and clears it once they recover
+type FaultLED struct {
+	logger         *slog.Logger
+	client         zfs.Client
+	runner         zfs.Runner
+	set            []string
+	clear          []string
+	readOnly       bool
+	commandTimeout time.Duration
+	suppressed     func(pool string) bool
+	// faulted records the devices whose LED has been set, keyed by path
+	faulted map[string]bool
+}
+
+// NewFaultLED returns a FaultLED hook
+func NewFaultLED(config FaultLEDConfig) (*FaultLED, error) {
+	set, err := parseCommand(config.SetCommand, PathPlaceholder)
+	if err != nil {
+		return nil, fmt.Errorf("set command: %w", err)
+	}
+	clear, err := parseCommand(config.ClearCommand, PathPlaceholder)
+	if err != nil {
+		return nil, fmt.Errorf("clear command: %w", err)
+	}
+
+	return &FaultLED{
+		logger:         config.Logger,
+		client:         config.Client,
+		runner:         config.Runner,
+		set:            set,
+		clear:          clear,
+		readOnly:       config.ReadOnly,
+		commandTimeout: config.CommandTimeout,
+		suppressed:     config.Suppressed,
+		faulted:        make(map[string]bool),
+	}, nil
+}
+
+// parseCommand splits command into arguments, requiring that it contains placeholder so that it acts upon a specific
+// device or pool
+func parseCommand(command, placeholder string) ([]string, error) {
+	args := strings.Fields(command)
+	if len(args) == 0 {
+		return nil, errors.New(`command must not be empty`)
+	}
+	if !strings.Contains(command, placeholder) {
+		return nil, fmt.Errorf("command '%s' must contain %s", command, placeholder)
+	}
+	return args, nil
+}
+
+// Run checks the state of all vdevs every interval until ctx is done
+func (f *FaultLED) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := f.Check(); err != nil {
+			f.logger.Error("Error updating fault LEDs", "err", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Check sets the fault LED of devices that have entered the FAULTED state since the previous check, and clears that of
+// devices that have since recovered. LEDs of devices that were not faulted when first seen are left untouched, so that
+// those set by other tools are not cleared.
+func (f *FaultLED) Check() error {
+	pools, err := f.client.PoolStatus(false)
+	if err != nil && len(pools) == 0 {
+		return err
+	}
+
+	seen := make(map[string]bool)
+	errs := []error{err}
+	for _, pool := range pools {
+		for _, vdev := range pool.AllVdevs() {
+			if vdev.Path == `` || len(vdev.Vdevs) > 0 {
+				continue
+			}
+			seen[vdev.Path] = true
+			faulted := zfs.PoolStatus(vdev.State) == zfs.PoolFaulted
+			if faulted == f.faulted[vdev.Path] {
+				continue
+			}
+			if f.suppressed != nil && f.suppressed(pool.Name) {
+				f.logger.Info("Fault LED change suppressed", "pool", pool.Name, "vdev", vdev.Name, "state", vdev.State)
+				continue
+			}
+
+			command := f.clear
+			if faulted {
+				command = f.set
+			}
+			if err := f.run(command, pool.Name, vdev); err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			if faulted {
+				f.faulted[vdev.Path] = true
+			} else {
+				delete(f.faulted, vdev.Path)
+			}
+		}
+	}
+	// Devices removed from the pool keep their LED, as the device may still require replacement. Devices of pools whose
+	// status could not be retrieved are not known to have been removed.
+	if err == nil {
+		for path := range f.faulted {
+			if !seen[path] {
+				delete(f.faulted, path)
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func (f *FaultLED) run(command []string, pool string, vdev zfs.VdevStatusT) error {
+	args := make([]string, len(command))
+	for i, arg := range command {
+		args[i] = strings.ReplaceAll(arg, PathPlaceholder, vdev.Path)
+	}
+
+	logger := f.logger.With("pool", pool, "vdev", vdev.Name, "state", vdev.State, "command", strings.Join(args, ` `))
+	if f.readOnly {
+		logger.Info("Read-only mode, not updating fault LED")
+		return nil
+	}
+
+	ctx, cancel := zfs.CommandContext(f.commandTimeout)
+	defer cancel()
+	if _, _, err := f.runner.Run(ctx, args[0], args[1:]...); err != nil {
+		return err
+	}
+	logger.Info("Updated fault LED")
+
+	return nil
+}
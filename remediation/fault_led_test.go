@@ -0,0 +1,111 @@
+package remediation
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/jmcgover/zfs_exporter/v2/zfs"
+	"github.com/jmcgover/zfs_exporter/v2/zfs/mock_zfs"
+	"go.uber.org/mock/gomock"
+)
+
+var logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+// recordingRunner records the commands it is asked to run
+type recordingRunner struct {
+	commands []string
+}
+
+func (r *recordingRunner) Run(ctx context.Context, name string, args ...string) ([]byte, []byte, error) {
+	r.commands = append(r.commands, strings.Join(append([]string{name}, args...), ` `))
+	return nil, nil, nil
+}
+
+func poolStatus(sda, sdb string) map[string]zfs.PoolStatusT {
+	return map[string]zfs.PoolStatusT{
+		`tank`: {
+			Name: `tank`,
+			Vdevs: map[string]zfs.VdevStatusT{
+				`tank`: {Name: `tank`, VdevType: `root`, Vdevs: map[string]zfs.VdevStatusT{
+					`mirror-0`: {Name: `mirror-0`, VdevType: `mirror`, State: `DEGRADED`, Vdevs: map[string]zfs.VdevStatusT{
+						`sda`: {Name: `sda`, VdevType: `disk`, Path: `/dev/sda1`, State: sda},
+						`sdb`: {Name: `sdb`, VdevType: `disk`, Path: `/dev/sdb1`, State: sdb},
+					}},
+				}},
+			},
+		},
+	}
+}
+
+func TestFaultLED(t *testing.T) {
+	testCases := []struct {
+		name       string
+		readOnly   bool
+		suppressed func(pool string) bool
+		want       []string
+	}{
+		{
+			name: `enabled`,
+			want: []string{`ledctl failure=/dev/sdb1`, `ledctl normal=/dev/sdb1`},
+		},
+		{
+			name:     `read-only`,
+			readOnly: true,
+			want:     nil,
+		},
+		{
+			name:       `suppressed`,
+			suppressed: func(pool string) bool { return pool == `tank` },
+			want:       nil,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			client := mock_zfs.NewMockClient(ctrl)
+			gomock.InOrder(
+				client.EXPECT().PoolStatus(false).Return(poolStatus(`ONLINE`, `FAULTED`), nil),
+				client.EXPECT().PoolStatus(false).Return(poolStatus(`ONLINE`, `FAULTED`), nil),
+				client.EXPECT().PoolStatus(false).Return(poolStatus(`ONLINE`, `ONLINE`), nil),
+				client.EXPECT().PoolStatus(false).Return(poolStatus(`ONLINE`, `ONLINE`), nil),
+			)
+
+			runner := &recordingRunner{}
+			hook, err := NewFaultLED(FaultLEDConfig{
+				Logger:       logger,
+				Client:       client,
+				Runner:       runner,
+				SetCommand:   `ledctl failure={path}`,
+				ClearCommand: `ledctl normal={path}`,
+				ReadOnly:     tc.readOnly,
+				Suppressed:   tc.suppressed,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// LEDs are only changed on transitions, and never for devices that were healthy when first seen.
+			for range 4 {
+				if err = hook.Check(); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if !reflect.DeepEqual(runner.commands, tc.want) {
+				t.Errorf("Expected commands %v, got %v", tc.want, runner.commands)
+			}
+		})
+	}
+}
+
+func TestNewFaultLEDInvalidCommand(t *testing.T) {
+	for _, command := range []string{``, `ledctl failure=/dev/sda`} {
+		if _, err := NewFaultLED(FaultLEDConfig{SetCommand: command, ClearCommand: `ledctl normal={path}`}); err == nil {
+			t.Errorf("Expected error for set command %q", command)
+		}
+	}
+}
@@ -0,0 +1,180 @@
+package remediation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/jmcgover/zfs_exporter/v2/zfs"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	// loadKeyKinds are the kinds of dataset that may be encryption roots
+	loadKeyKinds = []zfs.DatasetKind{zfs.DatasetFilesystem, zfs.DatasetVolume}
+	// loadKeyLocations are the key locations that can be loaded without an operator, unlike prompt
+	loadKeyLocations = []string{`file://`, `https://`, `http://`}
+)
+
+// LoadKeyConfig configures a LoadKey hook
+type LoadKeyConfig struct {
+	Logger *slog.Logger
+	Client zfs.Client
+	Runner zfs.Runner
+	// ReadOnly logs the commands that would be run, without running them
+	ReadOnly bool
+	// CommandTimeout limits the runtime of each command, zero disables the limit
+	CommandTimeout time.Duration
+	// Cooldown is the minimum interval between attempts to load the key of the same dataset
+	Cooldown time.Duration
+	// Suppressed, if set, reports whether changes to the pool should be deferred, e.g. during planned maintenance
+	Suppressed func(pool string) bool
+}
+
+// LoadKey loads the keys of encryption roots whose key is unavailable, where the key is stored in a file or at a URL,
+// so that encrypted datasets are not left locked following a reboot. Keys that must be entered at a prompt are left
+// for an operator.
+type LoadKey struct {
+	logger         *slog.Logger
+	client         zfs.Client
+	runner         zfs.Runner
+	readOnly       bool
+	commandTimeout time.Duration
+	cooldown       time.Duration
+	suppressed     func(pool string) bool
+	now            func() time.Time
+	// attempted records the time of the last attempt for each dataset whose key is unavailable
+	attempted map[string]time.Time
+	attempts  *prometheus.CounterVec
+}
+
+// NewLoadKey returns a LoadKey hook
+func NewLoadKey(config LoadKeyConfig) (*LoadKey, error) {
+	if config.Cooldown < 0 {
+		return nil, fmt.Errorf("cooldown must not be negative: %s", config.Cooldown)
+	}
+
+	return &LoadKey{
+		logger:         config.Logger,
+		client:         config.Client,
+		runner:         config.Runner,
+		readOnly:       config.ReadOnly,
+		commandTimeout: config.CommandTimeout,
+		cooldown:       config.Cooldown,
+		suppressed:     config.Suppressed,
+		now:            time.Now,
+		attempted:      make(map[string]time.Time),
+		attempts: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: `zfs_exporter`,
+			Subsystem: `load_key`,
+			Name:      `attempts_total`,
+			Help:      `Number of attempts to load the encryption key of a dataset by result (success or failure).`,
+		}, []string{`dataset`, `result`}),
+	}, nil
+}
+
+// Describe implements the prometheus.Collector interface
+func (l *LoadKey) Describe(ch chan<- *prometheus.Desc) {
+	l.attempts.Describe(ch)
+}
+
+// Collect implements the prometheus.Collector interface
+func (l *LoadKey) Collect(ch chan<- prometheus.Metric) {
+	l.attempts.Collect(ch)
+}
+
+// Run checks the key status of all encryption roots every interval until ctx is done
+func (l *LoadKey) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := l.Check(); err != nil {
+			l.logger.Error("Error loading encryption keys", "err", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Check attempts to load the keys of encryption roots whose key is unavailable and was not attempted within the
+// cooldown
+func (l *LoadKey) Check() error {
+	pools, err := l.client.PoolNames()
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	locked := make(map[string]bool)
+	for _, pool := range pools {
+		datasets, err := l.client.DatasetGet(pool, loadKeyKinds, `encryptionroot`, `keystatus`, `keylocation`)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		for name, dataset := range datasets {
+			props := dataset.Properties
+			// Children inherit the key of their encryption root, which is the only dataset whose key may be loaded
+			if string(props[`encryptionroot`].Value) != name || props[`keystatus`].Value != `unavailable` {
+				continue
+			}
+			locked[name] = true
+			location := string(props[`keylocation`].Value)
+			if !loadableKeyLocation(location) {
+				continue
+			}
+			if last, ok := l.attempted[name]; ok && l.now().Sub(last) < l.cooldown {
+				continue
+			}
+			if l.suppressed != nil && l.suppressed(pool) {
+				l.logger.Info("Loading encryption key suppressed", "pool", pool, "dataset", name)
+				continue
+			}
+			l.attempted[name] = l.now()
+			if err := l.load(name, location); err != nil {
+				errs = append(errs, fmt.Errorf("dataset %s: %w", name, err))
+			}
+		}
+	}
+	for name := range l.attempted {
+		if !locked[name] {
+			delete(l.attempted, name)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func loadableKeyLocation(location string) bool {
+	for _, prefix := range loadKeyLocations {
+		if strings.HasPrefix(location, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func (l *LoadKey) load(dataset, location string) error {
+	logger := l.logger.With("dataset", dataset, "keylocation", location)
+	if l.readOnly {
+		logger.Info("Read-only mode, not loading encryption key")
+		return nil
+	}
+
+	ctx, cancel := zfs.CommandContext(l.commandTimeout)
+	defer cancel()
+	if _, _, err := l.runner.Run(ctx, `zfs`, `load-key`, dataset); err != nil {
+		l.attempts.WithLabelValues(dataset, `failure`).Inc()
+		return err
+	}
+	l.attempts.WithLabelValues(dataset, `success`).Inc()
+	logger.Info("Loaded encryption key")
+
+	return nil
+}
@@ -0,0 +1,93 @@
+package remediation
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jmcgover/zfs_exporter/v2/zfs"
+	"github.com/jmcgover/zfs_exporter/v2/zfs/mock_zfs"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"go.uber.org/mock/gomock"
+)
+
+func encryptedDatasets(keystatus string) map[string]zfs.DatasetListT {
+	dataset := func(root, status, location string) zfs.DatasetListT {
+		return zfs.DatasetListT{Properties: map[string]zfs.PropertyT{
+			`encryptionroot`: {Value: zfs.PropertyValue(root)},
+			`keystatus`:      {Value: zfs.PropertyValue(status)},
+			`keylocation`:    {Value: zfs.PropertyValue(location)},
+		}}
+	}
+	return map[string]zfs.DatasetListT{
+		`tank`:              dataset(`-`, `-`, `none`),
+		`tank/secure`:       dataset(`tank/secure`, keystatus, `file:///etc/zfs/keys/secure`),
+		`tank/secure/child`: dataset(`tank/secure`, keystatus, `none`),
+		`tank/prompt`:       dataset(`tank/prompt`, `unavailable`, `prompt`),
+	}
+}
+
+func TestLoadKey(t *testing.T) {
+	testCases := []struct {
+		name     string
+		readOnly bool
+		want     []string
+		metrics  string
+	}{
+		{
+			// Retried once the cooldown has passed, and forgotten once loaded
+			name: `enabled`,
+			want: []string{`zfs load-key tank/secure`, `zfs load-key tank/secure`},
+			metrics: `# HELP zfs_exporter_load_key_attempts_total Number of attempts to load the encryption key of a dataset by result (success or failure).
+# TYPE zfs_exporter_load_key_attempts_total counter
+zfs_exporter_load_key_attempts_total{dataset="tank/secure",result="success"} 2
+`,
+		},
+		{
+			name:     `read-only`,
+			readOnly: true,
+			want:     nil,
+			metrics:  ``,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			client := mock_zfs.NewMockClient(ctrl)
+			client.EXPECT().PoolNames().Return([]string{`tank`}, nil).AnyTimes()
+			gomock.InOrder(
+				client.EXPECT().DatasetGet(`tank`, loadKeyKinds, `encryptionroot`, `keystatus`, `keylocation`).Return(encryptedDatasets(`unavailable`), nil).Times(3),
+				client.EXPECT().DatasetGet(`tank`, loadKeyKinds, `encryptionroot`, `keystatus`, `keylocation`).Return(encryptedDatasets(`available`), nil),
+			)
+
+			runner := &recordingRunner{}
+			hook, err := NewLoadKey(LoadKeyConfig{
+				Logger:   logger,
+				Client:   client,
+				Runner:   runner,
+				ReadOnly: tc.readOnly,
+				Cooldown: 10 * time.Minute,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+			hook.now = func() time.Time { return now }
+
+			for _, minutes := range []int{0, 5, 10, 15} {
+				now = time.Date(2025, 1, 1, 0, minutes, 0, 0, time.UTC)
+				if err = hook.Check(); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if !reflect.DeepEqual(runner.commands, tc.want) {
+				t.Errorf("Expected commands %v, got %v", tc.want, runner.commands)
+			}
+			if err = testutil.CollectAndCompare(hook, strings.NewReader(tc.metrics)); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
@@ -0,0 +1,190 @@
+package remediation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/jmcgover/zfs_exporter/v2/zfs"
+)
+
+// PoolPlaceholder is replaced by the name of the pool in suspended pool commands
+const PoolPlaceholder = `{pool}`
+
+// SuspendedPoolConfig configures a SuspendedPool hook
+type SuspendedPoolConfig struct {
+	Logger *slog.Logger
+	Client zfs.Client
+	Runner zfs.Runner
+	// Command is run for pools whose I/O has been suspended, e.g. `zpool clear {pool}`. Arguments are split on
+	// whitespace.
+	Command string
+	// ReadOnly logs the commands that would be run, without running them
+	ReadOnly bool
+	// CommandTimeout limits the runtime of each command, zero disables the limit
+	CommandTimeout time.Duration
+	// Cooldown is the minimum interval between runs of the command for the same pool
+	Cooldown time.Duration
+	// MaxAttempts is the number of times the command is run while a pool remains suspended, after which the pool is
+	// left for an operator. It must be at least one.
+	MaxAttempts int
+	// Suppressed, if set, reports whether changes to the pool should be deferred, e.g. during planned maintenance
+	Suppressed func(pool string) bool
+}
+
+// suspension tracks remediation of a pool while it remains suspended
+type suspension struct {
+	attempts int
+	last     time.Time
+	gaveUp   bool
+}
+
+// SuspendedPool runs a remediation command for pools whose I/O has been suspended, such as following a transient loss
+// of SAN paths. The command is run once the pool is first seen suspended, and then at most every cooldown until either
+// the pool recovers or the maximum number of attempts is reached. Every decision is logged with audit=true.
+type SuspendedPool struct {
+	logger         *slog.Logger
+	client         zfs.Client
+	runner         zfs.Runner
+	command        []string
+	readOnly       bool
+	commandTimeout time.Duration
+	cooldown       time.Duration
+	maxAttempts    int
+	suppressed     func(pool string) bool
+	now            func() time.Time
+	// suspended records the pools currently suspended, keyed by name
+	suspended map[string]*suspension
+}
+
+// NewSuspendedPool returns a SuspendedPool hook
+func NewSuspendedPool(config SuspendedPoolConfig) (*SuspendedPool, error) {
+	command, err := parseCommand(config.Command, PoolPlaceholder)
+	if err != nil {
+		return nil, err
+	}
+	if config.MaxAttempts < 1 {
+		return nil, fmt.Errorf("max attempts must be at least one: %d", config.MaxAttempts)
+	}
+	if config.Cooldown < 0 {
+		return nil, fmt.Errorf("cooldown must not be negative: %s", config.Cooldown)
+	}
+
+	return &SuspendedPool{
+		logger:         config.Logger,
+		client:         config.Client,
+		runner:         config.Runner,
+		command:        command,
+		readOnly:       config.ReadOnly,
+		commandTimeout: config.CommandTimeout,
+		cooldown:       config.Cooldown,
+		maxAttempts:    config.MaxAttempts,
+		suppressed:     config.Suppressed,
+		now:            time.Now,
+		suspended:      make(map[string]*suspension),
+	}, nil
+}
+
+// Run checks the state of all pools every interval until ctx is done
+func (s *SuspendedPool) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := s.Check(); err != nil {
+			s.logger.Error("Error remediating suspended pools", "err", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Check runs the remediation command for suspended pools that are due an attempt, and forgets pools that have
+// recovered
+func (s *SuspendedPool) Check() error {
+	// Pools whose status could not be retrieved are checked once it can be
+	pools, err := s.client.PoolStatus(false)
+	if err != nil && len(pools) == 0 {
+		return err
+	}
+
+	errs := []error{err}
+	for name, pool := range pools {
+		state, ok := s.suspended[name]
+		if zfs.PoolStatus(pool.State) != zfs.PoolSuspended {
+			if ok {
+				s.audit(name, "recovered", "attempts", state.attempts)
+				delete(s.suspended, name)
+			}
+			continue
+		}
+		if !ok {
+			state = &suspension{}
+			s.suspended[name] = state
+			s.audit(name, "suspended", "status", pool.Status)
+		}
+
+		now := s.now()
+		switch {
+		case state.attempts >= s.maxAttempts:
+			if !state.gaveUp {
+				s.audit(name, "gave up, the pool requires manual intervention", "attempts", state.attempts)
+				state.gaveUp = true
+			}
+			continue
+		case !state.last.IsZero() && now.Sub(state.last) < s.cooldown:
+			continue
+		case s.suppressed != nil && s.suppressed(name):
+			s.audit(name, "suppressed")
+			continue
+		}
+
+		state.attempts++
+		state.last = now
+		if err := s.run(name, state.attempts); err != nil {
+			errs = append(errs, fmt.Errorf("pool %s: %w", name, err))
+		}
+	}
+	for name := range s.suspended {
+		if _, ok := pools[name]; !ok {
+			delete(s.suspended, name)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func (s *SuspendedPool) run(pool string, attempt int) error {
+	args := make([]string, len(s.command))
+	for i, arg := range s.command {
+		args[i] = strings.ReplaceAll(arg, PoolPlaceholder, pool)
+	}
+
+	attrs := []any{"command", strings.Join(args, ` `), "attempt", attempt, "max_attempts", s.maxAttempts}
+	if s.readOnly {
+		s.audit(pool, "read-only, not running command", attrs...)
+		return nil
+	}
+
+	ctx, cancel := zfs.CommandContext(s.commandTimeout)
+	defer cancel()
+	stdout, stderr, err := s.runner.Run(ctx, args[0], args[1:]...)
+	attrs = append(attrs, "stdout", strings.TrimSpace(string(stdout)), "stderr", strings.TrimSpace(string(stderr)))
+	if err != nil {
+		s.audit(pool, "command failed", append(attrs, "err", err)...)
+		return err
+	}
+	s.audit(pool, "command succeeded", attrs...)
+
+	return nil
+}
+
+// audit logs a remediation decision for the pool, at warning level so that it is retained by default
+func (s *SuspendedPool) audit(pool, msg string, attrs ...any) {
+	s.logger.Warn("Suspended pool remediation: "+msg, append([]any{"audit", true, "pool", pool}, attrs...)...)
+}
@@ -0,0 +1,96 @@
+package remediation
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/jmcgover/zfs_exporter/v2/zfs"
+	"github.com/jmcgover/zfs_exporter/v2/zfs/mock_zfs"
+	"go.uber.org/mock/gomock"
+)
+
+func poolState(tank, backup string) map[string]zfs.PoolStatusT {
+	return map[string]zfs.PoolStatusT{
+		`tank`:   {Name: `tank`, State: tank},
+		`backup`: {Name: `backup`, State: backup},
+	}
+}
+
+func TestSuspendedPool(t *testing.T) {
+	testCases := []struct {
+		name       string
+		readOnly   bool
+		suppressed func(pool string) bool
+		want       []string
+	}{
+		{
+			// Attempts are limited by the cooldown and the maximum, and reset once the pool recovers
+			name: `enabled`,
+			want: []string{`zpool clear tank`, `zpool clear tank`, `zpool clear tank`},
+		},
+		{
+			name:     `read-only`,
+			readOnly: true,
+			want:     nil,
+		},
+		{
+			name:       `suppressed`,
+			suppressed: func(pool string) bool { return pool == `tank` },
+			want:       nil,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			client := mock_zfs.NewMockClient(ctrl)
+			suspended := poolState(`SUSPENDED`, `ONLINE`)
+			gomock.InOrder(
+				client.EXPECT().PoolStatus(false).Return(suspended, nil).Times(4),
+				client.EXPECT().PoolStatus(false).Return(poolState(`ONLINE`, `ONLINE`), nil),
+				client.EXPECT().PoolStatus(false).Return(suspended, nil),
+			)
+
+			runner := &recordingRunner{}
+			hook, err := NewSuspendedPool(SuspendedPoolConfig{
+				Logger:      logger,
+				Client:      client,
+				Runner:      runner,
+				Command:     `zpool clear {pool}`,
+				ReadOnly:    tc.readOnly,
+				Cooldown:    10 * time.Minute,
+				MaxAttempts: 2,
+				Suppressed:  tc.suppressed,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+			hook.now = func() time.Time { return now }
+
+			// Checks at 0, 5, 10 and 20 minutes while suspended, then recovery and a second suspension at 25 minutes
+			for _, minutes := range []int{0, 5, 10, 20, 21, 25} {
+				now = time.Date(2025, 1, 1, 0, minutes, 0, 0, time.UTC)
+				if err = hook.Check(); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if !reflect.DeepEqual(runner.commands, tc.want) {
+				t.Errorf("Expected commands %v, got %v", tc.want, runner.commands)
+			}
+		})
+	}
+}
+
+func TestNewSuspendedPoolInvalidConfig(t *testing.T) {
+	for _, config := range []SuspendedPoolConfig{
+		{Command: `zpool clear tank`, MaxAttempts: 1},
+		{Command: `zpool clear {pool}`, MaxAttempts: 0},
+		{Command: `zpool clear {pool}`, MaxAttempts: 1, Cooldown: -time.Second},
+	} {
+		if _, err := NewSuspendedPool(config); err == nil {
+			t.Errorf("Expected error for config %+v", config)
+		}
+	}
+}
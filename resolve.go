@@ -0,0 +1,82 @@
+package main
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/jmcgover/zfs_exporter/v2/zfs"
+)
+
+// resolvedGUID is the response of the resolve API
+type resolvedGUID struct {
+	// GUID is encoded as a string, as it may exceed the integers that JSON parsers represent exactly
+	GUID  uint64 `json:"guid,string"`
+	Kind  string `json:"kind"`
+	Pool  string `json:"pool"`
+	Name  string `json:"name"`
+	Type  string `json:"vdev_type,omitempty"`
+	Path  string `json:"path,omitempty"`
+	State string `json:"state"`
+}
+
+// resolveHandler resolves the GUIDs of pools and vdevs, as logged by the kernel and in zed events, to their current
+// names
+type resolveHandler struct {
+	logger *slog.Logger
+	client zfs.Client
+}
+
+// ServeHTTP resolves the guid query parameter on GET, which may be decimal or hexadecimal with a 0x prefix
+func (h resolveHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set(`Allow`, `GET`)
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	guid, err := strconv.ParseUint(r.URL.Query().Get(`guid`), 0, 64)
+	if err != nil {
+		http.Error(w, `guid must be a decimal or 0x-prefixed hexadecimal integer`, http.StatusBadRequest)
+		return
+	}
+	pools, err := h.client.PoolStatus(false)
+	if err != nil {
+		h.logger.Error("Error getting pool status", "err", err)
+	}
+	resolved, ok := resolveGUID(pools, guid)
+	// The GUID may belong to a pool whose status could not be retrieved
+	if !ok && err != nil {
+		http.Error(w, `error getting pool status, see the exporter log for details`, http.StatusInternalServerError)
+		return
+	}
+	if !ok {
+		http.Error(w, `guid not found in the status of any imported pool`, http.StatusNotFound)
+		return
+	}
+	writeAPI(w, r, resolved)
+}
+
+// resolveGUID finds the pool or vdev with the GUID in the pool status
+func resolveGUID(pools map[string]zfs.PoolStatusT, guid uint64) (resolvedGUID, bool) {
+	for name, pool := range pools {
+		if uint64(pool.PoolGuid) == guid {
+			return resolvedGUID{GUID: guid, Kind: `pool`, Pool: name, Name: name, State: pool.State}, true
+		}
+		for _, vdev := range pool.AllVdevs() {
+			if uint64(vdev.Guid) == guid {
+				return resolvedGUID{
+					GUID:  guid,
+					Kind:  `vdev`,
+					Pool:  name,
+					Name:  vdev.Name,
+					Type:  vdev.VdevType,
+					Path:  vdev.Path,
+					State: vdev.State,
+				}, true
+			}
+		}
+	}
+
+	return resolvedGUID{}, false
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jmcgover/zfs_exporter/v2/zfs"
+	"github.com/jmcgover/zfs_exporter/v2/zfs/mock_zfs"
+	"go.uber.org/mock/gomock"
+)
+
+func TestResolveHandler(t *testing.T) {
+	pools := map[string]zfs.PoolStatusT{
+		`tank`: {
+			Name:     `tank`,
+			State:    `DEGRADED`,
+			PoolGuid: 9536232137404412127,
+			Vdevs: map[string]zfs.VdevStatusT{
+				`tank`: {Name: `tank`, VdevType: `root`, Guid: 9536232137404412127, Vdevs: map[string]zfs.VdevStatusT{
+					`mirror-0`: {Name: `mirror-0`, VdevType: `mirror`, Guid: 1234, State: `DEGRADED`, Vdevs: map[string]zfs.VdevStatusT{
+						`sda`: {Name: `sda`, VdevType: `disk`, Guid: 0x5d7d4a1b2c3e4f60, Path: `/dev/sda1`, State: `FAULTED`},
+					}},
+				}},
+			},
+		},
+	}
+	ctrl := gomock.NewController(t)
+	client := mock_zfs.NewMockClient(ctrl)
+	client.EXPECT().PoolStatus(false).Return(pools, nil).Times(4)
+	client.EXPECT().PoolStatus(false).Return(nil, errors.New(`exit status 1`))
+	h := resolveHandler{logger: discardLogger, client: client}
+
+	for target, want := range map[string]resolvedGUID{
+		`/api/v1/resolve?guid=9536232137404412127`: {GUID: 9536232137404412127, Kind: `pool`, Pool: `tank`, Name: `tank`, State: `DEGRADED`},
+		`/api/v1/resolve?guid=0x5d7d4a1b2c3e4f60`: {
+			GUID: 0x5d7d4a1b2c3e4f60, Kind: `vdev`, Pool: `tank`, Name: `sda`, Type: `disk`, Path: `/dev/sda1`, State: `FAULTED`,
+		},
+	} {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Expected status %d for %s, got %d", http.StatusOK, target, rec.Code)
+		}
+		var resolved resolvedGUID
+		if err := json.NewDecoder(rec.Body).Decode(&resolved); err != nil {
+			t.Fatal(err)
+		}
+		if resolved != want {
+			t.Errorf("Expected %+v for %s, got %+v", want, target, resolved)
+		}
+	}
+
+	for _, tc := range []struct {
+		target string
+		code   int
+	}{
+		{`/api/v1/resolve?guid=tank`, http.StatusBadRequest},
+		{`/api/v1/resolve?guid=42`, http.StatusNotFound},
+		{`/api/v1/resolve?guid=1234`, http.StatusOK},
+		{`/api/v1/resolve?guid=1234`, http.StatusInternalServerError},
+	} {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tc.target, nil))
+		if rec.Code != tc.code {
+			t.Errorf("Expected status %d for %s, got %d", tc.code, tc.target, rec.Code)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, `/api/v1/resolve?guid=1234`, nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected status %d for POST, got %d", http.StatusMethodNotAllowed, rec.Code)
+	}
+}
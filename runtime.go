@@ -0,0 +1,169 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"strconv"
+	"strings"
+
+	"github.com/alecthomas/units"
+)
+
+const (
+	cgroupRoot = `/sys/fs/cgroup`
+	procCgroup = `/proc/self/cgroup`
+
+	// memLimitAuto derives the memory limit from the cgroup
+	memLimitAuto = `auto`
+	// memLimitRatio is the proportion of the cgroup memory limit applied as the soft limit when set to auto, leaving
+	// headroom for memory not managed by the Go runtime
+	memLimitRatio = 0.9
+)
+
+// cgroup locates the cgroup controllers of the current process
+type cgroup struct {
+	root string
+	// paths maps controllers to the path of the process within their hierarchy. The unified (v2) hierarchy is keyed
+	// by the empty string.
+	paths map[string]string
+}
+
+func newCgroup(root, procCgroup string) (cgroup, error) {
+	c := cgroup{root: root, paths: make(map[string]string)}
+	f, err := os.Open(procCgroup)
+	if err != nil {
+		return c, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		// hierarchy-ID:controller-list:cgroup-path
+		fields := strings.SplitN(scanner.Text(), `:`, 3)
+		if len(fields) != 3 {
+			continue
+		}
+		for _, controller := range strings.Split(fields[1], `,`) {
+			c.paths[controller] = fields[2]
+		}
+	}
+
+	return c, scanner.Err()
+}
+
+// read returns the contents of the first of the controller's files that exists, trying the cgroup of the process
+// before the root of the hierarchy, which is where the process's cgroup is mounted within a container
+func (c cgroup) read(controller, file string) (string, bool) {
+	dir := c.root
+	if controller != `` {
+		dir = filepath.Join(c.root, controller)
+	}
+	for _, path := range []string{filepath.Join(dir, c.paths[controller], file), filepath.Join(dir, file)} {
+		b, err := os.ReadFile(path)
+		if err == nil {
+			return strings.TrimSpace(string(b)), true
+		}
+	}
+	return ``, false
+}
+
+// cpuQuota returns the number of CPUs the cgroup is limited to, if any
+func (c cgroup) cpuQuota() (float64, bool) {
+	// cgroup v2: "$MAX $PERIOD", where $MAX may be "max"
+	if v, ok := c.read(``, `cpu.max`); ok {
+		fields := strings.Fields(v)
+		if len(fields) != 2 || fields[0] == `max` {
+			return 0, false
+		}
+		return ratio(fields[0], fields[1])
+	}
+
+	// cgroup v1: a quota of -1 is unlimited
+	quota, ok := c.read(`cpu`, `cpu.cfs_quota_us`)
+	if !ok || quota == `-1` {
+		return 0, false
+	}
+	period, ok := c.read(`cpu`, `cpu.cfs_period_us`)
+	if !ok {
+		return 0, false
+	}
+	return ratio(quota, period)
+}
+
+// memoryLimit returns the memory limit of the cgroup in bytes, if any
+func (c cgroup) memoryLimit() (int64, bool) {
+	v, ok := c.read(``, `memory.max`)
+	if !ok {
+		v, ok = c.read(`memory`, `memory.limit_in_bytes`)
+	}
+	if !ok || v == `max` {
+		return 0, false
+	}
+	limit, err := strconv.ParseInt(v, 10, 64)
+	// cgroup v1 reports an unlimited cgroup as a page-aligned near-maximum value
+	if err != nil || limit <= 0 || limit >= math.MaxInt64/2 {
+		return 0, false
+	}
+	return limit, true
+}
+
+func ratio(numerator, denominator string) (float64, bool) {
+	n, err := strconv.ParseFloat(numerator, 64)
+	if err != nil || n <= 0 {
+		return 0, false
+	}
+	d, err := strconv.ParseFloat(denominator, 64)
+	if err != nil || d <= 0 {
+		return 0, false
+	}
+	return n / d, true
+}
+
+// applyRuntimeSettings sets GOMAXPROCS and the soft memory limit. A maxProcs of zero derives the value from the
+// cgroup CPU quota unless the GOMAXPROCS environment variable is set, and a memLimit of auto derives the limit from the
+// cgroup memory limit. An empty memLimit leaves the limit unchanged, so that GOMEMLIMIT continues to be respected.
+func applyRuntimeSettings(logger *slog.Logger, cg cgroup, maxProcs int, memLimit string) error {
+	switch {
+	case maxProcs > 0:
+		runtime.GOMAXPROCS(maxProcs)
+	case maxProcs < 0:
+		return fmt.Errorf("invalid GOMAXPROCS: %d", maxProcs)
+	case os.Getenv(`GOMAXPROCS`) == ``:
+		if quota, ok := cg.cpuQuota(); ok {
+			procs := max(1, int(math.Ceil(quota)))
+			if procs < runtime.GOMAXPROCS(0) {
+				runtime.GOMAXPROCS(procs)
+			}
+		}
+	}
+
+	switch memLimit {
+	case ``:
+	case memLimitAuto:
+		limit, ok := cg.memoryLimit()
+		if !ok {
+			logger.Warn("No cgroup memory limit found, leaving memory limit unchanged")
+			break
+		}
+		debug.SetMemoryLimit(int64(float64(limit) * memLimitRatio))
+	default:
+		limit, err := units.ParseBase2Bytes(memLimit)
+		if err != nil {
+			return fmt.Errorf("invalid memory limit '%s': %w", memLimit, err)
+		}
+		if limit <= 0 {
+			return errors.New(`memory limit must be positive`)
+		}
+		debug.SetMemoryLimit(int64(limit))
+	}
+
+	logger.Info("Runtime settings", "gomaxprocs", runtime.GOMAXPROCS(0), "gomemlimit", debug.SetMemoryLimit(-1))
+	return nil
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"testing"
+)
+
+func writeFiles(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestCgroup(t *testing.T) {
+	testCases := []struct {
+		name      string
+		files     map[string]string
+		wantCPU   float64
+		wantCPUOk bool
+		wantMem   int64
+		wantMemOk bool
+	}{
+		{
+			name: `v2 service`,
+			files: map[string]string{
+				`proc`: "0::/system.slice/zfs_exporter.service\n",
+				`cgroup/system.slice/zfs_exporter.service/cpu.max`:    "150000 100000\n",
+				`cgroup/system.slice/zfs_exporter.service/memory.max`: "268435456\n",
+			},
+			wantCPU: 1.5, wantCPUOk: true,
+			wantMem: 268435456, wantMemOk: true,
+		},
+		{
+			name: `v2 container`,
+			files: map[string]string{
+				`proc`:              "0::/\n",
+				`cgroup/cpu.max`:    "max 100000\n",
+				`cgroup/memory.max`: "max\n",
+			},
+		},
+		{
+			name: `v1`,
+			files: map[string]string{
+				`proc`:                                   "4:memory:/docker/abc\n3:cpu,cpuacct:/docker/abc\n",
+				`cgroup/cpu/docker/abc/cpu.cfs_quota_us`: "200000\n",
+				`cgroup/cpu/docker/abc/cpu.cfs_period_us`: "100000\n",
+				`cgroup/memory/memory.limit_in_bytes`:     "9223372036854771712\n",
+			},
+			wantCPU: 2, wantCPUOk: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			root := t.TempDir()
+			writeFiles(t, root, tc.files)
+			cg, err := newCgroup(filepath.Join(root, `cgroup`), filepath.Join(root, `proc`))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cpu, ok := cg.cpuQuota(); cpu != tc.wantCPU || ok != tc.wantCPUOk {
+				t.Errorf("Expected CPU quota %v (%t), got %v (%t)", tc.wantCPU, tc.wantCPUOk, cpu, ok)
+			}
+			if mem, ok := cg.memoryLimit(); mem != tc.wantMem || ok != tc.wantMemOk {
+				t.Errorf("Expected memory limit %d (%t), got %d (%t)", tc.wantMem, tc.wantMemOk, mem, ok)
+			}
+		})
+	}
+}
+
+func TestApplyRuntimeSettings(t *testing.T) {
+	prevProcs, prevLimit := runtime.GOMAXPROCS(0), debug.SetMemoryLimit(-1)
+	t.Cleanup(func() {
+		runtime.GOMAXPROCS(prevProcs)
+		debug.SetMemoryLimit(prevLimit)
+	})
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	if err := applyRuntimeSettings(logger, cgroup{}, 1, `64MiB`); err != nil {
+		t.Fatal(err)
+	}
+	if procs := runtime.GOMAXPROCS(0); procs != 1 {
+		t.Errorf("Expected GOMAXPROCS 1, got %d", procs)
+	}
+	if limit := debug.SetMemoryLimit(-1); limit != 64<<20 {
+		t.Errorf("Expected memory limit %d, got %d", 64<<20, limit)
+	}
+
+	for _, memLimit := range []string{`lots`, `0B`} {
+		if err := applyRuntimeSettings(logger, cgroup{}, 0, memLimit); err == nil {
+			t.Errorf("Expected error for memory limit %q", memLimit)
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/jmcgover/zfs_exporter/v2/zfs"
+)
+
+// sendEstimate is the response of the send estimate API
+type sendEstimate struct {
+	Snapshot string `json:"snapshot"`
+	From     string `json:"from,omitempty"`
+	Bytes    uint64 `json:"bytes"`
+}
+
+// sendEstimateHandler serves estimates of the size of zfs send streams, e.g. for scheduling replication over a WAN
+type sendEstimateHandler struct {
+	logger *slog.Logger
+	client zfs.Client
+}
+
+// ServeHTTP estimates the size of the stream for the snapshot query parameter on GET, incremental from the snapshot or
+// bookmark in the from query parameter if set
+func (h sendEstimateHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set(`Allow`, `GET`)
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	estimate := sendEstimate{Snapshot: r.URL.Query().Get(`snapshot`), From: r.URL.Query().Get(`from`)}
+	var err error
+	estimate.Bytes, err = h.client.SendEstimate(estimate.Snapshot, estimate.From)
+	switch {
+	case errors.Is(err, zfs.ErrInvalidSnapshot):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	case err != nil:
+		// The command output may reveal more than the caller should see, so it is only logged
+		h.logger.Error("Error estimating send", "snapshot", estimate.Snapshot, "from", estimate.From, "err", err)
+		http.Error(w, `error estimating send, see the exporter log for details`, http.StatusInternalServerError)
+		return
+	}
+	writeAPI(w, r, estimate)
+}
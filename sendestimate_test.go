@@ -0,0 +1,52 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jmcgover/zfs_exporter/v2/zfs"
+	"github.com/jmcgover/zfs_exporter/v2/zfs/mock_zfs"
+	"go.uber.org/mock/gomock"
+)
+
+func TestSendEstimateHandler(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	client := mock_zfs.NewMockClient(ctrl)
+	client.EXPECT().SendEstimate(`tank/data@2`, `tank/data#offsite`).Return(uint64(1048576), nil)
+	client.EXPECT().SendEstimate(`tank/data`, ``).Return(uint64(0), zfs.ErrInvalidSnapshot)
+	client.EXPECT().SendEstimate(`tank/gone@1`, ``).Return(uint64(0), errors.New(`exit status 1`))
+	h := sendEstimateHandler{logger: discardLogger, client: client}
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, `/api/v1/send-estimate?snapshot=tank/data@2&from=tank/data%23offsite`, nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, rec.Code)
+	}
+	var estimate sendEstimate
+	if err := json.NewDecoder(rec.Body).Decode(&estimate); err != nil {
+		t.Fatal(err)
+	}
+	if want := (sendEstimate{Snapshot: `tank/data@2`, From: `tank/data#offsite`, Bytes: 1048576}); estimate != want {
+		t.Errorf("Expected %+v, got %+v", want, estimate)
+	}
+
+	for target, code := range map[string]int{
+		`/api/v1/send-estimate?snapshot=tank/data`:   http.StatusBadRequest,
+		`/api/v1/send-estimate?snapshot=tank/gone@1`: http.StatusInternalServerError,
+	} {
+		rec = httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		if rec.Code != code {
+			t.Errorf("Expected status %d for %s, got %d", code, target, rec.Code)
+		}
+	}
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, `/api/v1/send-estimate?snapshot=tank/data@2`, nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected status %d for POST, got %d", http.StatusMethodNotAllowed, rec.Code)
+	}
+}
@@ -0,0 +1,255 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"html/template"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+const (
+	statusPath = `/status`
+	// sparklineResolution is the interval between the points of a sparkline
+	sparklineResolution = 5 * time.Minute
+	// sparklineWindow is the period covered by a sparkline
+	sparklineWindow = 6 * time.Hour
+
+	sparklineWidth  = 240
+	sparklineHeight = 32
+)
+
+// sparklinePoint is a downsampled value of a metric for a pool
+type sparklinePoint struct {
+	Timestamp time.Time
+	Value     float64
+}
+
+type sparklineKey struct {
+	pool   string
+	metric string
+}
+
+// sparklineSeries holds the points of a metric for a pool within the sparkline window. Counters are recorded as their
+// average rate per second between consecutive points.
+type sparklineSeries struct {
+	counter bool
+	points  []sparklinePoint
+	// total and updated are the previous value of a counter and when it was read
+	total   float64
+	updated time.Time
+}
+
+// sparklines keeps a few hours of selected metrics per pool in memory at a coarse resolution, and renders them as
+// sparklines on a status page, for hosts without a time series database. Series of the same metric and pool, such as
+// those of each dataset, are summed.
+type sparklines struct {
+	logger   *slog.Logger
+	gatherer prometheus.Gatherer
+	metrics  []string
+
+	mu     sync.Mutex
+	series map[sparklineKey]*sparklineSeries
+}
+
+// newSparklines returns sparklines of the named metrics, shown in the order given
+func newSparklines(logger *slog.Logger, gatherer prometheus.Gatherer, metrics []string) *sparklines {
+	return &sparklines{
+		logger:   logger,
+		gatherer: gatherer,
+		metrics:  metrics,
+		series:   make(map[sparklineKey]*sparklineSeries),
+	}
+}
+
+// Run records points until ctx is done, waiting for the duration returned by interval before each point
+func (s *sparklines) Run(ctx context.Context, interval func() time.Duration) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval()):
+		}
+		s.record(time.Now())
+	}
+}
+
+// record gathers the selected metrics, adds a point to the series of each pool and discards points outside the window
+func (s *sparklines) record(now time.Time) {
+	families, err := s.gatherer.Gather()
+	if err != nil {
+		// Gather returns whatever could be collected along with the error
+		s.logger.Warn("Error gathering metrics for sparklines", "err", err)
+	}
+
+	selected := make(map[string]bool, len(s.metrics))
+	for _, metric := range s.metrics {
+		selected[metric] = true
+	}
+	totals := make(map[sparklineKey]float64)
+	counters := make(map[sparklineKey]bool)
+	for _, family := range families {
+		if !selected[family.GetName()] {
+			continue
+		}
+		for _, m := range family.GetMetric() {
+			pool, ok := labelValue(m, `pool`)
+			if !ok {
+				continue
+			}
+			v, ok := sampleValue(family.GetType(), m)
+			if !ok {
+				continue
+			}
+			key := sparklineKey{pool: pool, metric: family.GetName()}
+			totals[key] += v
+			counters[key] = family.GetType() == dto.MetricType_COUNTER
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for key, v := range totals {
+		series, ok := s.series[key]
+		if !ok {
+			series = &sparklineSeries{counter: counters[key]}
+			s.series[key] = series
+		}
+		if !series.counter {
+			series.points = append(series.points, sparklinePoint{Timestamp: now, Value: v})
+			continue
+		}
+		// The first value of a counter, or its value following a reset, only serves as the base of the next rate
+		if !series.updated.IsZero() && v >= series.total {
+			rate := (v - series.total) / now.Sub(series.updated).Seconds()
+			series.points = append(series.points, sparklinePoint{Timestamp: now, Value: rate})
+		}
+		series.total, series.updated = v, now
+	}
+
+	cutoff := now.Add(-sparklineWindow)
+	for key, series := range s.series {
+		expired := 0
+		for expired < len(series.points) && !series.points[expired].Timestamp.After(cutoff) {
+			expired++
+		}
+		series.points = series.points[expired:]
+		// Series of exported pools, or of metrics no longer collected, are forgotten once their points expire
+		if _, ok := totals[key]; !ok && len(series.points) == 0 {
+			delete(s.series, key)
+		}
+	}
+}
+
+// statusSparkline is a sparkline as rendered on the status page
+type statusSparkline struct {
+	Metric string
+	Latest string
+	Points string
+}
+
+type statusPool struct {
+	Name       string
+	Sparklines []statusSparkline
+}
+
+var statusTemplate = template.Must(template.New(`status`).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<title>ZFS Exporter Status</title>
+<style>
+body { font-family: sans-serif; }
+td { padding: 2px 12px 2px 0; }
+polyline { fill: none; stroke: #e6522c; stroke-width: 1.5; }
+</style>
+</head>
+<body>
+<h1>ZFS Exporter Status</h1>
+<p>The last {{.Window}} of each pool, at a resolution of {{.Resolution}}. Counters are shown as a rate per second.</p>
+{{range .Pools}}<h2>{{.Name}}</h2>
+<table>
+{{range .Sparklines}}<tr><td>{{.Metric}}</td><td><svg width="{{$.Width}}" height="{{$.Height}}"><polyline points="{{.Points}}"/></svg></td><td>{{.Latest}}</td></tr>
+{{end}}</table>
+{{else}}<p>No samples have been recorded yet.</p>
+{{end}}</body>
+</html>
+`))
+
+// ServeHTTP renders the sparklines of each pool as an HTML page
+func (s *sparklines) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set(`Allow`, `GET`)
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set(`Content-Type`, `text/html; charset=utf-8`)
+	if err := statusTemplate.Execute(w, map[string]any{
+		`Window`:     sparklineWindow,
+		`Resolution`: sparklineResolution,
+		`Width`:      sparklineWidth,
+		`Height`:     sparklineHeight,
+		`Pools`:      s.pools(),
+	}); err != nil {
+		s.logger.Error("Error rendering status page", "err", err)
+	}
+}
+
+// pools returns the sparklines of each pool, sorted by pool name
+func (s *sparklines) pools() []statusPool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	byPool := make(map[string][]statusSparkline)
+	for _, metric := range s.metrics {
+		for key, series := range s.series {
+			if key.metric != metric || len(series.points) == 0 {
+				continue
+			}
+			latest := fmt.Sprintf(`%.4g`, series.points[len(series.points)-1].Value)
+			if series.counter {
+				latest += `/s`
+			}
+			byPool[key.pool] = append(byPool[key.pool], statusSparkline{
+				Metric: metric,
+				Latest: latest,
+				Points: sparklinePolyline(series.points),
+			})
+		}
+	}
+
+	result := make([]statusPool, 0, len(byPool))
+	for pool, lines := range byPool {
+		result = append(result, statusPool{Name: pool, Sparklines: lines})
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result
+}
+
+// sparklinePolyline returns the points of an SVG polyline plotting the values against time, scaled to the range of the
+// values and the sparkline window
+func sparklinePolyline(points []sparklinePoint) string {
+	lo, hi := points[0].Value, points[0].Value
+	for _, p := range points {
+		lo, hi = min(lo, p.Value), max(hi, p.Value)
+	}
+	end := points[len(points)-1].Timestamp
+	coords := make([]string, len(points))
+	for i, p := range points {
+		x := sparklineWidth * (1 - end.Sub(p.Timestamp).Seconds()/sparklineWindow.Seconds())
+		// A constant series is drawn across the middle
+		y := sparklineHeight / 2.0
+		if hi > lo {
+			y = (sparklineHeight - 1) * (1 - (p.Value-lo)/(hi-lo))
+		}
+		coords[i] = fmt.Sprintf(`%.1f,%.1f`, x, y)
+	}
+	return strings.Join(coords, ` `)
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestSparklines(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	allocated := prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: `zfs_pool_allocated_bytes`, Help: `.`}, []string{`pool`})
+	written := prometheus.NewCounterVec(prometheus.CounterOpts{Name: `zfs_dataset_written_bytes_total`, Help: `.`}, []string{`pool`, `dataset`})
+	registry.MustRegister(allocated, written)
+	s := newSparklines(discardLogger, registry, []string{`zfs_pool_allocated_bytes`, `zfs_dataset_written_bytes_total`})
+
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := range 4 {
+		allocated.WithLabelValues(`tank`).Set(float64(1024 * (i + 1)))
+		// The datasets of a pool are summed, for a rate of 600 bytes per second
+		written.WithLabelValues(`tank`, `tank/a`).Add(60000)
+		written.WithLabelValues(`tank`, `tank/b`).Add(120000)
+		s.record(start.Add(time.Duration(i) * sparklineResolution))
+	}
+
+	gauge := s.series[sparklineKey{pool: `tank`, metric: `zfs_pool_allocated_bytes`}]
+	if len(gauge.points) != 4 || gauge.points[3].Value != 4096 {
+		t.Errorf("Unexpected gauge points %+v", gauge.points)
+	}
+	counter := s.series[sparklineKey{pool: `tank`, metric: `zfs_dataset_written_bytes_total`}]
+	if len(counter.points) != 3 || counter.points[2].Value != 600 {
+		t.Errorf("Unexpected counter points %+v", counter.points)
+	}
+
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, statusPath, nil))
+	body := rec.Body.String()
+	for _, want := range []string{`<h2>tank</h2>`, `<td>zfs_pool_allocated_bytes</td>`, `<td>600/s</td>`, `<polyline points="`} {
+		if !strings.Contains(body, want) {
+			t.Errorf("Expected status page to contain %q:\n%s", want, body)
+		}
+	}
+	if strings.Index(body, `zfs_pool_allocated_bytes`) > strings.Index(body, `zfs_dataset_written_bytes_total`) {
+		t.Error("Expected sparklines in the order of the metrics given")
+	}
+
+	// Once the pool is exported, its series are forgotten after the window
+	registry.Unregister(allocated)
+	registry.Unregister(written)
+	s.record(start.Add(sparklineWindow))
+	if len(s.series) != 2 {
+		t.Errorf("Expected series to be retained within the window, got %d", len(s.series))
+	}
+	s.record(start.Add(sparklineWindow + 4*sparklineResolution))
+	if len(s.series) != 0 {
+		t.Errorf("Expected expired series to be forgotten, got %d", len(s.series))
+	}
+}
+
+func TestSparklinePolyline(t *testing.T) {
+	end := time.Date(2025, 1, 1, 6, 0, 0, 0, time.UTC)
+	points := []sparklinePoint{
+		{Timestamp: end.Add(-sparklineWindow / 2), Value: 10},
+		{Timestamp: end, Value: 20},
+	}
+	if got, want := sparklinePolyline(points), `120.0,31.0 240.0,0.0`; got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+	if got, want := sparklinePolyline(points[:1]), `240.0,16.0`; got != want {
+		t.Errorf("Expected %q for a constant series, got %q", want, got)
+	}
+}
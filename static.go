@@ -0,0 +1,7 @@
+//go:build static && cgo
+
+package main
+
+// The static tag, set by release builds, guarantees a binary that does not link libc, so that it runs on appliances
+// that lack it or ship another. Building with the tag and cgo enabled fails on the undefined identifier below.
+var _ = static_build_requires_CGO_ENABLED_0
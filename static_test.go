@@ -0,0 +1,61 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+)
+
+var promuTagsRe = regexp.MustCompile(`-tags\s+(\S+)`)
+
+// cgoPackages lists the packages built from cgo files with cgo enabled and the given tags, of which standard packages
+// only where std is set
+func cgoPackages(t *testing.T, tags string, std bool) []string {
+	t.Helper()
+	format := `{{if and .CgoFiles (not .Standard)}}{{.ImportPath}}{{end}}`
+	if std {
+		format = `{{if .CgoFiles}}{{.ImportPath}}{{end}}`
+	}
+	cmd := exec.Command(`go`, `list`, `-deps`, `-tags`, tags, `-f`, format, `.`)
+	cmd.Env = append(os.Environ(), `CGO_ENABLED=1`)
+	out, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("go list failed: %v", err)
+	}
+	return strings.Fields(string(out))
+}
+
+// TestStaticBuild fails where the dependencies of a release build would use cgo even if it were enabled, so that the
+// static tag continues to build and the binary stays static without relying on CGO_ENABLED=0 alone.
+func TestStaticBuild(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping static build check, which runs go list, in short mode")
+	}
+	if _, err := exec.LookPath(`go`); err != nil {
+		t.Skip("Skipping static build check, go is not in PATH")
+	}
+
+	promu, err := os.ReadFile(`.promu.yml`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := promuTagsRe.FindSubmatch(promu)
+	if m == nil {
+		t.Fatal("No build tags in .promu.yml")
+	}
+	tags := string(m[1])
+	if !slices.Contains(strings.Split(tags, `,`), `static`) {
+		t.Errorf("Expected release build tags to include static, got %s", tags)
+	}
+	if pkgs := cgoPackages(t, tags, true); len(pkgs) > 0 {
+		t.Errorf("Expected no cgo packages with tags %s, got %v", tags, pkgs)
+	}
+
+	// cgo is confined to the lzc backend
+	if pkgs := cgoPackages(t, `lzc`, false); !slices.Equal(pkgs, []string{`github.com/jmcgover/zfs_exporter/v2/zfs`}) {
+		t.Errorf("Expected only the zfs package to use cgo with tag lzc, got %v", pkgs)
+	}
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// textfileName is the name of the file written for the node_exporter textfile collector, which reads files with a .prom
+// suffix
+const textfileName = `zfs_exporter.prom`
+
+// textfileWriter periodically writes the gathered metrics to a file in the directory read by the node_exporter textfile
+// collector, for hosts where running another listener is undesirable
+type textfileWriter struct {
+	logger   *slog.Logger
+	gatherer prometheus.Gatherer
+	path     string
+}
+
+func newTextfileWriter(logger *slog.Logger, gatherer prometheus.Gatherer, dir string) (*textfileWriter, error) {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("not a directory: %s", dir)
+	}
+
+	return &textfileWriter{logger: logger, gatherer: gatherer, path: filepath.Join(dir, textfileName)}, nil
+}
+
+// Run writes the metrics immediately, and then every interval until ctx is done
+func (w *textfileWriter) Run(ctx context.Context, interval func() time.Duration) {
+	for {
+		if err := w.write(); err != nil {
+			w.logger.Error("Error writing textfile", "path", w.path, "err", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval()):
+		}
+	}
+}
+
+// write replaces the file atomically, so that the textfile collector never reads a partially written file
+func (w *textfileWriter) write() error {
+	begin := time.Now()
+	if err := prometheus.WriteToTextfile(w.path, w.gatherer); err != nil {
+		return err
+	}
+	w.logger.Debug("Wrote textfile", "path", w.path, "duration_seconds", time.Since(begin).Seconds())
+
+	return nil
+}
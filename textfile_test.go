@@ -0,0 +1,45 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestTextfileWriter(t *testing.T) {
+	const expected = `# HELP zfs_pool_health Health.
+# TYPE zfs_pool_health gauge
+zfs_pool_health{pool="tank"} 0
+`
+
+	registry := prometheus.NewRegistry()
+	health := prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: `zfs_pool_health`, Help: `Health.`}, []string{`pool`})
+	registry.MustRegister(health)
+	health.WithLabelValues(`tank`)
+
+	dir := t.TempDir()
+	w, err := newTextfileWriter(discardLogger, registry, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = w.write(); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, textfileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != expected {
+		t.Errorf("Unexpected textfile:\n%s", data)
+	}
+	// Only the completed file remains, so that the textfile collector never reads a temporary file
+	if entries, err := os.ReadDir(dir); err != nil || len(entries) != 1 {
+		t.Errorf("Expected only %s in the directory, got %v, %v", textfileName, entries, err)
+	}
+
+	if _, err = newTextfileWriter(discardLogger, registry, filepath.Join(dir, textfileName)); err == nil {
+		t.Error("Expected error writing to a path that is not a directory")
+	}
+}
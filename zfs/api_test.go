@@ -0,0 +1,111 @@
+package zfs
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/importer"
+	"go/token"
+	"go/types"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"golang.org/x/exp/apidiff"
+	"golang.org/x/mod/modfile"
+	"golang.org/x/tools/go/gcexportdata"
+)
+
+var updateAPI = flag.Bool(`update-api`, false, `Write the exported API of the package as the baseline of the current major version`)
+
+// modulePathMajorRe matches the major version suffix of a package path, which is omitted for v0 and v1
+var modulePathMajorRe = regexp.MustCompile(`/v([2-9]|[1-9][0-9]+)(/|$)`)
+
+// apiBaseline returns the path of the exported API baseline of the major version
+func apiBaseline(major int) string {
+	return filepath.Join(`testdata`, `api`, fmt.Sprintf(`v%d.export`, major))
+}
+
+// versionMajor returns the major version released from the VERSION file at the root of the module
+func versionMajor(t *testing.T) int {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(`..`, `VERSION`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	major, _, _ := strings.Cut(strings.TrimSpace(string(data)), `.`)
+	v, err := strconv.Atoi(major)
+	if err != nil {
+		t.Fatalf("Invalid VERSION '%s'", strings.TrimSpace(string(data)))
+	}
+	return v
+}
+
+// TestAPICompatibility fails where the exported API of the package changes incompatibly with the baseline of the
+// current major version, as other tools consume the package as a library. An incompatible change requires a new major
+// version: bump VERSION and the module path, then write the baseline of the new version with -update-api. Compatible
+// changes, such as additions, are logged.
+func TestAPICompatibility(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping API compatibility check, which type checks the package, in short mode")
+	}
+
+	gomod, err := os.ReadFile(filepath.Join(`..`, `go.mod`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The package is type checked from source, as the export data of the compiler may be newer than gcexportdata reads
+	fset := token.NewFileSet()
+	current, err := importer.ForCompiler(fset, `source`, nil).Import(modfile.ModulePath(gomod) + `/zfs`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	major := versionMajor(t)
+	pathMajor := 1
+	if m := modulePathMajorRe.FindStringSubmatch(current.Path()); m != nil {
+		pathMajor, _ = strconv.Atoi(m[1])
+	}
+	if max(major, 1) != pathMajor {
+		t.Fatalf("VERSION is major version %d, but the module path %s is major version %d", major, current.Path(), pathMajor)
+	}
+
+	path := apiBaseline(major)
+	if *updateAPI {
+		var buf bytes.Buffer
+		if err = gcexportdata.Write(&buf, fset, current); err != nil {
+			t.Fatal(err)
+		}
+		if err = os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err = os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		t.Fatalf("No API baseline for major version %d, write one with: go test ./zfs -run TestAPICompatibility -update-api", major)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	baseline, err := gcexportdata.Read(f, token.NewFileSet(), make(map[string]*types.Package), current.Path())
+	if err != nil {
+		t.Fatalf("Error reading API baseline %s: %v", path, err)
+	}
+
+	for _, change := range apidiff.Changes(baseline, current).Changes {
+		if change.Compatible {
+			t.Logf("Compatible API change: %s", change.Message)
+			continue
+		}
+		t.Errorf("Incompatible API change within major version %d: %s", major, change.Message)
+	}
+}
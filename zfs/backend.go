@@ -0,0 +1,105 @@
+package zfs
+
+import (
+	"encoding/json"
+	"slices"
+	"strings"
+)
+
+// Backend selects how the client enumerates datasets and reads their properties
+type Backend string
+
+const (
+	// BackendCLI runs zfs for every call
+	BackendCLI Backend = `cli`
+	// BackendLZC reads filesystems and volumes with a channel program run in-process via libzfs_core, avoiding the
+	// fork and exec of zfs and the formatting and parsing of its output. It requires a build with the lzc tag.
+	BackendLZC Backend = `lzc`
+)
+
+// Backends are the supported backends
+var Backends = []string{string(BackendCLI), string(BackendLZC)}
+
+// lzcProgramLimits are those of channel programs run by the lzc backend for enumeration, the maximum permitted, as the
+// number of datasets is not known in advance
+var lzcProgramLimits = ProgramLimits{Instructions: 100_000_000, Memory: 100 << 20}
+
+// lzcClient reads filesystems and volumes with the dataset properties program run via libzfs_core, and runs commands
+// for all other calls, including those for snapshots, which channel programs do not list. Calls via libzfs_core cannot
+// be interrupted, so the command timeout does not apply to them.
+type lzcClient struct {
+	clientImpl
+}
+
+// lzcDatasets runs the dataset properties program for the pool
+func lzcDatasets(pool string, limits ProgramLimits, props []string) (map[string]DatasetListT, error) {
+	data, err := lzcChannelProgram(pool, limits, datasetPropertiesProgram, datasetProgramArgs(pool, props))
+	if err != nil {
+		return nil, err
+	}
+	var o datasetProgramOutputT
+	if err = json.Unmarshal(data, &o); err != nil {
+		return nil, err
+	}
+	return o.datasets(pool), nil
+}
+
+func (z lzcClient) Datasets(pool string, kind DatasetKind) Datasets {
+	if kind != DatasetFilesystem && kind != DatasetVolume {
+		return z.clientImpl.Datasets(pool, kind)
+	}
+	return lzcDatasetsImpl{pool: pool, kind: kind}
+}
+
+func (z lzcClient) DatasetList(pool string, depth int, kinds []DatasetKind, props ...string) (map[string]DatasetListT, error) {
+	if slices.Contains(kinds, DatasetSnapshot) {
+		return z.clientImpl.DatasetList(pool, depth, kinds, props...)
+	}
+	datasets, err := lzcDatasets(pool, lzcProgramLimits, props)
+	if err != nil {
+		return nil, err
+	}
+	for name, dataset := range datasets {
+		if !slices.Contains(kinds, dataset.Kind()) || (depth >= 0 && strings.Count(strings.TrimPrefix(name, pool), `/`) > depth) {
+			delete(datasets, name)
+		}
+	}
+	return datasets, nil
+}
+
+func (z lzcClient) DatasetProgram(pool string, limits ProgramLimits, props ...string) (map[string]DatasetListT, error) {
+	return lzcDatasets(pool, limits, props)
+}
+
+// lzcDatasetsImpl reads the properties of the filesystems or volumes of a pool via libzfs_core
+type lzcDatasetsImpl struct {
+	pool string
+	kind DatasetKind
+}
+
+func (d lzcDatasetsImpl) Pool() string {
+	return d.pool
+}
+
+func (d lzcDatasetsImpl) Kind() DatasetKind {
+	return d.kind
+}
+
+func (d lzcDatasetsImpl) Properties(props ...string) ([]DatasetProperties, error) {
+	datasets, err := lzcDatasets(d.pool, lzcProgramLimits, props)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]DatasetProperties, 0, len(datasets))
+	for name, dataset := range datasets {
+		if dataset.Kind() != d.kind {
+			continue
+		}
+		properties := make(map[string]string, len(dataset.Properties))
+		for k, v := range dataset.Properties {
+			properties[k] = string(v.Value)
+		}
+		result = append(result, &datasetPropertiesImpl{datasetName: name, properties: properties})
+	}
+	return result, nil
+}
@@ -0,0 +1,35 @@
+//go:build !lzc
+
+package zfs
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestLZCClient(t *testing.T) {
+	runner := &countingRunner{Runner: fixtureRunner{}, err: errors.New(`failed`)}
+	client := New(Config{Logger: testLogger, Runner: runner, Backend: BackendLZC})
+
+	// Filesystems and volumes are read via libzfs_core, which this build lacks, without running commands
+	if _, err := client.Datasets(`testpool`, DatasetFilesystem).Properties(`used`); !errors.Is(err, errLZCUnsupported) {
+		t.Errorf("Expected %v, got %v", errLZCUnsupported, err)
+	}
+	if _, err := client.DatasetList(`testpool`, -1, []DatasetKind{DatasetFilesystem, DatasetVolume}, `used`); !errors.Is(err, errLZCUnsupported) {
+		t.Errorf("Expected %v, got %v", errLZCUnsupported, err)
+	}
+	if runner.calls != 0 {
+		t.Errorf("Expected no commands, got %d", runner.calls)
+	}
+
+	// Snapshots are listed by zfs
+	if _, err := client.Datasets(`testpool`, DatasetSnapshot).Properties(`used`); err == nil || errors.Is(err, errLZCUnsupported) {
+		t.Errorf("Expected command error, got %v", err)
+	}
+	if _, err := client.DatasetList(`testpool`, -1, []DatasetKind{DatasetSnapshot}, `used`); err == nil || errors.Is(err, errLZCUnsupported) {
+		t.Errorf("Expected command error, got %v", err)
+	}
+	if runner.calls != 2 {
+		t.Errorf("Expected 2 commands, got %d", runner.calls)
+	}
+}
@@ -0,0 +1,251 @@
+package zfs
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+type cacheEntry struct {
+	value   any
+	expires time.Time
+}
+
+// cachingClient wraps a Client, so that frequent scrapes, e.g. by multiple Prometheus servers, do not each execute the
+// underlying commands. Concurrent identical calls share a single execution, and successful results are reused for the
+// TTL, if non-zero. Results are shared between callers, and must not be modified.
+type cachingClient struct {
+	client  Client
+	ttl     time.Duration
+	now     func() time.Time
+	group   singleflight.Group
+	mu      sync.Mutex
+	entries map[string]cacheEntry
+}
+
+func newCachingClient(client Client, ttl time.Duration) *cachingClient {
+	return &cachingClient{client: client, ttl: ttl, now: time.Now, entries: make(map[string]cacheEntry)}
+}
+
+// cached returns the value stored under key if it has not expired, otherwise calling fetch and storing the result.
+// Concurrent callers for the same key share a single call to fetch, and the time they spend waiting for another
+// caller's fetch is recorded as queue wait. Errors are not cached.
+func cached[T any](c *cachingClient, key string, fetch func() (T, error)) (T, error) {
+	if v, ok := c.lookup(key); ok {
+		return v.(T), nil
+	}
+
+	begin := time.Now()
+	leader := false
+	v, err, _ := c.group.Do(key, func() (any, error) {
+		leader = true
+		// A caller that missed the cache just as another call completed would otherwise run the command again.
+		if v, ok := c.lookup(key); ok {
+			return v, nil
+		}
+		v, err := fetch()
+		if err == nil {
+			c.store(key, v)
+		}
+		return v, err
+	})
+	var wait time.Duration
+	if !leader {
+		wait = time.Since(begin)
+	}
+	recordQueueWait(cacheOperation(key), wait)
+
+	// The assertion fails only where fetch returned a nil interface, for which the zero value is equivalent.
+	result, _ := v.(T)
+	return result, err
+}
+
+// lookup returns the value stored under key, if it has not expired
+func (c *cachingClient) lookup(key string) (any, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.entries[key]; ok && c.now().Before(e.expires) {
+		return e.value, true
+	}
+	return nil, false
+}
+
+// store records the value under key, and removes expired entries
+func (c *cachingClient) store(key string, v any) {
+	if c.ttl <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := c.now()
+	for k, e := range c.entries {
+		if !now.Before(e.expires) {
+			delete(c.entries, k)
+		}
+	}
+	c.entries[key] = cacheEntry{value: v, expires: now.Add(c.ttl)}
+}
+
+// cacheOperation returns the method name from a cache key
+func cacheOperation(key string) string {
+	method, _, _ := strings.Cut(key, "\x00")
+	return method
+}
+
+func cacheKey(method string, args ...any) string {
+	parts := make([]string, 0, len(args)+1)
+	parts = append(parts, method)
+	for _, arg := range args {
+		parts = append(parts, fmt.Sprint(arg))
+	}
+	return strings.Join(parts, "\x00")
+}
+
+func (c *cachingClient) PoolNames() ([]string, error) {
+	return cached(c, cacheKey(`PoolNames`), c.client.PoolNames)
+}
+
+func (c *cachingClient) Pool(name string) Pool {
+	return cachingPool{Pool: c.client.Pool(name), cache: c}
+}
+
+func (c *cachingClient) Datasets(pool string, kind DatasetKind) Datasets {
+	return cachingDatasets{Datasets: c.client.Datasets(pool, kind), cache: c}
+}
+
+func (c *cachingClient) PoolStatus(power bool) (map[string]PoolStatusT, error) {
+	return cached(c, cacheKey(`PoolStatus`, power), func() (map[string]PoolStatusT, error) {
+		return c.client.PoolStatus(power)
+	})
+}
+
+func (c *cachingClient) PoolIostats(vdevs bool) (map[string]PoolIostatT, error) {
+	return cached(c, cacheKey(`PoolIostats`, vdevs), func() (map[string]PoolIostatT, error) {
+		return c.client.PoolIostats(vdevs)
+	})
+}
+
+func (c *cachingClient) PoolList(props ...string) (map[string]PoolListT, error) {
+	return cached(c, cacheKey(`PoolList`, props), func() (map[string]PoolListT, error) {
+		return c.client.PoolList(props...)
+	})
+}
+
+func (c *cachingClient) PoolGet(props ...string) (map[string]PoolListT, error) {
+	return cached(c, cacheKey(`PoolGet`, props), func() (map[string]PoolListT, error) {
+		return c.client.PoolGet(props...)
+	})
+}
+
+func (c *cachingClient) DDTStats(pool string) (DDTStatsT, error) {
+	return cached(c, cacheKey(`DDTStats`, pool), func() (DDTStatsT, error) {
+		return c.client.DDTStats(pool)
+	})
+}
+
+func (c *cachingClient) RemovalStats(pool string) (RemovalStatsT, error) {
+	return cached(c, cacheKey(`RemovalStats`, pool), func() (RemovalStatsT, error) {
+		return c.client.RemovalStats(pool)
+	})
+}
+
+func (c *cachingClient) PoolErrorLog(pool string) (PoolErrorLogT, error) {
+	errorLog, ok := c.client.(ErrorLogClient)
+	if !ok {
+		return PoolErrorLogT{}, errors.ErrUnsupported
+	}
+	return cached(c, cacheKey(`PoolErrorLog`, pool), func() (PoolErrorLogT, error) {
+		return errorLog.PoolErrorLog(pool)
+	})
+}
+
+func (c *cachingClient) LatencyHistograms(pool string) (LatencyHistogramsT, error) {
+	histograms, ok := c.client.(HistogramClient)
+	if !ok {
+		return LatencyHistogramsT{}, errors.ErrUnsupported
+	}
+	return cached(c, cacheKey(`LatencyHistograms`, pool), func() (LatencyHistogramsT, error) {
+		return histograms.LatencyHistograms(pool)
+	})
+}
+
+func (c *cachingClient) DatasetProgram(pool string, limits ProgramLimits, props ...string) (map[string]DatasetListT, error) {
+	program, ok := c.client.(ProgramClient)
+	if !ok {
+		return nil, errors.ErrUnsupported
+	}
+	return cached(c, cacheKey(`DatasetProgram`, pool, limits, props), func() (map[string]DatasetListT, error) {
+		return program.DatasetProgram(pool, limits, props...)
+	})
+}
+
+func (c *cachingClient) DatasetList(pool string, depth int, kinds []DatasetKind, props ...string) (map[string]DatasetListT, error) {
+	return cached(c, cacheKey(`DatasetList`, pool, depth, kinds, props), func() (map[string]DatasetListT, error) {
+		return c.client.DatasetList(pool, depth, kinds, props...)
+	})
+}
+
+func (c *cachingClient) DatasetGet(pool string, kinds []DatasetKind, props ...string) (map[string]DatasetListT, error) {
+	return cached(c, cacheKey(`DatasetGet`, pool, kinds, props), func() (map[string]DatasetListT, error) {
+		return c.client.DatasetGet(pool, kinds, props...)
+	})
+}
+
+func (c *cachingClient) ArcStats() (map[string]string, error) {
+	return cached(c, cacheKey(`ArcStats`), c.client.ArcStats)
+}
+
+func (c *cachingClient) Txgs(pool string) ([]TxgT, error) {
+	return cached(c, cacheKey(`Txgs`, pool), func() ([]TxgT, error) {
+		return c.client.Txgs(pool)
+	})
+}
+
+func (c *cachingClient) ObjsetStats(pool string) (map[string]ObjsetStatsT, error) {
+	return cached(c, cacheKey(`ObjsetStats`, pool), func() (map[string]ObjsetStatsT, error) {
+		return c.client.ObjsetStats(pool)
+	})
+}
+
+func (c *cachingClient) Events() ([]EventT, error) {
+	return cached(c, cacheKey(`Events`), c.client.Events)
+}
+
+func (c *cachingClient) SendEstimate(snapshot, from string) (uint64, error) {
+	return cached(c, cacheKey(`SendEstimate`, snapshot, from), func() (uint64, error) {
+		return c.client.SendEstimate(snapshot, from)
+	})
+}
+
+func (c *cachingClient) LatestSnapshot(dataset string) (string, error) {
+	return cached(c, cacheKey(`LatestSnapshot`, dataset), func() (string, error) {
+		return c.client.LatestSnapshot(dataset)
+	})
+}
+
+type cachingPool struct {
+	Pool
+	cache *cachingClient
+}
+
+func (p cachingPool) Properties(props ...string) (PoolProperties, error) {
+	return cached(p.cache, cacheKey(`Pool.Properties`, p.Name(), props), func() (PoolProperties, error) {
+		return p.Pool.Properties(props...)
+	})
+}
+
+type cachingDatasets struct {
+	Datasets
+	cache *cachingClient
+}
+
+func (d cachingDatasets) Properties(props ...string) ([]DatasetProperties, error) {
+	return cached(d.cache, cacheKey(`Datasets.Properties`, d.Pool(), d.Kind(), props), func() ([]DatasetProperties, error) {
+		return d.Datasets.Properties(props...)
+	})
+}
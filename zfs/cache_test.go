@@ -0,0 +1,181 @@
+package zfs
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// countingRunner counts the commands run by the wrapped runner, optionally failing them
+type countingRunner struct {
+	Runner
+	mu    sync.Mutex
+	calls int
+	err   error
+}
+
+func (r *countingRunner) Run(ctx context.Context, name string, args ...string) ([]byte, []byte, error) {
+	r.mu.Lock()
+	r.calls++
+	r.mu.Unlock()
+	if r.err != nil {
+		return nil, nil, r.err
+	}
+	return r.Runner.Run(ctx, name, args...)
+}
+
+func TestCachingClient(t *testing.T) {
+	runner := &countingRunner{Runner: fixtureRunner{
+		`zpool list -Ho name`: `zpool_list_names.txt`,
+		`zpool get -Hpo name,property,value size,health tank`: `zpool_get.txt`,
+		`zpool get -Hpo name,property,value size tank`:        `zpool_get.txt`,
+	}}
+	now := time.Unix(0, 0)
+	client := newCachingClient(New(Config{Logger: testLogger, Runner: runner}), time.Minute)
+	client.now = func() time.Time { return now }
+
+	for range 2 {
+		if _, err := client.PoolNames(); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := client.Pool(`tank`).Properties(`size`, `health`); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if runner.calls != 2 {
+		t.Errorf("Expected 2 commands within the TTL, got %d", runner.calls)
+	}
+
+	// Differing arguments are cached separately.
+	if _, err := client.Pool(`tank`).Properties(`size`); err != nil {
+		t.Fatal(err)
+	}
+	if runner.calls != 3 {
+		t.Errorf("Expected 3 commands, got %d", runner.calls)
+	}
+
+	now = now.Add(time.Minute)
+	if _, err := client.PoolNames(); err != nil {
+		t.Fatal(err)
+	}
+	if runner.calls != 4 {
+		t.Errorf("Expected command to be run again after the TTL, got %d commands", runner.calls)
+	}
+	if len(client.entries) != 1 {
+		t.Errorf("Expected expired entries to be removed, got %d entries", len(client.entries))
+	}
+}
+
+func TestCachingClientErrors(t *testing.T) {
+	runner := &countingRunner{Runner: fixtureRunner{}, err: errors.New(`failed`)}
+	client := New(Config{Logger: testLogger, Runner: runner, CacheTTL: time.Minute})
+
+	for range 2 {
+		if _, err := client.PoolNames(); err == nil {
+			t.Fatal(`Expected error, got nil`)
+		}
+	}
+	if runner.calls != 2 {
+		t.Errorf("Expected errors not to be cached, got %d commands", runner.calls)
+	}
+}
+
+// blockingRunner blocks each command until released, signalling when a command has started
+type blockingRunner struct {
+	Runner
+	mu      sync.Mutex
+	calls   int
+	started chan struct{}
+	release chan struct{}
+}
+
+func (r *blockingRunner) Run(ctx context.Context, name string, args ...string) ([]byte, []byte, error) {
+	r.mu.Lock()
+	r.calls++
+	r.mu.Unlock()
+	r.started <- struct{}{}
+	<-r.release
+	return r.Runner.Run(ctx, name, args...)
+}
+
+func TestCachingClientConcurrent(t *testing.T) {
+	runner := &blockingRunner{
+		Runner:  fixtureRunner{`zpool list -Ho name`: `zpool_list_names.txt`},
+		started: make(chan struct{}, 2),
+		release: make(chan struct{}),
+	}
+	// Without a TTL, only concurrent calls are shared.
+	client := New(Config{Logger: testLogger, Runner: runner})
+	before := QueueWaits()[`PoolNames`]
+
+	var wg sync.WaitGroup
+	results := make([][]string, 2)
+	for i := range results {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			names, err := client.PoolNames()
+			if err != nil {
+				t.Error(err)
+			}
+			results[i] = names
+		}()
+		if i == 0 {
+			<-runner.started
+		}
+	}
+	// Allow the second call to join the first before it completes.
+	time.Sleep(50 * time.Millisecond)
+	close(runner.release)
+	wg.Wait()
+
+	if runner.calls != 1 {
+		t.Errorf("Expected concurrent calls to share 1 command, got %d", runner.calls)
+	}
+	if len(results[0]) != 2 || len(results[1]) != 2 {
+		t.Errorf("Expected both callers to receive the pool names, got %v", results)
+	}
+	// Only the caller that joined the first is recorded as waiting
+	after := QueueWaits()[`PoolNames`]
+	if after.Count-before.Count != 2 || after.Total-before.Total < 40*time.Millisecond {
+		t.Errorf("Expected 2 requests to record the wait of the second, got %d requests waiting %s", after.Count-before.Count, after.Total-before.Total)
+	}
+
+	if _, err := client.PoolNames(); err != nil {
+		t.Fatal(err)
+	}
+	if runner.calls != 2 {
+		t.Errorf("Expected subsequent call to run the command again, got %d commands", runner.calls)
+	}
+}
+
+func TestCachingClientLateCaller(t *testing.T) {
+	runner := &countingRunner{Runner: fixtureRunner{`zpool list -Ho name`: `zpool_list_names.txt`}}
+	now := time.Unix(0, 0)
+	client := newCachingClient(New(Config{Logger: testLogger, Runner: runner}), time.Minute)
+	client.now = func() time.Time { return now }
+	if _, err := client.PoolNames(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Another caller refreshes the expired entry just after this one has found it expired, as happens where a scrape
+	// and an API request race. The result of the other caller is reused rather than running the command again.
+	now = now.Add(time.Minute)
+	client.now = func() time.Time {
+		client.now = func() time.Time { return now }
+		client.entries[cacheKey(`PoolNames`)] = cacheEntry{value: []string{`tank`}, expires: now.Add(time.Minute)}
+		return now
+	}
+	names, err := client.PoolNames()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if runner.calls != 1 {
+		t.Errorf("Expected the refreshed entry to be reused, got %d commands", runner.calls)
+	}
+	if len(names) != 1 || names[0] != `tank` {
+		t.Errorf("Expected the pool names of the other caller, got %v", names)
+	}
+}
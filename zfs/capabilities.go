@@ -0,0 +1,142 @@
+package zfs
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Capability identifies optional functionality of the installed ZFS userland
+type Capability string
+
+const (
+	// CapabilityJSON indicates that zpool/zfs subcommands support --json output
+	CapabilityJSON Capability = `json`
+	// CapabilityJSONInt indicates that --json-int is supported, emitting numeric values as JSON numbers
+	CapabilityJSONInt Capability = `json-int`
+	// CapabilityIostatJSON indicates that `zpool iostat` supports --json output
+	CapabilityIostatJSON Capability = `iostat-json`
+	// CapabilityIostatLatency indicates that `zpool iostat -l` reports average latencies
+	CapabilityIostatLatency Capability = `iostat-latency`
+	// CapabilityTrimStatus indicates that `zpool status -t` reports TRIM status
+	CapabilityTrimStatus Capability = `trim-status`
+	// CapabilityPowerStatus indicates that `zpool status --power` reports the power state of enclosure slots
+	CapabilityPowerStatus Capability = `power-status`
+)
+
+// VersionT is a parsed OpenZFS release version
+type VersionT struct {
+	Major int
+	Minor int
+	Patch int
+}
+
+// ParseVersion parses the version from a release string as reported by `zfs version`, such as `zfs-2.3.1-1` or
+// `zfs-kmod-2.3.1-1`
+func ParseVersion(s string) (VersionT, error) {
+	release := strings.TrimPrefix(strings.TrimPrefix(s, `zfs-`), `kmod-`)
+	release, _, _ = strings.Cut(release, `-`)
+
+	var (
+		v     VersionT
+		err   error
+		parts = strings.SplitN(release, `.`, 3)
+	)
+	for i, dst := range []*int{&v.Major, &v.Minor, &v.Patch} {
+		if i >= len(parts) {
+			break
+		}
+		if *dst, err = strconv.Atoi(parts[i]); err != nil {
+			return VersionT{}, fmt.Errorf("%w: version '%s'", ErrInvalidOutput, s)
+		}
+	}
+
+	return v, nil
+}
+
+// AtLeast reports whether the version is major.minor or later
+func (v VersionT) AtLeast(major, minor int) bool {
+	return v.Major > major || (v.Major == major && v.Minor >= minor)
+}
+
+func (v VersionT) String() string {
+	return fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+}
+
+// Capabilities maps each known capability to whether it is supported
+type Capabilities map[Capability]bool
+
+// Has reports whether the capability is supported
+func (c Capabilities) Has(capability Capability) bool {
+	return c[capability]
+}
+
+// Missing returns those of the required capabilities that are unsupported
+func (c Capabilities) Missing(required ...Capability) []Capability {
+	missing := make([]Capability, 0)
+	for _, capability := range required {
+		if !c.Has(capability) {
+			missing = append(missing, capability)
+		}
+	}
+	return missing
+}
+
+func (c Capabilities) LogValue() slog.Value {
+	names := make([]string, 0, len(c))
+	for capability := range c {
+		names = append(names, string(capability))
+	}
+	sort.Strings(names)
+
+	attrs := make([]slog.Attr, len(names))
+	for i, name := range names {
+		attrs[i] = slog.Bool(name, c[Capability(name)])
+	}
+	return slog.GroupValue(attrs...)
+}
+
+// DetectCapabilities determines the versions and capabilities of the installed ZFS userland. Support for JSON output
+// is established by the presence of output_version in `zfs version --json`, as distribution backports make the
+// release version unreliable for this purpose, falling back to the human-readable output where JSON is unsupported.
+func DetectCapabilities(ctx context.Context, runner Runner, logger *slog.Logger) (*ZFSVersionT, Capabilities, error) {
+	var (
+		version       *ZFSVersionT
+		outputVersion *ZFSCommandOutputVersionT
+	)
+	o, err := GetZFSVersionViaJSON(ctx, runner, logger)
+	if err == nil {
+		version, outputVersion = &o.ZFSVersion, &o.OutputVersion
+	} else {
+		logger.Debug("JSON version output unavailable, falling back to text", "err", err)
+		if version, err = GetZFSVersionViaText(ctx, runner, logger); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	userland, err := ParseVersion(version.Userland)
+	if err != nil {
+		// Capabilities are still derived from output_version, assuming an otherwise recent release.
+		logger.Warn("Unable to parse ZFS version, assuming support for all capabilities of the output format", "version", version.Userland, "err", err)
+		userland = VersionT{Major: 2, Minor: 3}
+	}
+
+	return version, capabilitiesFor(userland, outputVersion), nil
+}
+
+// capabilitiesFor returns the capabilities of the userland release, where outputVersion is nil if JSON output is
+// unsupported
+func capabilitiesFor(userland VersionT, outputVersion *ZFSCommandOutputVersionT) Capabilities {
+	json := outputVersion != nil && outputVersion.Command != ``
+	return Capabilities{
+		CapabilityJSON:          json,
+		CapabilityJSONInt:       json && userland.AtLeast(2, 3),
+		CapabilityIostatJSON:    json && userland.AtLeast(2, 3),
+		CapabilityIostatLatency: userland.AtLeast(0, 8),
+		CapabilityTrimStatus:    userland.AtLeast(0, 8),
+		CapabilityPowerStatus:   userland.AtLeast(2, 2),
+	}
+}
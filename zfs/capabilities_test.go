@@ -0,0 +1,106 @@
+package zfs
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestParseVersion(t *testing.T) {
+	testCases := []struct {
+		input   string
+		want    VersionT
+		wantErr bool
+	}{
+		{input: `zfs-2.3.1-1`, want: VersionT{Major: 2, Minor: 3, Patch: 1}},
+		{input: `zfs-kmod-2.2.7-1`, want: VersionT{Major: 2, Minor: 2, Patch: 7}},
+		{input: `zfs-2.3.99-123_gabcdef`, want: VersionT{Major: 2, Minor: 3, Patch: 99}},
+		{input: `2.1`, want: VersionT{Major: 2, Minor: 1}},
+		{input: `zfs-unknown`, wantErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.input, func(t *testing.T) {
+			got, err := ParseVersion(tc.input)
+			if tc.wantErr {
+				if !errors.Is(err, ErrInvalidOutput) {
+					t.Fatalf("Expected %v, got %v", ErrInvalidOutput, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tc.want {
+				t.Errorf("Expected %v, got %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestDetectCapabilities(t *testing.T) {
+	testCases := []struct {
+		name     string
+		runner   fixtureRunner
+		userland string
+		want     Capabilities
+	}{
+		{
+			name:     `json`,
+			runner:   fixtureRunner{`zfs version --json`: `zfs_version.json`},
+			userland: `zfs-2.3.1-1`,
+			want: Capabilities{
+				CapabilityJSON:          true,
+				CapabilityJSONInt:       true,
+				CapabilityIostatJSON:    true,
+				CapabilityIostatLatency: true,
+				CapabilityTrimStatus:    true,
+				CapabilityPowerStatus:   true,
+			},
+		},
+		{
+			name:     `text`,
+			runner:   fixtureRunner{`zfs version`: `zfs_version.txt`},
+			userland: `zfs-2.1.5-1ubuntu6~22.04.4`,
+			want: Capabilities{
+				CapabilityJSON:          false,
+				CapabilityJSONInt:       false,
+				CapabilityIostatJSON:    false,
+				CapabilityIostatLatency: true,
+				CapabilityTrimStatus:    true,
+				CapabilityPowerStatus:   false,
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			version, caps, err := DetectCapabilities(context.Background(), tc.runner, testLogger)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if version.Userland != tc.userland {
+				t.Errorf("Expected userland %s, got %s", tc.userland, version.Userland)
+			}
+			if !reflect.DeepEqual(caps, tc.want) {
+				t.Errorf("Expected %v, got %v", tc.want, caps)
+			}
+		})
+	}
+}
+
+func TestCapabilitiesFor(t *testing.T) {
+	outputVersion := &ZFSCommandOutputVersionT{Command: `zfs version`, Major: 0, Minor: 1}
+
+	// JSON output backported to an earlier release does not imply the remaining JSON capabilities.
+	caps := capabilitiesFor(VersionT{Major: 2, Minor: 2, Patch: 7}, outputVersion)
+	if want := []Capability{CapabilityJSONInt, CapabilityIostatJSON}; !reflect.DeepEqual(caps.Missing(CapabilityJSON, CapabilityJSONInt, CapabilityIostatJSON), want) {
+		t.Errorf("Expected missing %v, got %v", want, caps.Missing(CapabilityJSON, CapabilityJSONInt, CapabilityIostatJSON))
+	}
+
+	caps = capabilitiesFor(VersionT{Major: 0, Minor: 7, Patch: 13}, nil)
+	if caps.Has(CapabilityIostatLatency) || caps.Has(CapabilityTrimStatus) {
+		t.Errorf("Expected no latency or trim capabilities for 0.7, got %v", caps)
+	}
+}
@@ -2,6 +2,7 @@ package zfs
 
 import (
 	"strings"
+	"time"
 )
 
 // DatasetKind enum of supported dataset types
@@ -17,8 +18,10 @@ const (
 )
 
 type datasetsImpl struct {
-	pool string
-	kind DatasetKind
+	pool    string
+	kind    DatasetKind
+	runner  Runner
+	timeout time.Duration
 }
 
 func (d datasetsImpl) Pool() string {
@@ -30,8 +33,10 @@ func (d datasetsImpl) Kind() DatasetKind {
 }
 
 func (d datasetsImpl) Properties(props ...string) ([]DatasetProperties, error) {
+	ctx, cancel := CommandContext(d.timeout)
+	defer cancel()
 	handler := newDatasetHandler()
-	if err := execute(d.pool, handler, `zfs`, `get`, `-Hprt`, string(d.kind), `-o`, `name,property,value`, strings.Join(props, `,`)); err != nil {
+	if err := execute(ctx, d.runner, d.pool, handler, `zfs`, `get`, `-Hprt`, string(d.kind), `-o`, `name,property,value`, strings.Join(props, `,`)); err != nil {
 		return nil, err
 	}
 	return handler.datasets(), nil
@@ -84,10 +89,12 @@ func newDatasetPropertiesImpl(name string) *datasetPropertiesImpl {
 	}
 }
 
-func newDatasetsImpl(pool string, kind DatasetKind) datasetsImpl {
+func newDatasetsImpl(pool string, kind DatasetKind, runner Runner, timeout time.Duration) datasetsImpl {
 	return datasetsImpl{
-		pool: pool,
-		kind: kind,
+		pool:    pool,
+		kind:    kind,
+		runner:  runner,
+		timeout: timeout,
 	}
 }
 
@@ -0,0 +1,83 @@
+package zfs
+
+import (
+	"context"
+	"log/slog"
+	"strconv"
+	"strings"
+)
+
+type DatasetListT struct {
+	Name       string               `json:"name"`
+	Type       string               `json:"type"`
+	Pool       string               `json:"pool"`
+	Properties map[string]PropertyT `json:"properties"`
+}
+
+// Kind returns the dataset type in the same form as the DatasetKind enum
+func (o DatasetListT) Kind() DatasetKind {
+	return DatasetKind(strings.ToLower(o.Type))
+}
+
+func (o DatasetListT) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("name", o.Name),
+		slog.String("type", o.Type),
+		slog.String("pool", o.Pool),
+		slog.Int("num_properties", len(o.Properties)),
+	)
+}
+
+type ZfsListOutputT struct {
+	OutputVersion ZFSCommandOutputVersionT `json:"output_version"`
+	Datasets      map[string]DatasetListT  `json:"datasets"`
+}
+
+func (o ZfsListOutputT) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("output_version.command", o.OutputVersion.Command),
+		slog.Int("output_version.major", o.OutputVersion.Major),
+		slog.Int("output_version.minor", o.OutputVersion.Minor),
+		slog.Int("num_datasets", len(o.Datasets)),
+	)
+}
+
+// ZfsListViaJSON returns the requested properties for datasets of the given kinds within the pool, descending at most
+// depth levels below the pool's root dataset (a negative depth is unlimited)
+func ZfsListViaJSON(ctx context.Context, runner Runner, logger *slog.Logger, pool string, depth int, kinds []DatasetKind, props ...string) (map[string]DatasetListT, error) {
+	types := make([]string, len(kinds))
+	for i, kind := range kinds {
+		types[i] = string(kind)
+	}
+	args := []string{`list`, `--json`, `--json-int`, `-p`, `-t`, strings.Join(types, `,`), `-o`, strings.Join(append([]string{`name`}, props...), `,`)}
+	if depth < 0 {
+		args = append(args, `-r`)
+	} else {
+		args = append(args, `-d`, strconv.Itoa(depth))
+	}
+
+	var o ZfsListOutputT
+	if err := executeJSON(ctx, runner, logger, &o, `zfs`, append(args, pool)...); err != nil {
+		return nil, err
+	}
+	logger.Debug("ZFS List Output Parsed", "output", o)
+	return o.Datasets, nil
+}
+
+// ZfsGetViaJSON returns the requested properties, which may include user properties such as
+// `com.example:backup-policy`, for all datasets of the given kinds within the pool. The output of `zfs get` shares the
+// layout of `zfs list`, but unlike `zfs list` it also reports the source of each value.
+func ZfsGetViaJSON(ctx context.Context, runner Runner, logger *slog.Logger, pool string, kinds []DatasetKind, props ...string) (map[string]DatasetListT, error) {
+	types := make([]string, len(kinds))
+	for i, kind := range kinds {
+		types[i] = string(kind)
+	}
+	args := []string{`get`, `--json`, `--json-int`, `-p`, `-r`, `-t`, strings.Join(types, `,`), `-o`, `name,property,value,source`, strings.Join(props, `,`), pool}
+
+	var o ZfsListOutputT
+	if err := executeJSON(ctx, runner, logger, &o, `zfs`, args...); err != nil {
+		return nil, err
+	}
+	logger.Debug("ZFS Get Output Parsed", "output", o)
+	return o.Datasets, nil
+}
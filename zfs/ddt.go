@@ -0,0 +1,84 @@
+package zfs
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var ddtStatsRe = regexp.MustCompile(`DDT entries (\d+), size (\S+) on disk, (\S+) in core`)
+
+// DDTStatsT summarises the dedup table (DDT) of a pool, as reported by `zpool status -D`
+type DDTStatsT struct {
+	// Entries is the number of unique blocks tracked by the DDT
+	Entries uint64
+	// EntryDiskBytes and EntryCoreBytes are the average size of each entry on disk and in memory
+	EntryDiskBytes uint64
+	EntryCoreBytes uint64
+}
+
+// DiskBytes returns the estimated on-disk size of the whole DDT
+func (s DDTStatsT) DiskBytes() uint64 {
+	return s.Entries * s.EntryDiskBytes
+}
+
+// CoreBytes returns the estimated in-memory size of the whole DDT
+func (s DDTStatsT) CoreBytes() uint64 {
+	return s.Entries * s.EntryCoreBytes
+}
+
+// ZpoolDDTStats returns the dedup table statistics of the pool. Pools without dedup report zero entries.
+func ZpoolDDTStats(ctx context.Context, runner Runner, pool string) (DDTStatsT, error) {
+	stdout, _, err := runner.Run(ctx, `zpool`, `status`, `-D`, `-p`, pool)
+	if err = statusErr(ctx, stdout, err); err != nil {
+		return DDTStatsT{}, err
+	}
+	return parseDDTStats(stdout)
+}
+
+// parseDDTStats parses the summary line of the dedup section of `zpool status -D`. The DDT histogram that follows it is
+// ignored.
+func parseDDTStats(out []byte) (DDTStatsT, error) {
+	var s DDTStatsT
+	m := ddtStatsRe.FindSubmatch(out)
+	if m == nil {
+		// "dedup: no DDT entries", or no dedup section at all where the pool config is unavailable
+		return s, nil
+	}
+
+	var err error
+	if s.Entries, err = strconv.ParseUint(string(m[1]), 10, 64); err != nil {
+		return s, fmt.Errorf("%w: DDT entries '%s'", ErrInvalidOutput, m[1])
+	}
+	if s.EntryDiskBytes, err = parseNiceBytes(string(m[2])); err != nil {
+		return s, fmt.Errorf("%w: DDT size on disk '%s'", ErrInvalidOutput, m[2])
+	}
+	if s.EntryCoreBytes, err = parseNiceBytes(string(m[3])); err != nil {
+		return s, fmt.Errorf("%w: DDT size in core '%s'", ErrInvalidOutput, m[3])
+	}
+
+	return s, nil
+}
+
+// parseNiceBytes parses a size that is either exact, as printed with -p, or abbreviated with a binary suffix such as
+// 1.5K, as printed by releases that ignore -p for the DDT summary
+func parseNiceBytes(v string) (uint64, error) {
+	v = strings.TrimSuffix(v, `B`)
+	if n, err := strconv.ParseUint(v, 10, 64); err == nil {
+		return n, nil
+	}
+	if v == `` {
+		return 0, strconv.ErrSyntax
+	}
+	i := strings.IndexByte(`KMGTPE`, v[len(v)-1])
+	if i < 0 {
+		return 0, strconv.ErrSyntax
+	}
+	f, err := strconv.ParseFloat(v[:len(v)-1], 64)
+	if err != nil {
+		return 0, err
+	}
+	return uint64(f * float64(uint64(1)<<(10*(i+1)))), nil
+}
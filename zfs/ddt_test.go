@@ -0,0 +1,58 @@
+package zfs
+
+import (
+	"context"
+	"testing"
+)
+
+func TestDDTStats(t *testing.T) {
+	client := newFixtureClient(fixtureRunner{`zpool status -D -p tank`: `zpool_status_dedup.txt`})
+	stats, err := client.DDTStats(`tank`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (DDTStatsT{Entries: 117463, EntryDiskBytes: 297, EntryCoreBytes: 161}); stats != want {
+		t.Errorf("Expected %+v, got %+v", want, stats)
+	}
+	if stats.CoreBytes() != 117463*161 {
+		t.Errorf("Unexpected core bytes %d", stats.CoreBytes())
+	}
+}
+
+func TestParseDDTStats(t *testing.T) {
+	testCases := []struct {
+		name    string
+		output  string
+		want    DDTStatsT
+		wantErr bool
+	}{
+		{name: `no entries`, output: " dedup: no DDT entries\n"},
+		{name: `abbreviated sizes`, output: " dedup: DDT entries 10, size 1.5K on disk, 320B in core\n", want: DDTStatsT{Entries: 10, EntryDiskBytes: 1536, EntryCoreBytes: 320}},
+		{name: `invalid size`, output: " dedup: DDT entries 10, size 1.5Q on disk, 320B in core\n", wantErr: true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := parseDDTStats([]byte(tc.output))
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("Expected error %t, got %v", tc.wantErr, err)
+			}
+			if !tc.wantErr && got != tc.want {
+				t.Errorf("Expected %+v, got %+v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestDDTStatsExitStatus(t *testing.T) {
+	runner := exitRunner{
+		Runner: fixtureRunner{`zpool status -D -p tank`: `zpool_status_dedup.txt`},
+		fail:   `zpool status -D -p tank`,
+	}
+	stats, err := ZpoolDDTStats(context.Background(), runner, `tank`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Entries != 117463 {
+		t.Errorf("Unexpected entries %d", stats.Entries)
+	}
+}
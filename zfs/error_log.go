@@ -0,0 +1,30 @@
+package zfs
+
+import (
+	"context"
+	"log/slog"
+)
+
+// PoolErrorLogT holds the permanent data errors of a pool, as listed by `zpool status -v`
+type PoolErrorLogT struct {
+	// Count is the number of data errors of the pool
+	Count uint64
+	// Entries are the objects affected, given as a path where their dataset is mounted and as DATASET:PATH otherwise.
+	// Names that cannot be resolved, such as those of deleted objects or metadata, are given as object numbers, e.g.
+	// tank/data:<0x1b> or <metadata>:<0x3f>.
+	Entries []string
+}
+
+// ZpoolErrorLog returns the permanent data errors of the pool. Listing them reads the error log of the pool from disk,
+// so it is queried separately from the status of all pools.
+func ZpoolErrorLog(ctx context.Context, runner Runner, logger *slog.Logger, pool string) (PoolErrorLogT, error) {
+	var o ZpoolStatusOutputT
+	if err := executeJSON(ctx, runner, logger, &o, `zpool`, `status`, `-v`, `--json`, `--json-int`, pool); err != nil {
+		return PoolErrorLogT{}, err
+	}
+	status, ok := o.Pools[pool]
+	if !ok {
+		return PoolErrorLogT{}, nil
+	}
+	return PoolErrorLogT{Count: uint64(status.ErrorCount), Entries: status.ErrList}, nil
+}
@@ -0,0 +1,82 @@
+package zfs
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// EventT is an event reported by `zpool events`
+type EventT struct {
+	// EID is the event ID, which increases monotonically until the ZFS module is reloaded
+	EID uint64
+	// Class is the event class, e.g. ereport.fs.zfs.checksum
+	Class string
+	// Pool is the name of the pool that the event relates to, if any
+	Pool string
+}
+
+// ZpoolEvents returns the events held in the kernel's event buffer
+func ZpoolEvents(ctx context.Context, runner Runner) ([]EventT, error) {
+	stdout, _, err := runner.Run(ctx, `zpool`, `events`, `-v`, `-H`)
+	if err != nil {
+		return nil, err
+	}
+	return parseEvents(stdout)
+}
+
+// parseEvents parses the verbose output of `zpool events`, in which each event is a header line followed by an
+// indented list of its fields, where nested lists are indented further
+func parseEvents(out []byte) ([]EventT, error) {
+	var (
+		events []EventT
+		event  *EventT
+		indent int
+	)
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		line := scanner.Text()
+		trimmed := strings.TrimLeft(line, " \t")
+		switch {
+		case trimmed == ``:
+			continue
+		case len(trimmed) == len(line):
+			// Header: TIME CLASS
+			fields := strings.Fields(line)
+			events = append(events, EventT{Class: fields[len(fields)-1]})
+			event, indent = &events[len(events)-1], 0
+			continue
+		case event == nil:
+			return nil, fmt.Errorf("%w: zpool events field outside of event: %s", ErrInvalidOutput, line)
+		}
+
+		// Only top-level fields are considered, as nested lists may reuse the same names.
+		if indent == 0 {
+			indent = len(line) - len(trimmed)
+		}
+		if len(line)-len(trimmed) != indent {
+			continue
+		}
+		key, value, ok := strings.Cut(trimmed, ` = `)
+		if !ok {
+			continue
+		}
+		switch key {
+		case `class`:
+			event.Class = strings.Trim(value, `"`)
+		case `pool`:
+			event.Pool = strings.Trim(value, `"`)
+		case `eid`:
+			eid, err := strconv.ParseUint(value, 0, 64)
+			if err != nil {
+				return nil, fmt.Errorf("%w: zpool events eid '%s'", ErrInvalidOutput, value)
+			}
+			event.EID = eid
+		}
+	}
+
+	return events, scanner.Err()
+}
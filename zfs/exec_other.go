@@ -0,0 +1,16 @@
+//go:build !unix
+
+package zfs
+
+import (
+	"os"
+	"os/exec"
+)
+
+// killProcessGroup is a no-op where process groups are unsupported, only the command itself is killed on cancellation.
+func killProcessGroup(c *exec.Cmd) {}
+
+// maxRSS is unsupported where rusage is unavailable
+func maxRSS(state *os.ProcessState) int64 {
+	return 0
+}
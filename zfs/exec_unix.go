@@ -0,0 +1,32 @@
+//go:build unix
+
+package zfs
+
+import (
+	"os"
+	"os/exec"
+	"runtime"
+	"syscall"
+)
+
+// killProcessGroup starts the command in its own process group, and kills the whole group on cancellation, so that
+// helpers spawned by zpool/zfs do not outlive a timed out command.
+func killProcessGroup(c *exec.Cmd) {
+	c.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	c.Cancel = func() error {
+		return syscall.Kill(-c.Process.Pid, syscall.SIGKILL)
+	}
+}
+
+// maxRSS returns the peak resident set size in bytes of the exited process
+func maxRSS(state *os.ProcessState) int64 {
+	rusage, ok := state.SysUsage().(*syscall.Rusage)
+	if !ok {
+		return 0
+	}
+	// Darwin reports bytes, other platforms KiB
+	if runtime.GOOS == `darwin` {
+		return int64(rusage.Maxrss)
+	}
+	return int64(rusage.Maxrss) * 1024
+}
@@ -0,0 +1,42 @@
+package zfs
+
+import (
+	"context"
+	"io"
+)
+
+// HostRunner runs commands on the host from within a container, where zpool and zfs of the container may not match
+// the kernel module, or be installed at all. Commands either enter the namespaces of the init process of the host with
+// nsenter, which requires the PID namespace of the host and CAP_SYS_ADMIN, or are chrooted into the root filesystem of
+// the host mounted in the container.
+type HostRunner struct {
+	Runner Runner
+	// Root is the mount point of the root filesystem of the host into which commands are chrooted, unless empty
+	Root string
+	// Nsenter enters the mount, UTS, network and IPC namespaces of PID 1, taking precedence over Root
+	Nsenter bool
+}
+
+// Run implements the Runner interface, resolving the command via the PATH of the host
+func (r HostRunner) Run(ctx context.Context, name string, args ...string) ([]byte, []byte, error) {
+	name, args = r.command(name, args...)
+	return r.Runner.Run(ctx, name, args...)
+}
+
+// Stream implements the StreamRunner interface, resolving the command via the PATH of the host
+func (r HostRunner) Stream(ctx context.Context, read func(stdout io.Reader) error, name string, args ...string) ([]byte, error) {
+	name, args = r.command(name, args...)
+	return RunStream(ctx, r.Runner, read, name, args...)
+}
+
+func (r HostRunner) command(name string, args ...string) (string, []string) {
+	switch {
+	case r.Nsenter:
+		args = append([]string{`-t`, `1`, `-m`, `-u`, `-n`, `-i`, `--`, name}, args...)
+		name = `nsenter`
+	case r.Root != ``:
+		args = append([]string{r.Root, name}, args...)
+		name = `chroot`
+	}
+	return name, args
+}
@@ -0,0 +1,40 @@
+package zfs
+
+import (
+	"context"
+	"testing"
+)
+
+func TestHostRunner(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		runner HostRunner
+	}{
+		{
+			name: `nsenter`,
+			runner: HostRunner{Nsenter: true, Runner: fixtureRunner{
+				`nsenter -t 1 -m -u -n -i -- zpool list -Ho name`: `zpool_list_names.txt`,
+			}},
+		},
+		{
+			name: `chroot`,
+			runner: HostRunner{Root: `/host`, Runner: fixtureRunner{
+				`chroot /host zpool list -Ho name`: `zpool_list_names.txt`,
+			}},
+		},
+		{
+			name:   `disabled`,
+			runner: HostRunner{Runner: fixtureRunner{`zpool list -Ho name`: `zpool_list_names.txt`}},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			pools, err := poolNames(context.Background(), tc.runner)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(pools) == 0 {
+				t.Error("Expected pools of the host")
+			}
+		})
+	}
+}
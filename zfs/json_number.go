@@ -0,0 +1,60 @@
+package zfs
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Uint64 holds a size, counter, GUID or timestamp of JSON output. Where --json-int is unsupported, or a field changes
+// type between releases, values are emitted as strings, which may be exact ("1000"), abbreviated ("1.81T"), formatted
+// dates, or "-" where not applicable.
+type Uint64 uint64
+
+// UnmarshalJSON implements the json.Unmarshaler interface. Strings that cannot be interpreted are an error, rather
+// than being read as zero.
+func (v *Uint64) UnmarshalJSON(b []byte) error {
+	if bytes.Equal(b, []byte(`null`)) {
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		var n json.Number
+		if err = json.Unmarshal(b, &n); err != nil {
+			return err
+		}
+		s = n.String()
+	}
+
+	n, err := parseUint64(strings.TrimSpace(s))
+	if err != nil {
+		return fmt.Errorf("%w: %s is not an unsigned integer", ErrInvalidOutput, b)
+	}
+	*v = Uint64(n)
+	return nil
+}
+
+func parseUint64(s string) (uint64, error) {
+	if s == `` || s == `-` {
+		return 0, nil
+	}
+	if n, err := strconv.ParseUint(s, 10, 64); err == nil {
+		return n, nil
+	}
+	// Exact values beyond 2^53 may be emitted in exponent form by encoders that use doubles
+	if f, err := strconv.ParseFloat(s, 64); err == nil && f >= 0 && f < math.MaxUint64 && f == math.Trunc(f) {
+		return uint64(f), nil
+	}
+	if n, err := parseNiceBytes(s); err == nil {
+		return n, nil
+	}
+	t, err := time.ParseInLocation(scanTimeLayout, s, time.Local)
+	if err != nil {
+		return 0, err
+	}
+	return uint64(t.Unix()), nil
+}
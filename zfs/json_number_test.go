@@ -0,0 +1,59 @@
+package zfs
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestUint64UnmarshalJSON(t *testing.T) {
+	testCases := []struct {
+		name    string
+		input   string
+		want    Uint64
+		wantErr bool
+	}{
+		{name: `number`, input: `1000`, want: 1000},
+		{name: `beyond int64`, input: `18446744073709551615`, want: 18446744073709551615},
+		{name: `exponent`, input: `1e+15`, want: 1000000000000000},
+		{name: `exact string`, input: `"1000"`, want: 1000},
+		{name: `abbreviated string`, input: `"1.81T"`, want: 1990116046274},
+		{name: `not applicable`, input: `"-"`, want: 0},
+		{name: `null`, input: `null`, want: 0},
+		{name: `date`, input: `"Sun Jul 13 00:34:33 2025"`, want: Uint64(time.Date(2025, time.July, 13, 0, 34, 33, 0, time.Local).Unix())},
+		{name: `negative`, input: `-1`, wantErr: true},
+		{name: `fraction`, input: `1.5`, wantErr: true},
+		{name: `invalid string`, input: `"lots"`, wantErr: true},
+		{name: `bool`, input: `true`, wantErr: true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var got Uint64
+			err := json.Unmarshal([]byte(tc.input), &got)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("Expected error %t, got %v", tc.wantErr, err)
+			}
+			if got != tc.want {
+				t.Errorf("Expected %d, got %d", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestVdevStatusUnmarshalStrings(t *testing.T) {
+	// Numeric fields as emitted without --json-int
+	var vdev VdevStatusT
+	err := json.Unmarshal([]byte(`{"name":"sda","guid":"1732050807568877293","rep_dev_size":"1.81T","phys_space":"1.82T","read_errors":"0","write_errors":"0","checksum_errors":"12","slow_ios":"-"}`), &vdev)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if vdev.Guid != 1732050807568877293 || vdev.RepDevSize != 1990116046274 || vdev.ChecksumErrors != 12 {
+		t.Errorf("Unexpected vdev status: %+v", vdev)
+	}
+
+	err = json.Unmarshal([]byte(`{"name":"sda","checksum_errors":"many"}`), &vdev)
+	if !errors.Is(err, ErrInvalidOutput) {
+		t.Errorf("Expected %v, got %v", ErrInvalidOutput, err)
+	}
+}
@@ -0,0 +1,178 @@
+package zfs
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultKstatPath is the location of the ZFS kstats on Linux
+	DefaultKstatPath = `/proc/spl/kstat/zfs`
+
+	kstatHeaderLines = 2
+	// kstatTypeString is the data type of kstats holding strings, which may contain whitespace
+	kstatTypeString = `7`
+)
+
+// readKstat parses a named kstat file into a map of kstat names to values
+func readKstat(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	kstats := make(map[string]string)
+	scanner := bufio.NewScanner(f)
+	line := 0
+	for scanner.Scan() {
+		line++
+		// The first line holds kstat metadata, the second the column headers.
+		if line <= kstatHeaderLines {
+			continue
+		}
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 3 || (len(fields) > 3 && fields[1] != kstatTypeString) {
+			return nil, fmt.Errorf("%w: %s line %d", ErrInvalidOutput, path, line)
+		}
+		kstats[fields[0]] = strings.Join(fields[2:], ` `)
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return kstats, nil
+}
+
+// ArcStats returns the ARC kstats found below kstatPath
+func ArcStats(kstatPath string) (map[string]string, error) {
+	return readKstat(filepath.Join(kstatPath, `arcstats`))
+}
+
+// TxgT holds the timings of a transaction group, as recorded in the pool's txgs kstat
+type TxgT struct {
+	Txg       uint64
+	State     string
+	OpenTime  time.Duration
+	QueueTime time.Duration
+	WaitTime  time.Duration
+	SyncTime  time.Duration
+}
+
+// Committed reports whether the transaction group has finished syncing
+func (o TxgT) Committed() bool {
+	return o.State == `C`
+}
+
+// Txgs returns the recent transaction group history for the pool found below kstatPath
+func Txgs(kstatPath, pool string) ([]TxgT, error) {
+	path := filepath.Join(kstatPath, pool, `txgs`)
+	rows, err := readKstatTable(path)
+	if err != nil {
+		return nil, err
+	}
+
+	txgs := make([]TxgT, 0, len(rows))
+	for _, row := range rows {
+		txg := TxgT{State: row[`state`]}
+		if txg.Txg, err = strconv.ParseUint(row[`txg`], 10, 64); err != nil {
+			return nil, fmt.Errorf("%w: %s: %w", ErrInvalidOutput, path, err)
+		}
+		for column, d := range map[string]*time.Duration{`otime`: &txg.OpenTime, `qtime`: &txg.QueueTime, `wtime`: &txg.WaitTime, `stime`: &txg.SyncTime} {
+			ns, err := strconv.ParseInt(row[column], 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %s: %w", ErrInvalidOutput, path, err)
+			}
+			*d = time.Duration(ns)
+		}
+		txgs = append(txgs, txg)
+	}
+
+	return txgs, nil
+}
+
+// ObjsetStatsT holds the I/O counters of a dataset, as recorded in the pool's objset kstats
+type ObjsetStatsT struct {
+	Reads    uint64
+	Writes   uint64
+	NRead    uint64
+	NWritten uint64
+	NUnlinks uint64
+}
+
+// ObjsetStats returns the I/O counters of each mounted dataset of the pool found below kstatPath, keyed by dataset name
+func ObjsetStats(kstatPath, pool string) (map[string]ObjsetStatsT, error) {
+	paths, err := filepath.Glob(filepath.Join(kstatPath, pool, `objset-*`))
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]ObjsetStatsT, len(paths))
+	for _, path := range paths {
+		kstats, err := readKstat(path)
+		if err != nil {
+			// Datasets may be unmounted or destroyed between listing and reading their kstats
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			return nil, err
+		}
+		var stats ObjsetStatsT
+		for name, v := range map[string]*uint64{`reads`: &stats.Reads, `writes`: &stats.Writes, `nread`: &stats.NRead, `nwritten`: &stats.NWritten, `nunlinks`: &stats.NUnlinks} {
+			if *v, err = strconv.ParseUint(kstats[name], 10, 64); err != nil {
+				return nil, fmt.Errorf("%w: %s: %w", ErrInvalidOutput, path, err)
+			}
+		}
+		result[kstats[`dataset_name`]] = stats
+	}
+
+	return result, nil
+}
+
+// readKstatTable parses a tabular kstat file into rows keyed by the column headers
+func readKstatTable(path string) ([]map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var (
+		headers []string
+		rows    []map[string]string
+	)
+	scanner := bufio.NewScanner(f)
+	line := 0
+	for scanner.Scan() {
+		line++
+		fields := strings.Fields(scanner.Text())
+		switch line {
+		case 1:
+			// kstat metadata
+			continue
+		case 2:
+			headers = fields
+			continue
+		}
+		if len(fields) != len(headers) {
+			return nil, fmt.Errorf("%w: %s line %d", ErrInvalidOutput, path, line)
+		}
+		row := make(map[string]string, len(headers))
+		for i, header := range headers {
+			row[header] = fields[i]
+		}
+		rows = append(rows, row)
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return rows, nil
+}
@@ -0,0 +1,128 @@
+//go:build lzc
+
+package zfs
+
+/*
+#cgo pkg-config: libzfs_core
+#include <stdlib.h>
+#include <libzfs_core.h>
+#include <libnvpair.h>
+*/
+import "C"
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"syscall"
+	"unsafe"
+)
+
+const (
+	// lzcDefaultInstructions and lzcDefaultMemory are the default limits of `zfs program`, which libzfs_core requires
+	// to be given explicitly
+	lzcDefaultInstructions = 10_000_000
+	lzcDefaultMemory       = 10 << 20
+)
+
+var (
+	lzcInit    sync.Once
+	lzcInitErr error
+)
+
+// LZCSupported returns whether the lzc backend is available, which requires a build with the lzc tag
+func LZCSupported() bool {
+	return true
+}
+
+// lzcChannelProgram runs the read-only channel program against the pool via lzc_channel_program_nosync, returning the
+// JSON encoding of the value returned by the program. Arguments are passed as argv, as by `zfs program`.
+func lzcChannelProgram(pool string, limits ProgramLimits, program []byte, args []string) ([]byte, error) {
+	lzcInit.Do(func() {
+		if errno := C.libzfs_core_init(); errno != 0 {
+			lzcInitErr = fmt.Errorf("error initializing libzfs_core: %w", syscall.Errno(errno))
+		}
+	})
+	if lzcInitErr != nil {
+		return nil, lzcInitErr
+	}
+	if limits.Instructions == 0 {
+		limits.Instructions = lzcDefaultInstructions
+	}
+	if limits.Memory == 0 {
+		limits.Memory = lzcDefaultMemory
+	}
+
+	cpool := C.CString(pool)
+	defer C.free(unsafe.Pointer(cpool))
+	cprogram := C.CString(string(program))
+	defer C.free(unsafe.Pointer(cprogram))
+	cargv := C.CString(`argv`)
+	defer C.free(unsafe.Pointer(cargv))
+	cargs := make([]*C.char, len(args))
+	for i, arg := range args {
+		cargs[i] = C.CString(arg)
+		defer C.free(unsafe.Pointer(cargs[i]))
+	}
+
+	argnvl := C.fnvlist_alloc()
+	defer C.fnvlist_free(argnvl)
+	if len(cargs) > 0 {
+		C.fnvlist_add_string_array(argnvl, cargv, &cargs[0], C.uint_t(len(cargs)))
+	}
+
+	var outnvl *C.nvlist_t
+	errno := C.lzc_channel_program_nosync(cpool, cprogram, C.uint64_t(limits.Instructions), C.uint64_t(limits.Memory), argnvl, &outnvl)
+	if outnvl != nil {
+		defer C.nvlist_free(outnvl)
+	}
+	out, err := lzcMap(outnvl)
+	if err != nil {
+		return nil, err
+	}
+	if errno != 0 {
+		if msg, ok := out[`error`].(string); ok {
+			return nil, fmt.Errorf("channel program failed: %w: %s", syscall.Errno(errno), msg)
+		}
+		return nil, fmt.Errorf("channel program failed: %w", syscall.Errno(errno))
+	}
+	v, ok := out[`return`]
+	if !ok {
+		return nil, fmt.Errorf("%w: channel program returned no value", ErrInvalidOutput)
+	}
+	return json.Marshal(v)
+}
+
+// lzcMap converts the nvlist to a map, of which nested nvlists are maps themselves
+func lzcMap(nvl *C.nvlist_t) (map[string]any, error) {
+	m := make(map[string]any)
+	if nvl == nil {
+		return m, nil
+	}
+	for nvp := C.nvlist_next_nvpair(nvl, nil); nvp != nil; nvp = C.nvlist_next_nvpair(nvl, nvp) {
+		v, err := lzcValue(nvp)
+		if err != nil {
+			return nil, err
+		}
+		m[C.GoString(C.nvpair_name(nvp))] = v
+	}
+	return m, nil
+}
+
+// lzcValue converts the value of an nvpair of the types returned by channel programs
+func lzcValue(nvp *C.nvpair_t) (any, error) {
+	switch t := C.nvpair_type(nvp); t {
+	case C.DATA_TYPE_NVLIST:
+		return lzcMap(C.fnvpair_value_nvlist(nvp))
+	case C.DATA_TYPE_STRING:
+		return C.GoString(C.fnvpair_value_string(nvp)), nil
+	case C.DATA_TYPE_INT64:
+		return int64(C.fnvpair_value_int64(nvp)), nil
+	case C.DATA_TYPE_UINT64:
+		return uint64(C.fnvpair_value_uint64(nvp)), nil
+	case C.DATA_TYPE_BOOLEAN_VALUE:
+		return C.fnvpair_value_boolean_value(nvp) != C.B_FALSE, nil
+	default:
+		return nil, fmt.Errorf("%w: unsupported nvpair type %d of %s", ErrInvalidOutput, t, C.GoString(C.nvpair_name(nvp)))
+	}
+}
@@ -0,0 +1,7 @@
+//go:build lzc && !cgo
+
+package zfs
+
+// The lzc backend links libzfs_core, so building with the lzc tag and cgo disabled fails on the undefined identifier
+// below, rather than on the missing lzcChannelProgram.
+var _ = lzc_build_requires_CGO_ENABLED_1
@@ -0,0 +1,16 @@
+//go:build !lzc
+
+package zfs
+
+import "errors"
+
+var errLZCUnsupported = errors.New(`built without libzfs_core support, rebuild with -tags lzc`)
+
+// LZCSupported returns whether the lzc backend is available, which requires a build with the lzc tag
+func LZCSupported() bool {
+	return false
+}
+
+func lzcChannelProgram(pool string, limits ProgramLimits, program []byte, args []string) ([]byte, error) {
+	return nil, errLZCUnsupported
+}
@@ -40,6 +40,76 @@ func (m *MockClient) EXPECT() *MockClientMockRecorder {
 	return m.recorder
 }
 
+// ArcStats mocks base method.
+func (m *MockClient) ArcStats() (map[string]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ArcStats")
+	ret0, _ := ret[0].(map[string]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ArcStats indicates an expected call of ArcStats.
+func (mr *MockClientMockRecorder) ArcStats() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ArcStats", reflect.TypeOf((*MockClient)(nil).ArcStats))
+}
+
+// DDTStats mocks base method.
+func (m *MockClient) DDTStats(pool string) (zfs.DDTStatsT, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DDTStats", pool)
+	ret0, _ := ret[0].(zfs.DDTStatsT)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DDTStats indicates an expected call of DDTStats.
+func (mr *MockClientMockRecorder) DDTStats(pool any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DDTStats", reflect.TypeOf((*MockClient)(nil).DDTStats), pool)
+}
+
+// DatasetGet mocks base method.
+func (m *MockClient) DatasetGet(pool string, kinds []zfs.DatasetKind, props ...string) (map[string]zfs.DatasetListT, error) {
+	m.ctrl.T.Helper()
+	varargs := []any{pool, kinds}
+	for _, a := range props {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "DatasetGet", varargs...)
+	ret0, _ := ret[0].(map[string]zfs.DatasetListT)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DatasetGet indicates an expected call of DatasetGet.
+func (mr *MockClientMockRecorder) DatasetGet(pool, kinds any, props ...any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]any{pool, kinds}, props...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DatasetGet", reflect.TypeOf((*MockClient)(nil).DatasetGet), varargs...)
+}
+
+// DatasetList mocks base method.
+func (m *MockClient) DatasetList(pool string, depth int, kinds []zfs.DatasetKind, props ...string) (map[string]zfs.DatasetListT, error) {
+	m.ctrl.T.Helper()
+	varargs := []any{pool, depth, kinds}
+	for _, a := range props {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "DatasetList", varargs...)
+	ret0, _ := ret[0].(map[string]zfs.DatasetListT)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DatasetList indicates an expected call of DatasetList.
+func (mr *MockClientMockRecorder) DatasetList(pool, depth, kinds any, props ...any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]any{pool, depth, kinds}, props...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DatasetList", reflect.TypeOf((*MockClient)(nil).DatasetList), varargs...)
+}
+
 // Datasets mocks base method.
 func (m *MockClient) Datasets(pool string, kind zfs.DatasetKind) zfs.Datasets {
 	m.ctrl.T.Helper()
@@ -54,6 +124,51 @@ func (mr *MockClientMockRecorder) Datasets(pool, kind any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Datasets", reflect.TypeOf((*MockClient)(nil).Datasets), pool, kind)
 }
 
+// Events mocks base method.
+func (m *MockClient) Events() ([]zfs.EventT, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Events")
+	ret0, _ := ret[0].([]zfs.EventT)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Events indicates an expected call of Events.
+func (mr *MockClientMockRecorder) Events() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Events", reflect.TypeOf((*MockClient)(nil).Events))
+}
+
+// LatestSnapshot mocks base method.
+func (m *MockClient) LatestSnapshot(dataset string) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LatestSnapshot", dataset)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// LatestSnapshot indicates an expected call of LatestSnapshot.
+func (mr *MockClientMockRecorder) LatestSnapshot(dataset any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LatestSnapshot", reflect.TypeOf((*MockClient)(nil).LatestSnapshot), dataset)
+}
+
+// ObjsetStats mocks base method.
+func (m *MockClient) ObjsetStats(pool string) (map[string]zfs.ObjsetStatsT, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ObjsetStats", pool)
+	ret0, _ := ret[0].(map[string]zfs.ObjsetStatsT)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ObjsetStats indicates an expected call of ObjsetStats.
+func (mr *MockClientMockRecorder) ObjsetStats(pool any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ObjsetStats", reflect.TypeOf((*MockClient)(nil).ObjsetStats), pool)
+}
+
 // Pool mocks base method.
 func (m *MockClient) Pool(name string) zfs.Pool {
 	m.ctrl.T.Helper()
@@ -68,6 +183,59 @@ func (mr *MockClientMockRecorder) Pool(name any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Pool", reflect.TypeOf((*MockClient)(nil).Pool), name)
 }
 
+// PoolGet mocks base method.
+func (m *MockClient) PoolGet(props ...string) (map[string]zfs.PoolListT, error) {
+	m.ctrl.T.Helper()
+	varargs := []any{}
+	for _, a := range props {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "PoolGet", varargs...)
+	ret0, _ := ret[0].(map[string]zfs.PoolListT)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PoolGet indicates an expected call of PoolGet.
+func (mr *MockClientMockRecorder) PoolGet(props ...any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PoolGet", reflect.TypeOf((*MockClient)(nil).PoolGet), props...)
+}
+
+// PoolIostats mocks base method.
+func (m *MockClient) PoolIostats(vdevs bool) (map[string]zfs.PoolIostatT, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PoolIostats", vdevs)
+	ret0, _ := ret[0].(map[string]zfs.PoolIostatT)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PoolIostats indicates an expected call of PoolIostats.
+func (mr *MockClientMockRecorder) PoolIostats(vdevs any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PoolIostats", reflect.TypeOf((*MockClient)(nil).PoolIostats), vdevs)
+}
+
+// PoolList mocks base method.
+func (m *MockClient) PoolList(props ...string) (map[string]zfs.PoolListT, error) {
+	m.ctrl.T.Helper()
+	varargs := []any{}
+	for _, a := range props {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "PoolList", varargs...)
+	ret0, _ := ret[0].(map[string]zfs.PoolListT)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PoolList indicates an expected call of PoolList.
+func (mr *MockClientMockRecorder) PoolList(props ...any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PoolList", reflect.TypeOf((*MockClient)(nil).PoolList), props...)
+}
+
 // PoolNames mocks base method.
 func (m *MockClient) PoolNames() ([]string, error) {
 	m.ctrl.T.Helper()
@@ -83,6 +251,188 @@ func (mr *MockClientMockRecorder) PoolNames() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PoolNames", reflect.TypeOf((*MockClient)(nil).PoolNames))
 }
 
+// PoolStatus mocks base method.
+func (m *MockClient) PoolStatus(power bool) (map[string]zfs.PoolStatusT, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PoolStatus", power)
+	ret0, _ := ret[0].(map[string]zfs.PoolStatusT)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PoolStatus indicates an expected call of PoolStatus.
+func (mr *MockClientMockRecorder) PoolStatus(power any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PoolStatus", reflect.TypeOf((*MockClient)(nil).PoolStatus), power)
+}
+
+// RemovalStats mocks base method.
+func (m *MockClient) RemovalStats(pool string) (zfs.RemovalStatsT, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RemovalStats", pool)
+	ret0, _ := ret[0].(zfs.RemovalStatsT)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RemovalStats indicates an expected call of RemovalStats.
+func (mr *MockClientMockRecorder) RemovalStats(pool any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemovalStats", reflect.TypeOf((*MockClient)(nil).RemovalStats), pool)
+}
+
+// SendEstimate mocks base method.
+func (m *MockClient) SendEstimate(snapshot, from string) (uint64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SendEstimate", snapshot, from)
+	ret0, _ := ret[0].(uint64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SendEstimate indicates an expected call of SendEstimate.
+func (mr *MockClientMockRecorder) SendEstimate(snapshot, from any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SendEstimate", reflect.TypeOf((*MockClient)(nil).SendEstimate), snapshot, from)
+}
+
+// Txgs mocks base method.
+func (m *MockClient) Txgs(pool string) ([]zfs.TxgT, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Txgs", pool)
+	ret0, _ := ret[0].([]zfs.TxgT)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Txgs indicates an expected call of Txgs.
+func (mr *MockClientMockRecorder) Txgs(pool any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Txgs", reflect.TypeOf((*MockClient)(nil).Txgs), pool)
+}
+
+// MockErrorLogClient is a mock of ErrorLogClient interface.
+type MockErrorLogClient struct {
+	ctrl     *gomock.Controller
+	recorder *MockErrorLogClientMockRecorder
+	isgomock struct{}
+}
+
+// MockErrorLogClientMockRecorder is the mock recorder for MockErrorLogClient.
+type MockErrorLogClientMockRecorder struct {
+	mock *MockErrorLogClient
+}
+
+// NewMockErrorLogClient creates a new mock instance.
+func NewMockErrorLogClient(ctrl *gomock.Controller) *MockErrorLogClient {
+	mock := &MockErrorLogClient{ctrl: ctrl}
+	mock.recorder = &MockErrorLogClientMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockErrorLogClient) EXPECT() *MockErrorLogClientMockRecorder {
+	return m.recorder
+}
+
+// PoolErrorLog mocks base method.
+func (m *MockErrorLogClient) PoolErrorLog(pool string) (zfs.PoolErrorLogT, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PoolErrorLog", pool)
+	ret0, _ := ret[0].(zfs.PoolErrorLogT)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PoolErrorLog indicates an expected call of PoolErrorLog.
+func (mr *MockErrorLogClientMockRecorder) PoolErrorLog(pool any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PoolErrorLog", reflect.TypeOf((*MockErrorLogClient)(nil).PoolErrorLog), pool)
+}
+
+// MockProgramClient is a mock of ProgramClient interface.
+type MockProgramClient struct {
+	ctrl     *gomock.Controller
+	recorder *MockProgramClientMockRecorder
+	isgomock struct{}
+}
+
+// MockProgramClientMockRecorder is the mock recorder for MockProgramClient.
+type MockProgramClientMockRecorder struct {
+	mock *MockProgramClient
+}
+
+// NewMockProgramClient creates a new mock instance.
+func NewMockProgramClient(ctrl *gomock.Controller) *MockProgramClient {
+	mock := &MockProgramClient{ctrl: ctrl}
+	mock.recorder = &MockProgramClientMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockProgramClient) EXPECT() *MockProgramClientMockRecorder {
+	return m.recorder
+}
+
+// DatasetProgram mocks base method.
+func (m *MockProgramClient) DatasetProgram(pool string, limits zfs.ProgramLimits, props ...string) (map[string]zfs.DatasetListT, error) {
+	m.ctrl.T.Helper()
+	varargs := []any{pool, limits}
+	for _, a := range props {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "DatasetProgram", varargs...)
+	ret0, _ := ret[0].(map[string]zfs.DatasetListT)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DatasetProgram indicates an expected call of DatasetProgram.
+func (mr *MockProgramClientMockRecorder) DatasetProgram(pool, limits any, props ...any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]any{pool, limits}, props...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DatasetProgram", reflect.TypeOf((*MockProgramClient)(nil).DatasetProgram), varargs...)
+}
+
+// MockHistogramClient is a mock of HistogramClient interface.
+type MockHistogramClient struct {
+	ctrl     *gomock.Controller
+	recorder *MockHistogramClientMockRecorder
+	isgomock struct{}
+}
+
+// MockHistogramClientMockRecorder is the mock recorder for MockHistogramClient.
+type MockHistogramClientMockRecorder struct {
+	mock *MockHistogramClient
+}
+
+// NewMockHistogramClient creates a new mock instance.
+func NewMockHistogramClient(ctrl *gomock.Controller) *MockHistogramClient {
+	mock := &MockHistogramClient{ctrl: ctrl}
+	mock.recorder = &MockHistogramClientMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockHistogramClient) EXPECT() *MockHistogramClientMockRecorder {
+	return m.recorder
+}
+
+// LatencyHistograms mocks base method.
+func (m *MockHistogramClient) LatencyHistograms(pool string) (zfs.LatencyHistogramsT, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LatencyHistograms", pool)
+	ret0, _ := ret[0].(zfs.LatencyHistogramsT)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// LatencyHistograms indicates an expected call of LatencyHistograms.
+func (mr *MockHistogramClientMockRecorder) LatencyHistograms(pool any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LatencyHistograms", reflect.TypeOf((*MockHistogramClient)(nil).LatencyHistograms), pool)
+}
+
 // MockPool is a mock of Pool interface.
 type MockPool struct {
 	ctrl     *gomock.Controller
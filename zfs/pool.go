@@ -2,10 +2,10 @@ package zfs
 
 import (
 	"bufio"
-	"fmt"
-	"io"
-	"os/exec"
+	"bytes"
+	"context"
 	"strings"
+	"time"
 )
 
 // PoolStatus enum contains status text
@@ -29,7 +29,9 @@ const (
 )
 
 type poolImpl struct {
-	name string
+	name    string
+	runner  Runner
+	timeout time.Duration
 }
 
 func (p poolImpl) Name() string {
@@ -37,8 +39,10 @@ func (p poolImpl) Name() string {
 }
 
 func (p poolImpl) Properties(props ...string) (PoolProperties, error) {
+	ctx, cancel := CommandContext(p.timeout)
+	defer cancel()
 	handler := newPoolPropertiesImpl()
-	if err := execute(p.name, handler, `zpool`, `get`, `-Hpo`, `name,property,value`, strings.Join(props, `,`)); err != nil {
+	if err := execute(ctx, p.runner, p.name, handler, `zpool`, `get`, `-Hpo`, `name,property,value`, strings.Join(props, `,`)); err != nil {
 		return handler, err
 	}
 	return handler, nil
@@ -63,38 +67,26 @@ func (p *poolPropertiesImpl) processLine(pool string, line []string) error {
 }
 
 // PoolNames returns a list of available pool names
-func poolNames() ([]string, error) {
-	pools := make([]string, 0)
-	cmd := exec.Command(`zpool`, `list`, `-Ho`, `name`)
-	out, err := cmd.StdoutPipe()
+func poolNames(ctx context.Context, runner Runner) ([]string, error) {
+	stdout, _, err := runner.Run(ctx, `zpool`, `list`, `-Ho`, `name`)
 	if err != nil {
 		return nil, err
 	}
-	stderr, err := cmd.StderrPipe()
-	if err != nil {
-		return nil, err
-	}
-	scanner := bufio.NewScanner(out)
-
-	if err = cmd.Start(); err != nil {
-		return nil, fmt.Errorf("failed to start command '%s': %w", cmd.String(), err)
-	}
 
+	pools := make([]string, 0)
+	scanner := bufio.NewScanner(bytes.NewReader(stdout))
 	for scanner.Scan() {
 		pools = append(pools, scanner.Text())
 	}
 
-	stde, _ := io.ReadAll(stderr)
-	if err = cmd.Wait(); err != nil {
-		return nil, fmt.Errorf("failed to execute command '%s'; output: '%s' (%w)", cmd.String(), strings.TrimSpace(string(stde)), err)
-	}
-
-	return pools, nil
+	return pools, scanner.Err()
 }
 
-func newPoolImpl(name string) poolImpl {
+func newPoolImpl(name string, runner Runner, timeout time.Duration) poolImpl {
 	return poolImpl{
-		name: name,
+		name:    name,
+		runner:  runner,
+		timeout: timeout,
 	}
 }
 
@@ -0,0 +1,86 @@
+package zfs
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"math/bits"
+	"strconv"
+	"strings"
+)
+
+// latencyHistogramColumns is the number of columns of `zpool iostat -w` that are parsed, those of the total, disk, sync
+// queue and async queue wait of reads and writes. Later columns, such as scrub, trim and rebuild, vary by release.
+const latencyHistogramColumns = 8
+
+// LatencyHistogramsT holds the latency histograms of a pool since import, as reported by `zpool iostat -w`. Bucket i
+// counts operations that took between 2^i and 2^(i+1)-1 nanoseconds, and the last bucket also those that took longer.
+type LatencyHistogramsT struct {
+	TotalReadWait   []uint64
+	TotalWriteWait  []uint64
+	DiskReadWait    []uint64
+	DiskWriteWait   []uint64
+	SyncqReadWait   []uint64
+	SyncqWriteWait  []uint64
+	AsyncqReadWait  []uint64
+	AsyncqWriteWait []uint64
+}
+
+// ZpoolLatencyHistograms returns the latency histograms of the pool, as reported by `zpool iostat -w` in scripted mode
+func ZpoolLatencyHistograms(ctx context.Context, runner Runner, pool string) (LatencyHistogramsT, error) {
+	stdout, _, err := runner.Run(ctx, `zpool`, `iostat`, `-w`, `-H`, `-p`, pool)
+	if err != nil {
+		return LatencyHistogramsT{}, err
+	}
+	return parseLatencyHistograms(stdout)
+}
+
+// parseLatencyHistograms parses the rows of `zpool iostat -w -H -p`, each of which starts with the upper bound of its
+// bucket in nanoseconds. Rows that do not, such as the name of the pool, are skipped.
+func parseLatencyHistograms(out []byte) (LatencyHistogramsT, error) {
+	var h LatencyHistogramsT
+	columns := []*[]uint64{
+		&h.TotalReadWait, &h.TotalWriteWait,
+		&h.DiskReadWait, &h.DiskWriteWait,
+		&h.SyncqReadWait, &h.SyncqWriteWait,
+		&h.AsyncqReadWait, &h.AsyncqWriteWait,
+	}
+	for _, column := range columns {
+		*column = make([]uint64, 0, 64)
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		upper, err := strconv.ParseUint(fields[0], 10, 64)
+		if err != nil {
+			continue
+		}
+		if upper == 0 || len(fields) < latencyHistogramColumns+1 {
+			return LatencyHistogramsT{}, fmt.Errorf("%w: latency histogram row '%s'", ErrInvalidOutput, scanner.Text())
+		}
+		bucket := bits.Len64(upper) - 1
+		for i, column := range columns {
+			count, err := strconv.ParseUint(fields[i+1], 10, 64)
+			if err != nil {
+				return LatencyHistogramsT{}, fmt.Errorf("%w: latency histogram count '%s'", ErrInvalidOutput, fields[i+1])
+			}
+			for len(*column) <= bucket {
+				*column = append(*column, 0)
+			}
+			(*column)[bucket] += count
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return LatencyHistogramsT{}, err
+	}
+	if len(h.TotalReadWait) == 0 {
+		return LatencyHistogramsT{}, fmt.Errorf("%w: no latency histogram rows", ErrInvalidOutput)
+	}
+
+	return h, nil
+}
@@ -0,0 +1,158 @@
+package zfs
+
+import (
+	"context"
+	"log/slog"
+	"strconv"
+	"time"
+)
+
+type VdevIostatT struct {
+	Name            string                 `json:"name"`
+	VdevType        string                 `json:"vdev_type"`
+	Guid            Uint64                 `json:"guid"`
+	Class           string                 `json:"class"`
+	State           string                 `json:"state"`
+	AllocSpace      Uint64                 `json:"alloc_space"`
+	TotalSpace      Uint64                 `json:"total_space"`
+	ReadOps         Uint64                 `json:"read_ops"`
+	WriteOps        Uint64                 `json:"write_ops"`
+	ReadBytes       Uint64                 `json:"read_bytes"`
+	WriteBytes      Uint64                 `json:"write_bytes"`
+	TotalReadWait   Uint64                 `json:"total_read_wait"`
+	TotalWriteWait  Uint64                 `json:"total_write_wait"`
+	DiskReadWait    Uint64                 `json:"disk_read_wait"`
+	DiskWriteWait   Uint64                 `json:"disk_write_wait"`
+	SyncqReadWait   Uint64                 `json:"syncq_read_wait"`
+	SyncqWriteWait  Uint64                 `json:"syncq_write_wait"`
+	AsyncqReadWait  Uint64                 `json:"asyncq_read_wait"`
+	AsyncqWriteWait Uint64                 `json:"asyncq_write_wait"`
+	ScrubWait       Uint64                 `json:"scrub_wait"`
+	TrimWait        Uint64                 `json:"trim_wait"`
+	RebuildWait     Uint64                 `json:"rebuild_wait"`
+	Vdevs           map[string]VdevIostatT `json:"vdevs,omitempty"`
+}
+
+func (o VdevIostatT) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("name", o.Name),
+		slog.String("vdev_type", o.VdevType),
+		slog.Uint64("guid", uint64(o.Guid)),
+		slog.String("class", o.Class),
+		slog.String("state", o.State),
+		slog.Uint64("alloc_space", uint64(o.AllocSpace)),
+		slog.Uint64("total_space", uint64(o.TotalSpace)),
+		slog.Uint64("read_ops", uint64(o.ReadOps)),
+		slog.Uint64("write_ops", uint64(o.WriteOps)),
+		slog.Uint64("read_bytes", uint64(o.ReadBytes)),
+		slog.Uint64("write_bytes", uint64(o.WriteBytes)),
+		slog.Uint64("total_read_wait", uint64(o.TotalReadWait)),
+		slog.Uint64("total_write_wait", uint64(o.TotalWriteWait)),
+		slog.Uint64("disk_read_wait", uint64(o.DiskReadWait)),
+		slog.Uint64("disk_write_wait", uint64(o.DiskWriteWait)),
+		slog.Uint64("syncq_read_wait", uint64(o.SyncqReadWait)),
+		slog.Uint64("syncq_write_wait", uint64(o.SyncqWriteWait)),
+		slog.Uint64("asyncq_read_wait", uint64(o.AsyncqReadWait)),
+		slog.Uint64("asyncq_write_wait", uint64(o.AsyncqWriteWait)),
+		slog.Uint64("scrub_wait", uint64(o.ScrubWait)),
+		slog.Uint64("trim_wait", uint64(o.TrimWait)),
+		slog.Uint64("rebuild_wait", uint64(o.RebuildWait)),
+		slog.Int("num_vdevs", len(o.Vdevs)),
+	)
+}
+
+type PoolIostatT struct {
+	Name     string                 `json:"name"`
+	State    string                 `json:"state"`
+	PoolGuid Uint64                 `json:"pool_guid"`
+	Vdevs    map[string]VdevIostatT `json:"vdevs"`
+	Logs     map[string]VdevIostatT `json:"logs,omitempty"`
+	L2cache  map[string]VdevIostatT `json:"l2cache,omitempty"`
+	Special  map[string]VdevIostatT `json:"special,omitempty"`
+	Dedup    map[string]VdevIostatT `json:"dedup,omitempty"`
+}
+
+// Totals returns the pool-wide statistics, which zpool reports against the root vdev
+func (o PoolIostatT) Totals() VdevIostatT {
+	return o.Vdevs[o.Name]
+}
+
+// AllVdevs returns the statistics for every vdev in the pool, excluding the root vdev
+func (o PoolIostatT) AllVdevs() []VdevIostatT {
+	result := make([]VdevIostatT, 0)
+	var walk func(vdevs map[string]VdevIostatT)
+	walk = func(vdevs map[string]VdevIostatT) {
+		for _, vdev := range vdevs {
+			if vdev.VdevType != `root` {
+				result = append(result, vdev)
+			}
+			walk(vdev.Vdevs)
+		}
+	}
+	for _, vdevs := range []map[string]VdevIostatT{o.Vdevs, o.Logs, o.L2cache, o.Special, o.Dedup} {
+		walk(vdevs)
+	}
+
+	return result
+}
+
+// LeafVdevs returns the statistics for the vdevs in the pool without children, i.e. the devices that service I/O
+func (o PoolIostatT) LeafVdevs() []VdevIostatT {
+	result := make([]VdevIostatT, 0)
+	for _, vdev := range o.AllVdevs() {
+		if len(vdev.Vdevs) == 0 {
+			result = append(result, vdev)
+		}
+	}
+
+	return result
+}
+
+func (o PoolIostatT) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("name", o.Name),
+		slog.String("state", o.State),
+		slog.Uint64("pool_guid", uint64(o.PoolGuid)),
+		slog.Any("totals", o.Totals()),
+	)
+}
+
+type ZpoolIostatOutputT struct {
+	OutputVersion ZFSCommandOutputVersionT `json:"output_version"`
+	Pools         map[string]PoolIostatT   `json:"pools"`
+}
+
+func (o ZpoolIostatOutputT) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("output_version.command", o.OutputVersion.Command),
+		slog.Int("output_version.major", o.OutputVersion.Major),
+		slog.Int("output_version.minor", o.OutputVersion.Minor),
+		slog.Int("num_pools", len(o.Pools)),
+	)
+}
+
+// ZpoolIostatViaJSON returns the I/O statistics for all pools, optionally including per-vdev statistics and average
+// latencies. As no interval is supplied, values are averaged since the pool was imported.
+func ZpoolIostatViaJSON(ctx context.Context, runner Runner, logger *slog.Logger, vdevs bool) (map[string]PoolIostatT, error) {
+	return ZpoolIostatIntervalViaJSON(ctx, runner, logger, vdevs, 0)
+}
+
+// ZpoolIostatIntervalViaJSON returns the I/O statistics for all pools as ZpoolIostatViaJSON, but averaged over a single
+// interval sampled by `zpool iostat -y`, so that they reflect current activity. A zero interval averages them since the
+// pool was imported.
+func ZpoolIostatIntervalViaJSON(ctx context.Context, runner Runner, logger *slog.Logger, vdevs bool, interval time.Duration) (map[string]PoolIostatT, error) {
+	args := []string{`iostat`, `--json`, `--json-int`, `-p`}
+	if vdevs {
+		args = append(args, `-v`, `-l`)
+	}
+	if interval > 0 {
+		args = append(args, `-y`, strconv.FormatFloat(interval.Seconds(), 'f', -1, 64), `1`)
+	}
+
+	var o ZpoolIostatOutputT
+	if err := executeJSON(ctx, runner, logger, &o, `zpool`, args...); err != nil {
+		return nil, err
+	}
+	logger.Debug("Zpool Iostat Output Parsed", "output", o)
+	return o.Pools, nil
+}
@@ -0,0 +1,94 @@
+package zfs
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+)
+
+// PropertyValue holds a property value, which may be emitted as either a JSON string or number depending on the
+// property and whether --json-int was requested
+type PropertyValue string
+
+// UnmarshalJSON implements the json.Unmarshaler interface
+func (v *PropertyValue) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err == nil {
+		*v = PropertyValue(s)
+		return nil
+	}
+	var n json.Number
+	if err := json.Unmarshal(b, &n); err != nil {
+		return err
+	}
+	*v = PropertyValue(n)
+	return nil
+}
+
+type PropertySourceT struct {
+	Type string `json:"type"`
+	Data string `json:"data"`
+}
+
+type PropertyT struct {
+	Value  PropertyValue   `json:"value"`
+	Source PropertySourceT `json:"source"`
+}
+
+type PoolListT struct {
+	Name       string               `json:"name"`
+	Type       string               `json:"type"`
+	State      string               `json:"state"`
+	PoolGuid   Uint64               `json:"pool_guid"`
+	Properties map[string]PropertyT `json:"properties"`
+}
+
+func (o PoolListT) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("name", o.Name),
+		slog.String("type", o.Type),
+		slog.String("state", o.State),
+		slog.Uint64("pool_guid", uint64(o.PoolGuid)),
+		slog.Int("num_properties", len(o.Properties)),
+	)
+}
+
+type ZpoolListOutputT struct {
+	OutputVersion ZFSCommandOutputVersionT `json:"output_version"`
+	Pools         map[string]PoolListT     `json:"pools"`
+}
+
+func (o ZpoolListOutputT) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("output_version.command", o.OutputVersion.Command),
+		slog.Int("output_version.major", o.OutputVersion.Major),
+		slog.Int("output_version.minor", o.OutputVersion.Minor),
+		slog.Int("num_pools", len(o.Pools)),
+	)
+}
+
+// ZpoolListViaJSON returns the requested properties for all pools in a single invocation
+func ZpoolListViaJSON(ctx context.Context, runner Runner, logger *slog.Logger, props ...string) (map[string]PoolListT, error) {
+	var o ZpoolListOutputT
+	if err := executeJSON(ctx, runner, logger, &o, `zpool`, `list`, `--json`, `--json-int`, `-p`, `-o`, strings.Join(append([]string{`name`}, props...), `,`)); err != nil {
+		return nil, err
+	}
+	logger.Debug("Zpool List Output Parsed", "output", o)
+	return o.Pools, nil
+}
+
+// ZpoolGetViaJSON returns the requested properties, or all properties if none are requested, for all pools in a
+// single invocation. The output of `zpool get` shares the layout of `zpool list`.
+func ZpoolGetViaJSON(ctx context.Context, runner Runner, logger *slog.Logger, props ...string) (map[string]PoolListT, error) {
+	list := `all`
+	if len(props) > 0 {
+		list = strings.Join(props, `,`)
+	}
+	var o ZpoolListOutputT
+	if err := executeJSON(ctx, runner, logger, &o, `zpool`, `get`, `--json`, `--json-int`, `-p`, list); err != nil {
+		return nil, err
+	}
+	logger.Debug("Zpool Get Output Parsed", "output", o)
+	return o.Pools, nil
+}
@@ -0,0 +1,45 @@
+package zfs
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+)
+
+// ZpoolListViaText returns the requested properties of all pools by parsing the tab-separated output of
+// `zpool list -Hp`, for ZFS releases that do not support JSON output
+func ZpoolListViaText(ctx context.Context, runner Runner, logger *slog.Logger, props ...string) (map[string]PoolListT, error) {
+	columns := append([]string{`name`, `guid`, `health`}, props...)
+	stdout, _, err := runner.Run(ctx, `zpool`, `list`, `-Hp`, `-o`, strings.Join(columns, `,`))
+	if err != nil {
+		return nil, err
+	}
+
+	pools := make(map[string]PoolListT)
+	scanner := bufio.NewScanner(bytes.NewReader(stdout))
+	for scanner.Scan() {
+		fields := strings.Split(scanner.Text(), "\t")
+		if len(fields) != len(columns) {
+			return nil, fmt.Errorf("%w: zpool list returned %d columns, expected %d", ErrInvalidOutput, len(fields), len(columns))
+		}
+		guid, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: pool guid '%s'", ErrInvalidOutput, fields[1])
+		}
+		properties := make(map[string]PropertyT, len(props))
+		for i, prop := range props {
+			properties[prop] = PropertyT{Value: PropertyValue(fields[i+3])}
+		}
+		pools[fields[0]] = PoolListT{Name: fields[0], State: fields[2], PoolGuid: Uint64(guid), Properties: properties}
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	logger.Debug("Zpool List Text Output Parsed", "num_pools", len(pools))
+	return pools, nil
+}
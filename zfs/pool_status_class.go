@@ -0,0 +1,100 @@
+package zfs
+
+import (
+	"regexp"
+	"strings"
+)
+
+// StatusClass is a coarse, language-independent classification of the status reported by `zpool status`
+type StatusClass string
+
+const (
+	// StatusOK indicates that no status was reported for the pool
+	StatusOK StatusClass = `ok`
+	// StatusDegraded indicates that the pool is functioning with reduced redundancy
+	StatusDegraded StatusClass = `degraded`
+	// StatusFaulted indicates that the pool, or part of it, cannot be used
+	StatusFaulted StatusClass = `faulted`
+	// StatusDataErrors indicates that data in the pool is corrupt
+	StatusDataErrors StatusClass = `data-errors`
+	// StatusSuspended indicates that I/O to the pool has been suspended
+	StatusSuspended StatusClass = `suspended`
+	// StatusErrata indicates that the pool is affected by a known errata
+	StatusErrata StatusClass = `errata`
+	// StatusResilvering indicates that a device is being resilvered
+	StatusResilvering StatusClass = `resilvering`
+	// StatusUpgrade indicates that the pool format or features may be upgraded
+	StatusUpgrade StatusClass = `upgrade`
+	// StatusUnknown indicates a status that could not be classified
+	StatusUnknown StatusClass = `unknown`
+)
+
+var (
+	msgidRe = regexp.MustCompile(`ZFS-\d{4}-[0-9A-Z]+`)
+
+	// msgidClasses maps the message IDs documented at https://openzfs.github.io/openzfs-docs/msg/ to classes
+	msgidClasses = map[string]StatusClass{
+		`ZFS-8000-14`: StatusFaulted,
+		`ZFS-8000-2Q`: StatusDegraded,
+		`ZFS-8000-3C`: StatusFaulted,
+		`ZFS-8000-4J`: StatusDegraded,
+		`ZFS-8000-5E`: StatusFaulted,
+		`ZFS-8000-6X`: StatusFaulted,
+		`ZFS-8000-72`: StatusFaulted,
+		`ZFS-8000-8A`: StatusDataErrors,
+		`ZFS-8000-9P`: StatusDegraded,
+		`ZFS-8000-A5`: StatusFaulted,
+		`ZFS-8000-EY`: StatusFaulted,
+		`ZFS-8000-ER`: StatusErrata,
+		`ZFS-8000-HC`: StatusSuspended,
+		`ZFS-8000-JQ`: StatusSuspended,
+		`ZFS-8000-K4`: StatusDegraded,
+	}
+
+	// statusTextClasses classifies statuses that are reported without a message ID by their untranslated text, which
+	// is produced as the runner forces the C locale
+	statusTextClasses = []struct {
+		substring string
+		class     StatusClass
+	}{
+		{`being resilvered`, StatusResilvering},
+		{`features are not enabled`, StatusUpgrade},
+		{`formatted using a legacy on-disk format`, StatusUpgrade},
+		{`errata`, StatusErrata},
+	}
+)
+
+// msgidFromURL returns the message ID referenced by a `see:` URL, if any
+func msgidFromURL(url string) string {
+	return msgidRe.FindString(url)
+}
+
+// Classify returns the class of the pool status. The enumerated message ID is used where available, as the status
+// text may be localized, falling back to matching the untranslated status text.
+func (o PoolStatusT) Classify() StatusClass {
+	msgid := o.Msgid
+	if msgid == `` {
+		msgid = msgidFromURL(o.Moreinfo)
+	}
+	if class, ok := msgidClasses[msgid]; ok {
+		return class
+	}
+
+	if o.Status == `` {
+		return StatusOK
+	}
+	for _, c := range statusTextClasses {
+		if strings.Contains(o.Status, c.substring) {
+			return c.class
+		}
+	}
+
+	return StatusUnknown
+}
+
+// UpgradeAvailable returns whether the on-disk format or features of the pool may be upgraded with `zpool upgrade`, as
+// reported by its status or recommended action. The action names the command even where the text is localized. Only the
+// most severe status of a pool is reported, so this is false while, for example, the pool is degraded.
+func (o PoolStatusT) UpgradeAvailable() bool {
+	return o.Classify() == StatusUpgrade || strings.Contains(o.Action, `zpool upgrade`)
+}
@@ -0,0 +1,131 @@
+package zfs
+
+import (
+	"context"
+	"testing"
+)
+
+func TestPoolStatusClassify(t *testing.T) {
+	testCases := []struct {
+		name   string
+		status PoolStatusT
+		want   StatusClass
+	}{
+		{
+			name: `ok`,
+			want: StatusOK,
+		},
+		{
+			name:   `msgid`,
+			status: PoolStatusT{Status: `Ein oder mehrere Geräte sind ausgefallen.`, Msgid: `ZFS-8000-9P`},
+			want:   StatusDegraded,
+		},
+		{
+			name:   `moreinfo`,
+			status: PoolStatusT{Status: `Dati danneggiati.`, Moreinfo: `https://openzfs.github.io/openzfs-docs/msg/ZFS-8000-8A`},
+			want:   StatusDataErrors,
+		},
+		{
+			name:   `suspended`,
+			status: PoolStatusT{Msgid: `ZFS-8000-HC`},
+			want:   StatusSuspended,
+		},
+		{
+			name:   `text`,
+			status: PoolStatusT{Status: `Some supported and requested features are not enabled on the pool.`},
+			want:   StatusUpgrade,
+		},
+		{
+			name:   `resilvering`,
+			status: PoolStatusT{Status: `One or more devices is currently being resilvered.`},
+			want:   StatusResilvering,
+		},
+		{
+			name:   `unknown`,
+			status: PoolStatusT{Status: `Quelque chose ne va pas.`},
+			want:   StatusUnknown,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.status.Classify(); got != tc.want {
+				t.Errorf("Expected %s, got %s", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestPoolStatusClassifyLocalizedText(t *testing.T) {
+	runner := fixtureRunner{
+		`zpool status -p`:           `zpool_status_localized.txt`,
+		`zpool list -Hpo name,guid`: `zpool_list_guid.txt`,
+	}
+	pools, err := ZpoolStatusViaText(context.Background(), runner, testLogger)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tank := (*pools)[`tank`]
+	if tank.Msgid != `ZFS-8000-9P` {
+		t.Errorf("Expected msgid ZFS-8000-9P, got %q", tank.Msgid)
+	}
+	if got := tank.Classify(); got != StatusDegraded {
+		t.Errorf("Expected %s, got %s", StatusDegraded, got)
+	}
+}
+
+func TestPoolStatusClassifyJSON(t *testing.T) {
+	runner := fixtureRunner{`zpool status --json --json-int`: `zpool_status_degraded.json`}
+	pools, err := ZpoolStatusViaJSON(context.Background(), runner, testLogger)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tank := (*pools)[`tank`]
+	if tank.Msgid != `ZFS-8000-9P` {
+		t.Errorf("Expected msgid ZFS-8000-9P, got %q", tank.Msgid)
+	}
+	if got := tank.Classify(); got != StatusDegraded {
+		t.Errorf("Expected %s, got %s", StatusDegraded, got)
+	}
+}
+
+func TestPoolStatusUpgradeAvailable(t *testing.T) {
+	testCases := []struct {
+		name   string
+		status PoolStatusT
+		want   bool
+	}{
+		{
+			name: `ok`,
+		},
+		{
+			name:   `features`,
+			status: PoolStatusT{Status: `Some supported and requested features are not enabled on the pool.`, Action: `Enable all features using 'zpool upgrade'.`},
+			want:   true,
+		},
+		{
+			name:   `legacy`,
+			status: PoolStatusT{Status: `The pool is formatted using a legacy on-disk format.`},
+			want:   true,
+		},
+		{
+			name:   `localized`,
+			status: PoolStatusT{Status: `Einige unterstützte Funktionen sind nicht aktiviert.`, Action: `Aktivieren Sie alle Funktionen mit 'zpool upgrade'.`},
+			want:   true,
+		},
+		{
+			name:   `degraded`,
+			status: PoolStatusT{Status: `One or more devices could not be used.`, Action: `Replace the device using 'zpool replace'.`, Msgid: `ZFS-8000-4J`},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.status.UpgradeAvailable(); got != tc.want {
+				t.Errorf("Expected %t, got %t", tc.want, got)
+			}
+		})
+	}
+}
@@ -1,120 +1,199 @@
 package zfs
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
 	"log/slog"
-	"os/exec"
-	"strings"
+	"slices"
+	"sync"
+	"time"
 )
 
 type VdevStatusT struct {
 	Name           string `json:"name"`
 	VdevType       string `json:"vdev_type"`
-	Guid           uint64 `json:"guid"`
+	Guid           Uint64 `json:"guid"`
 	Path           string `json:"path"`
 	PhysPath       string `json:"phys_path"`
 	Devid          string `json:"devid"`
 	Class          string `json:"class"`
 	State          string `json:"state"`
 	Parent         string `json:"parent"`
-	RepDevSize     int    `json:"rep_dev_size"`
-	SelfHealed     int    `json:"self_healed,omitempty"`
-	PhysSpace      int    `json:"phys_space"`
-	ReadErrors     int    `json:"read_errors"`
-	WriteErrors    int    `json:"write_errors"`
-	ChecksumErrors int    `json:"checksum_errors"`
-	ScanProcessed  int    `json:"scan_processed,omitempty"`
-	SlowIos        int    `json:"slow_ios"`
+	RepDevSize     Uint64 `json:"rep_dev_size"`
+	SelfHealed     Uint64 `json:"self_healed,omitempty"`
+	PhysSpace      Uint64 `json:"phys_space"`
+	ReadErrors     Uint64 `json:"read_errors"`
+	WriteErrors    Uint64 `json:"write_errors"`
+	ChecksumErrors Uint64 `json:"checksum_errors"`
+	ScanProcessed  Uint64 `json:"scan_processed,omitempty"`
+	SlowIos        Uint64 `json:"slow_ios"`
+	// PowerState is the power state of the enclosure slot (on, off or -), reported by `zpool status --power`
+	PowerState string `json:"power_state,omitempty"`
+	// Vdevs holds the children of the vdev, keyed by name
+	Vdevs map[string]VdevStatusT `json:"vdevs,omitempty"`
 }
 
 func (o VdevStatusT) LogValue() slog.Value {
 	return slog.GroupValue(
 		slog.String("name", o.Name),
 		slog.String("vdev_type", o.VdevType),
-		slog.Uint64("guid", o.Guid),
+		slog.Uint64("guid", uint64(o.Guid)),
 		slog.String("path", o.Path),
 		slog.String("phys_path", o.PhysPath),
 		slog.String("devid", o.Devid),
 		slog.String("class", o.Class),
 		slog.String("state", o.State),
 		slog.String("parent", o.Parent),
-		slog.Int("rep_dev_size", o.RepDevSize),
-		slog.Int("self_healed", o.SelfHealed),
-		slog.Int("phys_space", o.PhysSpace),
-		slog.Int("read_errors", o.ReadErrors),
-		slog.Int("write_errors", o.WriteErrors),
-		slog.Int("checksum_errors", o.ChecksumErrors),
-		slog.Int("scan_processed", o.ScanProcessed),
-		slog.Int("slow_ios", o.SlowIos),
+		slog.Uint64("rep_dev_size", uint64(o.RepDevSize)),
+		slog.Uint64("self_healed", uint64(o.SelfHealed)),
+		slog.Uint64("phys_space", uint64(o.PhysSpace)),
+		slog.Uint64("read_errors", uint64(o.ReadErrors)),
+		slog.Uint64("write_errors", uint64(o.WriteErrors)),
+		slog.Uint64("checksum_errors", uint64(o.ChecksumErrors)),
+		slog.Uint64("scan_processed", uint64(o.ScanProcessed)),
+		slog.Uint64("slow_ios", uint64(o.SlowIos)),
+		slog.String("power_state", o.PowerState),
+		slog.Int("num_vdevs", len(o.Vdevs)),
 	)
 }
 
 type ScanStatsT struct {
 	Function           string `json:"function"`
 	State              string `json:"state"`
-	StartTime          int    `json:"start_time"`
-	EndTime            int    `json:"end_time"`
-	ToExamine          int    `json:"to_examine"`
-	Examined           int    `json:"examined"`
-	Skipped            int    `json:"skipped"`
-	Processed          int    `json:"processed"`
-	Errors             int    `json:"errors"`
-	BytesPerScan       int    `json:"bytes_per_scan"`
-	PassStart          int    `json:"pass_start"`
-	ScrubPause         int    `json:"scrub_pause"`
-	ScrubSpentPaused   int    `json:"scrub_spent_paused"`
-	IssuedBytesPerScan int    `json:"issued_bytes_per_scan"`
-	Issued             int    `json:"issued"`
+	StartTime          Uint64 `json:"start_time"`
+	EndTime            Uint64 `json:"end_time"`
+	ToExamine          Uint64 `json:"to_examine"`
+	Examined           Uint64 `json:"examined"`
+	Skipped            Uint64 `json:"skipped"`
+	Processed          Uint64 `json:"processed"`
+	Errors             Uint64 `json:"errors"`
+	BytesPerScan       Uint64 `json:"bytes_per_scan"`
+	PassStart          Uint64 `json:"pass_start"`
+	ScrubPause         Uint64 `json:"scrub_pause"`
+	ScrubSpentPaused   Uint64 `json:"scrub_spent_paused"`
+	IssuedBytesPerScan Uint64 `json:"issued_bytes_per_scan"`
+	Issued             Uint64 `json:"issued"`
 }
 
 func (o ScanStatsT) LogValue() slog.Value {
 	return slog.GroupValue(
 		slog.String("function", o.Function),
 		slog.String("state", o.State),
-		slog.Int("start_time", o.StartTime),
-		slog.Int("end_time", o.EndTime),
-		slog.Int("to_examine", o.ToExamine),
-		slog.Int("examined", o.Examined),
-		slog.Int("skipped", o.Skipped),
-		slog.Int("processed", o.Processed),
-		slog.Int("errors", o.Errors),
-		slog.Int("BytesPerScan", o.BytesPerScan),
-		slog.Int("pass_start", o.PassStart),
-		slog.Int("scrub_pause", o.ScrubPause),
-		slog.Int("scrub_spent_paused", o.ScrubSpentPaused),
-		slog.Int("issued_bytes_per_scan", o.IssuedBytesPerScan),
+		slog.Uint64("start_time", uint64(o.StartTime)),
+		slog.Uint64("end_time", uint64(o.EndTime)),
+		slog.Uint64("to_examine", uint64(o.ToExamine)),
+		slog.Uint64("examined", uint64(o.Examined)),
+		slog.Uint64("skipped", uint64(o.Skipped)),
+		slog.Uint64("processed", uint64(o.Processed)),
+		slog.Uint64("errors", uint64(o.Errors)),
+		slog.Uint64("BytesPerScan", uint64(o.BytesPerScan)),
+		slog.Uint64("pass_start", uint64(o.PassStart)),
+		slog.Uint64("scrub_pause", uint64(o.ScrubPause)),
+		slog.Uint64("scrub_spent_paused", uint64(o.ScrubSpentPaused)),
+		slog.Uint64("issued_bytes_per_scan", uint64(o.IssuedBytesPerScan)),
 	)
 }
 
+// RaidzExpandStatsT reports the progress of the current or most recent expansion of a raidz vdev by an additional disk
+// (OpenZFS 2.3 or later)
+type RaidzExpandStatsT struct {
+	State string `json:"state"`
+	// ExpandingVdev is the name of the raidz vdev being expanded
+	ExpandingVdev string `json:"expanding_vdev"`
+	StartTime     Uint64 `json:"start_time"`
+	EndTime       Uint64 `json:"end_time"`
+	// ToReflow is the number of bytes to be copied to the new layout, of which Reflowed have been copied
+	ToReflow Uint64 `json:"to_reflow"`
+	Reflowed Uint64 `json:"reflowed"`
+	// WaitingForResilver is non-zero while the expansion is paused until a resilver completes
+	WaitingForResilver Uint64 `json:"waiting_for_resilver"`
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface. OpenZFS reports the expanding vdev as its index among the
+// top-level vdevs, with its name alongside, so the name is read where present.
+func (o *RaidzExpandStatsT) UnmarshalJSON(b []byte) error {
+	type stats RaidzExpandStatsT
+	var v struct {
+		stats
+		Name          string          `json:"name"`
+		ExpandingVdev json.RawMessage `json:"expanding_vdev"`
+	}
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	*o = RaidzExpandStatsT(v.stats)
+	switch {
+	case v.Name != ``:
+		o.ExpandingVdev = v.Name
+	case len(v.ExpandingVdev) > 0:
+		if err := json.Unmarshal(v.ExpandingVdev, &o.ExpandingVdev); err != nil {
+			// Without a name, the index is the best available label
+			o.ExpandingVdev = string(v.ExpandingVdev)
+		}
+	}
+	return nil
+}
+
 type PoolStatusT struct {
-	Name       string                 `json:"name"`
-	State      string                 `json:"state"`
-	PoolGuid   uint64                 `json:"pool_guid"`
-	Txg        int                    `json:"txg"`
-	SpaVersion int                    `json:"spa_version"`
-	ZplVersion int                    `json:"zpl_version"`
-	Status     string                 `json:"status"`
-	Action     string                 `json:"action"`
-	Moreinfo   string                 `json:"moreinfo"`
-	ErrorCount int                    `json:"error_count"`
-	ScanStats  ScanStatsT             `json:"scan_stats"`
-	Vdevs      map[string]VdevStatusT `json:"vdevs"`
+	Name       string `json:"name"`
+	State      string `json:"state"`
+	PoolGuid   Uint64 `json:"pool_guid"`
+	Txg        Uint64 `json:"txg"`
+	SpaVersion Uint64 `json:"spa_version"`
+	ZplVersion Uint64 `json:"zpl_version"`
+	Status     string `json:"status"`
+	Action     string `json:"action"`
+	Msgid      string `json:"msgid,omitempty"`
+	Moreinfo   string `json:"moreinfo"`
+	ErrorCount Uint64 `json:"error_count"`
+	// ErrList lists the objects affected by permanent data errors, and is only populated by `zpool status -v`
+	ErrList   []string               `json:"errlist,omitempty"`
+	ScanStats ScanStatsT             `json:"scan_stats"`
+	Vdevs     map[string]VdevStatusT `json:"vdevs"`
+	Logs      map[string]VdevStatusT `json:"logs,omitempty"`
+	L2cache   map[string]VdevStatusT `json:"l2cache,omitempty"`
+	Spares    map[string]VdevStatusT `json:"spares,omitempty"`
+	Special   map[string]VdevStatusT `json:"special,omitempty"`
+	Dedup     map[string]VdevStatusT `json:"dedup,omitempty"`
+
+	// RaidzExpandStats is nil unless a raidz vdev of the pool has been expanded
+	RaidzExpandStats *RaidzExpandStatsT `json:"raidz_expand_stats,omitempty"`
+}
+
+// AllVdevs returns the status of every vdev in the pool, including spares and excluding the root vdev
+func (o PoolStatusT) AllVdevs() []VdevStatusT {
+	result := make([]VdevStatusT, 0)
+	var walk func(vdevs map[string]VdevStatusT)
+	walk = func(vdevs map[string]VdevStatusT) {
+		for _, vdev := range vdevs {
+			if vdev.VdevType != `root` {
+				result = append(result, vdev)
+			}
+			walk(vdev.Vdevs)
+		}
+	}
+	for _, vdevs := range []map[string]VdevStatusT{o.Vdevs, o.Logs, o.L2cache, o.Spares, o.Special, o.Dedup} {
+		walk(vdevs)
+	}
+
+	return result
 }
 
 func (o PoolStatusT) LogValue() slog.Value {
 	return slog.GroupValue(
 		slog.String("name", o.Name),
 		slog.String("state", o.State),
-		slog.Uint64("pool_guid", o.PoolGuid),
-		slog.Int("txg", o.Txg),
-		slog.Int("spa_version", o.SpaVersion),
-		slog.Int("zpl_version", o.ZplVersion),
+		slog.Uint64("pool_guid", uint64(o.PoolGuid)),
+		slog.Uint64("txg", uint64(o.Txg)),
+		slog.Uint64("spa_version", uint64(o.SpaVersion)),
+		slog.Uint64("zpl_version", uint64(o.ZplVersion)),
 		slog.String("status", o.Status),
 		slog.String("action", o.Action),
+		slog.String("msgid", o.Msgid),
 		slog.String("more_info", o.Moreinfo),
-		slog.Int("error_count", o.ErrorCount),
+		slog.Uint64("error_count", uint64(o.ErrorCount)),
 		slog.Int("num_vdevs", len(o.Vdevs)),
 	)
 }
@@ -133,42 +212,83 @@ func (o ZpoolStatusOutputT) LogValue() slog.Value {
 	)
 }
 
-func ZpoolStatusViaJSON(logger *slog.Logger) (*map[string]PoolStatusT, error) {
-	cmd := exec.Command(`zpool`, `status`, `--json`, `--json-int`)
-
-	// Setup pipes
-	stdout, err := cmd.StdoutPipe()
+func ZpoolStatusViaJSON(ctx context.Context, runner Runner, logger *slog.Logger) (*map[string]PoolStatusT, error) {
+	pools, err := zpoolStatusViaJSON(ctx, runner, logger, false)
 	if err != nil {
 		return nil, err
 	}
-	stderr, err := cmd.StderrPipe()
-	if err != nil {
+	return &pools, nil
+}
+
+// zpoolStatusViaJSON returns the status of all pools, optionally including the power state of each vdev
+func zpoolStatusViaJSON(ctx context.Context, runner Runner, logger *slog.Logger, power bool) (map[string]PoolStatusT, error) {
+	args := []string{`status`, `--json`, `--json-int`}
+	if power {
+		args = append(args, `--power`)
+	}
+
+	var o ZpoolStatusOutputT
+	if err := executeJSON(ctx, runner, logger, &o, `zpool`, args...); err != nil {
 		return nil, err
 	}
+	logger.Debug("Zpool Status Output Parsed", "output", o)
+	return o.Pools, nil
+}
 
-	// command begin
-	if err = cmd.Start(); err != nil {
-		return nil, fmt.Errorf("failed to start command '%s': %w", cmd.String(), err)
+// ZpoolStatusPerPoolViaJSON returns the status of all pools, querying each pool separately with at most concurrency
+// commands in flight. Pools whose status could not be retrieved are omitted from the result and their errors joined, so
+// that a slow or suspended pool does not prevent the status of healthy pools from being reported.
+func ZpoolStatusPerPoolViaJSON(ctx context.Context, runner Runner, logger *slog.Logger, concurrency int) (*map[string]PoolStatusT, error) {
+	pools, err := zpoolStatusPerPool(ctx, runner, logger, concurrency, false)
+	if pools == nil {
+		return nil, err
 	}
+	return &pools, err
+}
 
-	// stdout
-	stdo, err := io.ReadAll(stdout)
+// zpoolStatusPerPool queries the status of each pool separately, optionally including the power state of each vdev
+func zpoolStatusPerPool(ctx context.Context, runner Runner, logger *slog.Logger, concurrency int, power bool) (map[string]PoolStatusT, error) {
+	names, err := poolNames(ctx, runner)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read output of '%s'; output: (%w)", cmd.String(), err)
+		return nil, err
 	}
-	logger.Debug("ZFS Command Output", "stdout", stdo)
-
-	// stderr
-	stde, _ := io.ReadAll(stderr)
-	if err = cmd.Wait(); err != nil {
-		return nil, fmt.Errorf("failed to execute command '%s'; output: '%s' (%w)", cmd.String(), strings.TrimSpace(string(stde)), err)
+	args := []string{`status`, `--json`, `--json-int`}
+	if power {
+		args = append(args, `--power`)
 	}
 
-	// unmarshal JSON into Go objects
-	var o ZpoolStatusOutputT
-	if err := json.Unmarshal(stdo, &o); err != nil {
-		return nil, fmt.Errorf("failed to read output of '%s'; output: (%w)", cmd.String(), err)
+	var (
+		wg     sync.WaitGroup
+		mu     sync.Mutex
+		errs   []error
+		pools  = make(map[string]PoolStatusT, len(names))
+		tokens = make(chan struct{}, max(1, concurrency))
+	)
+	for _, name := range names {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			begin := time.Now()
+			tokens <- struct{}{}
+			defer func() { <-tokens }()
+			recordQueueWait(`PoolStatusPerPool`, time.Since(begin))
+
+			var o ZpoolStatusOutputT
+			err := executeJSON(ctx, runner, logger, &o, `zpool`, append(slices.Clone(args), name)...)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				errs = append(errs, fmt.Errorf("pool %s: %w", name, err))
+				return
+			}
+			for n, pool := range o.Pools {
+				pools[n] = pool
+			}
+		}()
 	}
-	logger.Debug("Zpool Status Output Parsed", "output", o)
-	return &o.Pools, nil
+	wg.Wait()
+
+	logger.Debug("Zpool Status Output Parsed", "num_pools", len(pools), "num_errors", len(errs))
+	return pools, errors.Join(errs...)
 }
@@ -0,0 +1,382 @@
+package zfs
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+const scanTimeLayout = `Mon Jan _2 15:04:05 2006`
+
+var (
+	// statusClasses maps the allocation class section headers of `zpool status` to vdev classes
+	statusClasses = map[string]string{
+		`logs`:    `log`,
+		`cache`:   `l2cache`,
+		`spares`:  `spare`,
+		`special`: `special`,
+		`dedup`:   `dedup`,
+	}
+
+	statusHeaderRe   = regexp.MustCompile(`^\s*([a-z]+):\s?(.*)$`)
+	statusErrorsRe   = regexp.MustCompile(`^(\d+) data errors`)
+	scanFinishedRe   = regexp.MustCompile(`^(scrub repaired|resilvered) .* with (\d+) errors on (.+)$`)
+	scanInProgressRe = regexp.MustCompile(`^(scrub|resilver) in progress since (.+)$`)
+	scanCanceledRe   = regexp.MustCompile(`^(scrub|resilver) canceled on (.+)$`)
+	scanPausedRe     = regexp.MustCompile(`^scrub paused since (.+)$`)
+	// scanProgressRe matches the progress of a scan reported by releases prior to 2.2, and scanProgressTotalRe that
+	// reported since
+	scanProgressRe      = regexp.MustCompile(`^(\S+) scanned at [^,]+, (\S+) issued at [^,]+, (\S+) total$`)
+	scanProgressTotalRe = regexp.MustCompile(`^(\S+) / (\S+) scanned at [^,]+, (\S+) / \S+ issued at`)
+)
+
+// statusNode is a vdev in the process of being parsed from the `zpool status` config tree
+type statusNode struct {
+	vdev     VdevStatusT
+	children []*statusNode
+}
+
+func (n *statusNode) status() VdevStatusT {
+	v := n.vdev
+	switch {
+	case v.VdevType != ``:
+	case len(n.children) == 0 && strings.HasPrefix(v.Name, `/`):
+		v.VdevType = `file`
+	case len(n.children) == 0:
+		v.VdevType = `disk`
+	default:
+		// Interior vdevs are named for their type, e.g. mirror-0, raidz2-1, draid1:4d:8c:1s-0
+		v.VdevType = v.Name
+		if i := strings.IndexFunc(v.Name, func(r rune) bool { return !unicode.IsLetter(r) }); i > 0 {
+			v.VdevType = v.Name[:i]
+		}
+	}
+	if len(n.children) > 0 {
+		v.Vdevs = make(map[string]VdevStatusT, len(n.children))
+		for _, child := range n.children {
+			v.Vdevs[child.vdev.Name] = child.status()
+		}
+	}
+	return v
+}
+
+// statusParser parses the human-readable output of `zpool status -p`, for releases without JSON output
+type statusParser struct {
+	pools    map[string]PoolStatusT
+	pool     *PoolStatusT
+	field    string
+	root     *statusNode
+	class    string
+	classes  map[string][]*statusNode
+	stack    []*statusNode
+	inConfig bool
+}
+
+func (p *statusParser) parse(out []byte) (map[string]PoolStatusT, error) {
+	p.pools = make(map[string]PoolStatusT)
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	line := 0
+	for scanner.Scan() {
+		line++
+		if err := p.parseLine(scanner.Text()); err != nil {
+			return nil, fmt.Errorf("%w: zpool status line %d: %w", ErrInvalidOutput, line, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	p.finishPool()
+
+	return p.pools, nil
+}
+
+func (p *statusParser) parseLine(line string) error {
+	if strings.TrimSpace(line) == `` {
+		return nil
+	}
+
+	if p.inConfig && strings.HasPrefix(line, "\t") {
+		return p.parseVdev(strings.TrimPrefix(line, "\t"))
+	}
+
+	if m := statusHeaderRe.FindStringSubmatch(line); m != nil && !strings.HasPrefix(line, "\t") {
+		p.field = m[1]
+		p.inConfig = false
+		return p.parseHeader(m[1], m[2])
+	}
+
+	// Continuation of a multi-line header value
+	if p.pool != nil && strings.HasPrefix(line, "\t") {
+		value := strings.TrimSpace(line)
+		switch p.field {
+		case `status`:
+			p.pool.Status += ` ` + value
+		case `action`:
+			p.pool.Action += ` ` + value
+		case `scan`:
+			parseScanProgress(&p.pool.ScanStats, value)
+		}
+	}
+
+	return nil
+}
+
+func (p *statusParser) parseHeader(field, value string) error {
+	if field == `pool` {
+		p.finishPool()
+		p.pool = &PoolStatusT{Name: value}
+		p.root = nil
+		p.classes = make(map[string][]*statusNode)
+		return nil
+	}
+	if p.pool == nil {
+		return fmt.Errorf("field %s outside of pool", field)
+	}
+
+	switch field {
+	case `state`:
+		p.pool.State = value
+	case `status`:
+		p.pool.Status = value
+	case `action`:
+		p.pool.Action = value
+	case `see`:
+		p.pool.Moreinfo = value
+		p.pool.Msgid = msgidFromURL(value)
+	case `scan`:
+		p.pool.ScanStats = parseScan(value)
+	case `config`:
+		p.inConfig = true
+	case `errors`:
+		if m := statusErrorsRe.FindStringSubmatch(value); m != nil {
+			n, _ := strconv.ParseUint(m[1], 10, 64)
+			p.pool.ErrorCount = Uint64(n)
+		}
+	}
+
+	return nil
+}
+
+func (p *statusParser) parseVdev(line string) error {
+	indent := len(line) - len(strings.TrimLeft(line, ` `))
+	level := indent / 2
+	fields := strings.Fields(line)
+	if fields[0] == `NAME` && level == 0 {
+		return nil
+	}
+
+	if level == 0 {
+		if class, ok := statusClasses[fields[0]]; ok && len(fields) == 1 {
+			p.class = class
+			p.stack = []*statusNode{nil}
+			return nil
+		}
+		p.class = `normal`
+		p.root = &statusNode{vdev: VdevStatusT{Name: fields[0], VdevType: `root`}}
+		p.stack = []*statusNode{p.root}
+		return p.parseVdevFields(p.root, fields)
+	}
+
+	if level > len(p.stack) {
+		return fmt.Errorf("unexpected indentation of vdev %s", fields[0])
+	}
+	node := &statusNode{vdev: VdevStatusT{Name: fields[0], Class: p.class}}
+	p.stack = append(p.stack[:level], node)
+	parent := p.stack[level-1]
+	if parent == nil {
+		p.classes[p.class] = append(p.classes[p.class], node)
+	} else {
+		node.vdev.Parent = parent.vdev.Name
+		parent.children = append(parent.children, node)
+	}
+
+	return p.parseVdevFields(node, fields)
+}
+
+func (p *statusParser) parseVdevFields(node *statusNode, fields []string) error {
+	if len(fields) > 1 {
+		node.vdev.State = fields[1]
+	}
+	if len(fields) < 5 {
+		// Spares and some unavailable devices omit the error counters
+		return nil
+	}
+	for i, dst := range []*Uint64{&node.vdev.ReadErrors, &node.vdev.WriteErrors, &node.vdev.ChecksumErrors} {
+		v, err := strconv.ParseUint(fields[i+2], 10, 64)
+		if err != nil {
+			return fmt.Errorf("error count for vdev %s: %w", node.vdev.Name, err)
+		}
+		*dst = Uint64(v)
+	}
+	return nil
+}
+
+func (p *statusParser) finishPool() {
+	if p.pool == nil {
+		return
+	}
+	if p.root != nil {
+		p.pool.Vdevs = map[string]VdevStatusT{p.root.vdev.Name: p.root.status()}
+	}
+	for class, nodes := range p.classes {
+		vdevs := make(map[string]VdevStatusT, len(nodes))
+		for _, node := range nodes {
+			vdevs[node.vdev.Name] = node.status()
+		}
+		switch class {
+		case `log`:
+			p.pool.Logs = vdevs
+		case `l2cache`:
+			p.pool.L2cache = vdevs
+		case `spare`:
+			p.pool.Spares = vdevs
+		case `special`:
+			p.pool.Special = vdevs
+		case `dedup`:
+			p.pool.Dedup = vdevs
+		}
+	}
+	p.pools[p.pool.Name] = *p.pool
+	p.pool = nil
+}
+
+// parseScanProgress interprets the progress line of a scan in progress, setting the bytes examined, issued and to
+// examine
+func parseScanProgress(s *ScanStatsT, value string) {
+	var examined, issued, total string
+	if m := scanProgressRe.FindStringSubmatch(value); m != nil {
+		examined, issued, total = m[1], m[2], m[3]
+	} else if m := scanProgressTotalRe.FindStringSubmatch(value); m != nil {
+		examined, total, issued = m[1], m[2], m[3]
+	} else {
+		return
+	}
+	for dst, v := range map[*Uint64]string{&s.Examined: examined, &s.Issued: issued, &s.ToExamine: total} {
+		if n, err := parseNiceBytes(v); err == nil {
+			*dst = Uint64(n)
+		}
+	}
+}
+
+// parseScan interprets the first line of the scan field, using the same function and state names as JSON output
+func parseScan(value string) ScanStatsT {
+	var s ScanStatsT
+	parseTime := func(v string) Uint64 {
+		t, err := time.ParseInLocation(scanTimeLayout, strings.TrimSpace(v), time.Local)
+		if err != nil {
+			return 0
+		}
+		return Uint64(t.Unix())
+	}
+
+	if m := scanFinishedRe.FindStringSubmatch(value); m != nil {
+		s.Function = `SCRUB`
+		if m[1] == `resilvered` {
+			s.Function = `RESILVER`
+		}
+		s.State = `FINISHED`
+		n, _ := strconv.ParseUint(m[2], 10, 64)
+		s.Errors = Uint64(n)
+		s.EndTime = parseTime(m[3])
+	} else if m := scanInProgressRe.FindStringSubmatch(value); m != nil {
+		s.Function = strings.ToUpper(m[1])
+		s.State = `SCANNING`
+		s.StartTime = parseTime(m[2])
+	} else if m := scanCanceledRe.FindStringSubmatch(value); m != nil {
+		s.Function = strings.ToUpper(m[1])
+		s.State = `CANCELED`
+		s.EndTime = parseTime(m[2])
+	} else if m := scanPausedRe.FindStringSubmatch(value); m != nil {
+		s.Function = `SCRUB`
+		s.State = `SCANNING`
+		s.ScrubPause = parseTime(m[1])
+	}
+
+	return s
+}
+
+// statusErr returns the error of `zpool status`, or nil where it failed yet printed the status of a pool
+func statusErr(ctx context.Context, stdout []byte, err error) error {
+	if err != nil && partialOutput(ctx, stdout) && bytes.Contains(stdout, []byte(`pool:`)) {
+		return nil
+	}
+	return err
+}
+
+// ZpoolStatusViaText returns the status of all pools by parsing the human-readable output of `zpool status`, for ZFS
+// releases that do not support JSON output. Fields that are only available via JSON, such as vdev GUIDs and the pool
+// txg, are left unset.
+func ZpoolStatusViaText(ctx context.Context, runner Runner, logger *slog.Logger) (*map[string]PoolStatusT, error) {
+	stdout, _, runErr := runner.Run(ctx, `zpool`, `status`, `-p`)
+	if runErr != nil && !partialOutput(ctx, stdout) {
+		return nil, runErr
+	}
+	pools, err := new(statusParser).parse(stdout)
+	if runErr != nil {
+		if err != nil || len(pools) == 0 {
+			return nil, runErr
+		}
+		logger.Warn("Command failed, using its output", "cmd", `zpool status -p`, "err", runErr)
+	} else if err != nil {
+		return nil, err
+	}
+
+	stdout, _, err = runner.Run(ctx, `zpool`, `list`, `-Hpo`, `name,guid`)
+	if err != nil {
+		return nil, err
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(stdout))
+	for scanner.Scan() {
+		fields := strings.Split(scanner.Text(), "\t")
+		pool, ok := pools[fields[0]]
+		if len(fields) != 2 || !ok {
+			continue
+		}
+		guid, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: pool guid '%s'", ErrInvalidOutput, fields[1])
+		}
+		pool.PoolGuid = Uint64(guid)
+		pools[fields[0]] = pool
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	logger.Debug("Zpool Status Text Output Parsed", "num_pools", len(pools))
+	return &pools, nil
+}
+
+// ZpoolStatus returns the status of all pools, using JSON output where supported, and otherwise falling back to
+// parsing the human-readable output. Where JSON output is supported and concurrency is greater than one, pools are
+// queried separately, and the status of the pools that could be retrieved is returned along with any errors.
+func ZpoolStatus(ctx context.Context, runner Runner, logger *slog.Logger, capabilities Capabilities, concurrency int) (*map[string]PoolStatusT, error) {
+	pools, err := zpoolStatus(ctx, runner, logger, capabilities.Has(CapabilityJSON), concurrency, false)
+	if pools == nil {
+		return nil, err
+	}
+	return &pools, err
+}
+
+// zpoolStatus returns the status of all pools, as ZpoolStatus, optionally including the power state of each vdev,
+// which the human-readable output does not report
+func zpoolStatus(ctx context.Context, runner Runner, logger *slog.Logger, json bool, concurrency int, power bool) (map[string]PoolStatusT, error) {
+	if !json {
+		pools, err := ZpoolStatusViaText(ctx, runner, logger)
+		if err != nil {
+			return nil, err
+		}
+		return *pools, nil
+	}
+	if concurrency > 1 {
+		return zpoolStatusPerPool(ctx, runner, logger, concurrency, power)
+	}
+	return zpoolStatusViaJSON(ctx, runner, logger, power)
+}
@@ -0,0 +1,151 @@
+package zfs
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestZpoolStatusViaText(t *testing.T) {
+	runner := fixtureRunner{
+		`zpool status -p`:           `zpool_status.txt`,
+		`zpool list -Hpo name,guid`: `zpool_list_guid.txt`,
+	}
+	pools, err := ZpoolStatus(context.Background(), runner, testLogger, Capabilities{CapabilityJSON: false}, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tank := (*pools)[`tank`]
+	wantTank := PoolStatusT{
+		Name:     `tank`,
+		State:    `ONLINE`,
+		PoolGuid: 8148409446217839142,
+		ScanStats: ScanStatsT{
+			Function: `SCRUB`,
+			State:    `FINISHED`,
+			EndTime:  Uint64(time.Date(2025, time.July, 13, 0, 34, 33, 0, time.Local).Unix()),
+		},
+		Vdevs: map[string]VdevStatusT{
+			`tank`: {Name: `tank`, VdevType: `root`, State: `ONLINE`, Vdevs: map[string]VdevStatusT{
+				`mirror-0`: {Name: `mirror-0`, VdevType: `mirror`, Class: `normal`, State: `ONLINE`, Parent: `tank`, Vdevs: map[string]VdevStatusT{
+					`sda`: {Name: `sda`, VdevType: `disk`, Class: `normal`, State: `ONLINE`, Parent: `mirror-0`},
+					`sdb`: {Name: `sdb`, VdevType: `disk`, Class: `normal`, State: `ONLINE`, Parent: `mirror-0`},
+				}},
+			}},
+		},
+		Logs: map[string]VdevStatusT{
+			`nvme0n1`: {Name: `nvme0n1`, VdevType: `disk`, Class: `log`, State: `ONLINE`},
+		},
+		L2cache: map[string]VdevStatusT{
+			`nvme1n1`: {Name: `nvme1n1`, VdevType: `disk`, Class: `l2cache`, State: `ONLINE`},
+		},
+	}
+	if !reflect.DeepEqual(tank, wantTank) {
+		t.Errorf("Expected %+v, got %+v", wantTank, tank)
+	}
+
+	backup := (*pools)[`backup`]
+	if backup.State != `DEGRADED` || backup.ErrorCount != 3 || backup.Moreinfo != `https://openzfs.github.io/openzfs-docs/msg/ZFS-8000-4J` {
+		t.Errorf("Unexpected pool status: %+v", backup)
+	}
+	if want := `One or more devices could not be used because the label is missing or invalid.  Sufficient replicas exist for the pool to continue functioning in a degraded state.`; backup.Status != want {
+		t.Errorf("Expected status %q, got %q", want, backup.Status)
+	}
+	if backup.ScanStats.Function != `RESILVER` || backup.ScanStats.State != `SCANNING` || backup.ScanStats.StartTime == 0 {
+		t.Errorf("Unexpected scan stats: %+v", backup.ScanStats)
+	}
+	raidz := backup.Vdevs[`backup`].Vdevs[`raidz2-0`]
+	if raidz.VdevType != `raidz` || len(raidz.Vdevs) != 4 {
+		t.Errorf("Unexpected raidz vdev: %+v", raidz)
+	}
+	if sdg := raidz.Vdevs[`sdg`]; sdg.ChecksumErrors != 12 {
+		t.Errorf("Expected 12 checksum errors for sdg, got %d", sdg.ChecksumErrors)
+	}
+	replacing := raidz.Vdevs[`replacing-2`]
+	if replacing.VdevType != `replacing` || replacing.Vdevs[`2711871123456789`].State != `UNAVAIL` {
+		t.Errorf("Unexpected replacing vdev: %+v", replacing)
+	}
+	if spare := backup.Spares[`sdh`]; spare.State != `AVAIL` || spare.Class != `spare` {
+		t.Errorf("Unexpected spare: %+v", spare)
+	}
+}
+
+func TestClientPoolStatusViaText(t *testing.T) {
+	runner := fixtureRunner{
+		`zpool status -p`:           `zpool_status.txt`,
+		`zpool list -Hpo name,guid`: `zpool_list_guid.txt`,
+	}
+	// Releases prior to OpenZFS 2.3 lack JSON output, for which the client parses the human-readable output
+	client := New(Config{Logger: testLogger, Runner: runner, StatusConcurrency: 4, TextOutput: TextOutputRequired(Capabilities{CapabilityJSON: false})})
+	pools, err := client.PoolStatus(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The progress of a scan is reported abbreviated, as 1.21T scanned at 1.02G/s, 512G issued at 431M/s, 1.72T total
+	scan, tib := pools[`backup`].ScanStats, float64(1<<40)
+	if scan.Examined != Uint64(1.21*tib) || scan.Issued != 512<<30 || scan.ToExamine != Uint64(1.72*tib) {
+		t.Errorf("Unexpected scan progress: %+v", scan)
+	}
+	if pools[`tank`].PoolGuid != 8148409446217839142 {
+		t.Errorf("Unexpected pool status: %+v", pools[`tank`])
+	}
+}
+
+func TestParseScanProgress(t *testing.T) {
+	var s ScanStatsT
+	parseScanProgress(&s, `1099511627776 / 2199023255552 scanned at 1073741824/s, 549755813888 / 2199023255552 issued at 536870912/s`)
+	if s.Examined != 1<<40 || s.ToExamine != 2<<40 || s.Issued != 512<<30 {
+		t.Errorf("Unexpected scan progress: %+v", s)
+	}
+}
+
+func TestClientPoolListViaText(t *testing.T) {
+	runner := fixtureRunner{`zpool list -Hp -o name,guid,health,size,health,checkpoint`: `zpool_list.txt`}
+	client := New(Config{Logger: testLogger, Runner: runner, TextOutput: true})
+	pools, err := client.PoolList(`size`, `health`, `checkpoint`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := PoolListT{Name: `backup`, State: `DEGRADED`, PoolGuid: 1234567890123456789, Properties: map[string]PropertyT{
+		`size`:       {Value: `1992864825344`},
+		`health`:     {Value: `DEGRADED`},
+		`checkpoint`: {Value: `-`},
+	}}
+	if !reflect.DeepEqual(pools[`backup`], want) || len(pools) != 2 {
+		t.Errorf("Expected %+v, got %+v", want, pools)
+	}
+}
+
+func TestZpoolStatusViaTextExitStatus(t *testing.T) {
+	runner := exitRunner{
+		Runner: fixtureRunner{
+			`zpool status -p`:           `zpool_status.txt`,
+			`zpool list -Hpo name,guid`: `zpool_list_guid.txt`,
+		},
+		fail: `zpool status -p`,
+	}
+	pools, err := ZpoolStatusViaText(context.Background(), runner, testLogger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if (*pools)[`backup`].State != `DEGRADED` || (*pools)[`tank`].State != `ONLINE` {
+		t.Errorf("Unexpected pool status: %+v", *pools)
+	}
+
+	// Without parseable output, the failure of the command is reported
+	runner.Runner = fixtureRunner{`zpool status -p`: `zpool_list_names.txt`}
+	if _, err = ZpoolStatusViaText(context.Background(), runner, testLogger); err == nil {
+		t.Error("Expected error without parseable output")
+	}
+}
+
+func TestZpoolStatusViaTextInvalid(t *testing.T) {
+	_, err := new(statusParser).parse([]byte("  pool: tank\nconfig:\n\n\tNAME STATE READ WRITE CKSUM\n\ttank ONLINE 0 x 0\n"))
+	if !errors.Is(err, ErrInvalidOutput) {
+		t.Errorf("Expected %v, got %v", ErrInvalidOutput, err)
+	}
+}
@@ -0,0 +1,100 @@
+package zfs
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+)
+
+//go:embed programs/dataset_properties.lua
+var datasetPropertiesProgram []byte
+
+// ProgramLimits bounds the resources of a channel program, zero values leaving the defaults of `zfs program`
+type ProgramLimits struct {
+	// Instructions is the maximum number of Lua instructions executed, 10 million by default and at most 100 million
+	Instructions uint64
+	// Memory is the maximum memory in bytes, 10 MiB by default and at most 100 MiB
+	Memory uint64
+}
+
+type zfsProgramOutputT struct {
+	Return json.RawMessage `json:"return"`
+}
+
+// ZfsProgram runs the Lua channel program against the pool with `zfs program -n`, so that it cannot modify the pool,
+// and decodes the value returned by the program into v. The program is written to a temporary file, which zfs must be
+// able to read, so it fails where commands are run in another mount namespace or on another host.
+func ZfsProgram(ctx context.Context, runner Runner, logger *slog.Logger, pool string, limits ProgramLimits, program []byte, v any, args ...string) error {
+	f, err := os.CreateTemp(``, `zfs_exporter-*.lua`)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if _, err = f.Write(program); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err = f.Close(); err != nil {
+		return err
+	}
+
+	cmdArgs := []string{`program`, `-j`, `-n`}
+	if limits.Instructions > 0 {
+		cmdArgs = append(cmdArgs, `-t`, strconv.FormatUint(limits.Instructions, 10))
+	}
+	if limits.Memory > 0 {
+		cmdArgs = append(cmdArgs, `-m`, strconv.FormatUint(limits.Memory, 10))
+	}
+	cmdArgs = append(append(cmdArgs, pool, f.Name()), args...)
+
+	var o zfsProgramOutputT
+	if err = executeJSON(ctx, runner, logger, &o, `zfs`, cmdArgs...); err != nil {
+		return err
+	}
+	if len(o.Return) == 0 {
+		return fmt.Errorf("%w: channel program returned no value", ErrInvalidOutput)
+	}
+	return json.Unmarshal(o.Return, v)
+}
+
+// datasetProgramOutputT is the value returned by the dataset properties program
+type datasetProgramOutputT map[string]struct {
+	Type       string                   `json:"type"`
+	Properties map[string]PropertyValue `json:"properties"`
+}
+
+// datasets returns the datasets of the pool as listed by `zfs list`
+func (o datasetProgramOutputT) datasets(pool string) map[string]DatasetListT {
+	datasets := make(map[string]DatasetListT, len(o))
+	for name, dataset := range o {
+		properties := make(map[string]PropertyT, len(dataset.Properties))
+		for k, v := range dataset.Properties {
+			properties[k] = PropertyT{Value: v}
+		}
+		datasets[name] = DatasetListT{Name: name, Type: strings.ToUpper(dataset.Type), Pool: pool, Properties: properties}
+	}
+	return datasets
+}
+
+// datasetProgramArgs returns the arguments of the dataset properties program
+func datasetProgramArgs(pool string, props []string) []string {
+	return append([]string{pool}, props...)
+}
+
+// ZfsProgramDatasets returns the requested properties of all filesystems and volumes within the pool, read by a
+// channel program in a single pass rather than listing them with `zfs list`, which is slow for pools with very many
+// datasets. User properties are not supported.
+func ZfsProgramDatasets(ctx context.Context, runner Runner, logger *slog.Logger, pool string, limits ProgramLimits, props ...string) (map[string]DatasetListT, error) {
+	var o datasetProgramOutputT
+	if err := ZfsProgram(ctx, runner, logger, pool, limits, datasetPropertiesProgram, &o, datasetProgramArgs(pool, props)...); err != nil {
+		return nil, err
+	}
+	datasets := o.datasets(pool)
+	logger.Debug("ZFS Program Output Parsed", "num_datasets", len(datasets))
+	return datasets, nil
+}
@@ -0,0 +1,81 @@
+package zfs
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// programRunner replays the fixture for `zfs program`, checking that the file given in place of <program> holds the
+// program
+type programRunner struct {
+	program []byte
+	args    []string
+	fixture string
+}
+
+func (r programRunner) Run(ctx context.Context, name string, args ...string) ([]byte, []byte, error) {
+	cmd := commandString(name, args...)
+	if len(args) != len(r.args) || name != `zfs` {
+		return nil, nil, fmt.Errorf("unexpected command '%s'", cmd)
+	}
+	for i, arg := range r.args {
+		if arg == `<program>` {
+			program, err := os.ReadFile(args[i])
+			if err != nil {
+				return nil, nil, err
+			}
+			if !bytes.Equal(program, r.program) {
+				return nil, nil, fmt.Errorf("unexpected program %s", args[i])
+			}
+		} else if args[i] != arg {
+			return nil, nil, fmt.Errorf("unexpected command '%s'", cmd)
+		}
+	}
+	stdout, err := os.ReadFile(filepath.Join(`testdata`, r.fixture))
+	return stdout, nil, err
+}
+
+func TestDatasetProgram(t *testing.T) {
+	runner := programRunner{
+		program: datasetPropertiesProgram,
+		args:    []string{`program`, `-j`, `-n`, `-t`, `50000000`, `tank`, `<program>`, `tank`, `available`, `quota`, `used`},
+		fixture: `zfs_program_datasets.json`,
+	}
+	client := New(Config{Logger: testLogger, Runner: runner})
+	datasets, err := client.(ProgramClient).DatasetProgram(`tank`, ProgramLimits{Instructions: 50000000}, `available`, `quota`, `used`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]DatasetListT{
+		`tank/home`: {Name: `tank/home`, Type: `FILESYSTEM`, Pool: `tank`, Properties: map[string]PropertyT{
+			`available`: {Value: `2012304687104`},
+			`quota`:     {Value: `1099511627776`},
+			`used`:      {Value: `865228390400`},
+		}},
+		`tank/vm`: {Name: `tank/vm`, Type: `VOLUME`, Pool: `tank`, Properties: map[string]PropertyT{
+			`available`: {Value: `2066018918400`},
+			`used`:      {Value: `53687091200`},
+		}},
+	}
+	if len(datasets) != 3 {
+		t.Errorf("Expected 3 datasets, got %d", len(datasets))
+	}
+	for name, dataset := range want {
+		if !reflect.DeepEqual(datasets[name], dataset) {
+			t.Errorf("Expected %+v, got %+v", dataset, datasets[name])
+		}
+		if datasets[name].Kind() != DatasetKind(strings.ToLower(dataset.Type)) {
+			t.Errorf("Expected kind %s, got %s", strings.ToLower(dataset.Type), datasets[name].Kind())
+		}
+	}
+	if matches, _ := filepath.Glob(filepath.Join(os.TempDir(), `zfs_exporter-*.lua`)); len(matches) != 0 {
+		t.Errorf("Expected the program file to be removed, found %v", matches)
+	}
+}
@@ -0,0 +1,21 @@
+-- Returns the type and the properties given as arguments of the dataset given as the first argument and of all
+-- filesystems and volumes below it, keyed by name. Properties that do not apply to a dataset are omitted.
+local argv = (...)["argv"]
+local datasets = {}
+
+local function collect(name)
+	local props = {}
+	for i = 2, #argv do
+		local value = zfs.get_prop(name, argv[i])
+		if value ~= nil then
+			props[argv[i]] = value
+		end
+	end
+	datasets[name] = {type = zfs.get_prop(name, "type"), properties = props}
+	for child in zfs.list.children(name) do
+		collect(child)
+	end
+end
+
+collect(argv[1])
+return datasets
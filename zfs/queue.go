@@ -0,0 +1,43 @@
+package zfs
+
+import (
+	"sync"
+	"time"
+)
+
+// QueueWaitT summarises the time that requests for an operation spent waiting behind other executions, as opposed to
+// running their own commands
+type QueueWaitT struct {
+	// Count is the number of requests that required the operation to be executed, rather than served from the cache
+	Count uint64
+	// Total is the time those requests spent waiting
+	Total time.Duration
+}
+
+var queueWaits = struct {
+	sync.Mutex
+	waits map[string]QueueWaitT
+}{
+	waits: make(map[string]QueueWaitT),
+}
+
+// recordQueueWait records the time a request for the operation spent waiting, which is zero where it ran immediately
+func recordQueueWait(operation string, wait time.Duration) {
+	queueWaits.Lock()
+	defer queueWaits.Unlock()
+	w := queueWaits.waits[operation]
+	w.Count++
+	w.Total += wait
+	queueWaits.waits[operation] = w
+}
+
+// QueueWaits returns a snapshot of the time spent waiting behind other executions, by operation
+func QueueWaits() map[string]QueueWaitT {
+	queueWaits.Lock()
+	defer queueWaits.Unlock()
+	result := make(map[string]QueueWaitT, len(queueWaits.waits))
+	for operation, w := range queueWaits.waits {
+		result[operation] = w
+	}
+	return result
+}
@@ -0,0 +1,85 @@
+package zfs
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var removalMappingRe = regexp.MustCompile(`(\S+) memory used for removed device mappings`)
+
+// RemovalStatsT summarises the lasting cost of removing top-level vdevs from a pool with `zpool remove`
+type RemovalStatsT struct {
+	// IndirectVdevs replace removed data vdevs, remapping blocks that were copied elsewhere
+	IndirectVdevs int
+	// IndirectBytes is the allocatable size of the removed data vdevs
+	IndirectBytes uint64
+	// HoleVdevs replace removed log vdevs, and hold no data
+	HoleVdevs int
+	// MappingMemoryBytes is the memory used for the mappings of the indirect vdevs
+	MappingMemoryBytes uint64
+}
+
+// ZpoolRemovalStats returns the indirect and hole vdevs of the pool, as reported by `zdb -C`, and the memory used for
+// their mappings, as reported by `zpool status`
+func ZpoolRemovalStats(ctx context.Context, runner Runner, pool string) (RemovalStatsT, error) {
+	stdout, _, err := runner.Run(ctx, `zdb`, `-C`, pool)
+	if err != nil {
+		return RemovalStatsT{}, err
+	}
+	s, err := parseRemovedVdevs(stdout)
+	if err != nil {
+		return s, err
+	}
+
+	stdout, _, err = runner.Run(ctx, `zpool`, `status`, `-p`, pool)
+	if err = statusErr(ctx, stdout, err); err != nil {
+		return s, err
+	}
+	if m := removalMappingRe.FindSubmatch(stdout); m != nil {
+		if s.MappingMemoryBytes, err = parseNiceBytes(string(m[1])); err != nil {
+			return s, fmt.Errorf("%w: removed device mapping memory '%s'", ErrInvalidOutput, m[1])
+		}
+	}
+
+	return s, nil
+}
+
+// parseRemovedVdevs counts the indirect and hole vdevs in the pool configuration printed by `zdb -C`. Neither has
+// children, so each is a top-level vdev, whose asize follows its type.
+func parseRemovedVdevs(out []byte) (RemovalStatsT, error) {
+	var s RemovalStatsT
+	vdevType := ``
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(strings.TrimSpace(scanner.Text()), `: `)
+		if !ok {
+			continue
+		}
+		switch key {
+		case `type`:
+			vdevType = strings.Trim(value, `'`)
+			switch vdevType {
+			case `indirect`:
+				s.IndirectVdevs++
+			case `hole`:
+				s.HoleVdevs++
+			}
+		case `asize`:
+			if vdevType != `indirect` {
+				continue
+			}
+			asize, err := strconv.ParseUint(value, 10, 64)
+			if err != nil {
+				return s, fmt.Errorf("%w: indirect vdev asize '%s'", ErrInvalidOutput, value)
+			}
+			s.IndirectBytes += asize
+		}
+	}
+
+	return s, scanner.Err()
+}
@@ -0,0 +1,39 @@
+package zfs
+
+import (
+	"testing"
+)
+
+func TestRemovalStats(t *testing.T) {
+	client := newFixtureClient(fixtureRunner{
+		`zdb -C tank`:          `zdb_c.txt`,
+		`zpool status -p tank`: `zpool_status_removal.txt`,
+	})
+	stats, err := client.RemovalStats(`tank`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := RemovalStatsT{
+		IndirectVdevs:      2,
+		IndirectBytes:      1999307276288 + 499826819072,
+		HoleVdevs:          1,
+		MappingMemoryBytes: 19293798,
+	}
+	if stats != want {
+		t.Errorf("Expected %+v, got %+v", want, stats)
+	}
+}
+
+func TestParseRemovedVdevs(t *testing.T) {
+	got, err := parseRemovedVdevs([]byte("        vdev_tree:\n            type: 'root'\n            children[0]:\n                type: 'disk'\n                asize: 1000\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != (RemovalStatsT{}) {
+		t.Errorf("Expected no removed vdevs, got %+v", got)
+	}
+
+	if _, err = parseRemovedVdevs([]byte("                type: 'indirect'\n                asize: -1\n")); err == nil {
+		t.Error("Expected error for invalid asize")
+	}
+}
@@ -0,0 +1,295 @@
+package zfs
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// commandWaitDelay bounds how long to wait for output to drain after a timed out command has been killed
+const commandWaitDelay = time.Second
+
+// Runner executes commands on behalf of the client, allowing alternate backends to be substituted
+type Runner interface {
+	Run(ctx context.Context, name string, args ...string) (stdout, stderr []byte, err error)
+}
+
+// ExecStatsT summarises the subprocesses executed by ExecRunner
+type ExecStatsT struct {
+	// Started is the number of subprocesses started
+	Started uint64
+	// Failed is the number of subprocesses that could not be started, or exited unsuccessfully
+	Failed uint64
+	// Running is the number of subprocesses currently running
+	Running int64
+	// CPUTime is the total user and system CPU time consumed by exited subprocesses
+	CPUTime time.Duration
+	// MaxRSS is the largest peak resident set size in bytes of any exited subprocess, where supported by the platform
+	MaxRSS int64
+}
+
+var execStats struct {
+	ExecStatsT
+	sync.Mutex
+}
+
+// ExecStats returns a snapshot of the statistics for subprocesses executed by ExecRunner
+func ExecStats() ExecStatsT {
+	execStats.Lock()
+	defer execStats.Unlock()
+	return execStats.ExecStatsT
+}
+
+// CommandStatsT summarises the executions of a command, e.g. `zpool status`
+type CommandStatsT struct {
+	// Count is the number of executions
+	Count uint64
+	// Errors is the number of executions that failed, including those that timed out
+	Errors uint64
+	// Duration is the total time spent executing the command
+	Duration time.Duration
+}
+
+var commandStats = struct {
+	sync.Mutex
+	commands        map[string]CommandStatsT
+	jsonParseErrors uint64
+}{
+	commands: make(map[string]CommandStatsT),
+}
+
+// CommandStats returns a snapshot of the statistics for commands executed via CommandRunner, keyed by the command and
+// its subcommand
+func CommandStats() map[string]CommandStatsT {
+	commandStats.Lock()
+	defer commandStats.Unlock()
+	result := make(map[string]CommandStatsT, len(commandStats.commands))
+	for command, stats := range commandStats.commands {
+		result[command] = stats
+	}
+	return result
+}
+
+// JSONParseErrors returns the number of command outputs that could not be parsed as the expected JSON
+func JSONParseErrors() uint64 {
+	commandStats.Lock()
+	defer commandStats.Unlock()
+	return commandStats.jsonParseErrors
+}
+
+func recordCommand(command string, duration time.Duration, err error) {
+	commandStats.Lock()
+	defer commandStats.Unlock()
+	stats := commandStats.commands[command]
+	stats.Count++
+	stats.Duration += duration
+	if err != nil {
+		stats.Errors++
+	}
+	commandStats.commands[command] = stats
+}
+
+func recordJSONParseError() {
+	commandStats.Lock()
+	defer commandStats.Unlock()
+	commandStats.jsonParseErrors++
+}
+
+// subcommand identifies a command by its name and the first argument that is not a flag, e.g. `zpool status`, so
+// that statistics are not split by pool or property names
+func subcommand(name string, args ...string) string {
+	for _, arg := range args {
+		if !strings.HasPrefix(arg, `-`) {
+			return name + ` ` + arg
+		}
+	}
+	return name
+}
+
+// streamBufferSize is the size of the buffer in which the output of streamed commands is read
+const streamBufferSize = 64 * 1024
+
+// StreamRunner is implemented by runners that pass the output of a command to a reader as it is written, so that
+// large output, such as the status of pools with thousands of vdevs, need not be held in memory in full before it is
+// parsed
+type StreamRunner interface {
+	Runner
+	// Stream runs the command, passing its standard output to read, and returns its standard error. Output left unread
+	// when read returns is discarded. The error of the command is returned in preference to that of read, so callers
+	// that parse the output of failed commands should record the error of read themselves.
+	Stream(ctx context.Context, read func(stdout io.Reader) error, name string, args ...string) (stderr []byte, err error)
+}
+
+// RunStream runs the command via runner, streaming its output to read where runner is a StreamRunner, and otherwise
+// reading the output once the command has exited
+func RunStream(ctx context.Context, runner Runner, read func(stdout io.Reader) error, name string, args ...string) ([]byte, error) {
+	if s, ok := runner.(StreamRunner); ok {
+		return s.Stream(ctx, read, name, args...)
+	}
+	stdout, stderr, err := runner.Run(ctx, name, args...)
+	if readErr := read(bytes.NewReader(stdout)); err == nil {
+		err = readErr
+	}
+	return stderr, err
+}
+
+// ExecRunner runs commands as local processes
+type ExecRunner struct{}
+
+// Run implements the Runner interface. The command, along with any children it spawned, is killed when ctx is done.
+// Commands run in the C locale, as the human-readable output is parsed and localized output would not be recognised.
+func (ExecRunner) Run(ctx context.Context, name string, args ...string) ([]byte, []byte, error) {
+	var stdout, stderr bytes.Buffer
+	c := execCommand(ctx, name, args...)
+	c.Stdout = &stdout
+	c.Stderr = &stderr
+
+	if err := wait(c, nil); err != nil {
+		return stdout.Bytes(), stderr.Bytes(), execError(ctx, c, stderr.Bytes(), err)
+	}
+
+	return stdout.Bytes(), stderr.Bytes(), nil
+}
+
+// Stream implements the StreamRunner interface, reading the output from a pipe, while standard error is collected
+// concurrently so that a command writing heavily to both does not block
+func (ExecRunner) Stream(ctx context.Context, read func(stdout io.Reader) error, name string, args ...string) ([]byte, error) {
+	var stderr bytes.Buffer
+	c := execCommand(ctx, name, args...)
+	c.Stderr = &stderr
+	pipe, err := c.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+
+	var readErr error
+	if err = wait(c, func() {
+		readErr = read(bufio.NewReaderSize(pipe, streamBufferSize))
+		// Wait closes the pipe, so the output must be drained for the command to exit should read return early
+		_, _ = io.Copy(io.Discard, pipe)
+	}); err != nil {
+		return stderr.Bytes(), execError(ctx, c, stderr.Bytes(), err)
+	}
+
+	return stderr.Bytes(), readErr
+}
+
+func execCommand(ctx context.Context, name string, args ...string) *exec.Cmd {
+	c := exec.CommandContext(ctx, name, args...)
+	c.Env = append(os.Environ(), `LC_ALL=C`)
+	killProcessGroup(c)
+	c.WaitDelay = commandWaitDelay
+	return c
+}
+
+// execError describes the failure of the command, reporting expiry of ctx in preference to the resulting exit status
+func execError(ctx context.Context, c *exec.Cmd, stderr []byte, err error) error {
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		err = ctxErr
+	}
+	return fmt.Errorf("failed to execute command '%s'; output: '%s' (%w)", c.String(), strings.TrimSpace(string(stderr)), err)
+}
+
+// wait starts the command and waits for it to exit, recording its resource usage. If set, running is called once the
+// command has started, before waiting for it.
+func wait(c *exec.Cmd, running func()) error {
+	execStats.Lock()
+	execStats.Started++
+	execStats.Unlock()
+
+	err := c.Start()
+	if err == nil {
+		execStats.Lock()
+		execStats.Running++
+		execStats.Unlock()
+		if running != nil {
+			running()
+		}
+		err = c.Wait()
+	}
+
+	execStats.Lock()
+	defer execStats.Unlock()
+	if c.Process != nil {
+		execStats.Running--
+	}
+	if err != nil {
+		execStats.Failed++
+	}
+	if state := c.ProcessState; state != nil {
+		execStats.CPUTime += state.UserTime() + state.SystemTime()
+		execStats.MaxRSS = max(execStats.MaxRSS, maxRSS(state))
+	}
+
+	return err
+}
+
+// commandString formats a command for use in error messages
+func commandString(name string, args ...string) string {
+	return strings.Join(append([]string{name}, args...), ` `)
+}
+
+// CommandRunner resolves the zpool/zfs binaries to configured paths, optionally running them via a privilege
+// escalation wrapper such as sudo or doas, before delegating to Runner
+type CommandRunner struct {
+	Runner Runner
+	// Paths maps command names to the path of the binary to execute, unmapped commands are resolved via PATH
+	Paths map[string]string
+	// Sudo is the wrapper used to execute commands, which is invoked non-interactively (-n) so that a missing rule
+	// fails immediately rather than prompting for a password. Empty disables the wrapper.
+	Sudo string
+}
+
+// Run implements the Runner interface, recording the duration and outcome of the command
+func (r CommandRunner) Run(ctx context.Context, name string, args ...string) ([]byte, []byte, error) {
+	command := subcommand(name, args...)
+	begin := time.Now()
+	stdout, stderr, err := r.run(ctx, name, args...)
+	recordCommand(command, time.Since(begin), err)
+
+	return stdout, stderr, err
+}
+
+// Stream implements the StreamRunner interface, recording the duration and outcome of the command
+func (r CommandRunner) Stream(ctx context.Context, read func(stdout io.Reader) error, name string, args ...string) ([]byte, error) {
+	command := subcommand(name, args...)
+	begin := time.Now()
+	name, args = r.command(name, args...)
+	var readErr error
+	stderr, err := RunStream(ctx, r.Runner, func(stdout io.Reader) error {
+		readErr = read(stdout)
+		return readErr
+	}, name, args...)
+	// Output that could not be read is not a failure of the command
+	if err == readErr {
+		recordCommand(command, time.Since(begin), nil)
+	} else {
+		recordCommand(command, time.Since(begin), err)
+	}
+
+	return stderr, err
+}
+
+func (r CommandRunner) run(ctx context.Context, name string, args ...string) ([]byte, []byte, error) {
+	name, args = r.command(name, args...)
+	return r.Runner.Run(ctx, name, args...)
+}
+
+// command returns the command line to execute, resolving the path of the binary and applying the wrapper
+func (r CommandRunner) command(name string, args ...string) (string, []string) {
+	if path, ok := r.Paths[name]; ok && path != `` {
+		name = path
+	}
+	if r.Sudo != `` {
+		args = append([]string{`-n`, name}, args...)
+		name = r.Sudo
+	}
+	return name, args
+}
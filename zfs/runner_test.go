@@ -0,0 +1,249 @@
+package zfs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fixtureRunner replays recorded command output from testdata, keyed by the full command line
+type fixtureRunner map[string]string
+
+func (f fixtureRunner) Run(ctx context.Context, name string, args ...string) ([]byte, []byte, error) {
+	cmd := commandString(name, args...)
+	fixture, ok := f[cmd]
+	if !ok {
+		return nil, nil, fmt.Errorf("unexpected command '%s'", cmd)
+	}
+	stdout, err := os.ReadFile(filepath.Join(`testdata`, fixture))
+	return stdout, nil, err
+}
+
+// exitRunner runs commands via its runner, failing the named command after it has written its output, as zpool does
+// where pools are unhealthy
+type exitRunner struct {
+	Runner
+	fail string
+}
+
+func (r exitRunner) Run(ctx context.Context, name string, args ...string) ([]byte, []byte, error) {
+	stdout, stderr, err := r.Runner.Run(ctx, name, args...)
+	if err == nil && commandString(name, args...) == r.fail {
+		err = fmt.Errorf("failed to execute command '%s' (exit status 1)", r.fail)
+	}
+	return stdout, stderr, err
+}
+
+func TestExecRunnerTimeout(t *testing.T) {
+	ctx, cancel := CommandContext(100 * time.Millisecond)
+	defer cancel()
+
+	// The backgrounded sleep inherits stdout, so output only drains once the whole process group has been killed.
+	begin := time.Now()
+	_, _, err := ExecRunner{}.Run(ctx, `sh`, `-c`, `sleep 10 & sleep 10`)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected %v, got %v", context.DeadlineExceeded, err)
+	}
+	if elapsed := time.Since(begin); elapsed > commandWaitDelay {
+		t.Errorf("Expected command to be killed within %s, took %s", commandWaitDelay, elapsed)
+	}
+}
+
+func TestExecRunnerOutput(t *testing.T) {
+	before := ExecStats()
+	stdout, stderr, err := ExecRunner{}.Run(context.Background(), `sh`, `-c`, `echo out; echo err >&2`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(stdout) != "out\n" || string(stderr) != "err\n" {
+		t.Errorf("Expected stdout %q and stderr %q, got %q and %q", "out\n", "err\n", stdout, stderr)
+	}
+
+	_, _, err = ExecRunner{}.Run(context.Background(), `sh`, `-c`, `echo failed >&2; exit 1`)
+	if err == nil {
+		t.Fatal(`Expected error, got nil`)
+	}
+	if want := `output: 'failed' (exit status 1)`; !strings.HasSuffix(err.Error(), want) {
+		t.Errorf("Expected error ending with %q, got %q", want, err.Error())
+	}
+
+	after := ExecStats()
+	if started := after.Started - before.Started; started != 2 {
+		t.Errorf("Expected 2 subprocesses started, got %d", started)
+	}
+	if failed := after.Failed - before.Failed; failed != 1 {
+		t.Errorf("Expected 1 subprocess failed, got %d", failed)
+	}
+	if after.Running != 0 {
+		t.Errorf("Expected no running subprocesses, got %d", after.Running)
+	}
+	if after.MaxRSS <= 0 {
+		t.Errorf("Expected max RSS to be recorded, got %d", after.MaxRSS)
+	}
+}
+
+func TestExecRunnerStream(t *testing.T) {
+	// Heavy output to stderr must not block the command while stdout is read
+	var got struct{ Pools map[string]string }
+	stderr, err := ExecRunner{}.Stream(context.Background(), func(stdout io.Reader) error {
+		return json.NewDecoder(stdout).Decode(&got)
+	}, `sh`, `-c`, `head -c 1048576 /dev/zero >&2; echo '{"pools": {"tank": "ONLINE"}}'`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stderr) != 1048576 || got.Pools[`tank`] != `ONLINE` {
+		t.Errorf("Unexpected stderr of %d bytes and output %+v", len(stderr), got)
+	}
+
+	// Output left unread is drained, so that the command exits
+	_, err = ExecRunner{}.Stream(context.Background(), func(stdout io.Reader) error {
+		return nil
+	}, `sh`, `-c`, `head -c 1048576 /dev/zero`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The error of the command is reported in preference to that of read
+	_, err = ExecRunner{}.Stream(context.Background(), func(stdout io.Reader) error {
+		return errors.New(`unread`)
+	}, `sh`, `-c`, `echo failed >&2; exit 1`)
+	if want := `output: 'failed' (exit status 1)`; err == nil || !strings.HasSuffix(err.Error(), want) {
+		t.Errorf("Expected error ending with %q, got %v", want, err)
+	}
+}
+
+func TestRunStream(t *testing.T) {
+	// Runners that do not stream are read once the command has exited
+	var got ZFSVersionOutputT
+	_, err := RunStream(context.Background(), fixtureRunner{`zfs version --json`: `zfs_version.json`}, func(stdout io.Reader) error {
+		return json.NewDecoder(stdout).Decode(&got)
+	}, `zfs`, `version`, `--json`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.OutputVersion.Command == `` {
+		t.Errorf("Expected decoded output, got %+v", got)
+	}
+}
+
+func TestExecuteJSONStreamExitStatus(t *testing.T) {
+	var o ZpoolStatusOutputT
+	err := executeJSON(context.Background(), ExecRunner{}, testLogger, &o, `sh`, `-c`, `cat testdata/zpool_status_degraded.json; exit 1`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if o.Pools[`tank`].State != `DEGRADED` {
+		t.Errorf("Unexpected pool status: %+v", o.Pools)
+	}
+
+	if err = executeJSON(context.Background(), ExecRunner{}, testLogger, &o, `sh`, `-c`, `exit 1`); err == nil || !strings.Contains(err.Error(), `exit status 1`) {
+		t.Errorf("Expected exit status error, got %v", err)
+	}
+	before := JSONParseErrors()
+	if err = executeJSON(context.Background(), ExecRunner{}, testLogger, &o, `echo`, `not json`); err == nil {
+		t.Error("Expected parse error")
+	}
+	if JSONParseErrors() != before+1 {
+		t.Errorf("Expected parse error to be recorded")
+	}
+}
+
+func TestExecuteJSONStartFailure(t *testing.T) {
+	// A command that cannot be started produces no output, which must not be mistaken for an empty result
+	for _, runner := range []Runner{ExecRunner{}, CommandRunner{Runner: ExecRunner{}}} {
+		o := make(map[string]any)
+		err := executeJSON(context.Background(), runner, testLogger, &o, `/nonexistent/zpool`, `list`, `--json`)
+		if err == nil {
+			t.Errorf("%T: Expected error, got nil and %v", runner, o)
+		}
+	}
+}
+
+func TestExecRunnerLocale(t *testing.T) {
+	t.Setenv(`LC_ALL`, `de_DE.UTF-8`)
+	t.Setenv(`LANG`, `de_DE.UTF-8`)
+
+	stdout, _, err := ExecRunner{}.Run(context.Background(), `sh`, `-c`, `echo $LC_ALL`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.TrimSpace(string(stdout)); got != `C` {
+		t.Errorf("Expected LC_ALL=C, got %q", got)
+	}
+}
+
+func TestCommandRunner(t *testing.T) {
+	testCases := []struct {
+		name   string
+		runner CommandRunner
+		want   string
+	}{
+		{
+			name:   `default`,
+			runner: CommandRunner{},
+			want:   `zpool status --json`,
+		},
+		{
+			name:   `path`,
+			runner: CommandRunner{Paths: map[string]string{`zpool`: `/usr/sbin/zpool`, `zfs`: `/usr/sbin/zfs`}},
+			want:   `/usr/sbin/zpool status --json`,
+		},
+		{
+			name:   `sudo`,
+			runner: CommandRunner{Paths: map[string]string{`zpool`: `/usr/sbin/zpool`}, Sudo: `sudo`},
+			want:   `sudo -n /usr/sbin/zpool status --json`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.runner.Runner = fixtureRunner{tc.want: `zpool_status.json`}
+			if _, _, err := tc.runner.Run(context.Background(), `zpool`, `status`, `--json`); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestCommandStats(t *testing.T) {
+	runner := CommandRunner{Runner: fixtureRunner{
+		`zpool status -p --json`: `zpool_status.json`,
+		`zfs version --json`:     `zpool_list_names.txt`,
+	}}
+	before, beforeJSON := CommandStats(), JSONParseErrors()
+
+	var v any
+	logger := slog.New(slog.DiscardHandler)
+	if err := executeJSON(context.Background(), runner, logger, &v, `zpool`, `status`, `-p`, `--json`); err != nil {
+		t.Fatal(err)
+	}
+	if err := executeJSON(context.Background(), runner, logger, &v, `zfs`, `version`, `--json`); err == nil {
+		t.Fatal(`Expected error parsing output that is not JSON`)
+	}
+	if _, _, err := runner.Run(context.Background(), `zpool`, `-H`, `status`, `tank`); err == nil {
+		t.Fatal(`Expected error running unexpected command`)
+	}
+
+	after := CommandStats()
+	status := after[`zpool status`]
+	if got := status.Count - before[`zpool status`].Count; got != 2 {
+		t.Errorf("Expected 2 executions of zpool status, got %d", got)
+	}
+	if got := status.Errors - before[`zpool status`].Errors; got != 1 {
+		t.Errorf("Expected 1 error from zpool status, got %d", got)
+	}
+	if got := after[`zfs version`].Count - before[`zfs version`].Count; got != 1 {
+		t.Errorf("Expected 1 execution of zfs version, got %d", got)
+	}
+	if got := JSONParseErrors() - beforeJSON; got != 1 {
+		t.Errorf("Expected 1 JSON parse error, got %d", got)
+	}
+}
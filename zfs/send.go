@@ -0,0 +1,88 @@
+package zfs
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalidSnapshot is returned for snapshot and bookmark names that may not be passed to zfs send
+var ErrInvalidSnapshot = errors.New(`invalid snapshot`)
+
+// ZfsSendEstimate returns the estimated size in bytes of the stream that would be sent for the snapshot, as reported by
+// a dry run of `zfs send`. If from is not empty, the stream is incremental from that snapshot or bookmark.
+func ZfsSendEstimate(ctx context.Context, runner Runner, snapshot, from string) (uint64, error) {
+	if err := validateSendName(snapshot, `@`); err != nil {
+		return 0, err
+	}
+	args := []string{`send`, `-n`, `-v`, `-P`}
+	if from != `` {
+		if err := validateSendName(from, `@#`); err != nil {
+			return 0, err
+		}
+		args = append(args, `-i`, from)
+	}
+	args = append(args, snapshot)
+
+	stdout, stderr, err := runner.Run(ctx, `zfs`, args...)
+	if err != nil {
+		return 0, err
+	}
+	// Releases prior to OpenZFS 0.8 print the estimate of a dry run to stderr
+	if size, ok, err := parseSendEstimate(stdout); ok || err != nil {
+		return size, err
+	}
+	if size, ok, err := parseSendEstimate(stderr); ok || err != nil {
+		return size, err
+	}
+
+	return 0, fmt.Errorf("%w: no size in zfs send estimate for '%s'", ErrInvalidOutput, snapshot)
+}
+
+// parseSendEstimate parses the total size line of the parsable output of `zfs send -nvP`
+func parseSendEstimate(out []byte) (uint64, bool, error) {
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 || fields[0] != `size` {
+			continue
+		}
+		size, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			return 0, true, fmt.Errorf("%w: zfs send size '%s'", ErrInvalidOutput, fields[1])
+		}
+		return size, true, nil
+	}
+
+	return 0, false, scanner.Err()
+}
+
+// ZfsLatestSnapshot returns the name of the most recently created snapshot of the dataset
+func ZfsLatestSnapshot(ctx context.Context, runner Runner, dataset string) (string, error) {
+	if dataset == `` || strings.HasPrefix(dataset, `-`) || strings.ContainsAny(dataset, `@#`) {
+		return ``, fmt.Errorf("%w: dataset '%s'", ErrInvalidSnapshot, dataset)
+	}
+	stdout, _, err := runner.Run(ctx, `zfs`, `list`, `-H`, `-p`, `-t`, `snapshot`, `-o`, `name`, `-s`, `createtxg`, `-d`, `1`, dataset)
+	if err != nil {
+		return ``, err
+	}
+	lines := strings.Fields(string(stdout))
+	if len(lines) == 0 {
+		return ``, fmt.Errorf("%w: dataset '%s' has no snapshots", ErrInvalidSnapshot, dataset)
+	}
+
+	return lines[len(lines)-1], nil
+}
+
+// validateSendName rejects names that could be mistaken for options, or that lack one of the separators that mark a
+// snapshot or bookmark. Names of the form @snap or #bookmark are relative to the dataset being sent.
+func validateSendName(name, separators string) error {
+	if name == `` || strings.HasPrefix(name, `-`) || !strings.ContainsAny(name, separators) {
+		return fmt.Errorf("%w: '%s'", ErrInvalidSnapshot, name)
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package zfs
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestSendEstimate(t *testing.T) {
+	client := newFixtureClient(fixtureRunner{
+		`zfs send -n -v -P -i tank/data#base tank/data@daily-2025-01-02`: `zfs_send_estimate.txt`,
+		`zfs list -H -p -t snapshot -o name -s createtxg -d 1 tank/data`: `zfs_list_snapshots.txt`,
+	})
+
+	snapshot, err := client.LatestSnapshot(`tank/data`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if snapshot != `tank/data@daily-2025-01-02` {
+		t.Errorf("Expected latest snapshot tank/data@daily-2025-01-02, got %s", snapshot)
+	}
+	size, err := client.SendEstimate(snapshot, `tank/data#base`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if size != 1048576 {
+		t.Errorf("Expected estimate of 1048576 bytes, got %d", size)
+	}
+
+	for _, tc := range [][2]string{{`tank/data`, ``}, {`-R`, ``}, {`tank/data@1`, `-i`}, {`tank/data@1`, `tank/data`}} {
+		if _, err = client.SendEstimate(tc[0], tc[1]); !errors.Is(err, ErrInvalidSnapshot) {
+			t.Errorf("Expected %v for snapshot %q from %q, got %v", ErrInvalidSnapshot, tc[0], tc[1], err)
+		}
+	}
+}
+
+// stderrRunner returns fixed output on stderr for every command
+type stderrRunner string
+
+func (r stderrRunner) Run(ctx context.Context, name string, args ...string) ([]byte, []byte, error) {
+	return nil, []byte(r), nil
+}
+
+func TestParseSendEstimate(t *testing.T) {
+	// Releases prior to OpenZFS 0.8 print the estimate to stderr
+	runner := stderrRunner("full\ttank/data@1\t4096\nsize\t4096\n")
+	size, err := ZfsSendEstimate(context.Background(), runner, `tank/data@1`, ``)
+	if err != nil || size != 4096 {
+		t.Errorf("Expected estimate of 4096 bytes, got %d, %v", size, err)
+	}
+
+	if _, _, err = parseSendEstimate([]byte("size\tlots\n")); !errors.Is(err, ErrInvalidOutput) {
+		t.Errorf("Expected %v, got %v", ErrInvalidOutput, err)
+	}
+}
@@ -0,0 +1,90 @@
+package zfs
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalidTarget is returned for SSH targets that could be mistaken for options of the ssh client
+var ErrInvalidTarget = errors.New("invalid target")
+
+// SSHRunner runs commands on a remote host via the ssh client, for hosts on which the exporter cannot be installed.
+// Commands are run in batch mode, so authentication must not prompt, e.g. using a key without a passphrase.
+type SSHRunner struct {
+	Runner Runner
+	// Path of the ssh client, resolved via PATH if empty
+	Path string
+	// Target is the destination of ssh, as [USER@]HOST
+	Target string
+	// Port of the ssh server, the default of the ssh client if zero
+	Port int
+	// IdentityFile is the private key used to authenticate, the defaults of the ssh client if empty
+	IdentityFile string
+	// Options are passed to ssh with -o, e.g. IdentityFile=/etc/zfs_exporter/id_ed25519
+	Options []string
+}
+
+// Run implements the Runner interface, running the command on the target via its login shell
+func (r SSHRunner) Run(ctx context.Context, name string, args ...string) ([]byte, []byte, error) {
+	path, sshArgs, err := r.command(name, args...)
+	if err != nil {
+		return nil, nil, err
+	}
+	return r.Runner.Run(ctx, path, sshArgs...)
+}
+
+// Stream implements the StreamRunner interface, running the command on the target via its login shell
+func (r SSHRunner) Stream(ctx context.Context, read func(stdout io.Reader) error, name string, args ...string) ([]byte, error) {
+	path, sshArgs, err := r.command(name, args...)
+	if err != nil {
+		return nil, err
+	}
+	return RunStream(ctx, r.Runner, read, path, sshArgs...)
+}
+
+// command returns the ssh command line running the command on the target
+func (r SSHRunner) command(name string, args ...string) (string, []string, error) {
+	if err := ValidateSSHTarget(r.Target); err != nil {
+		return ``, nil, err
+	}
+	path := r.Path
+	if path == `` {
+		path = `ssh`
+	}
+	sshArgs := []string{`-o`, `BatchMode=yes`}
+	if r.IdentityFile != `` {
+		sshArgs = append(sshArgs, `-i`, r.IdentityFile)
+	}
+	if r.Port != 0 {
+		sshArgs = append(sshArgs, `-p`, strconv.Itoa(r.Port))
+	}
+	for _, option := range r.Options {
+		sshArgs = append(sshArgs, `-o`, option)
+	}
+	// ssh joins the command with spaces for the remote shell, so each argument is quoted
+	command := make([]string, 0, len(args)+1)
+	for _, arg := range append([]string{name}, args...) {
+		command = append(command, shellQuote(arg))
+	}
+	return path, append(sshArgs, `--`, r.Target, strings.Join(command, ` `)), nil
+}
+
+// ValidateSSHTarget returns ErrInvalidTarget unless target is a non-empty destination without whitespace that does
+// not begin with a dash
+func ValidateSSHTarget(target string) error {
+	if target == `` || strings.HasPrefix(target, `-`) || strings.ContainsFunc(target, func(r rune) bool { return r <= ' ' || r == 0x7f }) {
+		return ErrInvalidTarget
+	}
+	return nil
+}
+
+// shellQuote quotes s for a POSIX shell, unless it consists only of characters without special meaning
+func shellQuote(s string) string {
+	if s != `` && strings.Trim(s, `abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-_./=:,@%+`) == `` {
+		return s
+	}
+	return `'` + strings.ReplaceAll(s, `'`, `'\''`) + `'`
+}
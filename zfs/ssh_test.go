@@ -0,0 +1,56 @@
+package zfs
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestSSHRunner(t *testing.T) {
+	runner := SSHRunner{
+		Runner: fixtureRunner{
+			`/usr/bin/ssh -o BatchMode=yes -o ConnectTimeout=5 -- monitor@nas1 zpool list -Ho name`: `zpool_list_names.txt`,
+		},
+		Path:    `/usr/bin/ssh`,
+		Target:  `monitor@nas1`,
+		Options: []string{`ConnectTimeout=5`},
+	}
+	pools, err := poolNames(context.Background(), runner)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pools) == 0 {
+		t.Error("Expected pools from the remote host")
+	}
+
+	runner.Runner = fixtureRunner{
+		`/usr/bin/ssh -o BatchMode=yes -i /etc/zfs_exporter/id_ed25519 -p 2222 -o ConnectTimeout=5 -- monitor@nas1 zpool list -Ho name`: `zpool_list_names.txt`,
+	}
+	runner.IdentityFile = `/etc/zfs_exporter/id_ed25519`
+	runner.Port = 2222
+	if _, err = poolNames(context.Background(), runner); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, target := range []string{``, `-oProxyCommand=sh`, `nas1 zpool`, "nas1\n"} {
+		runner.Target = target
+		if _, _, err = runner.Run(context.Background(), `zpool`, `list`); !errors.Is(err, ErrInvalidTarget) {
+			t.Errorf("Expected ErrInvalidTarget for target %q, got %v", target, err)
+		}
+	}
+}
+
+func TestShellQuote(t *testing.T) {
+	for s, want := range map[string]string{
+		`tank/data@daily`: `tank/data@daily`,
+		``:                `''`,
+		`name,used`:       `name,used`,
+		`tank/my data`:    `'tank/my data'`,
+		`it's`:            `'it'\''s'`,
+		`$(reboot)`:       `'$(reboot)'`,
+	} {
+		if got := shellQuote(s); got != want {
+			t.Errorf("Expected %s for %q, got %s", want, s, got)
+		}
+	}
+}
@@ -1,18 +1,14 @@
 package zfs
 
 import (
-	"encoding/json"
-	"fmt"
-	"io"
+	"context"
 	"log/slog"
-	"os/exec"
-	"strings"
 )
 
 type ZFSCommandOutputVersionT struct {
 	Command string `json:"command"`
-	Major   int    `json:"major"`
-	Minor   int    `json:"minor"`
+	Major   int    `json:"vers_major"`
+	Minor   int    `json:"vers_minor"`
 }
 
 func (o ZFSCommandOutputVersionT) LogValue() slog.Value {
@@ -43,42 +39,13 @@ func (o ZFSVersionOutputT) LogValue() slog.Value {
 	)
 }
 
-func GetZFSVersionViaJSON(logger *slog.Logger) (*string, error) {
-	cmd := exec.Command(`zfs`, `version`, `--json`)
-
-	// Setup pipes
-	stdout, err := cmd.StdoutPipe()
-	if err != nil {
-		return nil, err
-	}
-	stderr, err := cmd.StderrPipe()
-	if err != nil {
-		return nil, err
-	}
-
-	// command begin
-	if err = cmd.Start(); err != nil {
-		return nil, fmt.Errorf("failed to start command '%s': %w", cmd.String(), err)
-	}
-
-	// stdout
-	stdo, err := io.ReadAll(stdout)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read output of '%s'; output: (%w)", cmd.String(), err)
-	}
-	logger.Debug("ZFS Command Output", "stdout", stdo)
-
-	// stderr
-	stde, _ := io.ReadAll(stderr)
-	if err = cmd.Wait(); err != nil {
-		return nil, fmt.Errorf("failed to execute command '%s'; output: '%s' (%w)", cmd.String(), strings.TrimSpace(string(stde)), err)
-	}
-
-	// unmarshal JSON into Go objects
+// GetZFSVersionViaJSON returns the versions of the ZFS userland and kernel module, along with the version of the JSON
+// output format
+func GetZFSVersionViaJSON(ctx context.Context, runner Runner, logger *slog.Logger) (*ZFSVersionOutputT, error) {
 	var o ZFSVersionOutputT
-	if err := json.Unmarshal(stdo, &o); err != nil {
-		return nil, fmt.Errorf("failed to read output of '%s'; output: (%w)", cmd.String(), err)
+	if err := executeJSON(ctx, runner, logger, &o, `zfs`, `version`, `--json`); err != nil {
+		return nil, err
 	}
 	logger.Debug("ZFS Command Output Parsed", "output", o)
-	return &o.ZFSVersion.Userland, nil
+	return &o, nil
 }
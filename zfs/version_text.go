@@ -0,0 +1,34 @@
+package zfs
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+)
+
+// GetZFSVersionViaText returns the versions of the ZFS userland and kernel module from the human-readable output of
+// `zfs version`, for releases that do not support JSON output
+func GetZFSVersionViaText(ctx context.Context, runner Runner, logger *slog.Logger) (*ZFSVersionT, error) {
+	stdout, _, err := runner.Run(ctx, `zfs`, `version`)
+	if err != nil {
+		return nil, err
+	}
+
+	var v ZFSVersionT
+	for _, line := range strings.Split(strings.TrimSpace(string(stdout)), "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(line, `zfs-kmod-`):
+			v.Kernel = line
+		case strings.HasPrefix(line, `zfs-`):
+			v.Userland = line
+		}
+	}
+	if v.Userland == `` {
+		return nil, fmt.Errorf("%w: zfs version: '%s'", ErrInvalidOutput, strings.TrimSpace(string(stdout)))
+	}
+	logger.Debug("ZFS Version Text Output Parsed", "userland", v.Userland, "kernel", v.Kernel)
+
+	return &v, nil
+}
@@ -0,0 +1,18 @@
+package zfs
+
+import (
+	"context"
+	"testing"
+)
+
+func TestGetZFSVersionViaText(t *testing.T) {
+	runner := fixtureRunner{`zfs version`: `zfs_version.txt`}
+	version, err := GetZFSVersionViaText(context.Background(), runner, testLogger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := ZFSVersionT{Userland: `zfs-2.1.5-1ubuntu6~22.04.4`, Kernel: `zfs-kmod-2.1.5-1ubuntu6~22.04.4`}
+	if *version != want {
+		t.Errorf("Expected %+v, got %+v", want, *version)
+	}
+}
@@ -3,12 +3,15 @@
 package zfs
 
 import (
+	"bytes"
+	"context"
 	"encoding/csv"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
-	"os/exec"
-	"strings"
+	"log/slog"
+	"time"
 )
 
 // ErrInvalidOutput is returned on unparseable CLI output
@@ -19,6 +22,40 @@ type Client interface {
 	PoolNames() ([]string, error)
 	Pool(name string) Pool
 	Datasets(pool string, kind DatasetKind) Datasets
+	// PoolStatus returns the status of all pools. Where only some pools could be queried, their status is returned
+	// along with the error.
+	PoolStatus(power bool) (map[string]PoolStatusT, error)
+	PoolIostats(vdevs bool) (map[string]PoolIostatT, error)
+	PoolList(props ...string) (map[string]PoolListT, error)
+	PoolGet(props ...string) (map[string]PoolListT, error)
+	DDTStats(pool string) (DDTStatsT, error)
+	RemovalStats(pool string) (RemovalStatsT, error)
+	DatasetList(pool string, depth int, kinds []DatasetKind, props ...string) (map[string]DatasetListT, error)
+	DatasetGet(pool string, kinds []DatasetKind, props ...string) (map[string]DatasetListT, error)
+	ArcStats() (map[string]string, error)
+	Txgs(pool string) ([]TxgT, error)
+	ObjsetStats(pool string) (map[string]ObjsetStatsT, error)
+	Events() ([]EventT, error)
+	SendEstimate(snapshot, from string) (uint64, error)
+	LatestSnapshot(dataset string) (string, error)
+}
+
+// ErrorLogClient is implemented by clients that list the permanent data errors of pools. It is separate from Client,
+// so that implementations of Client outside this module are not broken by its addition.
+type ErrorLogClient interface {
+	PoolErrorLog(pool string) (PoolErrorLogT, error)
+}
+
+// ProgramClient is implemented by clients that read dataset properties with channel programs, which, like
+// ErrorLogClient, is separate from Client.
+type ProgramClient interface {
+	DatasetProgram(pool string, limits ProgramLimits, props ...string) (map[string]DatasetListT, error)
+}
+
+// HistogramClient is implemented by clients that read the latency histograms of pools, which, like ErrorLogClient, is
+// separate from Client.
+type HistogramClient interface {
+	LatencyHistograms(pool string) (LatencyHistogramsT, error)
 }
 
 // Pool allows querying pool properties
@@ -49,42 +86,179 @@ type handler interface {
 	processLine(pool string, line []string) error
 }
 
-type clientImpl struct{}
+// Config configures a ZFS Client
+type Config struct {
+	Logger    *slog.Logger
+	KstatPath string
+	// CommandTimeout limits the runtime of each command executed by the client, zero disables the limit
+	CommandTimeout time.Duration
+	// Runner executes commands on behalf of the client, defaulting to ExecRunner
+	Runner Runner
+	// CacheTTL is the duration for which results are reused by subsequent calls, zero disables caching. Concurrent
+	// identical calls always share a single execution.
+	CacheTTL time.Duration
+	// Backend selects how datasets are read, defaulting to BackendCLI. BackendLZC requires LZCSupported.
+	Backend Backend
+	// StatusConcurrency is the maximum number of pools queried concurrently by PoolStatus, so that a slow or suspended
+	// pool does not delay or fail the others. One or less queries all pools with a single command.
+	StatusConcurrency int
+	// TextOutput parses the human-readable output of commands for which the client supports it, for releases whose
+	// Capabilities lack JSON output, as reported by TextOutputRequired
+	TextOutput bool
+	// IostatInterval is the interval over which PoolIostats samples the I/O statistics of pools, which must be shorter
+	// than CommandTimeout. Zero averages them since the pools were imported.
+	IostatInterval time.Duration
+}
+
+// TextOutputRequired reports whether the capabilities lack the JSON output with integer values used by the client
+func TextOutputRequired(capabilities Capabilities) bool {
+	return len(capabilities.Missing(CapabilityJSON, CapabilityJSONInt)) > 0
+}
+
+type clientImpl struct {
+	logger            *slog.Logger
+	kstatPath         string
+	commandTimeout    time.Duration
+	runner            Runner
+	statusConcurrency int
+	textOutput        bool
+	iostatInterval    time.Duration
+}
 
 func (z clientImpl) PoolNames() ([]string, error) {
-	return poolNames()
+	ctx, cancel := CommandContext(z.commandTimeout)
+	defer cancel()
+	return poolNames(ctx, z.runner)
 }
 
 func (z clientImpl) Pool(name string) Pool {
-	return newPoolImpl(name)
+	return newPoolImpl(name, z.runner, z.commandTimeout)
 }
 
 func (z clientImpl) Datasets(pool string, kind DatasetKind) Datasets {
-	return newDatasetsImpl(pool, kind)
+	return newDatasetsImpl(pool, kind, z.runner, z.commandTimeout)
 }
 
-func execute(pool string, h handler, cmd string, args ...string) error {
-	c := exec.Command(cmd, append(args, pool)...)
-	out, err := c.StdoutPipe()
-	if err != nil {
-		return err
+func (z clientImpl) PoolStatus(power bool) (map[string]PoolStatusT, error) {
+	ctx, cancel := CommandContext(z.commandTimeout)
+	defer cancel()
+	return zpoolStatus(ctx, z.runner, z.logger, !z.textOutput, z.statusConcurrency, power)
+}
+
+func (z clientImpl) PoolIostats(vdevs bool) (map[string]PoolIostatT, error) {
+	ctx, cancel := CommandContext(z.commandTimeout)
+	defer cancel()
+	return ZpoolIostatIntervalViaJSON(ctx, z.runner, z.logger, vdevs, z.iostatInterval)
+}
+
+func (z clientImpl) PoolGet(props ...string) (map[string]PoolListT, error) {
+	ctx, cancel := CommandContext(z.commandTimeout)
+	defer cancel()
+	return ZpoolGetViaJSON(ctx, z.runner, z.logger, props...)
+}
+
+func (z clientImpl) DDTStats(pool string) (DDTStatsT, error) {
+	ctx, cancel := CommandContext(z.commandTimeout)
+	defer cancel()
+	return ZpoolDDTStats(ctx, z.runner, pool)
+}
+
+func (z clientImpl) RemovalStats(pool string) (RemovalStatsT, error) {
+	ctx, cancel := CommandContext(z.commandTimeout)
+	defer cancel()
+	return ZpoolRemovalStats(ctx, z.runner, pool)
+}
+
+func (z clientImpl) PoolErrorLog(pool string) (PoolErrorLogT, error) {
+	ctx, cancel := CommandContext(z.commandTimeout)
+	defer cancel()
+	return ZpoolErrorLog(ctx, z.runner, z.logger, pool)
+}
+
+func (z clientImpl) LatencyHistograms(pool string) (LatencyHistogramsT, error) {
+	ctx, cancel := CommandContext(z.commandTimeout)
+	defer cancel()
+	return ZpoolLatencyHistograms(ctx, z.runner, pool)
+}
+
+func (z clientImpl) PoolList(props ...string) (map[string]PoolListT, error) {
+	ctx, cancel := CommandContext(z.commandTimeout)
+	defer cancel()
+	if z.textOutput {
+		return ZpoolListViaText(ctx, z.runner, z.logger, props...)
 	}
+	return ZpoolListViaJSON(ctx, z.runner, z.logger, props...)
+}
+
+func (z clientImpl) DatasetList(pool string, depth int, kinds []DatasetKind, props ...string) (map[string]DatasetListT, error) {
+	ctx, cancel := CommandContext(z.commandTimeout)
+	defer cancel()
+	return ZfsListViaJSON(ctx, z.runner, z.logger, pool, depth, kinds, props...)
+}
+
+func (z clientImpl) DatasetGet(pool string, kinds []DatasetKind, props ...string) (map[string]DatasetListT, error) {
+	ctx, cancel := CommandContext(z.commandTimeout)
+	defer cancel()
+	return ZfsGetViaJSON(ctx, z.runner, z.logger, pool, kinds, props...)
+}
 
-	stderr, err := c.StderrPipe()
+func (z clientImpl) DatasetProgram(pool string, limits ProgramLimits, props ...string) (map[string]DatasetListT, error) {
+	ctx, cancel := CommandContext(z.commandTimeout)
+	defer cancel()
+	return ZfsProgramDatasets(ctx, z.runner, z.logger, pool, limits, props...)
+}
+
+func (z clientImpl) ArcStats() (map[string]string, error) {
+	return ArcStats(z.kstatPath)
+}
+
+func (z clientImpl) Txgs(pool string) ([]TxgT, error) {
+	return Txgs(z.kstatPath, pool)
+}
+
+func (z clientImpl) ObjsetStats(pool string) (map[string]ObjsetStatsT, error) {
+	return ObjsetStats(z.kstatPath, pool)
+}
+
+func (z clientImpl) Events() ([]EventT, error) {
+	ctx, cancel := CommandContext(z.commandTimeout)
+	defer cancel()
+	return ZpoolEvents(ctx, z.runner)
+}
+
+func (z clientImpl) SendEstimate(snapshot, from string) (uint64, error) {
+	ctx, cancel := CommandContext(z.commandTimeout)
+	defer cancel()
+	return ZfsSendEstimate(ctx, z.runner, snapshot, from)
+}
+
+func (z clientImpl) LatestSnapshot(dataset string) (string, error) {
+	ctx, cancel := CommandContext(z.commandTimeout)
+	defer cancel()
+	return ZfsLatestSnapshot(ctx, z.runner, dataset)
+}
+
+// CommandContext returns a context suitable for bounding a single command, which expires after timeout, or never if
+// timeout is zero
+func CommandContext(timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(context.Background())
+	}
+	return context.WithTimeout(context.Background(), timeout)
+}
+
+func execute(ctx context.Context, runner Runner, pool string, h handler, cmd string, args ...string) error {
+	stdout, _, err := runner.Run(ctx, cmd, append(args, pool)...)
 	if err != nil {
 		return err
 	}
 
-	r := csv.NewReader(out)
+	r := csv.NewReader(bytes.NewReader(stdout))
 	r.Comma = '\t'
 	r.LazyQuotes = true
 	r.ReuseRecord = true
 	r.FieldsPerRecord = 3
 
-	if err = c.Start(); err != nil {
-		return fmt.Errorf("failed to start command '%s': %w", c.String(), err)
-	}
-
 	for {
 		line, err := r.Read()
 		if errors.Is(err, io.EOF) {
@@ -98,14 +272,69 @@ func execute(pool string, h handler, cmd string, args ...string) error {
 		}
 	}
 
-	stde, _ := io.ReadAll(stderr)
-	if err = c.Wait(); err != nil {
-		return fmt.Errorf("failed to execute command '%s'; output: '%s' (%w)", c.String(), strings.TrimSpace(string(stde)), err)
-	}
 	return nil
 }
 
+// executeJSON runs the command and decodes its JSON output into v as it is read, so that large output is not held in
+// memory in full alongside the decoded value
+func executeJSON(ctx context.Context, runner Runner, logger *slog.Logger, v any, cmd string, args ...string) error {
+	// decoded is only set once output was read and decoded, which never happens where the command cannot be started
+	var (
+		decoded   bool
+		decodeErr error
+	)
+	_, err := RunStream(ctx, runner, func(stdout io.Reader) error {
+		decodeErr = json.NewDecoder(stdout).Decode(v)
+		decoded = decodeErr == nil
+		return decodeErr
+	}, cmd, args...)
+	if decoded {
+		logger.Debug("ZFS Command Output", "cmd", commandString(cmd, args...), "output", v)
+	}
+	switch {
+	case err == nil:
+		return nil
+	case decoded:
+		// Output is discarded where ctx has expired, as it may be truncated
+		if ctx.Err() != nil {
+			return err
+		}
+		logger.Warn("Command failed, using its output", "cmd", commandString(cmd, args...), "err", err)
+		return nil
+	case err != decodeErr:
+		// The command failed without parseable output
+		return err
+	}
+	recordJSONParseError()
+	return fmt.Errorf("failed to read output of '%s'; output: (%w)", commandString(cmd, args...), err)
+}
+
+// partialOutput reports whether the output of a failed command may still be parsed. zpool exits non-zero where pools
+// are unhealthy, which is when their status matters most, while still printing it. Output is discarded where ctx has
+// expired, as it may be truncated.
+func partialOutput(ctx context.Context, stdout []byte) bool {
+	return ctx.Err() == nil && len(bytes.TrimSpace(stdout)) > 0
+}
+
 // New instantiates a ZFS Client
-func New() Client {
-	return clientImpl{}
+func New(config Config) Client {
+	if config.KstatPath == `` {
+		config.KstatPath = DefaultKstatPath
+	}
+	if config.Runner == nil {
+		config.Runner = ExecRunner{}
+	}
+	client := clientImpl{
+		logger:            config.Logger,
+		kstatPath:         config.KstatPath,
+		commandTimeout:    config.CommandTimeout,
+		runner:            config.Runner,
+		statusConcurrency: config.StatusConcurrency,
+		textOutput:        config.TextOutput,
+		iostatInterval:    config.IostatInterval,
+	}
+	if config.Backend == BackendLZC {
+		return newCachingClient(lzcClient{clientImpl: client}, config.CacheTTL)
+	}
+	return newCachingClient(client, config.CacheTTL)
 }
@@ -0,0 +1,375 @@
+package zfs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testLogger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+func newFixtureClient(f fixtureRunner) Client {
+	return New(Config{Logger: testLogger, Runner: f})
+}
+
+func TestGetZFSVersionViaJSON(t *testing.T) {
+	runner := fixtureRunner{`zfs version --json`: `zfs_version.json`}
+	o, err := GetZFSVersionViaJSON(context.Background(), runner, testLogger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (ZFSVersionT{Userland: `zfs-2.3.1-1`, Kernel: `zfs-kmod-2.3.1-1`}); o.ZFSVersion != want {
+		t.Errorf("Expected %+v, got %+v", want, o.ZFSVersion)
+	}
+	if want := (ZFSCommandOutputVersionT{Command: `zfs version`, Major: 0, Minor: 1}); o.OutputVersion != want {
+		t.Errorf("Expected %+v, got %+v", want, o.OutputVersion)
+	}
+}
+
+func TestZpoolStatusViaJSON(t *testing.T) {
+	runner := fixtureRunner{`zpool status --json --json-int`: `zpool_status.json`}
+	pools, err := ZpoolStatusViaJSON(context.Background(), runner, testLogger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool, ok := (*pools)[`tank`]
+	if !ok {
+		t.Fatalf("Expected pool tank, got %v", *pools)
+	}
+	if pool.State != `ONLINE` || pool.ScanStats.Function != `SCRUB` || pool.ScanStats.Examined != 1893470216192 {
+		t.Errorf("Unexpected pool status: %+v", pool)
+	}
+}
+
+func TestZpoolStatusViaJSONPetabyteScale(t *testing.T) {
+	runner := fixtureRunner{`zpool status --json --json-int`: `zpool_status_petabyte.json`}
+	pools, err := ZpoolStatusViaJSON(context.Background(), runner, testLogger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool := (*pools)[`archive`]
+	// Sizes of 10 EiB and counters beyond the range of int64 are decoded without overflow
+	if scan := pool.ScanStats; scan.ToExamine != 10<<60 || scan.Examined != 9511602413006487552 || scan.Issued != 1<<63 {
+		t.Errorf("Unexpected scan stats: %+v", scan)
+	}
+	vdevs := pool.AllVdevs()
+	for _, vdev := range vdevs {
+		switch vdev.Name {
+		case `draid2:8d:90c:2s-0`:
+			if vdev.PhysSpace != 10<<60 {
+				t.Errorf("Unexpected physical space of %s: %d", vdev.Name, vdev.PhysSpace)
+			}
+		case `sda`:
+			if vdev.RepDevSize != 128102389760000 || vdev.ChecksumErrors != math.MaxUint64 {
+				t.Errorf("Unexpected status of %s: %+v", vdev.Name, vdev)
+			}
+		}
+	}
+	if len(vdevs) != 2 {
+		t.Errorf("Expected 2 vdevs, got %d", len(vdevs))
+	}
+}
+
+func TestZpoolStatusViaJSONExitStatus(t *testing.T) {
+	runner := exitRunner{
+		Runner: fixtureRunner{`zpool status --json --json-int`: `zpool_status_degraded.json`},
+		fail:   `zpool status --json --json-int`,
+	}
+	pools, err := ZpoolStatusViaJSON(context.Background(), runner, testLogger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if (*pools)[`tank`].State != `DEGRADED` {
+		t.Errorf("Unexpected pool status: %+v", *pools)
+	}
+
+	// Without parseable output, the failure of the command is reported
+	runner.Runner = fixtureRunner{`zpool status --json --json-int`: `zpool_status.txt`}
+	if _, err = ZpoolStatusViaJSON(context.Background(), runner, testLogger); err == nil || !strings.Contains(err.Error(), `exit status 1`) {
+		t.Errorf("Expected exit status error, got %v", err)
+	}
+}
+
+func TestZpoolStatusPerPoolViaJSON(t *testing.T) {
+	runner := fixtureRunner{
+		`zpool list -Ho name`:                   `zpool_list_names.txt`,
+		`zpool status --json --json-int tank`:   `zpool_status.json`,
+		`zpool status --json --json-int backup`: `zpool_status_backup.json`,
+	}
+	pools, err := ZpoolStatus(context.Background(), runner, testLogger, Capabilities{CapabilityJSON: true}, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if (*pools)[`tank`].State != `ONLINE` || (*pools)[`backup`].PoolGuid != 1234567890123456789 {
+		t.Errorf("Unexpected pool status: %+v", *pools)
+	}
+
+	// A failing pool is omitted, without affecting the others.
+	delete(runner, `zpool status --json --json-int backup`)
+	pools, err = ZpoolStatusPerPoolViaJSON(context.Background(), runner, testLogger, 2)
+	if err == nil || !strings.Contains(err.Error(), `pool backup`) {
+		t.Errorf("Expected error for pool backup, got %v", err)
+	}
+	if _, ok := (*pools)[`backup`]; ok || len(*pools) != 1 {
+		t.Errorf("Expected only pool tank, got %+v", *pools)
+	}
+}
+
+func TestClientPoolStatusPerPool(t *testing.T) {
+	runner := fixtureRunner{
+		`zpool list -Ho name`:                         `zpool_list_names.txt`,
+		`zpool status --json --json-int --power tank`: `zpool_status_power.json`,
+	}
+	client := New(Config{Logger: testLogger, Runner: runner, StatusConcurrency: 2})
+
+	// The status of the failing pool is omitted, and that of the others returned along with the error
+	pools, err := client.PoolStatus(true)
+	if err == nil || !strings.Contains(err.Error(), `pool backup`) {
+		t.Errorf("Expected error for pool backup, got %v", err)
+	}
+	if _, ok := pools[`tank`]; !ok || len(pools) != 1 {
+		t.Errorf("Expected only pool tank, got %+v", pools)
+	}
+}
+
+func TestPoolStatusPower(t *testing.T) {
+	client := newFixtureClient(fixtureRunner{`zpool status --json --json-int --power`: `zpool_status_power.json`})
+	pools, err := client.PoolStatus(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := make(map[string]string)
+	for _, vdev := range pools[`tank`].AllVdevs() {
+		got[vdev.Name] = vdev.PowerState
+	}
+	if want := map[string]string{`mirror-0`: ``, `sda`: `on`, `sdb`: `off`, `sdc`: `-`}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected power states %v, got %v", want, got)
+	}
+}
+
+func TestPoolStatusRaidzExpand(t *testing.T) {
+	client := newFixtureClient(fixtureRunner{`zpool status --json --json-int`: `zpool_status_raidz_expand.json`})
+	pools, err := client.PoolStatus(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := &RaidzExpandStatsT{State: `SCANNING`, ExpandingVdev: `raidz1-0`, StartTime: 1753056000, ToReflow: 11993247744, Reflowed: 3145728000}
+	if got := pools[`tank`].RaidzExpandStats; !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %+v, got %+v", want, got)
+	}
+
+	// Without a name, the index of the vdev is used
+	var stats RaidzExpandStatsT
+	if err = json.Unmarshal([]byte(`{"state": "FINISHED", "expanding_vdev": 1}`), &stats); err != nil {
+		t.Fatal(err)
+	}
+	if stats.ExpandingVdev != `1` {
+		t.Errorf("Expected expanding vdev 1, got '%s'", stats.ExpandingVdev)
+	}
+}
+
+func TestEvents(t *testing.T) {
+	client := newFixtureClient(fixtureRunner{`zpool events -v -H`: `zpool_events_v.txt`})
+	events, err := client.Events()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []EventT{
+		{EID: 41, Class: `sysevent.fs.zfs.config_sync`, Pool: `tank`},
+		{EID: 42, Class: `ereport.fs.zfs.checksum`, Pool: `tank`},
+		{EID: 43, Class: `sysevent.fs.zfs.history_event`},
+	}
+	if !reflect.DeepEqual(events, want) {
+		t.Errorf("Expected %+v, got %+v", want, events)
+	}
+}
+
+func TestPoolNames(t *testing.T) {
+	client := newFixtureClient(fixtureRunner{`zpool list -Ho name`: `zpool_list_names.txt`})
+	names, err := client.PoolNames()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{`tank`, `backup`}; !reflect.DeepEqual(names, want) {
+		t.Errorf("Expected %v, got %v", want, names)
+	}
+}
+
+func TestPoolProperties(t *testing.T) {
+	client := newFixtureClient(fixtureRunner{`zpool get -Hpo name,property,value size,health tank`: `zpool_get.txt`})
+	props, err := client.Pool(`tank`).Properties(`size`, `health`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := map[string]string{`size`: `3985729650688`, `health`: `ONLINE`}; !reflect.DeepEqual(props.Properties(), want) {
+		t.Errorf("Expected %v, got %v", want, props.Properties())
+	}
+}
+
+func TestDatasetProperties(t *testing.T) {
+	client := newFixtureClient(fixtureRunner{`zfs get -Hprt filesystem -o name,property,value used,available tank`: `zfs_get.txt`})
+	datasets, err := client.Datasets(`tank`, DatasetFilesystem).Properties(`used`, `available`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make(map[string]map[string]string)
+	for _, dataset := range datasets {
+		got[dataset.DatasetName()] = dataset.Properties()
+	}
+	want := map[string]map[string]string{
+		`tank`:      {`used`: `1893470216192`},
+		`tank/home`: {`used`: `1073741824`, `available`: `2092259434496`},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+}
+
+func TestPoolIostats(t *testing.T) {
+	client := newFixtureClient(fixtureRunner{`zpool iostat --json --json-int -p -v -l`: `zpool_iostat_vl.json`})
+	stats, err := client.PoolIostats(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool := stats[`tank`]
+	if totals := pool.Totals(); totals.ReadOps != 42 || totals.TotalWriteWait != 3000000 || totals.DiskWriteWait != 2500000 || totals.AsyncqWriteWait != 400000 {
+		t.Errorf("Unexpected totals: %+v", totals)
+	}
+
+	names := make([]string, 0)
+	for _, vdev := range pool.AllVdevs() {
+		names = append(names, vdev.Name)
+	}
+	sort.Strings(names)
+	if want := []string{`mirror-0`, `nvme0n1`, `sda`, `sdb`}; !reflect.DeepEqual(names, want) {
+		t.Errorf("Expected vdevs %v, got %v", want, names)
+	}
+
+	names = names[:0]
+	for _, vdev := range pool.LeafVdevs() {
+		names = append(names, vdev.Name)
+	}
+	sort.Strings(names)
+	if want := []string{`nvme0n1`, `sda`, `sdb`}; !reflect.DeepEqual(names, want) {
+		t.Errorf("Expected leaf vdevs %v, got %v", want, names)
+	}
+}
+
+func TestPoolIostatsInterval(t *testing.T) {
+	client := New(Config{Logger: testLogger, Runner: fixtureRunner{`zpool iostat --json --json-int -p -y 0.5 1`: `zpool_iostat_vl.json`}, IostatInterval: 500 * time.Millisecond})
+	stats, err := client.PoolIostats(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if totals := stats[`tank`].Totals(); totals.ReadOps != 42 {
+		t.Errorf("Unexpected totals: %+v", totals)
+	}
+}
+
+func TestPoolList(t *testing.T) {
+	client := newFixtureClient(fixtureRunner{`zpool list --json --json-int -p -o name,size,health`: `zpool_list.json`})
+	pools, err := client.PoolList(`size`, `health`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	props := pools[`tank`].Properties
+	if props[`size`].Value != `3985729650688` || props[`health`].Value != `ONLINE` {
+		t.Errorf("Unexpected properties: %+v", props)
+	}
+}
+
+func TestPoolGet(t *testing.T) {
+	client := newFixtureClient(fixtureRunner{`zpool get --json --json-int -p all`: `zpool_get_all.json`})
+	pools, err := client.PoolGet()
+	if err != nil {
+		t.Fatal(err)
+	}
+	props := pools[`tank`].Properties
+	if props[`ashift`].Value != `12` || props[`autotrim`].Value != `on` || props[`failmode`].Value != `wait` {
+		t.Errorf("Unexpected properties: %+v", props)
+	}
+}
+
+func TestDatasetGet(t *testing.T) {
+	client := newFixtureClient(fixtureRunner{`zfs get --json --json-int -p -r -t filesystem,volume -o name,property,value,source com.example:backup-policy,com.example:retention-days tank`: `zfs_get_user.json`})
+	datasets, err := client.DatasetGet(`tank`, []DatasetKind{DatasetFilesystem, DatasetVolume}, `com.example:backup-policy`, `com.example:retention-days`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	prop := datasets[`tank/home/alice`].Properties[`com.example:backup-policy`]
+	if prop.Value != `daily` || prop.Source.Type != `INHERITED` {
+		t.Errorf("Unexpected backup-policy property: %+v", prop)
+	}
+}
+
+func TestDatasetList(t *testing.T) {
+	client := newFixtureClient(fixtureRunner{`zfs list --json --json-int -p -t filesystem,volume -o name,used -r tank`: `zfs_list.json`})
+	datasets, err := client.DatasetList(`tank`, -1, []DatasetKind{DatasetFilesystem, DatasetVolume}, `used`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if kind := datasets[`tank/vm`].Kind(); kind != DatasetVolume {
+		t.Errorf("Expected kind %s, got %s", DatasetVolume, kind)
+	}
+	if used := datasets[`tank`].Properties[`used`].Value; used != `1893470216192` {
+		t.Errorf("Expected used 1893470216192, got %s", used)
+	}
+}
+
+func TestObjsetStats(t *testing.T) {
+	client := New(Config{Logger: testLogger, Runner: fixtureRunner{}, KstatPath: `testdata/kstat`})
+	stats, err := client.ObjsetStats(`tank`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]ObjsetStatsT{
+		`tank`:               {Reads: 3, Writes: 12, NRead: 12288, NWritten: 49152, NUnlinks: 1},
+		`tank/media library`: {Reads: 4096, Writes: 2048, NRead: 536870912, NWritten: 268435456, NUnlinks: 10},
+	}
+	if !reflect.DeepEqual(stats, want) {
+		t.Errorf("Expected %+v, got %+v", want, stats)
+	}
+
+	if stats, err = client.ObjsetStats(`missing`); err != nil || len(stats) != 0 {
+		t.Errorf("Expected no stats for a pool without kstats, got %+v (%v)", stats, err)
+	}
+}
+
+func TestLatencyHistograms(t *testing.T) {
+	client := newFixtureClient(fixtureRunner{`zpool iostat -w -H -p tank`: `zpool_iostat_w.txt`})
+	histograms, err := client.(HistogramClient).LatencyHistograms(`tank`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(histograms.TotalReadWait) != 37 || len(histograms.AsyncqWriteWait) != 37 {
+		t.Fatalf("Expected 37 buckets, got %d", len(histograms.TotalReadWait))
+	}
+	if histograms.TotalReadWait[12] != 2652 || histograms.DiskWriteWait[13] != 3516 || histograms.AsyncqWriteWait[14] != 2028 {
+		t.Errorf("Unexpected histograms: %+v", histograms)
+	}
+
+	if _, err = parseLatencyHistograms([]byte("tank\n1\t0\t0\n")); !errors.Is(err, ErrInvalidOutput) {
+		t.Errorf("Expected invalid output for a truncated row, got %v", err)
+	}
+}
+
+func TestPoolErrorLog(t *testing.T) {
+	client := newFixtureClient(fixtureRunner{`zpool status -v --json --json-int tank`: `zpool_status_errors.json`})
+	errorLog, err := client.(ErrorLogClient).PoolErrorLog(`tank`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if errorLog.Count != 5 || len(errorLog.Entries) != 5 || errorLog.Entries[2] != `tank/backup:/2025/07/db.dump` {
+		t.Errorf("Unexpected error log: %+v", errorLog)
+	}
+}
@@ -1,11 +1,18 @@
 package main
 
 import (
+	"bytes"
+	"context"
+	"log/slog"
 	"net/http"
 	"os"
-	"strings"
+	"sort"
+	"time"
 
 	"github.com/jmcgover/zfs_exporter/v2/collector"
+	"github.com/jmcgover/zfs_exporter/v2/config"
+	"github.com/jmcgover/zfs_exporter/v2/policy"
+	"github.com/jmcgover/zfs_exporter/v2/remediation"
 	"github.com/jmcgover/zfs_exporter/v2/zfs"
 
 	"github.com/alecthomas/kingpin/v2"
@@ -20,42 +27,365 @@ import (
 	"github.com/prometheus/common/version"
 )
 
+// newPromHandler returns the metrics handler for the default registry. The exposition format is negotiated with the
+// scraper, so protobuf is served where requested, as required for native histograms.
+func newPromHandler(logger *slog.Logger) http.Handler {
+	return promhttp.InstrumentMetricHandler(
+		prometheus.DefaultRegisterer,
+		promhttp.HandlerFor(prometheus.DefaultGatherer, promhttp.HandlerOpts{
+			ErrorLog: slog.NewLogLogger(logger.Handler(), slog.LevelError),
+		}),
+	)
+}
+
 func main() {
 	var (
 		metricsPath             = kingpin.Flag("web.telemetry-path", "Path under which to expose metrics.").Default("/metrics").String()
 		metricsExporterDisabled = kingpin.Flag(`web.disable-exporter-metrics`, `Exclude metrics about the exporter itself (promhttp_*, process_*, go_*).`).Default(`false`).Bool()
+		conditionalRequests     = kingpin.Flag(`web.conditional-requests`, `Respond to scrapes with If-None-Match set with 304 Not Modified when no new ZFS data has been collected. Metrics about the exporter itself are only refreshed alongside ZFS data when enabled.`).Default(`false`).Bool()
 		deadline                = kingpin.Flag("deadline", "Maximum duration that a collection should run before returning cached data. Should be set to a value shorter than your scrape timeout duration. The current collection run will continue and update the cache when complete (default: 8s)").Default("8s").Duration()
+		circuitThreshold        = kingpin.Flag("circuit-breaker.threshold", "Number of consecutive failures after which a collector is skipped for the cooldown period. Zero disables the circuit breaker.").Default("3").Int()
+		circuitCooldown         = kingpin.Flag("circuit-breaker.cooldown", "Duration for which a repeatedly failing collector is skipped, before it is retried.").Default("5m").Duration()
+		schedules               = kingpin.Flag("collector.schedule", "Only run a collector within a daily time window, as COLLECTOR=HH:MM-HH:MM in local time, serving the metrics of its last run otherwise, e.g. snapshot-summary=01:00-05:00. May be specified multiple times.").Strings()
+		stateFormat             = kingpin.Flag("collector.state-format", "Export pool and vdev states as numeric codes (zfs_pool_health, zfs_vdev_health), as one series per state (zfs_pool_state, zfs_vdev_state), or both. One of: [numeric, one-hot, both]").Default("both").Enum(collector.StateFormats...)
+		firstScrape             = kingpin.Flag("collector.first-scrape", "Serving of scrapes following startup. Collect runs the first collection on the first scrape, block collects at startup and holds scrapes until that collection completes or the warm-up timeout passes, and serve collects at startup and serves whatever has been collected so far, alongside zfs_exporter_warming_up. One of: [collect, block, serve]").Default("collect").Enum(collector.FirstScrapeModes...)
+		warmupTimeout           = kingpin.Flag("collector.warmup-timeout", "Maximum duration that a scrape is held awaiting the collection at startup with --collector.first-scrape=block, after which the metrics collected so far are served. Zero waits indefinitely.").Default("1m").Duration()
+		disableDefaults         = kingpin.Flag("collector.disable-defaults", "Set all collectors to disabled by default, such that only those explicitly enabled are run.").Default("false").Bool()
 		pools                   = kingpin.Flag("pool", "Name of the pool(s) to collect, repeat for multiple pools (default: all pools).").Strings()
 		excludes                = kingpin.Flag("exclude", "Exclude datasets/snapshots/volumes that match the provided regex (e.g. '^rpool/docker/'), may be specified multiple times.").Strings()
+		poolIncludes            = kingpin.Flag("zfs.pool-include", "Only collect pools that match the provided regex, may be specified multiple times.").Strings()
+		poolExcludes            = kingpin.Flag("zfs.pool-exclude", "Exclude pools that match the provided regex, may be specified multiple times.").Strings()
+		datasetExcludes         = kingpin.Flag("zfs.dataset-exclude", "Exclude datasets/snapshots/volumes that match the provided regex, equivalent to --exclude. May be specified multiple times.").Strings()
+		kstatPath               = kingpin.Flag("zfs.kstat-path", "Path to the ZFS kstats.").Default(zfs.DefaultKstatPath).String()
+		commandTimeout          = kingpin.Flag("zfs.command-timeout", "Maximum duration of each zpool/zfs command, after which the command and any children are killed. Zero disables the limit.").Default("1m").Duration()
+		statusConcurrency       = kingpin.Flag("zfs.status-concurrency", "Maximum number of pools queried concurrently by zpool status, so that a slow or suspended pool does not delay the others. One queries all pools with a single command.").Default("4").Int()
+		iostatInterval          = kingpin.Flag("zfs.iostat-interval", "Interval over which zpool iostat samples the I/O rates and latencies of pools, which must be shorter than --zfs.command-timeout. Zero averages them since the pools were imported.").Default("1s").Duration()
+		cacheTTL                = kingpin.Flag("zfs.cache-ttl", "Duration for which the results of zpool/zfs commands are reused by subsequent scrapes. Zero disables caching.").Default("0s").Duration()
+		backend                 = kingpin.Flag("zfs.backend", "Backend reading filesystems and volumes. Cli runs zfs, lzc runs a read-only channel program in-process via libzfs_core, which requires a build with the lzc tag. One of: [cli, lzc]").Default(string(zfs.BackendCLI)).Enum(zfs.Backends...)
+		zpoolPath               = kingpin.Flag("zfs.zpool-path", "Path to the zpool binary (default: resolved via PATH).").String()
+		zfsPath                 = kingpin.Flag("zfs.zfs-path", "Path to the zfs binary (default: resolved via PATH).").String()
+		useSudo                 = kingpin.Flag("zfs.use-sudo", "Execute zpool/zfs non-interactively via the --zfs.sudo-path wrapper, allowing the exporter to run as an unprivileged user.").Default("false").Bool()
+		sudoPath                = kingpin.Flag("zfs.sudo-path", "Privilege escalation wrapper used when --zfs.use-sudo is set, e.g. sudo or doas.").Default("sudo").String()
+		hostRoot                = kingpin.Flag("zfs.host-root", "Mount point of the root filesystem of the host, into which zpool/zfs are chrooted when running in a container, e.g. /host.").String()
+		hostNamespaces          = kingpin.Flag("zfs.host-namespaces", "Run zpool/zfs in the namespaces of the host when running in a container, via nsenter -t 1 -m -u -n -i. Requires the PID namespace of the host and CAP_SYS_ADMIN.").Default("false").Bool()
+		goMaxProcs              = kingpin.Flag("runtime.gomaxprocs", "The target number of CPUs the Go runtime will run on (GOMAXPROCS). Zero derives the value from the cgroup CPU quota, unless GOMAXPROCS is set.").Default("0").Int()
+		goMemLimit              = kingpin.Flag("runtime.gomemlimit", "Soft memory limit for the Go runtime (GOMEMLIMIT), e.g. 512MiB, or 'auto' for 90% of the cgroup memory limit. Unset leaves the limit unchanged.").String()
+		readOnly                = kingpin.Flag("read-only", "Do not run commands that modify system state, such as remediation hooks, logging the intended action instead.").Default("true").Bool()
+		faultLED                = kingpin.Flag("remediation.fault-led", "Light the enclosure fault LED of devices that become FAULTED, and clear it once they recover. Commands are only logged unless --no-read-only is set.").Default("false").Bool()
+		faultLEDSet             = kingpin.Flag("remediation.fault-led.set-command", "Command that lights the fault LED of the device at {path}, e.g. a sg_ses wrapper.").Default("ledctl failure={path}").String()
+		faultLEDClear           = kingpin.Flag("remediation.fault-led.clear-command", "Command that clears the fault LED of the device at {path}.").Default("ledctl normal={path}").String()
+		suspendedPool           = kingpin.Flag("remediation.suspended-pool", "Run a command for pools whose I/O has been suspended, e.g. following a transient loss of SAN paths. Commands are only logged unless --no-read-only is set.").Default("false").Bool()
+		suspendedPoolCommand    = kingpin.Flag("remediation.suspended-pool.command", "Command run for the suspended pool {pool}.").Default("zpool clear {pool}").String()
+		suspendedPoolCooldown   = kingpin.Flag("remediation.suspended-pool.cooldown", "Minimum interval between runs of the command for the same pool.").Default("15m").Duration()
+		suspendedPoolAttempts   = kingpin.Flag("remediation.suspended-pool.max-attempts", "Number of times the command is run while a pool remains suspended, after which the pool is left for an operator.").Default("3").Int()
+		loadKey                 = kingpin.Flag("remediation.load-key", "Load the keys of encrypted datasets whose key is unavailable and stored in a file or at a URL. Commands are only logged unless --no-read-only is set.").Default("false").Bool()
+		loadKeyCooldown         = kingpin.Flag("remediation.load-key.cooldown", "Minimum interval between attempts to load the key of the same dataset.").Default("15m").Duration()
+		adaptiveMax             = kingpin.Flag("background.max-stretch", "Maximum factor by which the intervals of background collection, such as events and history, are stretched while ZFS is under load, as indicated by slow commands or a resilver. One disables stretching.").Default("1").Float64()
+		adaptiveSlow            = kingpin.Flag("background.slow-command", "Average command duration beyond which the intervals of background collection are stretched in proportion.").Default("5s").Duration()
+		events                  = kingpin.Flag("zfs.events", "Count the events reported by zpool events, polling in the background.").Default("false").Bool()
+		eventsInterval          = kingpin.Flag("zfs.events-interval", "Interval at which zpool events is polled for new events.").Default("30s").Duration()
+		maintenanceWindows      = kingpin.Flag("maintenance.window", "Start a maintenance window at startup, as [POOL:]DURATION, for the whole host if the pool is omitted, e.g. tank:2h. May be specified multiple times.").Strings()
+		maintenanceAPI          = kingpin.Flag("web.maintenance-api", "Enable the /api/v1/maintenance endpoint for starting and ending maintenance windows.").Default("false").Bool()
+		sendEstimateAPI         = kingpin.Flag("web.send-estimate-api", "Enable the /api/v1/send-estimate endpoint, which estimates the size of a zfs send stream with a dry run.").Default("false").Bool()
+		metadataAPI             = kingpin.Flag("web.metrics-metadata-api", "Enable the /api/v1/metrics-metadata endpoint, which lists the type, help, labels and collector of every metric served.").Default("false").Bool()
+		resolveAPI              = kingpin.Flag("web.resolve-api", "Enable the /api/v1/resolve endpoint, which resolves pool and vdev GUIDs to their current names.").Default("false").Bool()
+		sendEstimateInterval    = kingpin.Flag("replication.estimate-interval", "Interval at which the next send of each replication pair in the configuration file is estimated.").Default("1h").Duration()
+		maintenanceSuppress     = kingpin.Flag("maintenance.suppress-remediation", "Defer remediation hooks, such as fault LEDs, for pools in a maintenance window.").Default("false").Bool()
+		remediationInterval     = kingpin.Flag("remediation.interval", "Interval at which remediation hooks check the state of ZFS.").Default("1m").Duration()
+		profileThreshold        = kingpin.Flag("debug.slow-scrape-threshold", "Capture a CPU profile of scrapes that run for longer than this duration, retrievable under /debug/profiles/. Zero disables profiling.").Default("0s").Duration()
+		profileRetain           = kingpin.Flag("debug.slow-scrape-profiles", "Number of slow scrape profiles to retain.").Default("5").Int()
+		enablePprof             = kingpin.Flag("web.enable-pprof", "Serve the Go runtime profiles of the exporter under /debug/pprof/.").Default("false").Bool()
+		statusPage              = kingpin.Flag("web.status-page", "Serve sparklines of the last six hours of selected metrics of each pool under /status, for hosts without a time series database.").Default("false").Bool()
+		statusMetrics           = kingpin.Flag("web.status-metric", "Name of a metric with a pool label to show on the status page, may be specified multiple times.").Default("zfs_pool_allocated_bytes", "zfs_pool_fragmentation_ratio", "zfs_dataset_read_bytes_total", "zfs_dataset_written_bytes_total").Strings()
+		historyFile             = kingpin.Flag("history.file", "Record selected metrics to this file, and serve them under /api/v1/history, for hosts without a time series database. Empty disables history.").String()
+		historyMetrics          = kingpin.Flag("history.metric", "Name of a metric to record in the history, may be specified multiple times.").Default("zfs_pool_allocated_bytes", "zfs_pool_size_bytes", "zfs_pool_health").Strings()
+		historyInterval         = kingpin.Flag("history.interval", "Interval at which metrics are recorded in the history.").Default("5m").Duration()
+		historyRetention        = kingpin.Flag("history.retention", "Duration for which samples are retained in the history.").Default("720h").Duration()
+		enableProbe             = kingpin.Flag("web.enable-probe", "Enable the /probe endpoint, which collects the metrics of the remote host given by the target parameter over ssh.").Default("false").Bool()
+		probeSSHPath            = kingpin.Flag("probe.ssh-path", "Path to the ssh client used for probes.").Default("ssh").String()
+		probeSSHIdentityFile    = kingpin.Flag("probe.ssh-identity-file", "Private key used to authenticate probes, overridden by the identity_file of hosts in ssh_hosts. Uses the defaults of the ssh client if unset.").Default("").String()
+		probeSSHOptions         = kingpin.Flag("probe.ssh-option", "Option passed to ssh with -o for probes, e.g. ConnectTimeout=10. May be specified multiple times.").Strings()
+		probeTargets            = kingpin.Flag("probe.allowed-target", "Regex that probe targets must match, may be specified multiple times. Either this or ssh_hosts is required by --web.enable-probe, use '.+' to allow all targets.").Strings()
+		replayFile              = kingpin.Flag("zfs.replay", "Serve metrics from a fixture bundle written by the record command rather than running zpool/zfs, e.g. to reproduce the output of another ZFS version.").String()
+		textfileDirectory       = kingpin.Flag("output.textfile-directory", "Write metrics to zfs_exporter.prom in this directory for the node_exporter textfile collector, instead of serving them over HTTP. Go runtime and process metrics are omitted, as node_exporter exports its own.").String()
+		textfileInterval        = kingpin.Flag("output.textfile-interval", "Interval at which the textfile is rewritten.").Default("1m").Duration()
+		configFile              = kingpin.Flag("config.file", "Path to a YAML configuration file. Flags set on the command line take precedence over the file.").String()
 		toolkitFlags            = kingpinflag.AddFlags(kingpin.CommandLine, ":9134")
+
+		_                = kingpin.Command("serve", "Serve metrics (default).").Default()
+		bundleCommand    = kingpin.Command("bundle", "Collect command output, parsed status, metrics, flags and logs into a tarball for attaching to bug reports.")
+		bundleOutput     = bundleCommand.Flag("output", "Path of the tarball to write (default: zfs_exporter-bundle-<timestamp>.tar.gz in the working directory).").Short('o').String()
+		onceCommand      = kingpin.Command("once", "Collect metrics once and write them to stdout, exiting non-zero if any collector failed.")
+		recordCommand    = kingpin.Command("record", "Record the output of every zpool/zfs command and the kstats read while collecting metrics into a fixture bundle, which may be replayed with --zfs.replay.")
+		recordOutput     = recordCommand.Flag("output", "Path of the fixture bundle to write (default: zfs_exporter-fixture-<timestamp>.tar.gz in the working directory).").Short('o').String()
+		inventoryCommand = kingpin.Command("inventory", "Export pools, vdevs, datasets and snapshots along with their properties as CSV, for capacity planning.")
+		inventoryOutput  = inventoryCommand.Flag("output", "Path of the CSV file to write (default: stdout).").Short('o').String()
+		diffCommand      = kingpin.Command("diff", "Report the changes between two inventory exports, such as new datasets, capacity growth and topology changes.")
+		diffFrom         = diffCommand.Flag("from", "Path of the earlier inventory export.").Required().String()
+		diffTo           = diffCommand.Flag("to", "Path of the later inventory export, or 'live' to compare with the current state of the host.").Default(diffLive).String()
 	)
 
 	promslogConfig := &promslog.Config{}
 	flag.AddFlags(kingpin.CommandLine, promslogConfig)
+	logOutput := kingpin.Flag("log.output", "Destination of log messages. One of: [stderr, file, syslog]").Default(logOutputStderr).Enum(logOutputStderr, logOutputFile, logOutputSyslog)
+	logFile := kingpin.Flag("log.file", "Path of the file to which log messages are appended with --log.output=file.").String()
 	kingpin.Version(version.Print("zfs_exporter"))
 	kingpin.HelpFlag.Short('h')
-	kingpin.Parse()
-	logger := promslog.New(promslogConfig)
+	command := kingpin.Parse()
+	bundling := command == bundleCommand.FullCommand()
+	recording := command == recordCommand.FullCommand()
+
+	logOut, err := logWriter(*logOutput, *logFile)
+	if err != nil {
+		kingpin.Fatalf("error opening log output: %s", err)
+	}
+	promslogConfig.Writer = logOut
+	// Bundles include the debug log of their own collection, regardless of --log.level
+	var bundleLog bytes.Buffer
+	var handler slog.Handler = promslog.New(promslogConfig).Handler()
+	if bundling {
+		handler = teeHandler{handler, slog.NewTextHandler(&bundleLog, &slog.HandlerOptions{Level: slog.LevelDebug})}
+	}
+	logHandler := newCountingHandler(handler)
+	logger := slog.New(logHandler)
 
-	logger.Info("Starting zfs_exporter", "version", version.Info())
 	logger.Info("Build context", "context", version.BuildContext())
 
+	cg, err := newCgroup(cgroupRoot, procCgroup)
+	if err != nil {
+		logger.Debug("Unable to determine cgroup", "err", err)
+	}
+	if err = applyRuntimeSettings(logger, cg, *goMaxProcs, *goMemLimit); err != nil {
+		logger.Error("Error applying runtime settings", "err", err)
+		os.Exit(1)
+	}
+
+	// Saved inventories may be compared without running any commands
+	diffing := command == diffCommand.FullCommand()
+	if diffing && *diffTo != diffLive {
+		if err = diffFiles(*diffFrom, *diffTo); err != nil {
+			logger.Error("Error comparing inventories", "err", err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
+	if *disableDefaults {
+		collector.DisableDefaultCollectors()
+	}
+	if err = collector.SetStateFormat(collector.StateFormat(*stateFormat)); err != nil {
+		logger.Error("Error setting state format", "err", err)
+		os.Exit(1)
+	}
+
+	var (
+		policies   []*policy.Policy
+		writable   []string
+		poolLabels []config.PoolLabels
+		pairs      []collector.ReplicationPair
+		sshHosts   = make(map[string]config.SSHHost)
+	)
+	if *configFile != "" {
+		cfg, err := config.Load(*configFile)
+		if err != nil {
+			logger.Error("Error loading config file", "err", err)
+			os.Exit(1)
+		}
+		explicit, err := explicitFlags(kingpin.CommandLine, os.Args[1:])
+		if err != nil {
+			logger.Error("Error parsing flags", "err", err)
+			os.Exit(1)
+		}
+		if policies, err = policy.CompileAll(cfg.HealthPolicies); err != nil {
+			logger.Error("Error compiling health policies", "file", *configFile, "err", err)
+			os.Exit(1)
+		}
+		// Policies and the health score enable their collectors before the collectors section of the file is applied,
+		// which may disable them
+		if err = collector.ConfigureHealthPolicies(policies); err != nil {
+			logger.Error("Error configuring health policies", "file", *configFile, "err", err)
+			os.Exit(1)
+		}
+		if hs := cfg.HealthScore; hs != nil {
+			if err = collector.ConfigureHealthScore(collector.HealthScoreConfig{
+				Weights:           hs.Weights,
+				CapacityThreshold: hs.CapacityThreshold,
+				ErrorThreshold:    hs.ErrorThreshold,
+				ScrubMaxAge:       hs.ScrubMaxAge,
+			}); err != nil {
+				logger.Error("Error configuring health score", "file", *configFile, "err", err)
+				os.Exit(1)
+			}
+		}
+		if err = applyConfig(cfg, settings{
+			listenAddresses: toolkitFlags.WebListenAddresses,
+			webConfigFile:   toolkitFlags.WebConfigFile,
+			pools:           pools,
+			excludes:        excludes,
+			deadline:        deadline,
+			commandTimeout:  commandTimeout,
+			cacheTTL:        cacheTTL,
+		}, explicit); err != nil {
+			logger.Error("Error applying config file", "file", *configFile, "err", err)
+			os.Exit(1)
+		}
+		writable = cfg.Writable
+		poolLabels = cfg.PoolLabels
+		for _, r := range cfg.Replication {
+			pairs = append(pairs, collector.ReplicationPair{Name: r.Name, From: r.From, Snapshot: r.Snapshot})
+		}
+		for _, h := range cfg.SSHHosts {
+			sshHosts[h.Host] = h
+		}
+		logger.Info("Loaded config file", "file", *configFile, "version", cfg.Version)
+	}
+
+	// Validate the web configuration before running any commands, rather than once the listener is started
+	if err = web.Validate(*toolkitFlags.WebConfigFile); err != nil {
+		logger.Error("Invalid web config file", "file", *toolkitFlags.WebConfigFile, "err", err)
+		os.Exit(1)
+	}
+
+	if *hostRoot != "" && *hostNamespaces {
+		logger.Error("--zfs.host-root and --zfs.host-namespaces are mutually exclusive")
+		os.Exit(1)
+	}
+	if zfs.Backend(*backend) == zfs.BackendLZC {
+		if !zfs.LZCSupported() {
+			logger.Error("--zfs.backend=lzc requires a build with the lzc tag")
+			os.Exit(1)
+		}
+		if *replayFile != "" {
+			logger.Error("--zfs.backend=lzc cannot be combined with --zfs.replay")
+			os.Exit(1)
+		}
+		// libzfs_core runs in the exporter process, so it cannot be wrapped by sudo or moved into the host
+		if *useSudo || *hostRoot != "" || *hostNamespaces {
+			logger.Error("--zfs.backend=lzc cannot be combined with --zfs.use-sudo, --zfs.host-root or --zfs.host-namespaces")
+			os.Exit(1)
+		}
+	}
+	commandRunner := zfs.CommandRunner{
+		Runner: zfs.HostRunner{Runner: zfs.ExecRunner{}, Root: *hostRoot, Nsenter: *hostNamespaces},
+		Paths:  map[string]string{`zpool`: *zpoolPath, `zfs`: *zfsPath},
+	}
+	if *useSudo {
+		commandRunner.Sudo = *sudoPath
+	}
+	if *replayFile != "" {
+		replay, replayKstats, err := readFixtureFile(*replayFile)
+		if err != nil {
+			logger.Error("Error reading fixture bundle", "err", err)
+			os.Exit(1)
+		}
+		// Commands were recorded before path resolution and sudo, so are replayed as run by the collectors
+		commandRunner = zfs.CommandRunner{Runner: replay}
+		*kstatPath = replayKstats
+		logger.Warn("Replaying fixture bundle, metrics do not reflect this host", "file", *replayFile, "kstat_path", replayKstats)
+	}
+	var runner zfs.Runner = commandRunner
+	recorder := &recordingRunner{Runner: commandRunner}
+	bundle := bundleContents{
+		recorder:   recorder,
+		flags:      bundleFlags(kingpin.CommandLine),
+		configFile: *configFile,
+		kstatPath:  *kstatPath,
+		log:        &bundleLog,
+	}
+	// finishBundle writes whatever has been collected, so that bundles are produced even where collection fails
+	finishBundle := func(code int) {
+		path, err := writeBundleFile(*bundleOutput, bundle, time.Now())
+		if err != nil {
+			logger.Error("Error writing bundle", "err", err)
+			os.Exit(1)
+		}
+		logger.Info("Wrote bundle", "path", path)
+		os.Exit(code)
+	}
+	// finishRecord writes the commands recorded and the kstats of the pools found, so that fixtures are produced even
+	// where collection fails, as with ZFS versions whose output is not understood
+	var recordPools []string
+	finishRecord := func(code int) {
+		path, err := writeFixtureFile(*recordOutput, recorder, *kstatPath, recordPools, time.Now())
+		if err != nil {
+			logger.Error("Error writing fixture bundle", "err", err)
+			os.Exit(1)
+		}
+		logger.Info("Wrote fixture bundle", "path", path)
+		os.Exit(code)
+	}
+	if bundling || recording {
+		runner = recorder
+	}
+	if *iostatInterval < 0 || *commandTimeout > 0 && *iostatInterval >= *commandTimeout {
+		logger.Error("Invalid iostat interval, must be at least zero and shorter than the command timeout", "interval", *iostatInterval, "command_timeout", *commandTimeout)
+		os.Exit(1)
+	}
+	if *eventsInterval <= 0 {
+		logger.Error("Invalid events interval, must be greater than zero", "interval", *eventsInterval)
+		os.Exit(1)
+	}
+	if *remediationInterval <= 0 {
+		logger.Error("Invalid remediation interval, must be greater than zero", "interval", *remediationInterval)
+		os.Exit(1)
+	}
+	if *historyInterval <= 0 || *historyRetention <= 0 {
+		logger.Error("Invalid history interval or retention, both must be greater than zero", "interval", *historyInterval, "retention", *historyRetention)
+		os.Exit(1)
+	}
+	if *textfileInterval <= 0 {
+		logger.Error("Invalid textfile interval, must be greater than zero", "interval", *textfileInterval)
+		os.Exit(1)
+	}
+	if *adaptiveMax < 1 {
+		logger.Error("Invalid background interval stretch, must be at least one", "max_stretch", *adaptiveMax)
+		os.Exit(1)
+	}
+	intervals := newAdaptiveIntervals(logger, runner, *adaptiveMax, *adaptiveSlow)
+	runner = intervals
+
 	// ZFS Version
-	zfs_version, err := zfs.GetZFSVersionViaJSON(logger)
+	ctx, cancel := zfs.CommandContext(*commandTimeout)
+	zfs_version, capabilities, err := zfs.DetectCapabilities(ctx, runner, logger)
+	cancel()
 	if err != nil {
 		logger.Error("Error getting ZFS version", "err", err)
+		if bundling {
+			finishBundle(7)
+		}
+		if recording {
+			finishRecord(7)
+		}
 		os.Exit(7)
 	}
-	logger.Info("ZFS Version", "version", *zfs_version)
+	bundle.version, bundle.capabilities = zfs_version, capabilities
 
 	// Pool Status
-	pool_name_status_map, err := zfs.ZpoolStatusViaJSON(logger)
-	if err != nil {
+	ctx, cancel = zfs.CommandContext(*commandTimeout)
+	pool_name_status_map, err := zfs.ZpoolStatus(ctx, runner, logger, capabilities, *statusConcurrency)
+	cancel()
+	if err != nil && pool_name_status_map == nil {
 		logger.Error("Error getting pool status", "err", err)
+		if bundling {
+			finishBundle(8)
+		}
+		if recording {
+			finishRecord(8)
+		}
 		os.Exit(8)
 	}
+	bundle.status = *pool_name_status_map
+	for pool := range *pool_name_status_map {
+		recordPools = append(recordPools, pool)
+	}
+	if err != nil {
+		logger.Warn("Error getting status of some pools", "err", err)
+	}
 	logger.Debug("Num Pools", "num_pools", len(*pool_name_status_map))
 	for pool_name, pool_status := range *pool_name_status_map {
+		if class := pool_status.Classify(); class != zfs.StatusOK {
+			logger.Warn("Pool requires attention", "pool", pool_name, "class", class, "msgid", pool_status.Msgid, "status", pool_status.Status)
+		}
 		logger.Debug("Pool Name", "name", pool_name)
 		logger.Debug("Pool Vdevs", "num_vdevs", len(pool_status.Vdevs))
 		logger.Debug("Pool Status", "status", pool_status)
@@ -66,31 +396,228 @@ func main() {
 		}
 	}
 
+	windows := newMaintenance(logger)
+	for _, spec := range *maintenanceWindows {
+		pool, duration, err := parseMaintenanceWindow(spec)
+		if err != nil {
+			logger.Error("Error parsing maintenance window", "err", err)
+			os.Exit(1)
+		}
+		windows.start(pool, duration)
+	}
+
+	zfsClient := zfs.New(zfs.Config{Logger: logger, KstatPath: *kstatPath, CommandTimeout: *commandTimeout, Runner: runner, CacheTTL: *cacheTTL, Backend: zfs.Backend(*backend), StatusConcurrency: *statusConcurrency, TextOutput: zfs.TextOutputRequired(capabilities), IostatInterval: *iostatInterval})
+	intervals.status = func() (map[string]zfs.PoolStatusT, error) { return zfsClient.PoolStatus(false) }
+
+	if command == inventoryCommand.FullCommand() || diffing {
+		if missing := capabilities.Missing(zfs.CapabilityJSON, zfs.CapabilityJSONInt); len(missing) > 0 {
+			logger.Error("Inventory export requires JSON output from zpool and zfs", "missing", missing)
+			os.Exit(1)
+		}
+		inv := inventory{client: zfsClient, status: *pool_name_status_map, pools: *pools}
+		if diffing {
+			if err = diffLiveInventory(*diffFrom, inv); err != nil {
+				logger.Error("Error comparing inventories", "err", err)
+				os.Exit(1)
+			}
+			os.Exit(0)
+		}
+		if err = writeInventoryFile(*inventoryOutput, inv, time.Now()); err != nil {
+			logger.Error("Error writing inventory", "err", err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
+	if len(policies) > 0 && !capabilities.Has(zfs.CapabilityJSON) {
+		logger.Error("Health policies require JSON output from zpool status")
+		os.Exit(1)
+	}
+
+	// Only serving collects at startup, the once, record and bundle commands collect once and exit
+	serveFirstScrape := collector.FirstScrape(*firstScrape)
+	if command == onceCommand.FullCommand() || recording || bundling {
+		serveFirstScrape = collector.FirstScrapeCollect
+	}
 	c, err := collector.NewZFS(collector.ZFSConfig{
-		DisableMetrics: *metricsExporterDisabled,
-		Deadline:       *deadline,
-		Pools:          *pools,
-		Excludes:       *excludes,
-		Logger:         logger,
-		ZFSClient:      zfs.New(),
+		// The once command determines its exit status from zfs_exporter_collector_success
+		DisableMetrics:   *metricsExporterDisabled && command != onceCommand.FullCommand(),
+		Deadline:         *deadline,
+		Pools:            *pools,
+		Excludes:         append(*excludes, *datasetExcludes...),
+		PoolIncludes:     *poolIncludes,
+		PoolExcludes:     *poolExcludes,
+		Logger:           logger,
+		CircuitThreshold: *circuitThreshold,
+		CircuitCooldown:  *circuitCooldown,
+		Schedules:        *schedules,
+		Capabilities:     capabilities,
+		ZFSClient:        zfsClient,
+		FirstScrape:      serveFirstScrape,
+		WarmupTimeout:    *warmupTimeout,
 	})
 	if err != nil {
 		logger.Error("Error creating an exporter", "err", err)
 		os.Exit(1)
 	}
 
-	if *metricsExporterDisabled {
+	if bundling {
+		registry := prometheus.NewRegistry()
+		registry.MustRegister(c)
+		bundle.gatherer = registry
+		finishBundle(0)
+	}
+	if command == onceCommand.FullCommand() {
+		registry := prometheus.NewRegistry()
+		registry.MustRegister(c)
+		failed, err := writeOnce(os.Stdout, registry)
+		if err != nil {
+			logger.Error("Error writing metrics", "err", err)
+			os.Exit(1)
+		}
+		if len(failed) > 0 {
+			logger.Error("Collectors failed", "collectors", failed)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+	if recording {
+		// Metrics are gathered only to run the commands of every enabled collector
+		registry := prometheus.NewRegistry()
+		registry.MustRegister(c)
+		if _, err = registry.Gather(); err != nil {
+			logger.Warn("Error collecting metrics", "err", err)
+		}
+		finishRecord(0)
+	}
+
+	if *faultLED {
+		if !capabilities.Has(zfs.CapabilityJSON) {
+			logger.Error("Fault LED remediation requires JSON output from zpool status")
+			os.Exit(1)
+		}
+		faultLEDConfig := remediation.FaultLEDConfig{
+			Logger:         logger.With("hook", "fault-led"),
+			Client:         zfsClient,
+			Runner:         runner,
+			SetCommand:     *faultLEDSet,
+			ClearCommand:   *faultLEDClear,
+			ReadOnly:       *readOnly,
+			CommandTimeout: *commandTimeout,
+		}
+		if *maintenanceSuppress {
+			faultLEDConfig.Suppressed = windows.active
+		}
+		hook, err := remediation.NewFaultLED(faultLEDConfig)
+		if err != nil {
+			logger.Error("Error creating fault LED hook", "err", err)
+			os.Exit(1)
+		}
+		go hook.Run(context.Background(), *remediationInterval)
+	}
+	if *suspendedPool {
+		suspendedPoolConfig := remediation.SuspendedPoolConfig{
+			Logger:         logger.With("hook", "suspended-pool"),
+			Client:         zfsClient,
+			Runner:         runner,
+			Command:        *suspendedPoolCommand,
+			ReadOnly:       *readOnly,
+			CommandTimeout: *commandTimeout,
+			Cooldown:       *suspendedPoolCooldown,
+			MaxAttempts:    *suspendedPoolAttempts,
+		}
+		if *maintenanceSuppress {
+			suspendedPoolConfig.Suppressed = windows.active
+		}
+		hook, err := remediation.NewSuspendedPool(suspendedPoolConfig)
+		if err != nil {
+			logger.Error("Error creating suspended pool hook", "err", err)
+			os.Exit(1)
+		}
+		go hook.Run(context.Background(), *remediationInterval)
+	}
+	var loadKeyHook *remediation.LoadKey
+	if *loadKey {
+		if missing := capabilities.Missing(zfs.CapabilityJSON, zfs.CapabilityJSONInt); len(missing) > 0 {
+			logger.Error("Loading encryption keys requires JSON output from zfs get", "missing", missing)
+			os.Exit(1)
+		}
+		loadKeyConfig := remediation.LoadKeyConfig{
+			Logger:         logger.With("hook", "load-key"),
+			Client:         zfsClient,
+			Runner:         runner,
+			ReadOnly:       *readOnly,
+			CommandTimeout: *commandTimeout,
+			Cooldown:       *loadKeyCooldown,
+		}
+		if *maintenanceSuppress {
+			loadKeyConfig.Suppressed = windows.active
+		}
+		loadKeyHook, err = remediation.NewLoadKey(loadKeyConfig)
+		if err != nil {
+			logger.Error("Error creating load key hook", "err", err)
+			os.Exit(1)
+		}
+		go loadKeyHook.Run(context.Background(), *remediationInterval)
+	}
+
+	// The node_exporter textfile collector rejects metrics that node_exporter exports itself, such as go_*
+	if *metricsExporterDisabled || *textfileDirectory != "" {
 		r := prometheus.NewRegistry()
 		prometheus.DefaultRegisterer = r
 		prometheus.DefaultGatherer = r
 	}
 	prometheus.MustRegister(c)
+	if serveFirstScrape != collector.FirstScrapeCollect {
+		go c.Warmup()
+	}
 	prometheus.MustRegister(versioncollector.NewCollector("zfs_exporter"))
-
-	if len(c.Pools) > 0 {
-		logger.Info("Enabling pools", "pools", strings.Join(c.Pools, ", "))
-	} else {
-		logger.Info("Enabling pools", "pools", "(all)")
+	prometheus.MustRegister(windows)
+	prometheus.MustRegister(intervals)
+	if loadKeyHook != nil {
+		prometheus.MustRegister(loadKeyHook)
+	}
+	if !*metricsExporterDisabled {
+		prometheus.MustRegister(collector.NewSubprocessCollector())
+		prometheus.MustRegister(logHandler)
+	}
+	if len(writable) > 0 {
+		if missing := capabilities.Missing(zfs.CapabilityJSON, zfs.CapabilityJSONInt); len(missing) > 0 {
+			logger.Error("Read-only dataset detection requires JSON output from zpool and zfs", "missing", missing)
+			os.Exit(1)
+		}
+		readonlyCollector, err := collector.NewReadonlyCollector(logger, zfsClient, writable)
+		if err != nil {
+			logger.Error("Error creating read-only dataset collector", "err", err)
+			os.Exit(1)
+		}
+		prometheus.MustRegister(readonlyCollector)
+	}
+	if *events {
+		eventsCollector := collector.NewEventsCollector(logger.With("collector", "events"), zfsClient)
+		prometheus.MustRegister(eventsCollector)
+		go eventsCollector.Run(context.Background(), intervals.interval("events", *eventsInterval))
+	}
+	if len(pairs) > 0 {
+		sendEstimates := collector.NewSendEstimateCollector(logger.With("collector", "send-estimate"), zfsClient, pairs)
+		prometheus.MustRegister(sendEstimates)
+		go sendEstimates.Run(context.Background(), intervals.interval("send-estimate", *sendEstimateInterval))
+	}
+	if len(poolLabels) > 0 {
+		gatherer, err := newPoolLabelGatherer(prometheus.DefaultGatherer, poolLabels)
+		if err != nil {
+			logger.Error("Error compiling pool labels", "file", *configFile, "err", err)
+			os.Exit(1)
+		}
+		prometheus.DefaultGatherer = gatherer
+	}
+	var hist *history
+	if *historyFile != "" {
+		if hist, err = newHistory(logger.With("component", "history"), prometheus.DefaultGatherer, *historyFile, *historyMetrics, *historyRetention); err != nil {
+			logger.Error("Error loading history", "file", *historyFile, "err", err)
+			os.Exit(1)
+		}
+		go hist.Run(context.Background(), intervals.interval("history", *historyInterval))
 	}
 
 	collectorNames := make([]string, 0, len(c.Collectors))
@@ -99,30 +626,126 @@ func main() {
 			collectorNames = append(collectorNames, n)
 		}
 	}
-	logger.Info("Enabling collectors", "collectors", strings.Join(collectorNames, ", "))
+	sort.Strings(collectorNames)
+	logger.Info("Starting zfs_exporter", "summary", startupSummary{
+		zfsVersion:      *zfs_version,
+		capabilities:    capabilities,
+		collectors:      collectorNames,
+		pools:           c.Pools,
+		configHash:      configHash(kingpin.CommandLine),
+		listenAddresses: *toolkitFlags.WebListenAddresses,
+		systemdSocket:   *toolkitFlags.WebSystemdSocket,
+	})
 
-	http.Handle(*metricsPath, promhttp.Handler())
+	if *textfileDirectory != "" {
+		textfile, err := newTextfileWriter(logger.With("component", "textfile"), prometheus.DefaultGatherer, *textfileDirectory)
+		if err != nil {
+			logger.Error("Error creating textfile writer", "err", err)
+			os.Exit(1)
+		}
+		textfile.Run(context.Background(), intervals.interval("textfile", *textfileInterval))
+		return
+	}
+
+	mux := http.NewServeMux()
+	var metrics http.Handler = &metricsHandler{
+		handler:     newPromHandler(logger),
+		generation:  c.Generation,
+		updated:     c.Updated,
+		conditional: *conditionalRequests,
+	}
+	if *profileThreshold > 0 {
+		profiler := newSlowScrapeProfiler(logger, *profileThreshold, *profileRetain)
+		metrics = profiler.wrap(metrics)
+		mux.Handle(profilesPath, profiler)
+	}
+	mux.Handle(*metricsPath, metrics)
+	if *enablePprof {
+		registerPprof(mux)
+	}
+	if *maintenanceAPI {
+		mux.Handle("/api/v1/maintenance", windows)
+	}
+	if *sendEstimateAPI {
+		mux.Handle("/api/v1/send-estimate", sendEstimateHandler{logger: logger.With("component", "send-estimate"), client: zfsClient})
+	}
+	if *enableProbe {
+		if len(*probeTargets) == 0 && len(sshHosts) == 0 {
+			logger.Error("--web.enable-probe requires --probe.allowed-target or ssh_hosts, as it would otherwise connect to any target")
+			os.Exit(1)
+		}
+		allowed, err := compileProbeTargets(*probeTargets)
+		if err != nil {
+			logger.Error("Invalid probe target regex", "err", err)
+			os.Exit(1)
+		}
+		mux.Handle(probePath, probeHandler{
+			logger:  logger.With("component", "probe"),
+			allowed: allowed,
+			hosts:   sshHosts,
+			runner: func(target string) zfs.Runner {
+				// Commands are resolved via the PATH of the target, and run with its sudo where enabled
+				r := zfs.CommandRunner{Runner: probeSSHRunner(zfs.SSHRunner{
+					Runner:       zfs.ExecRunner{},
+					Path:         *probeSSHPath,
+					IdentityFile: *probeSSHIdentityFile,
+					Options:      *probeSSHOptions,
+				}, sshHosts, target)}
+				if *useSudo {
+					r.Sudo = *sudoPath
+				}
+				return r
+			},
+			commandTimeout: *commandTimeout,
+			iostatInterval: *iostatInterval,
+			// Each probe collects afresh, so schedules and circuit breakers, which depend on earlier runs, do not apply.
+			// All pools of the target are collected, subject to the pool filters.
+			collector: newProbeCollector(collector.ZFSConfig{
+				DisableMetrics: *metricsExporterDisabled,
+				Deadline:       *deadline,
+				Excludes:       append(*excludes, *datasetExcludes...),
+				PoolIncludes:   *poolIncludes,
+				PoolExcludes:   *poolExcludes,
+			}),
+		})
+	}
+	if *metadataAPI {
+		mux.Handle("/api/v1/metrics-metadata", metadataHandler{logger: logger.With("component", "metadata"), gatherer: prometheus.DefaultGatherer, origins: c.MetricOrigins})
+	}
+	if *resolveAPI {
+		mux.Handle("/api/v1/resolve", resolveHandler{logger: logger.With("component", "resolve"), client: zfsClient})
+	}
+	if hist != nil {
+		mux.Handle("/api/v1/history", hist)
+	}
+	landingLinks := []web.LandingLinks{
+		{
+			Address: *metricsPath,
+			Text:    "Metrics",
+		},
+	}
+	if *statusPage {
+		sparks := newSparklines(logger.With("component", "status"), prometheus.DefaultGatherer, *statusMetrics)
+		go sparks.Run(context.Background(), intervals.interval("status", sparklineResolution))
+		mux.Handle(statusPath, sparks)
+		landingLinks = append(landingLinks, web.LandingLinks{Address: statusPath, Text: "Status"})
+	}
 	if *metricsPath != "/" {
 		landingConfig := web.LandingConfig{
 			Name:        "ZFS Exporter",
 			Description: "Prometheus ZFS Exporter",
 			Version:     version.Info(),
-			Links: []web.LandingLinks{
-				{
-					Address: *metricsPath,
-					Text:    "Metrics",
-				},
-			},
+			Links:       landingLinks,
 		}
 		landingPage, err := web.NewLandingPage(landingConfig)
 		if err != nil {
 			logger.Error("Error creating landing page", "err", err)
 			os.Exit(1)
 		}
-		http.Handle("/", landingPage)
+		mux.Handle("/", landingPage)
 	}
 
-	server := &http.Server{}
+	server := &http.Server{Handler: mux}
 	err = web.ListenAndServe(server, toolkitFlags, logger)
 	if err != nil {
 		logger.Error("Error starting HTTP server", "err", err)